@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/config"
+	"github.com/ashwingopalsamy/transactions-service/internal/logging"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+)
+
+// closeCycles generates the statements of the billing cycles closing on --date, yesterday by default
+func closeCycles(args []string) error {
+	fs := newFlagSet("close-cycles")
+	date := fs.String("date", clock.System().Now().AddDate(0, 0, -1).Format(time.DateOnly), "closing date of the cycles to close, YYYY-MM-DD")
+
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return err
+	}
+
+	closingDate, err := time.Parse(time.DateOnly, *date)
+	if err != nil {
+		return fmt.Errorf("invalid --date %q: must be YYYY-MM-DD", *date)
+	}
+	if cfg.Storage == config.StorageMemory {
+		return errors.New("close-cycles requires postgres storage; in-memory data lives only in the serving process")
+	}
+
+	dbPool, err := InitDB(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer dbPool.Close()
+
+	ctx := context.Background()
+	if _, err := ensureSchema(ctx, dbPool, false); err != nil {
+		return err
+	}
+
+	repos := newPostgresRepositories(dbPool)
+	stmtService := service.NewStatementsService(repos.billing, repos.accounts, repos.transactions, repos.ledger, repos.audit, repos.transactor)
+
+	statements, err := stmtService.CloseCycles(ctx, closingDate)
+	for _, statement := range statements {
+		fmt.Printf("statement %d account %d %s..%s closing_balance %.2f due %s\n",
+			statement.ID, statement.AccountID, statement.PeriodStart, statement.PeriodEnd, statement.ClosingBalance, statement.DueDate)
+	}
+	fmt.Printf("closed %d billing cycles on %s\n", len(statements), closingDate.Format(time.DateOnly))
+	return err
+}
+
+// accrue charges the interest and late fees of --date, today by default, to every past-due account
+func accrue(args []string) error {
+	fs := newFlagSet("accrue")
+	date := fs.String("date", "", "accrual date, YYYY-MM-DD; today when empty")
+
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return err
+	}
+
+	accrualClock := clock.System()
+	if *date != "" {
+		accrualDate, err := time.Parse(time.DateOnly, *date)
+		if err != nil {
+			return fmt.Errorf("invalid --date %q: must be YYYY-MM-DD", *date)
+		}
+		accrualClock = clock.Fixed(accrualDate)
+	}
+	if cfg.Storage == config.StorageMemory {
+		return errors.New("accrue requires postgres storage; in-memory data lives only in the serving process")
+	}
+
+	dbPool, err := InitDB(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer dbPool.Close()
+
+	ctx := context.Background()
+	if _, err := ensureSchema(ctx, dbPool, false); err != nil {
+		return err
+	}
+
+	repos := newPostgresRepositories(dbPool)
+	// The job only posts charges, which are never checked for duplicates nor back-dated
+	trxService := service.NewTransactionsService(repos.transactions, repos.accounts, repos.ledger, repos.fees, repos.screening, repos.audit, repos.billing, repos.transactor, accrualClock, service.DuplicatePolicy{}, service.BackdatingPolicy{})
+	accrualService := service.NewAccrualsService(repos.accruals, repos.billing, repos.accounts, repos.transactions, trxService, repos.transactor, accrualClock)
+
+	runs, err := accrualService.Accrue(ctx)
+	for _, run := range runs {
+		for _, accrual := range run.Accruals {
+			fmt.Printf("account %d %s %.2f transaction %d\n", run.AccountID, accrual.Kind, accrual.Amount, accrual.TransactionID)
+		}
+	}
+	fmt.Printf("accrued %d accounts on %s\n", len(runs), accrualClock.Now().Format(time.DateOnly))
+	return err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/config"
+	"github.com/ashwingopalsamy/transactions-service/internal/logging"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+)
+
+// verifyChain recomputes the transaction hash chain of --account-id, or of every chained account, and
+// fails if any chain is broken
+func verifyChain(args []string) error {
+	fs := newFlagSet("verify-chain")
+	accountID := fs.Int64("account-id", 0, "account whose chain to verify; every chained account when 0")
+
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return err
+	}
+
+	if *accountID < 0 {
+		return fmt.Errorf("invalid --account-id %d: must not be negative", *accountID)
+	}
+	if cfg.Storage == config.StorageMemory {
+		return errors.New("verify-chain requires postgres storage; in-memory data lives only in the serving process")
+	}
+
+	dbPool, err := InitDB(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer dbPool.Close()
+
+	ctx := context.Background()
+	if _, err := ensureSchema(ctx, dbPool, false); err != nil {
+		return err
+	}
+
+	repos := newPostgresRepositories(dbPool)
+	integrityService := service.NewIntegrityService(repos.transactions, repos.accounts)
+
+	var reports []*service.ChainReport
+	if *accountID != 0 {
+		report, err := integrityService.VerifyChain(ctx, *accountID)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	} else if reports, err = integrityService.VerifyChains(ctx); err != nil {
+		return err
+	}
+
+	broken := 0
+	for _, report := range reports {
+		if report.Intact {
+			fmt.Printf("account %d intact length %d unchained %d head %s\n", report.AccountID, report.Length, report.Unchained, report.Head)
+			continue
+		}
+		broken++
+		fmt.Printf("account %d broken at transaction %d: %s expected %s actual %s\n",
+			report.AccountID, report.Break.TransactionID, report.Break.Reason, report.Break.Expected, report.Break.Actual)
+	}
+	fmt.Printf("verified %d chains, %d broken\n", len(reports), broken)
+	if broken > 0 {
+		return fmt.Errorf("%d transaction chains are broken", broken)
+	}
+	return nil
+}
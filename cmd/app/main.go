@@ -1,115 +1,135 @@
 package main
 
 import (
-	"context"
 	"errors"
-	"net/http"
+	"flag"
+	"fmt"
+	"io"
 	"os"
-	"os/signal"
-	"strconv"
-	"syscall"
-	"time"
-
-	"github.com/ashwingopalsamy/transactions-service/internal/handler"
-	"github.com/ashwingopalsamy/transactions-service/internal/repository"
-	"github.com/ashwingopalsamy/transactions-service/internal/service"
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
-)
-
-const defaultWebPort = 8080
 
-type EnvCfg struct {
-	DBUser     string
-	DBPassword string
-	DBHost     string
-	DBName     string
-	DBPort     int
-	Port       int
-}
+	"github.com/ashwingopalsamy/transactions-service/internal/config"
+	"github.com/ashwingopalsamy/transactions-service/internal/migration"
+	"gopkg.in/yaml.v3"
+)
 
-func main() {
-	// Initialize logger
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
 
-	// Load environmental config
-	cfg := loadEnvConfig()
+const usage = `Usage: app <command> [flags]
 
-	// Initialize database
-	dbPool, err := InitDB(cfg)
-	if err != nil {
-		log.Fatal().Err(err).Msg("failed to initialize database")
-	}
-	defer dbPool.Close()
+Commands:
+  serve [--migrate]               run the HTTP server (default)
+  migrate up|down|status|redo     manage the database schema
+  close-cycles [--date DATE]      generate the statements of cycles closing on DATE (YYYY-MM-DD, default yesterday)
+  accrue [--date DATE]            charge the interest and late fees of DATE (YYYY-MM-DD, default today)
+  verify-chain [--account-id ID]  recompute the transaction hash chains, of every account by default
+  verify-receipt [--receipt FILE] [--keys FILE]
+                                  check a transaction receipt's signature offline
+  config print                    print the effective configuration, secrets redacted
+  version                         print the build and schema versions
 
-	// Wiring the architecture layer
-	accRepo := repository.NewAccountsRepository(dbPool)
-	accService := service.NewAccountsService(accRepo)
-	accHandler := handler.NewAccountsHandler(accService)
+Flags:
+`
 
-	trxRepo := repository.NewTransactionsRepository(dbPool)
-	trxService := service.NewTransactionsService(trxRepo, accRepo)
-	trxHandler := handler.NewTransactionHandler(trxService)
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	// Setup server
-	router := NewRouter(accHandler, trxHandler)
+// run dispatches to a sub-command and returns the process exit code
+func run(args []string) int {
+	command := "serve"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
 
-	// Init Server
-	server := NewServer(router, withPort(cfg.Port))
-	go func() {
-		if err := server.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatal().Err(err).Msg("failed to start web server")
+	var err error
+	switch command {
+	case "serve":
+		err = serve(args)
+	case "migrate":
+		err = migrate(args)
+	case "close-cycles":
+		err = closeCycles(args)
+	case "accrue":
+		err = accrue(args)
+	case "verify-chain":
+		err = verifyChain(args)
+	case "verify-receipt":
+		err = verifyReceipt(args)
+	case "config":
+		if len(args) == 0 || args[0] != "print" {
+			err = errUsage
+			break
 		}
-	}()
+		err = printConfig(args[1:])
+	case "version":
+		err = printVersion()
+	case "help":
+		printUsage(os.Stdout)
+	default:
+		err = errUsage
+	}
 
-	// Setup graceful shutdown
-	gracefulShutdown(server)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		printUsage(os.Stderr)
+		return 2
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
 }
 
-func gracefulShutdown(server HTTPServer) {
-	stopChan := make(chan os.Signal, 1)
-	signal.Notify(stopChan, syscall.SIGINT, syscall.SIGTERM)
+var errUsage = errors.New("invalid usage")
 
-	<-stopChan
-
-	log.Info().Msg("Received termination signal, shutting down gracefully...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, usage)
+	config.Usage(w)
+}
 
-	if err := server.Stop(ctx); err != nil {
-		log.Fatal().Err(err).Msg("failed to shut down server gracefully")
-	}
-	log.Info().Msg("Server gracefully stopped")
+// newFlagSet returns a FlagSet for a sub-command; config flags are registered by config.Load
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
 }
 
-func loadEnvConfig() *EnvCfg {
-	return &EnvCfg{
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "postgres"),
-		DBHost:     getEnv("DB_HOST", "db"),
-		DBPort:     getEnvAsInt("DB_PORT", 5432),
-		Port:       getEnvAsInt("PORT", defaultWebPort),
+// loadConfig layers defaults, config file, env and flags, and validates the result
+func loadConfig(fs *flag.FlagSet, args []string) (*config.Config, error) {
+	cfg, err := config.Load(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// printConfig writes the effective configuration as YAML with secrets redacted
+func printConfig(args []string) error {
+	cfg, err := loadConfig(newFlagSet("config print"), args)
+	if err != nil {
+		return err
 	}
-	return defaultValue
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	defer enc.Close()
+	return enc.Encode(cfg.Redacted())
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
-	if value, exists := os.LookupEnv(key); exists {
-		intValue, err := strconv.Atoi(value)
-		if err != nil {
-			log.Fatal().Msgf("invalid port value: %s", value)
-			return defaultValue
-		}
-		return intValue
+// printVersion writes the build version and the schema version the binary expects
+func printVersion() error {
+	schemaVersion, err := migration.ExpectedVersion()
+	if err != nil {
+		return err
 	}
-	return defaultValue
+	fmt.Printf("version: %s\nschema_version: %d\n", version, schemaVersion)
+	return nil
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/config"
+	"github.com/ashwingopalsamy/transactions-service/internal/logging"
+	"github.com/ashwingopalsamy/transactions-service/internal/migration"
+	"github.com/pressly/goose/v3"
+)
+
+// migrate applies or inspects the embedded schema migrations
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	action, args := args[0], args[1:]
+
+	cfg, err := loadConfig(newFlagSet("migrate"), args)
+	if err != nil {
+		return err
+	}
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return err
+	}
+	if cfg.Storage == config.StorageMemory {
+		return errors.New("migrate requires postgres storage; the in-memory store has no schema")
+	}
+
+	dbPool, err := InitDB(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer dbPool.Close()
+
+	provider, err := migration.NewProvider(migration.OpenDB(dbPool))
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "up":
+		results, err := provider.Up(ctx)
+		printResults(results)
+		if err != nil {
+			return fmt.Errorf("migrate up: %w", err)
+		}
+		if len(results) == 0 {
+			fmt.Println("no migrations to apply")
+		}
+	case "down":
+		result, err := provider.Down(ctx)
+		printResults([]*goose.MigrationResult{result})
+		if err != nil {
+			return fmt.Errorf("migrate down: %w", err)
+		}
+	case "redo":
+		down, err := provider.Down(ctx)
+		printResults([]*goose.MigrationResult{down})
+		if err != nil {
+			return fmt.Errorf("migrate redo: %w", err)
+		}
+		up, err := provider.UpByOne(ctx)
+		printResults([]*goose.MigrationResult{up})
+		if err != nil {
+			return fmt.Errorf("migrate redo: %w", err)
+		}
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("migrate status: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tSOURCE")
+		for _, s := range statuses {
+			appliedAt := "-"
+			if !s.AppliedAt.IsZero() {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, appliedAt, s.Source.Path)
+		}
+		return w.Flush()
+	default:
+		return errUsage
+	}
+	return nil
+}
+
+func printResults(results []*goose.MigrationResult) {
+	for _, r := range results {
+		if r != nil && r.Source != nil {
+			fmt.Printf("%s %d %s (%s)\n", r.Direction, r.Source.Version, r.Source.Path, r.Duration.Round(time.Millisecond))
+		}
+	}
+}
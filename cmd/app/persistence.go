@@ -4,15 +4,76 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/ashwingopalsamy/transactions-service/internal/config"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
 
-func InitDB(c *EnvCfg) (*pgxpool.Pool, error) {
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
-		c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)
+// repositories groups the repositories of the selected storage backend
+type repositories struct {
+	customers    repository.CustomersRepository
+	accounts     repository.AccountsRepository
+	transactions repository.TransactionsRepository
+	trxRequests  repository.TransactionRequestsRepository
+	transfers    repository.TransfersRepository
+	ledger       repository.LedgerRepository
+	billing      repository.BillingRepository
+	accruals     repository.AccrualsRepository
+	fees         repository.FeesRepository
+	screening    repository.ScreeningRepository
+	audit        repository.AuditRepository
+	transactor   repository.Transactor
+}
+
+func newPostgresRepositories(dbPool *pgxpool.Pool) repositories {
+	return repositories{
+		customers:    repository.NewCustomersRepository(dbPool),
+		accounts:     repository.NewAccountsRepository(dbPool),
+		transactions: repository.NewTransactionsRepository(dbPool),
+		trxRequests:  repository.NewTransactionRequestsRepository(dbPool),
+		transfers:    repository.NewTransfersRepository(dbPool),
+		ledger:       repository.NewLedgerRepository(dbPool),
+		billing:      repository.NewBillingRepository(dbPool),
+		accruals:     repository.NewAccrualsRepository(dbPool),
+		fees:         repository.NewFeesRepository(dbPool),
+		screening:    repository.NewScreeningRepository(dbPool),
+		audit:        repository.NewAuditRepository(dbPool),
+		transactor:   repository.NewTransactor(dbPool),
+	}
+}
+
+func newMemoryRepositories(store *memory.Store) repositories {
+	return repositories{
+		customers:    memory.NewCustomersRepository(store),
+		accounts:     memory.NewAccountsRepository(store),
+		transactions: memory.NewTransactionsRepository(store),
+		trxRequests:  memory.NewTransactionRequestsRepository(store),
+		transfers:    memory.NewTransfersRepository(store),
+		ledger:       memory.NewLedgerRepository(store),
+		billing:      memory.NewBillingRepository(store),
+		accruals:     memory.NewAccrualsRepository(store),
+		fees:         memory.NewFeesRepository(store),
+		screening:    memory.NewScreeningRepository(store),
+		audit:        memory.NewAuditRepository(store),
+		transactor:   memory.NewTransactor(store),
+	}
+}
+
+func InitDB(c config.DatabaseConfig) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(c.PoolDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+	poolCfg.MaxConns = c.MaxConns
+	poolCfg.MinConns = c.MinConns
+	poolCfg.MaxConnLifetime = c.MaxConnLifetime
+	poolCfg.MaxConnIdleTime = c.MaxConnIdleTime
+	poolCfg.ConnConfig.Tracer = telemetry.NewQueryTracer()
 
-	dbPool, err := pgxpool.New(context.Background(), dsn)
+	dbPool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
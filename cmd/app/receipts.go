@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/receipt"
+)
+
+// verifyReceipt checks the signature of a receipt offline, against a key set saved from /v1/keys or
+// the configured receipt keys
+func verifyReceipt(args []string) error {
+	fs := newFlagSet("verify-receipt")
+	receiptPath := fs.String("receipt", "-", "receipt or create transaction response JSON file; - reads stdin")
+	keysPath := fs.String("keys", "", "JSON Web Key Set saved from /v1/keys; the configured receipt keys when empty")
+
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var keys map[string]ed25519.PublicKey
+	switch {
+	case *keysPath != "":
+		data, err := os.ReadFile(*keysPath)
+		if err != nil {
+			return fmt.Errorf("failed to read key set: %w", err)
+		}
+		if keys, err = receipt.ParseJWKSet(data); err != nil {
+			return err
+		}
+	case cfg.Receipts.KeysDir != "":
+		keyring, err := receipt.LoadKeyring(cfg.Receipts.KeysDir, cfg.Receipts.ActiveKeyID)
+		if err != nil {
+			return err
+		}
+		keys = keyring.VerificationKeys()
+	default:
+		return errors.New("verify-receipt requires --keys or receipts.keys_dir")
+	}
+
+	var data []byte
+	if *receiptPath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*receiptPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read receipt: %w", err)
+	}
+	r, err := decodeReceipt(data)
+	if err != nil {
+		return err
+	}
+
+	if err := receipt.Verify(r, keys); err != nil {
+		return fmt.Errorf("transaction %d: %w", r.TransactionID, err)
+	}
+	fmt.Printf("receipt of transaction %d account %d amount %.2f at %s is valid, signed by key %s\n",
+		r.TransactionID, r.AccountID, r.Amount, r.EventDate.Format(time.RFC3339), r.KeyID)
+	return nil
+}
+
+// decodeReceipt reads a bare receipt or the one embedded in a create transaction response
+func decodeReceipt(data []byte) (*receipt.Receipt, error) {
+	var envelope struct {
+		Receipt *receipt.Receipt `json:"receipt"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid receipt: %w", err)
+	}
+	if envelope.Receipt != nil {
+		return envelope.Receipt, nil
+	}
+
+	r := &receipt.Receipt{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("invalid receipt: %w", err)
+	}
+	if r.Signature == "" {
+		return nil, errors.New("invalid receipt: no signature")
+	}
+	return r, nil
+}
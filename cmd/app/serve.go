@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/config"
+	"github.com/ashwingopalsamy/transactions-service/internal/executor"
+	"github.com/ashwingopalsamy/transactions-service/internal/export"
+	"github.com/ashwingopalsamy/transactions-service/internal/handler"
+	"github.com/ashwingopalsamy/transactions-service/internal/health"
+	"github.com/ashwingopalsamy/transactions-service/internal/logging"
+	"github.com/ashwingopalsamy/transactions-service/internal/migration"
+	"github.com/ashwingopalsamy/transactions-service/internal/receipt"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// serve runs the HTTP server until SIGINT or SIGTERM
+func serve(args []string) error {
+	fs := newFlagSet("serve")
+	applyMigrations := fs.Bool("migrate", false, "apply pending migrations before serving")
+
+	// Load and validate config
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// Initialize logger
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return err
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := telemetry.InitTracing(context.Background(), telemetry.TracingConfig{
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to flush traces")
+		}
+	}()
+
+	// Load the receipt signing keys; receipts are disabled without a keys directory
+	var keyring *receipt.Keyring
+	if cfg.Receipts.KeysDir != "" {
+		if keyring, err = receipt.LoadKeyring(cfg.Receipts.KeysDir, cfg.Receipts.ActiveKeyID); err != nil {
+			return err
+		}
+		log.Info().Str("key_id", keyring.ActiveKeyID()).Msg("signing transaction receipts")
+	}
+
+	// Initialize storage and the dependency checks backing /readyz and /healthz
+	checker := health.NewChecker(cfg.Health.CheckTimeout)
+	repos, closeStorage, err := initStorage(context.Background(), cfg, checker, *applyMigrations)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+	// In sandbox mode time is virtual and only moves when a client advances it
+	var clk clock.Clock = clock.System()
+	var sandboxClock *clock.Virtual
+	if cfg.Features.Sandbox {
+		sandboxClock = clock.NewVirtual(clock.System())
+		clk = sandboxClock
+		log.Warn().Msg("sandbox mode: the service runs on a virtual clock")
+	}
+
+	// Wiring the architecture layer
+	custService := service.NewCustomersService(repos.customers, repos.accounts, repos.audit, repos.transactor, clk)
+	accService := service.NewAccountsService(repos.accounts, repos.customers, repos.audit, repos.transactor)
+	trxService := service.NewTransactionsService(repos.transactions, repos.accounts, repos.ledger, repos.fees, repos.screening, repos.audit, repos.billing, repos.transactor, clk, service.DuplicatePolicy{
+		Window: cfg.Duplicates.Window,
+		Action: cfg.Duplicates.Action,
+	}, service.BackdatingPolicy{Window: cfg.Backdating.Window})
+	trfService := service.NewTransfersService(repos.transfers, repos.transactions, repos.accounts, repos.ledger, repos.audit, repos.transactor, clk)
+	ledgerService := service.NewLedgerService(repos.ledger, repos.transactions, repos.accounts)
+	integrityService := service.NewIntegrityService(repos.transactions, repos.accounts)
+	exportService := service.NewExportService(repos.transactions, repos.accounts)
+	stmtService := service.NewStatementsService(repos.billing, repos.accounts, repos.transactions, repos.ledger, repos.audit, repos.transactor)
+	auditService := service.NewAuditService(repos.audit)
+	// Transactions are only queued with Prefer: respond-async when there are workers to process them
+	var requestsService service.TransactionRequestsService
+	if cfg.Async.Workers > 0 {
+		requestsService = service.NewTransactionRequestsService(repos.trxRequests, repos.transactions, repos.accounts, trxService, repos.transactor)
+	}
+
+	// Serialize the synchronous transactions of an account in process; queued and internal ones already
+	// run in a unit of work of their own and keep the unwrapped service
+	apiTrxService := trxService
+	var exec *executor.Executor
+	if cfg.Executor.Shards > 0 {
+		if exec, err = executor.New(executor.Config{
+			Shards:    cfg.Executor.Shards,
+			QueueSize: cfg.Executor.QueueSize,
+			Timeout:   cfg.Executor.Timeout,
+		}); err != nil {
+			return err
+		}
+		exec.Start()
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+			defer cancel()
+			if err := exec.Stop(ctx); err != nil {
+				log.Error().Err(err).Msg("failed to drain the executor")
+			}
+		}()
+		apiTrxService = service.NewSerializedTransactionsService(trxService, exec)
+	}
+
+	h := handlers{
+		health:       handler.NewHealthHandler(checker),
+		customers:    handler.NewCustomersHandler(custService),
+		accounts:     handler.NewAccountsHandler(accService),
+		transactions: handler.NewTransactionHandler(apiTrxService, requestsService, keyring),
+		transfers:    handler.NewTransfersHandler(trfService),
+		ledger:       handler.NewLedgerHandler(ledgerService),
+		integrity:    handler.NewIntegrityHandler(integrityService),
+		export: handler.NewExportHandler(exportService, export.Options{
+			Currency: cfg.Export.OFXCurrency,
+			BankID:   cfg.Export.OFXBankID,
+			Now:      clk.Now,
+		}),
+		statements: handler.NewStatementsHandler(stmtService),
+		audit:      handler.NewAuditHandler(auditService),
+	}
+	if requestsService != nil {
+		h.trxRequests = handler.NewTransactionRequestsHandler(requestsService)
+	}
+	if keyring != nil {
+		h.keys = handler.NewKeysHandler(keyring)
+	}
+	if cfg.Features.AdminAPI {
+		h.admin = handler.NewAdminHandler(cfg.Admin.Token, auditService)
+		if exec != nil {
+			h.executor = handler.NewExecutorHandler(exec)
+		}
+	}
+	if cfg.Features.Sandbox {
+		accrualService := service.NewAccrualsService(repos.accruals, repos.billing, repos.accounts, repos.transactions, trxService, repos.transactor, clk)
+		h.sandbox = handler.NewSandboxHandler(service.NewSandboxService(sandboxClock, stmtService, accrualService))
+	}
+
+	// Setup server
+	trustedProxies, err := cfg.Server.TrustedProxyPrefixes()
+	if err != nil {
+		return err
+	}
+	router := NewRouter(h, trustedProxies)
+
+	// Init Server
+	server := NewServer(router,
+		withPort(cfg.Server.Port),
+		withReadTimeout(cfg.Server.ReadTimeout),
+		withWriteTimeout(cfg.Server.WriteTimeout),
+		withIdleTimeout(cfg.Server.IdleTimeout),
+	)
+	go func() {
+		if err := server.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Err(err).Msg("failed to start web server")
+		}
+	}()
+
+	// Process queued transactions; workers finish the request in hand once the server is stopped
+	workersDone := make(chan struct{})
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	go func() {
+		defer close(workersDone)
+		if requestsService != nil {
+			log.Info().Int("workers", cfg.Async.Workers).Msg("processing queued transactions")
+			requestsService.Run(workersCtx, cfg.Async.Workers, cfg.Async.PollInterval)
+		}
+	}()
+	defer func() {
+		stopWorkers()
+		<-workersDone
+	}()
+
+	// Setup graceful shutdown
+	return gracefulShutdown(server, checker, cfg.Server)
+}
+
+// initStorage opens the configured storage backend, registers its health checks, and returns
+// its repositories with a function that releases the backend
+func initStorage(ctx context.Context, cfg *config.Config, checker *health.Checker, applyMigrations bool) (repositories, func(), error) {
+	if cfg.Storage == config.StorageMemory {
+		log.Warn().Msg("using in-memory storage; all data is lost on restart")
+		return newMemoryRepositories(memory.NewStore()), func() {}, nil
+	}
+
+	dbPool, err := InitDB(cfg.Database)
+	if err != nil {
+		return repositories{}, nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	// Refuse to serve against a schema older than this binary expects
+	schemaVersion, err := ensureSchema(ctx, dbPool, applyMigrations)
+	if err != nil {
+		dbPool.Close()
+		return repositories{}, nil, err
+	}
+
+	checker.AddCheck("database", health.DatabaseCheck(dbPool))
+	checker.AddCheck("schema_version", health.SchemaVersionCheck(dbPool, schemaVersion))
+
+	return newPostgresRepositories(dbPool), dbPool.Close, nil
+}
+
+// ensureSchema optionally applies pending migrations, then verifies the schema is at least at the
+// version embedded in this binary. It returns that expected version.
+func ensureSchema(ctx context.Context, dbPool *pgxpool.Pool, applyMigrations bool) (int64, error) {
+	expected, err := migration.ExpectedVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	provider, err := migration.NewProvider(migration.OpenDB(dbPool))
+	if err != nil {
+		return 0, err
+	}
+
+	if applyMigrations {
+		results, err := provider.Up(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		for _, result := range results {
+			log.Info().Int64("version", result.Source.Version).Dur("duration", result.Duration).Msg("applied migration")
+		}
+	}
+
+	current, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current < expected {
+		return 0, fmt.Errorf("database schema version %d is behind expected version %d; run `app migrate up` or start with --migrate", current, expected)
+	}
+
+	log.Info().Int64("schema_version", current).Msg("database schema is up to date")
+	return expected, nil
+}
+
+func gracefulShutdown(server HTTPServer, checker *health.Checker, cfg config.ServerConfig) error {
+	stopChan := make(chan os.Signal, 1)
+	signal.Notify(stopChan, syscall.SIGINT, syscall.SIGTERM)
+
+	<-stopChan
+
+	log.Info().Msg("Received termination signal, shutting down gracefully...")
+
+	// Fail readiness first and give load balancers time to stop routing to us before draining
+	checker.SetShuttingDown()
+	if cfg.ShutdownDrainDelay > 0 {
+		log.Info().Dur("drain_delay", cfg.ShutdownDrainDelay).Msg("readiness set to failing, waiting before draining connections")
+		time.Sleep(cfg.ShutdownDrainDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Stop(ctx); err != nil {
+		return fmt.Errorf("failed to shut down server gracefully: %w", err)
+	}
+	log.Info().Msg("Server gracefully stopped")
+	return nil
+}
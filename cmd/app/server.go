@@ -3,16 +3,19 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	stdlog "log"
 	"net/http"
+	"net/netip"
 	"runtime"
+	"runtime/debug"
 	"time"
 
 	"github.com/ashwingopalsamy/transactions-service/internal/handler"
 	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
 	"github.com/ashwingopalsamy/transactions-service/internal/writer"
 	"github.com/go-chi/chi/v5"
-	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 )
 
 const DefaultWebPort = 8080
@@ -22,7 +25,6 @@ type (
 	httpServer struct {
 		server   *http.Server
 		tracesCh chan string
-		errorLog *log.Logger
 	}
 
 	// HTTPServer is the interface for starting and stopping the HTTP server
@@ -34,9 +36,9 @@ type (
 	// Options are for configuring the server's behavior (e.g., port, timeouts)
 	options struct {
 		port           int
-		errorLogger    *log.Logger
 		readTimeout    time.Duration
 		writeTimeout   time.Duration
+		idleTimeout    time.Duration
 		disableRecover bool
 	}
 	Option func(*options)
@@ -61,13 +63,13 @@ func NewServer(h http.Handler, opts ...Option) HTTPServer {
 
 	return &httpServer{
 		tracesCh: tracesCh,
-		errorLog: setup.errorLogger,
 		server: &http.Server{
 			Addr:         fmt.Sprintf(":%d", setup.port),
 			Handler:      h,
-			ErrorLog:     setup.errorLogger,
+			ErrorLog:     stdlog.New(serverErrorWriter{}, "", 0),
 			ReadTimeout:  setup.readTimeout,
 			WriteTimeout: setup.writeTimeout,
+			IdleTimeout:  setup.idleTimeout,
 		},
 	}
 }
@@ -80,25 +82,33 @@ func (h *httpServer) Start() error {
 
 // Stop gracefully shuts down the server
 func (h *httpServer) Stop(ctx context.Context) error {
+	// Drain in-flight requests before closing tracesCh, so a late panic cannot send on a closed channel
+	err := h.server.Shutdown(ctx)
 	close(h.tracesCh)
-	return h.server.Shutdown(ctx)
+	return err
 }
 
 // outputStackTraces listens for panic recovery and logs stack traces
 func (h *httpServer) outputStackTraces() {
 	for trace := range h.tracesCh {
-		if h.errorLog != nil {
-			h.errorLog.Printf("recovered from panic: \n %s", trace)
-		}
+		log.Error().Str("stack", trace).Msg("recovered from panic")
 	}
 }
 
+// serverErrorWriter forwards net/http's internal error log to zerolog
+type serverErrorWriter struct{}
+
+func (serverErrorWriter) Write(p []byte) (int, error) {
+	log.WithLevel(zerolog.ErrorLevel).Str("component", "http_server").Msg(string(p))
+	return len(p), nil
+}
+
 // withRecovery is middleware that recovers from panics and sends them to tracesCh
 func withRecovery(next http.Handler, tracesCh chan string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				stackTrace := fmt.Sprintf("panic recovered: %v", err)
+				stackTrace := fmt.Sprintf("panic recovered: %v\n%s", err, debug.Stack())
 				tracesCh <- stackTrace
 				writer.WriteError(
 					w, r.Context(),
@@ -120,32 +130,132 @@ func withPort(port int) Option {
 	}
 }
 
-// NewRouter creates a new router with all the routes registered
-func NewRouter(accHandler *handler.AccountsHandler, trxHandler *handler.TransactionsHandler) http.Handler {
+// withReadTimeout overrides the default read timeout.
+func withReadTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.readTimeout = d
+	}
+}
+
+// withWriteTimeout overrides the default write timeout.
+func withWriteTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.writeTimeout = d
+	}
+}
+
+// withIdleTimeout sets the keep-alive idle timeout.
+func withIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// handlers groups the HTTP handlers mounted by NewRouter
+type handlers struct {
+	health       *handler.HealthHandler
+	customers    *handler.CustomersHandler
+	accounts     *handler.AccountsHandler
+	transactions *handler.TransactionsHandler
+	trxRequests  *handler.TransactionRequestsHandler
+	transfers    *handler.TransfersHandler
+	ledger       *handler.LedgerHandler
+	integrity    *handler.IntegrityHandler
+	export       *handler.ExportHandler
+	statements   *handler.StatementsHandler
+	audit        *handler.AuditHandler
+	keys         *handler.KeysHandler
+	executor     *handler.ExecutorHandler
+	admin        *handler.AdminHandler
+	sandbox      *handler.SandboxHandler
+}
+
+// NewRouter creates a new router with all the routes registered; X-Principal and X-Forwarded-For are only honored from trustedProxies.
+// Transaction request, keys, admin and sandbox routes are only mounted when their handlers are provided.
+func NewRouter(h handlers, trustedProxies []netip.Prefix) http.Handler {
 	router := chi.NewRouter()
 
 	// Middlewares
-	router.Use(chimiddleware.Logger)
-	router.Use(chimiddleware.Recoverer)
+	router.Use(middleware.Tracing)
 	router.Use(middleware.SetRequestIDToContext)
+	router.Use(middleware.SetCorrelationIDToContext)
+	router.Use(middleware.SetPrincipalToContext(trustedProxies))
+	router.Use(middleware.SetClientIPToContext(trustedProxies))
+	router.Use(middleware.AccessLog)
 
-	// Healthcheck route
-	router.Get("/health", healthCheckHandler)
+	// Healthcheck routes; /health is kept for existing liveness probes
+	router.Get("/health", h.health.Livez)
+	router.Get("/livez", h.health.Livez)
+	router.Get("/readyz", h.health.Readyz)
+	router.Get("/healthz", h.health.Healthz)
+
+	// Customer Routes
+	router.Route("/v1/customers", func(r chi.Router) {
+		r.Post("/", h.customers.CreateCustomer)
+		r.Get("/{id}", h.customers.GetCustomer)
+		r.Get("/{id}/accounts", h.customers.GetCustomerAccounts)
+	})
 
 	// Account Routes
 	router.Route("/v1/accounts", func(r chi.Router) {
-		r.Post("/", accHandler.CreateAccount)
-		r.Get("/{id}", accHandler.GetAccount)
+		r.Post("/", h.accounts.CreateAccount)
+		r.Get("/{id}", h.accounts.GetAccount)
+		r.Put("/{id}/status", h.accounts.SetAccountStatus)
+		r.Get("/{id}/ledger", h.ledger.VerifyAccountLedger)
+		r.Get("/{id}/integrity", h.integrity.VerifyAccountChain)
+		r.Get("/{id}/transactions/export", h.export.ExportTransactions)
+		r.Get("/{id}/billing-cycle", h.statements.GetBillingCycle)
+		r.Put("/{id}/billing-cycle", h.statements.SetBillingCycle)
+		r.Get("/{id}/statements", h.statements.GetStatements)
+		r.Get("/{id}/statements/{sid}", h.statements.GetStatement)
 	})
 
 	// Transaction Routes
 	router.Route("/v1/transactions", func(r chi.Router) {
-		r.Post("/", trxHandler.CreateTransaction)
+		r.Post("/", h.transactions.CreateTransaction)
+		r.Post("/batch", h.transactions.CreateTransactionBatch)
+		r.Get("/{id}", h.transactions.GetTransaction)
 	})
 
-	return router
-}
+	// Transaction Request Routes
+	if h.trxRequests != nil {
+		router.Get("/v1/transaction-requests/{id}", h.trxRequests.GetTransactionRequest)
+	}
+
+	// Transfer Routes
+	router.Route("/v1/transfers", func(r chi.Router) {
+		r.Post("/", h.transfers.CreateTransfer)
+		r.Get("/{id}", h.transfers.GetTransfer)
+	})
+
+	// Audit Routes
+	router.Get("/v1/audit-events", h.audit.GetAuditEvents)
 
-func healthCheckHandler(w http.ResponseWriter, _ *http.Request) {
-	writer.WriteJSON(w, 200, "OK")
+	// Receipt Key Routes
+	if h.keys != nil {
+		router.Get("/v1/keys", h.keys.GetKeys)
+	}
+
+	// Sandbox Routes
+	if h.sandbox != nil {
+		router.Route("/v1/sandbox", func(r chi.Router) {
+			r.Get("/clock", h.sandbox.GetClock)
+			r.Post("/clock", h.sandbox.AdvanceClock)
+		})
+	}
+
+	// Admin Routes
+	if h.admin != nil {
+		router.Route("/admin", func(r chi.Router) {
+			r.Use(h.admin.RequireAdminToken)
+			r.Get("/log-level", h.admin.GetLogLevel)
+			r.Put("/log-level", h.admin.SetLogLevel)
+			r.Post("/transactions", h.transactions.CreateTransactionAsOperator)
+			if h.executor != nil {
+				r.Get("/executor", h.executor.GetStats)
+			}
+		})
+	}
+
+	return router
 }
@@ -0,0 +1,82 @@
+// Package clock abstracts the current time so that date-dependent behavior can be tested
+// and run for dates other than today.
+package clock
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+// System returns the wall clock, in UTC
+func System() Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+type fixedClock struct {
+	t time.Time
+}
+
+// Fixed returns a clock stopped at t
+func Fixed(t time.Time) Clock {
+	return fixedClock{t: t}
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.t
+}
+
+// ErrBackwards is returned when a virtual clock is asked to move back in time
+var ErrBackwards = errors.New("clock cannot move backwards")
+
+// Virtual is a clock that runs at the pace of a base clock but can be moved forward, so sandboxes
+// can rehearse date-dependent behavior such as billing in minutes. It is safe for concurrent use.
+type Virtual struct {
+	base Clock
+
+	mu     sync.RWMutex
+	offset time.Duration
+}
+
+// NewVirtual returns a virtual clock that starts in step with base
+func NewVirtual(base Clock) *Virtual {
+	return &Virtual{base: base}
+}
+
+func (c *Virtual) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.base.Now().Add(c.offset)
+}
+
+// Offset returns how far the clock runs ahead of its base
+func (c *Virtual) Offset() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offset
+}
+
+// AdvanceTo moves the clock forward to t, from where it keeps running. It fails with ErrBackwards
+// if t is before the clock's current time.
+func (c *Virtual) AdvanceTo(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.base.Now()
+	if t.Before(now.Add(c.offset)) {
+		return ErrBackwards
+	}
+	c.offset = t.Sub(now)
+	return nil
+}
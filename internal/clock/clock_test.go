@@ -0,0 +1,59 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystem(t *testing.T) {
+	before := time.Now()
+	now := clock.System().Now()
+
+	assert.Equal(t, time.UTC, now.Location())
+	assert.False(t, now.Before(before.Truncate(time.Second)))
+}
+
+func TestFixed(t *testing.T) {
+	at := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+	c := clock.Fixed(at)
+
+	assert.Equal(t, at, c.Now())
+	assert.Equal(t, at, c.Now())
+}
+
+func TestVirtual(t *testing.T) {
+	start := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+	t.Run("Starts in step with its base", func(t *testing.T) {
+		c := clock.NewVirtual(clock.Fixed(start))
+
+		assert.Equal(t, start, c.Now())
+		assert.Zero(t, c.Offset())
+	})
+
+	t.Run("Advances and keeps running from there", func(t *testing.T) {
+		c := clock.NewVirtual(clock.System())
+		target := time.Now().UTC().AddDate(0, 1, 0)
+
+		require.NoError(t, c.AdvanceTo(target))
+
+		now := c.Now()
+		assert.False(t, now.Before(target))
+		assert.WithinDuration(t, target, now, time.Minute)
+		assert.InDelta(t, float64(30*24*time.Hour), float64(c.Offset()), float64(2*24*time.Hour))
+	})
+
+	t.Run("Cannot move backwards", func(t *testing.T) {
+		c := clock.NewVirtual(clock.Fixed(start))
+		require.NoError(t, c.AdvanceTo(start.AddDate(0, 0, 2)))
+
+		err := c.AdvanceTo(start.AddDate(0, 0, 1))
+
+		assert.ErrorIs(t, err, clock.ErrBackwards)
+		assert.Equal(t, start.AddDate(0, 0, 2), c.Now())
+	})
+}
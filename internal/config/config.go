@@ -0,0 +1,221 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Config is the effective service configuration.
+// Values are layered in order: defaults, optional YAML file, environment variables, then flags.
+type Config struct {
+	Storage    string           `yaml:"storage"`
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Log        LogConfig        `yaml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Health     HealthConfig     `yaml:"health"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Duplicates DuplicatesConfig `yaml:"duplicates"`
+	Backdating BackdatingConfig `yaml:"backdating"`
+	Receipts   ReceiptsConfig   `yaml:"receipts"`
+	Export     ExportConfig     `yaml:"export"`
+	Async      AsyncConfig      `yaml:"async"`
+	Executor   ExecutorConfig   `yaml:"executor"`
+	Features   FeaturesConfig   `yaml:"features"`
+}
+
+// Storage backends
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// Log formats
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+// Span exporters
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+// What to do with suspected duplicates
+const (
+	DuplicateActionFlag   = "flag"
+	DuplicateActionReject = "reject"
+)
+
+// ServerConfig configures the HTTP server and its shutdown behavior
+type ServerConfig struct {
+	Port               int           `yaml:"port"`
+	ReadTimeout        time.Duration `yaml:"read_timeout"`
+	WriteTimeout       time.Duration `yaml:"write_timeout"`
+	IdleTimeout        time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout"`
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay"`
+	// TrustedProxies are the CIDR ranges of the gateways whose X-Principal and X-Forwarded-For headers are believed
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// TrustedProxyPrefixes parses TrustedProxies
+func (c *ServerConfig) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, cidr := range c.TrustedProxies {
+		p, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// DatabaseConfig configures the Postgres connection pool
+type DatabaseConfig struct {
+	DSN             string        `yaml:"dsn"`
+	SSLMode         string        `yaml:"sslmode"` // overrides the DSN's sslmode when set
+	SSLRootCert     string        `yaml:"sslrootcert"`
+	MaxConns        int32         `yaml:"max_conns"`
+	MinConns        int32         `yaml:"min_conns"`
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time"`
+}
+
+// LogConfig configures the global logger
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// TracingConfig configures span export
+type TracingConfig struct {
+	ServiceName string `yaml:"service_name"`
+	Exporter    string `yaml:"exporter"`
+	Endpoint    string `yaml:"endpoint"`
+	Insecure    bool   `yaml:"insecure"`
+}
+
+// HealthConfig configures the readiness dependency checks
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+}
+
+// AdminConfig configures the admin API
+type AdminConfig struct {
+	Token string `yaml:"token"`
+}
+
+// DuplicatesConfig configures the detection of transactions resent by processors: a transaction matching the
+// account, operation type, amount and merchant reference of one posted within Window is flagged or rejected
+// per Action. A zero Window disables the check.
+type DuplicatesConfig struct {
+	Window time.Duration `yaml:"window"`
+	Action string        `yaml:"action"`
+}
+
+// BackdatingConfig configures how long before it is posted a transaction may have taken place. A zero Window
+// rejects back-dated transactions.
+type BackdatingConfig struct {
+	Window time.Duration `yaml:"window"`
+}
+
+// ReceiptsConfig configures the signing of transaction receipts. KeysDir holds one <key id>.pem file per
+// Ed25519 key; ActiveKeyID pins the signing key, the greatest key id otherwise. An empty KeysDir disables
+// receipts.
+type ReceiptsConfig struct {
+	KeysDir     string `yaml:"keys_dir"`
+	ActiveKeyID string `yaml:"active_key_id"`
+}
+
+// ExportConfig configures transaction exports. OFX statements carry a currency and, for bank accounts, a
+// bank id; the service keeps neither per account.
+type ExportConfig struct {
+	OFXCurrency string `yaml:"ofx_currency"`
+	OFXBankID   string `yaml:"ofx_bank_id"`
+}
+
+// AsyncConfig configures the processing of transactions submitted with Prefer: respond-async. Workers process
+// queued requests concurrently, polling the queue every PollInterval when idle. Zero Workers disables
+// asynchronous processing: the preference is then ignored and transactions are posted synchronously.
+type AsyncConfig struct {
+	Workers      int           `yaml:"workers"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+// ExecutorConfig configures the in-process executor running the synchronous transactions of an account one at
+// a time: Shards workers, each queueing up to QueueSize transactions that must be posted within Timeout of
+// their arrival. Zero Shards disables it, leaving concurrent transactions to wait on the account lock.
+type ExecutorConfig struct {
+	Shards    int           `yaml:"shards"`
+	QueueSize int           `yaml:"queue_size"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+// FeaturesConfig holds feature toggles
+type FeaturesConfig struct {
+	AdminAPI bool `yaml:"admin_api"`
+	// Sandbox runs the service on a virtual clock that clients move forward, for integrators to
+	// rehearse billing; never enable it in production
+	Sandbox bool `yaml:"sandbox"`
+}
+
+// Default returns the configuration used when nothing else is provided
+func Default() *Config {
+	return &Config{
+		Storage: StoragePostgres,
+		Server: ServerConfig{
+			Port:               8080,
+			ReadTimeout:        15 * time.Second,
+			WriteTimeout:       15 * time.Second,
+			IdleTimeout:        60 * time.Second,
+			ShutdownTimeout:    10 * time.Second,
+			ShutdownDrainDelay: 0,
+		},
+		Database: DatabaseConfig{
+			MaxConns:        10,
+			MinConns:        0,
+			MaxConnLifetime: time.Hour,
+			MaxConnIdleTime: 30 * time.Minute,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: LogFormatJSON,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "transactions-service",
+			Exporter:    TracingExporterNone,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		// Detection is opt-in: a resend is only turned down once DUPLICATE_WINDOW is set
+		Duplicates: DuplicatesConfig{
+			Action: DuplicateActionReject,
+		},
+		Backdating: BackdatingConfig{
+			Window: 72 * time.Hour,
+		},
+		Export: ExportConfig{
+			OFXCurrency: "USD",
+			OFXBankID:   "000000000",
+		},
+		Async: AsyncConfig{
+			Workers:      4,
+			PollInterval: time.Second,
+		},
+		Executor: ExecutorConfig{
+			Shards:    16,
+			QueueSize: 64,
+			Timeout:   5 * time.Second,
+		},
+		Features: FeaturesConfig{
+			AdminAPI: false,
+			Sandbox:  false,
+		},
+	}
+}
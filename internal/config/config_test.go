@@ -0,0 +1,193 @@
+package config
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envOf(values map[string]string) LookupEnvFunc {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Defaults are valid once a DSN is given", func(t *testing.T) {
+		cfg, err := load(nil, nil, envOf(map[string]string{"DB_DSN": "postgres://u:p@h:5432/db"}))
+		require.NoError(t, err)
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, 10*time.Second, cfg.Server.ShutdownTimeout)
+		assert.Zero(t, cfg.Duplicates.Window, "duplicate detection should be opt-in")
+	})
+
+	t.Run("File, env and flags are layered in order", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  port: 9000
+  shutdown_timeout: 20s
+log:
+  level: debug
+database:
+  max_conns: 5
+`), 0o600))
+
+		cfg, err := load(nil,
+			[]string{"--config", path, "--port", "9100"},
+			envOf(map[string]string{"PORT": "9050", "SHUTDOWN_TIMEOUT": "5s", "DB_DSN": "postgres://u:p@h:5432/db"}),
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, 9100, cfg.Server.Port, "flag wins over env and file")
+		assert.Equal(t, 5*time.Second, cfg.Server.ShutdownTimeout, "env wins over file")
+		assert.Equal(t, "debug", cfg.Log.Level, "file wins over default")
+		assert.Equal(t, int32(5), cfg.Database.MaxConns)
+		assert.Equal(t, "postgres://u:p@h:5432/db", cfg.Database.DSN)
+		assert.Equal(t, 15*time.Second, cfg.Server.ReadTimeout, "untouched keys keep defaults")
+	})
+
+	t.Run("Config file path can come from env", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("log:\n  format: console\n"), 0o600))
+
+		cfg, err := load(nil, nil, envOf(map[string]string{ConfigFileEnv: path}))
+		require.NoError(t, err)
+		assert.Equal(t, "console", cfg.Log.Format)
+	})
+
+	t.Run("Unknown file keys are rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("server:\n  prot: 1\n"), 0o600))
+
+		_, err := load(nil, []string{"--config", path}, envOf(nil))
+		assert.Error(t, err)
+	})
+
+	t.Run("All parse errors are reported together", func(t *testing.T) {
+		_, err := load(nil,
+			[]string{"--db-max-conns", "many"},
+			envOf(map[string]string{"PORT": "http", "SHUTDOWN_TIMEOUT": "soon"}),
+		)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "env PORT")
+		assert.Contains(t, err.Error(), "env SHUTDOWN_TIMEOUT")
+		assert.Contains(t, err.Error(), "flag --db-max-conns")
+	})
+
+	t.Run("Duplicate detection can be tuned", func(t *testing.T) {
+		cfg, err := load(nil,
+			[]string{"--duplicate-action", "flag"},
+			envOf(map[string]string{"DUPLICATE_WINDOW": "2m", "STORAGE": "memory"}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Minute, cfg.Duplicates.Window)
+		assert.Equal(t, "flag", cfg.Duplicates.Action)
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("Trusted proxies are a comma-separated list", func(t *testing.T) {
+		cfg, err := load(nil, nil, envOf(map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8, 2001:db8::1/32,", "STORAGE": "memory"}))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.0/8", "2001:db8::1/32"}, cfg.Server.TrustedProxies)
+		assert.NoError(t, cfg.Validate())
+
+		prefixes, err := cfg.Server.TrustedProxyPrefixes()
+		require.NoError(t, err)
+		assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")}, prefixes)
+	})
+
+	t.Run("DSN has no default and is only required with postgres storage", func(t *testing.T) {
+		cfg, err := load(nil, nil, envOf(nil))
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Database.DSN)
+		assert.ErrorContains(t, cfg.Validate(), "database.dsn is required")
+
+		cfg.Storage = StorageMemory
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Storage = "disk"
+	cfg.Server.Port = 0
+	cfg.Server.ShutdownTimeout = 0
+	cfg.Server.TrustedProxies = []string{"gateway"}
+	cfg.Database.SSLMode = "sometimes"
+	cfg.Database.MinConns = 20
+	cfg.Log.Level = "loud"
+	cfg.Tracing.Exporter = "jaeger"
+	cfg.Duplicates.Window = -time.Minute
+	cfg.Duplicates.Action = "ignore"
+	cfg.Backdating.Window = -time.Hour
+	cfg.Receipts.ActiveKeyID = "2025-04"
+	cfg.Export.OFXCurrency = "usd"
+	cfg.Export.OFXBankID = ""
+	cfg.Async.Workers = -1
+	cfg.Async.PollInterval = 0
+	cfg.Executor.Shards = -1
+	cfg.Executor.QueueSize = 0
+	cfg.Executor.Timeout = 0
+	cfg.Features.AdminAPI = true
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	for _, field := range []string{
+		"storage", "server.port", "server.shutdown_timeout", "server.trusted_proxies", "database.sslmode", "database.min_conns",
+		"log.level", "tracing.exporter", "duplicates.window", "duplicates.action", "backdating.window",
+		"receipts.keys_dir", "export.ofx_currency", "export.ofx_bank_id", "async.workers", "async.poll_interval",
+		"executor.shards", "executor.queue_size", "executor.timeout", "admin.token",
+	} {
+		assert.Contains(t, err.Error(), field)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		name     string
+		dsn      string
+		expected string
+	}{
+		{"URL DSN", "postgres://app:s3cret@db:5432/app?sslmode=disable", "postgres://app:REDACTED@db:5432/app?sslmode=disable"},
+		{"URL DSN without password", "postgres://app@db:5432/app", "postgres://app@db:5432/app"},
+		{"Keyword DSN", "host=db user=app password=s3cret dbname=app", "host=db user=app password=REDACTED dbname=app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Database.DSN = tt.dsn
+			cfg.Admin.Token = "token"
+
+			out := cfg.Redacted()
+			assert.Equal(t, tt.expected, out.Database.DSN)
+			assert.Equal(t, redacted, out.Admin.Token)
+			assert.Equal(t, tt.dsn, cfg.Database.DSN, "original is not modified")
+		})
+	}
+}
+
+func TestPoolDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		db       DatabaseConfig
+		expected string
+	}{
+		{"No TLS override keeps DSN", DatabaseConfig{DSN: "postgres://u:p@db/app?sslmode=disable"}, "postgres://u:p@db/app?sslmode=disable"},
+		{"URL DSN sslmode override", DatabaseConfig{DSN: "postgres://u:p@db/app?sslmode=disable", SSLMode: "verify-full", SSLRootCert: "/ca.pem"}, "postgres://u:p@db/app?sslmode=verify-full&sslrootcert=%2Fca.pem"},
+		{"Keyword DSN sslmode override", DatabaseConfig{DSN: "host=db sslmode=disable", SSLMode: "require"}, "host=db sslmode=disable sslmode=require"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.db.PoolDSN())
+		})
+	}
+}
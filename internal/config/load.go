@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable pointing to an optional YAML config file
+const ConfigFileEnv = "CONFIG_FILE"
+
+// binding ties one setting to its environment variable and command-line flag
+type binding struct {
+	env   string
+	flag  string
+	usage string
+	set   func(string) error
+}
+
+// LookupEnvFunc matches os.LookupEnv
+type LookupEnvFunc func(key string) (string, bool)
+
+// Load builds the configuration from defaults, the optional YAML file, the environment and args.
+// Commands may pass a FlagSet with flags of their own registered; nil uses an empty one.
+// All parse errors are reported together; call Validate on the result before using it.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	return load(fs, args, os.LookupEnv)
+}
+
+func load(fs *flag.FlagSet, args []string, lookupEnv LookupEnvFunc) (*Config, error) {
+	cfg := Default()
+	bs := bindings(cfg)
+
+	// Flags are applied last, but must be parsed first to find --config
+	if fs == nil {
+		fs = flag.NewFlagSet("config", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+	}
+	configFile := fs.String("config", "", "path to a YAML config file (env "+ConfigFileEnv+")")
+
+	type flagValue struct {
+		b     binding
+		value string
+	}
+	var flagValues []flagValue
+	for _, b := range bs {
+		b := b
+		fs.Func(b.flag, b.usage+" (env "+b.env+")", func(v string) error {
+			flagValues = append(flagValues, flagValue{b: b, value: v})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv(ConfigFileEnv)
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, b := range bs {
+		if value, ok := lookupEnv(b.env); ok {
+			if err := b.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", b.env, err))
+			}
+		}
+	}
+	for _, fv := range flagValues {
+		if err := fv.b.set(fv.value); err != nil {
+			errs = append(errs, fmt.Errorf("flag --%s: %w", fv.b.flag, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// loadFile overlays the YAML file at path onto cfg; keys absent from the file keep their current value
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Usage lists every setting with its flag and environment variable
+func Usage(w io.Writer) {
+	fmt.Fprintf(w, "  --%-24s %s\n", "config", "path to a YAML config file (env "+ConfigFileEnv+")")
+	for _, b := range bindings(Default()) {
+		fmt.Fprintf(w, "  --%-24s %s (env %s)\n", b.flag, b.usage, b.env)
+	}
+}
+
+func bindings(cfg *Config) []binding {
+	return []binding{
+		{"STORAGE", "storage", "storage backend (postgres or memory)", stringVar(&cfg.Storage)},
+
+		{"PORT", "port", "HTTP listen port", intVar(&cfg.Server.Port)},
+		{"SERVER_READ_TIMEOUT", "read-timeout", "HTTP read timeout", durationVar(&cfg.Server.ReadTimeout)},
+		{"SERVER_WRITE_TIMEOUT", "write-timeout", "HTTP write timeout", durationVar(&cfg.Server.WriteTimeout)},
+		{"SERVER_IDLE_TIMEOUT", "idle-timeout", "HTTP keep-alive idle timeout", durationVar(&cfg.Server.IdleTimeout)},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "maximum time to drain in-flight requests", durationVar(&cfg.Server.ShutdownTimeout)},
+		{"SHUTDOWN_DRAIN_DELAY", "shutdown-drain-delay", "time between failing readiness and draining", durationVar(&cfg.Server.ShutdownDrainDelay)},
+		{"TRUSTED_PROXIES", "trusted-proxies", "comma-separated CIDRs of the gateways allowed to set X-Principal and X-Forwarded-For", stringsVar(&cfg.Server.TrustedProxies)},
+
+		{"DB_DSN", "db-dsn", "Postgres connection string", stringVar(&cfg.Database.DSN)},
+		{"DB_SSLMODE", "db-sslmode", "Postgres TLS mode", stringVar(&cfg.Database.SSLMode)},
+		{"DB_SSLROOTCERT", "db-sslrootcert", "CA bundle used to verify the Postgres server", stringVar(&cfg.Database.SSLRootCert)},
+		{"DB_MAX_CONNS", "db-max-conns", "maximum pool size", int32Var(&cfg.Database.MaxConns)},
+		{"DB_MIN_CONNS", "db-min-conns", "minimum idle pool size", int32Var(&cfg.Database.MinConns)},
+		{"DB_MAX_CONN_LIFETIME", "db-max-conn-lifetime", "maximum lifetime of a pooled connection", durationVar(&cfg.Database.MaxConnLifetime)},
+		{"DB_MAX_CONN_IDLE_TIME", "db-max-conn-idle-time", "maximum idle time of a pooled connection", durationVar(&cfg.Database.MaxConnIdleTime)},
+
+		{"LOG_LEVEL", "log-level", "log level", stringVar(&cfg.Log.Level)},
+		{"LOG_FORMAT", "log-format", "log format (json or console)", stringVar(&cfg.Log.Format)},
+
+		{"OTEL_SERVICE_NAME", "service-name", "service name reported in traces", stringVar(&cfg.Tracing.ServiceName)},
+		{"OTEL_TRACES_EXPORTER", "traces-exporter", "span exporter (none, otlp or stdout)", stringVar(&cfg.Tracing.Exporter)},
+		{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "traces-endpoint", "OTLP/HTTP traces endpoint", stringVar(&cfg.Tracing.Endpoint)},
+		{"OTEL_EXPORTER_OTLP_INSECURE", "traces-insecure", "disable TLS for the OTLP exporter", boolVar(&cfg.Tracing.Insecure)},
+
+		{"HEALTH_CHECK_TIMEOUT", "health-check-timeout", "timeout for each readiness check", durationVar(&cfg.Health.CheckTimeout)},
+
+		{"ADMIN_TOKEN", "admin-token", "bearer token for the admin API", stringVar(&cfg.Admin.Token)},
+
+		{"DUPLICATE_WINDOW", "duplicate-window", "window in which a matching transaction is a suspected duplicate (0 disables)", durationVar(&cfg.Duplicates.Window)},
+		{"DUPLICATE_ACTION", "duplicate-action", "what to do with suspected duplicates (flag or reject)", stringVar(&cfg.Duplicates.Action)},
+
+		{"BACKDATING_WINDOW", "backdating-window", "how long before posting a transaction may have taken place (0 rejects back-dated ones)", durationVar(&cfg.Backdating.Window)},
+
+		{"RECEIPT_KEYS_DIR", "receipt-keys-dir", "directory of Ed25519 receipt keys (empty disables receipts)", stringVar(&cfg.Receipts.KeysDir)},
+		{"RECEIPT_ACTIVE_KEY_ID", "receipt-active-key-id", "receipt signing key id (default the greatest)", stringVar(&cfg.Receipts.ActiveKeyID)},
+
+		{"EXPORT_OFX_CURRENCY", "export-ofx-currency", "ISO 4217 currency of OFX exports", stringVar(&cfg.Export.OFXCurrency)},
+		{"EXPORT_OFX_BANK_ID", "export-ofx-bank-id", "bank id of prepaid accounts in OFX exports", stringVar(&cfg.Export.OFXBankID)},
+
+		{"ASYNC_WORKERS", "async-workers", "workers processing asynchronous transactions (0 disables)", intVar(&cfg.Async.Workers)},
+		{"ASYNC_POLL_INTERVAL", "async-poll-interval", "interval at which idle workers poll the queue", durationVar(&cfg.Async.PollInterval)},
+
+		{"EXECUTOR_SHARDS", "executor-shards", "shards serializing the transactions of an account (0 disables)", intVar(&cfg.Executor.Shards)},
+		{"EXECUTOR_QUEUE_SIZE", "executor-queue-size", "transactions queued per shard before new ones are turned down", intVar(&cfg.Executor.QueueSize)},
+		{"EXECUTOR_TIMEOUT", "executor-timeout", "time a queued transaction has to be posted", durationVar(&cfg.Executor.Timeout)},
+
+		{"FEATURE_ADMIN_API", "feature-admin-api", "enable the admin API", boolVar(&cfg.Features.AdminAPI)},
+		{"FEATURE_SANDBOX", "feature-sandbox", "run on a virtual clock moved by the sandbox API", boolVar(&cfg.Features.Sandbox)},
+	}
+}
+
+func stringVar(p *string) func(string) error {
+	return func(v string) error {
+		*p = v
+		return nil
+	}
+}
+
+func stringsVar(p *[]string) func(string) error {
+	return func(v string) error {
+		*p = nil
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				*p = append(*p, s)
+			}
+		}
+		return nil
+	}
+}
+
+func intVar(p *int) func(string) error {
+	return func(v string) error {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		*p = i
+		return nil
+	}
+}
+
+func int32Var(p *int32) func(string) error {
+	return func(v string) error {
+		i, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		*p = int32(i)
+		return nil
+	}
+}
+
+func durationVar(p *time.Duration) func(string) error {
+	return func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*p = d
+		return nil
+	}
+}
+
+func boolVar(p *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*p = b
+		return nil
+	}
+}
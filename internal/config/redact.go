@@ -0,0 +1,80 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// keywordPassword matches the password in keyword/value DSNs such as "host=db password=secret"
+var keywordPassword = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// Redacted returns a copy of the configuration that is safe to print or log
+func (c *Config) Redacted() Config {
+	out := *c
+	out.Database.DSN = redactDSN(c.Database.DSN)
+	if out.Admin.Token != "" {
+		out.Admin.Token = redacted
+	}
+	return out
+}
+
+// PoolDSN returns the DSN with the configured TLS settings applied, overriding any present in the DSN
+func (c *DatabaseConfig) PoolDSN() string {
+	params := map[string]string{}
+	if c.SSLMode != "" {
+		params["sslmode"] = c.SSLMode
+	}
+	if c.SSLRootCert != "" {
+		params["sslrootcert"] = c.SSLRootCert
+	}
+	if len(params) == 0 {
+		return c.DSN
+	}
+
+	if isURLDSN(c.DSN) {
+		u, err := url.Parse(c.DSN)
+		if err != nil {
+			return c.DSN
+		}
+		q := u.Query()
+		for k, v := range params {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+
+	// Later keywords win in keyword/value DSNs
+	dsn := c.DSN
+	for _, k := range []string{"sslmode", "sslrootcert"} {
+		if v, ok := params[k]; ok {
+			dsn += " " + k + "=" + v
+		}
+	}
+	return dsn
+}
+
+func redactDSN(dsn string) string {
+	if isURLDSN(dsn) {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return redacted
+		}
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+		if q := u.Query(); q.Has("password") {
+			q.Set("password", redacted)
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	return keywordPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+func isURLDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+)
+
+// sslModes are the libpq TLS modes understood by pgx
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// currencyCode matches ISO 4217 alphabetic codes
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Storage != StoragePostgres && c.Storage != StorageMemory {
+		add("storage must be %s or %s, got %q", StoragePostgres, StorageMemory, c.Storage)
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		add("server.port must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	if c.Server.ReadTimeout <= 0 {
+		add("server.read_timeout must be positive")
+	}
+	if c.Server.WriteTimeout <= 0 {
+		add("server.write_timeout must be positive")
+	}
+	if c.Server.IdleTimeout <= 0 {
+		add("server.idle_timeout must be positive")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		add("server.shutdown_timeout must be positive")
+	}
+	if c.Server.ShutdownDrainDelay < 0 {
+		add("server.shutdown_drain_delay must not be negative")
+	}
+	if _, err := c.Server.TrustedProxyPrefixes(); err != nil {
+		add("server.trusted_proxies: %v", err)
+	}
+
+	if strings.TrimSpace(c.Database.DSN) == "" {
+		if c.Storage != StorageMemory {
+			add("database.dsn is required")
+		}
+	} else if _, err := pgconn.ParseConfig(c.Database.DSN); err != nil {
+		// pgconn redacts the password in its parse errors
+		add("database.dsn is invalid: %v", err)
+	}
+	if c.Database.SSLMode != "" && !contains(sslModes, c.Database.SSLMode) {
+		add("database.sslmode must be one of %s, got %q", strings.Join(sslModes, ", "), c.Database.SSLMode)
+	}
+	if c.Database.SSLRootCert != "" && c.Database.SSLMode != "" && !contains([]string{"verify-ca", "verify-full"}, c.Database.SSLMode) {
+		add("database.sslrootcert requires database.sslmode verify-ca or verify-full")
+	}
+	if c.Database.MaxConns < 1 {
+		add("database.max_conns must be at least 1, got %d", c.Database.MaxConns)
+	}
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		add("database.min_conns must be between 0 and database.max_conns, got %d", c.Database.MinConns)
+	}
+	if c.Database.MaxConnLifetime <= 0 {
+		add("database.max_conn_lifetime must be positive")
+	}
+	if c.Database.MaxConnIdleTime <= 0 {
+		add("database.max_conn_idle_time must be positive")
+	}
+
+	if level, err := zerolog.ParseLevel(strings.ToLower(c.Log.Level)); err != nil || level == zerolog.NoLevel {
+		add("log.level is invalid: %q", c.Log.Level)
+	}
+	if !contains([]string{LogFormatJSON, LogFormatConsole}, strings.ToLower(c.Log.Format)) {
+		add("log.format must be %s or %s, got %q", LogFormatJSON, LogFormatConsole, c.Log.Format)
+	}
+
+	if strings.TrimSpace(c.Tracing.ServiceName) == "" {
+		add("tracing.service_name is required")
+	}
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		if c.Tracing.Endpoint != "" {
+			if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+				add("tracing.endpoint must be an absolute URL, got %q", c.Tracing.Endpoint)
+			}
+		}
+	default:
+		add("tracing.exporter must be one of none, otlp, stdout, got %q", c.Tracing.Exporter)
+	}
+
+	if c.Health.CheckTimeout <= 0 {
+		add("health.check_timeout must be positive")
+	}
+
+	if c.Duplicates.Window < 0 {
+		add("duplicates.window must not be negative")
+	}
+	if c.Duplicates.Action != DuplicateActionFlag && c.Duplicates.Action != DuplicateActionReject {
+		add("duplicates.action must be %s or %s, got %q", DuplicateActionFlag, DuplicateActionReject, c.Duplicates.Action)
+	}
+
+	if c.Backdating.Window < 0 {
+		add("backdating.window must not be negative")
+	}
+
+	if c.Receipts.ActiveKeyID != "" && c.Receipts.KeysDir == "" {
+		add("receipts.keys_dir is required when receipts.active_key_id is set")
+	}
+
+	if !currencyCode.MatchString(c.Export.OFXCurrency) {
+		add("export.ofx_currency must be an ISO 4217 code such as USD, got %q", c.Export.OFXCurrency)
+	}
+	if strings.TrimSpace(c.Export.OFXBankID) == "" || len(c.Export.OFXBankID) > 9 {
+		add("export.ofx_bank_id must be 1 to 9 characters, got %q", c.Export.OFXBankID)
+	}
+
+	if c.Async.Workers < 0 {
+		add("async.workers must not be negative, got %d", c.Async.Workers)
+	}
+	if c.Async.PollInterval <= 0 {
+		add("async.poll_interval must be positive")
+	}
+
+	if c.Executor.Shards < 0 {
+		add("executor.shards must not be negative, got %d", c.Executor.Shards)
+	}
+	if c.Executor.QueueSize <= 0 {
+		add("executor.queue_size must be positive, got %d", c.Executor.QueueSize)
+	}
+	if c.Executor.Timeout <= 0 {
+		add("executor.timeout must be positive")
+	}
+
+	if c.Features.AdminAPI && c.Admin.Token == "" {
+		add("admin.token is required when features.admin_api is enabled")
+	}
+
+	return errors.Join(errs...)
+}
+
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+// Package executor runs work keyed by an id, such as an account id, on a fixed set of shards: work of one
+// key runs serially and in submission order, work of keys on different shards in parallel.
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	// ErrQueueFull is returned when the shard of a key has as much work queued as it accepts
+	ErrQueueFull = errors.New("executor queue is full")
+	// ErrStopped is returned for work submitted once the executor is stopped
+	ErrStopped = errors.New("executor is stopped")
+)
+
+// Config sizes an executor: Shards workers each queueing up to QueueSize pieces of work, each of which must
+// start and finish within Timeout of its submission
+type Config struct {
+	Shards    int
+	QueueSize int
+	Timeout   time.Duration
+}
+
+// Executor runs work on the shard its key hashes to. It must be started before use.
+type Executor struct {
+	cfg    Config
+	shards []chan *task
+
+	mu      sync.RWMutex
+	stopped bool
+	wg      sync.WaitGroup
+
+	rejected atomic.Int64
+	expired  atomic.Int64
+
+	rejections   metric.Int64Counter
+	registration metric.Registration
+}
+
+// task is a piece of work; whichever of its worker and its submitter flips started first decides whether
+// it runs or is abandoned
+type task struct {
+	ctx     context.Context
+	fn      func(ctx context.Context) error
+	started atomic.Bool
+	done    chan error
+}
+
+// Stats is a snapshot of the executor's queues and of the work it turned down since it started
+type Stats struct {
+	Shards        int   `json:"shards"`
+	QueueCapacity int   `json:"queue_capacity"`
+	Queued        int   `json:"queued"`
+	MaxDepth      int   `json:"max_depth"`
+	Depths        []int `json:"depths"`
+	Rejected      int64 `json:"rejected"`
+	Expired       int64 `json:"expired"`
+}
+
+// New returns an executor for cfg, which must have at least one shard, a positive queue size and timeout
+func New(cfg Config) (*Executor, error) {
+	if cfg.Shards <= 0 || cfg.QueueSize <= 0 || cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid executor config: shards, queue size and timeout must be positive")
+	}
+
+	e := &Executor{cfg: cfg, shards: make([]chan *task, cfg.Shards)}
+	for i := range e.shards {
+		e.shards[i] = make(chan *task, cfg.QueueSize)
+	}
+
+	meter := telemetry.Meter()
+	depth, err := meter.Int64ObservableGauge("executor.queue.depth",
+		metric.WithDescription("Work queued on a shard of the executor"),
+		metric.WithUnit("{task}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue depth gauge: %w", err)
+	}
+	if e.rejections, err = meter.Int64Counter("executor.rejections",
+		metric.WithDescription("Work turned down by the executor, by reason"),
+		metric.WithUnit("{task}"),
+	); err != nil {
+		return nil, fmt.Errorf("failed to create rejections counter: %w", err)
+	}
+	if e.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for i, shard := range e.shards {
+			o.ObserveInt64(depth, int64(len(shard)), metric.WithAttributes(attribute.Int("shard", i)))
+		}
+		return nil
+	}, depth); err != nil {
+		return nil, fmt.Errorf("failed to register queue depth callback: %w", err)
+	}
+	return e, nil
+}
+
+// Start starts a worker per shard
+func (e *Executor) Start() {
+	for _, shard := range e.shards {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			for t := range shard {
+				e.run(t)
+			}
+		}()
+	}
+}
+
+// Stop turns down new work and waits for the queued work to run, or to expire, until ctx is done
+func (e *Executor) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.stopped {
+		e.stopped = true
+		for _, shard := range e.shards {
+			close(shard)
+		}
+		_ = e.registration.Unregister()
+	}
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Do runs fn on the shard of key after the work submitted before it for the same shard, and returns its
+// error. fn is given ctx bounded by the executor's timeout. It returns ErrQueueFull rather than waiting
+// when the shard's queue is full, and the context's error when the timeout elapses or ctx is done before
+// fn starts; once started, fn is always waited for.
+func (e *Executor) Do(ctx context.Context, key int64, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	t := &task{ctx: ctx, fn: fn, done: make(chan error, 1)}
+	if err := e.enqueue(key, t); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-t.done:
+		return err
+	case <-ctx.Done():
+		if t.started.CompareAndSwap(false, true) {
+			e.expire()
+			return ctx.Err()
+		}
+		return <-t.done
+	}
+}
+
+// Stats returns a snapshot of the executor's queues
+func (e *Executor) Stats() Stats {
+	stats := Stats{
+		Shards:        len(e.shards),
+		QueueCapacity: e.cfg.QueueSize,
+		Depths:        make([]int, len(e.shards)),
+		Rejected:      e.rejected.Load(),
+		Expired:       e.expired.Load(),
+	}
+	for i, shard := range e.shards {
+		stats.Depths[i] = len(shard)
+		stats.Queued += stats.Depths[i]
+		stats.MaxDepth = max(stats.MaxDepth, stats.Depths[i])
+	}
+	return stats
+}
+
+func (e *Executor) enqueue(key int64, t *task) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.stopped {
+		return ErrStopped
+	}
+	select {
+	case e.shards[e.shard(key)] <- t:
+		return nil
+	default:
+		e.rejected.Add(1)
+		e.rejections.Add(t.ctx, 1, metric.WithAttributes(attribute.String("reason", "queue_full")))
+		return ErrQueueFull
+	}
+}
+
+// run runs a task unless its submitter gave up on it or its time is up
+func (e *Executor) run(t *task) {
+	if !t.started.CompareAndSwap(false, true) {
+		return
+	}
+	if err := t.ctx.Err(); err != nil {
+		e.expire()
+		t.done <- err
+		return
+	}
+	t.done <- t.fn(t.ctx)
+}
+
+func (e *Executor) expire() {
+	e.expired.Add(1)
+	e.rejections.Add(context.Background(), 1, metric.WithAttributes(attribute.String("reason", "expired")))
+}
+
+// shard maps a key to a shard, mixing its bits first so that sequential ids spread evenly
+func (e *Executor) shard(key int64) int {
+	h := uint64(key)
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return int(h % uint64(len(e.shards)))
+}
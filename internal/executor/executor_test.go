@@ -0,0 +1,173 @@
+package executor_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExecutor(t *testing.T, cfg executor.Config) *executor.Executor {
+	t.Helper()
+	e, err := executor.New(cfg)
+	require.NoError(t, err)
+	e.Start()
+	t.Cleanup(func() { _ = e.Stop(context.Background()) })
+	return e
+}
+
+func TestNew(t *testing.T) {
+	for _, cfg := range []executor.Config{
+		{Shards: 0, QueueSize: 1, Timeout: time.Second},
+		{Shards: 1, QueueSize: 0, Timeout: time.Second},
+		{Shards: 1, QueueSize: 1, Timeout: 0},
+	} {
+		_, err := executor.New(cfg)
+		assert.Error(t, err)
+	}
+}
+
+func TestDo(t *testing.T) {
+	t.Run("Work of a key should run serially and in order", func(t *testing.T) {
+		e := newExecutor(t, executor.Config{Shards: 4, QueueSize: 100, Timeout: 5 * time.Second})
+
+		// Work is queued behind a blocked piece of work one at a time, so its submission order is known
+		release := make(chan struct{})
+		started := make(chan struct{})
+		go func() {
+			_ = e.Do(context.Background(), 7, func(context.Context) error {
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+		<-started
+
+		var running atomic.Int32
+		var order []int
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := e.Do(context.Background(), 7, func(context.Context) error {
+					assert.Equal(t, int32(1), running.Add(1))
+					defer running.Add(-1)
+					order = append(order, i)
+					return nil
+				})
+				assert.NoError(t, err)
+			}()
+			require.Eventually(t, func() bool { return e.Stats().Queued == i+1 }, time.Second, time.Millisecond)
+		}
+		close(release)
+		wg.Wait()
+
+		require.Len(t, order, 50)
+		for i := range order {
+			assert.Equal(t, i, order[i])
+		}
+	})
+
+	t.Run("Work of keys on different shards should run in parallel", func(t *testing.T) {
+		e := newExecutor(t, executor.Config{Shards: 64, QueueSize: 1, Timeout: 5 * time.Second})
+
+		// Two workers waiting for each other only finish if they run at the same time
+		var barrier sync.WaitGroup
+		barrier.Add(2)
+		errs := make(chan error, 2)
+		for _, key := range []int64{1, 2} {
+			go func() {
+				errs <- e.Do(context.Background(), key, func(context.Context) error {
+					barrier.Done()
+					barrier.Wait()
+					return nil
+				})
+			}()
+		}
+		for range 2 {
+			select {
+			case err := <-errs:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("keys were not run in parallel")
+			}
+		}
+	})
+
+	t.Run("Full queue should turn down work rather than wait", func(t *testing.T) {
+		e := newExecutor(t, executor.Config{Shards: 1, QueueSize: 1, Timeout: 5 * time.Second})
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		go func() {
+			_ = e.Do(context.Background(), 1, func(context.Context) error {
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+		<-started
+		go func() { _ = e.Do(context.Background(), 1, func(context.Context) error { return nil }) }()
+		require.Eventually(t, func() bool { return e.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+		err := e.Do(context.Background(), 1, func(context.Context) error { return nil })
+		assert.ErrorIs(t, err, executor.ErrQueueFull)
+		close(release)
+
+		stats := e.Stats()
+		assert.Equal(t, int64(1), stats.Rejected)
+		assert.Equal(t, 1, stats.QueueCapacity)
+	})
+
+	t.Run("Work that could not start in time should not run", func(t *testing.T) {
+		e := newExecutor(t, executor.Config{Shards: 1, QueueSize: 10, Timeout: 50 * time.Millisecond})
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		go func() {
+			_ = e.Do(context.Background(), 1, func(context.Context) error {
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+		<-started
+
+		var ran atomic.Bool
+		err := e.Do(context.Background(), 1, func(context.Context) error {
+			ran.Store(true)
+			return nil
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		close(release)
+
+		require.NoError(t, e.Stop(context.Background()))
+		assert.False(t, ran.Load())
+		assert.Equal(t, int64(1), e.Stats().Expired)
+	})
+
+	t.Run("Started work should be waited for past the timeout", func(t *testing.T) {
+		e := newExecutor(t, executor.Config{Shards: 1, QueueSize: 1, Timeout: 20 * time.Millisecond})
+
+		err := e.Do(context.Background(), 1, func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Stopped executor should turn down work", func(t *testing.T) {
+		e := newExecutor(t, executor.Config{Shards: 2, QueueSize: 1, Timeout: time.Second})
+		require.NoError(t, e.Stop(context.Background()))
+
+		err := e.Do(context.Background(), 1, func(context.Context) error { return nil })
+		assert.ErrorIs(t, err, executor.ErrStopped)
+	})
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+// csvHeader is the column order of CSV exports; columns are only ever appended
+var csvHeader = []string{
+	"transaction_id", "account_id", "event_date", "operation_type_id", "operation_type", "amount", "balance",
+	"merchant_reference", "transfer_id", "parent_transaction_id", "duplicate_of_transaction_id",
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Begin(*repository.Account, time.Time, time.Time, float64) error {
+	return e.w.Write(csvHeader)
+}
+
+func (e *csvEncoder) Write(txn *repository.Transaction) error {
+	return e.w.Write([]string{
+		strconv.FormatInt(txn.ID, 10),
+		strconv.FormatInt(txn.AccountID, 10),
+		txn.EventDate.UTC().Format(time.RFC3339Nano),
+		strconv.FormatInt(txn.OperationTypeID, 10),
+		csvText(txn.OperationType),
+		formatAmount(txn.Amount),
+		formatAmount(txn.Balance),
+		csvText(txn.MerchantReference),
+		optionalID(txn.TransferID),
+		optionalID(txn.ParentTransactionID),
+		optionalID(txn.DuplicateOfID),
+	})
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvText neutralizes text a spreadsheet would evaluate as a formula
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// optionalID leaves the cell of a missing link empty
+func optionalID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,73 @@
+// Package export encodes an account's transactions for download as CSV, NDJSON or OFX. Encoders write
+// each transaction as it arrives, so exports of any size stream in constant memory.
+package export
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+// Formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatOFX    = "ofx"
+)
+
+// Encoder writes an export: Begin once with the account, the exported period and the balance the period
+// opens with, Write per transaction oldest first, then End, which flushes the output
+type Encoder interface {
+	Begin(account *repository.Account, from, to time.Time, opening float64) error
+	Write(txn *repository.Transaction) error
+	End() error
+}
+
+// Options configures the encoders. Currency and BankID identify the account in OFX, which requires
+// them; Now stamps OFX files and closes open-ended periods.
+type Options struct {
+	Currency string
+	BankID   string
+	Now      func() time.Time
+}
+
+// NewEncoder returns the encoder of the format writing to w
+func NewEncoder(format string, w io.Writer, opts Options) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVEncoder(w), nil
+	case FormatNDJSON:
+		return newNDJSONEncoder(w), nil
+	case FormatOFX:
+		return newOFXEncoder(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q: must be %s, %s or %s", format, FormatCSV, FormatNDJSON, FormatOFX)
+	}
+}
+
+// ContentType returns the media type of the format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatOFX:
+		return "application/x-ofx"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// formatAmount renders an amount with exactly two decimals and no negative zero
+func formatAmount(amount float64) string {
+	cents := math.Round(amount * 100)
+	if cents == 0 {
+		cents = 0
+	}
+	return strconv.FormatFloat(cents/100, 'f', 2, 64)
+}
@@ -0,0 +1,119 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/export"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	exportFrom = time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	exportTo   = time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func exportTransactions() []*repository.Transaction {
+	return []*repository.Transaction{
+		{ID: 7, AccountID: 1, OperationTypeID: 1, OperationType: "Normal Purchase", Amount: -50.5, Balance: 0,
+			EventDate: time.Date(2025, 4, 2, 9, 30, 0, 0, time.UTC), MerchantReference: "=HYPERLINK(\"x\")"},
+		{ID: 8, AccountID: 1, OperationTypeID: 9, OperationType: "Fee", Amount: -1.1, Balance: -1.1,
+			EventDate: time.Date(2025, 4, 2, 9, 30, 0, 0, time.UTC), ParentTransactionID: 7},
+		{ID: 9, AccountID: 1, OperationTypeID: 4, OperationType: "Credit Voucher & Refund", Amount: 60, Balance: 8.4,
+			EventDate: time.Date(2025, 4, 3, 12, 0, 0, 0, time.UTC)},
+	}
+}
+
+func encode(t *testing.T, format string, account *repository.Account, opening float64, transactions []*repository.Transaction) string {
+	t.Helper()
+	var buf bytes.Buffer
+	enc, err := export.NewEncoder(format, &buf, export.Options{
+		Currency: "USD",
+		BankID:   "000000000",
+		Now:      func() time.Time { return exportTo },
+	})
+	require.NoError(t, err)
+	require.NoError(t, enc.Begin(account, exportFrom, exportTo, opening))
+	for _, txn := range transactions {
+		require.NoError(t, enc.Write(txn))
+	}
+	require.NoError(t, enc.End())
+	return buf.String()
+}
+
+func TestCSV(t *testing.T) {
+	out := encode(t, export.FormatCSV, &repository.Account{ID: 1, Product: "credit"}, 0, exportTransactions())
+
+	assert.Equal(t, `transaction_id,account_id,event_date,operation_type_id,operation_type,amount,balance,merchant_reference,transfer_id,parent_transaction_id,duplicate_of_transaction_id
+7,1,2025-04-02T09:30:00Z,1,Normal Purchase,-50.50,0.00,"'=HYPERLINK(""x"")",,,
+8,1,2025-04-02T09:30:00Z,9,Fee,-1.10,-1.10,,,7,
+9,1,2025-04-03T12:00:00Z,4,Credit Voucher & Refund,60.00,8.40,,,,
+`, out)
+}
+
+func TestNDJSON(t *testing.T) {
+	out := encode(t, export.FormatNDJSON, &repository.Account{ID: 1, Product: "credit"}, 0, exportTransactions()[1:2])
+
+	assert.Equal(t, `{"transaction_id":8,"account_id":1,"event_date":"2025-04-02T09:30:00Z","operation_type_id":9,"operation_type":"Fee","amount":-1.10,"balance":-1.10,"parent_transaction_id":7}`+"\n", out)
+}
+
+func TestOFX(t *testing.T) {
+	t.Run("Credit accounts export a credit card statement", func(t *testing.T) {
+		out := encode(t, export.FormatOFX, &repository.Account{ID: 1, Product: "credit"}, 0, exportTransactions())
+
+		assert.True(t, strings.HasPrefix(out, `<?xml version="1.0"`))
+		assert.Contains(t, out, `<?OFX OFXHEADER="200" VERSION="220"`)
+		assert.Contains(t, out, "<CCACCTFROM><ACCTID>1</ACCTID></CCACCTFROM>")
+		assert.Contains(t, out, "<DTSTART>20250401000000.000[0:UTC]</DTSTART><DTEND>20250501000000.000[0:UTC]</DTEND>")
+		assert.Contains(t, out, "<STMTTRN><TRNTYPE>POS</TRNTYPE><DTPOSTED>20250402093000.000[0:UTC]</DTPOSTED><TRNAMT>-50.50</TRNAMT><FITID>7</FITID><NAME>Normal Purchase</NAME><MEMO>=HYPERLINK(&#34;x&#34;)</MEMO></STMTTRN>")
+		assert.Contains(t, out, "<TRNTYPE>FEE</TRNTYPE>")
+		assert.Contains(t, out, "<NAME>Credit Voucher &amp; Refund</NAME>")
+		assert.Contains(t, out, "<LEDGERBAL><BALAMT>8.40</BALAMT>")
+		assertWellFormed(t, out)
+	})
+
+	t.Run("Ledger balance carries the balance the period opens with", func(t *testing.T) {
+		out := encode(t, export.FormatOFX, &repository.Account{ID: 1, Product: "credit"}, -100, exportTransactions())
+
+		assert.Contains(t, out, "<LEDGERBAL><BALAMT>-91.60</BALAMT><DTASOF>20250501000000.000[0:UTC]</DTASOF></LEDGERBAL>")
+	})
+
+	t.Run("Prepaid accounts export a checking account statement", func(t *testing.T) {
+		out := encode(t, export.FormatOFX, &repository.Account{ID: 2, Product: "prepaid"}, 0, nil)
+
+		assert.Contains(t, out, "<BANKACCTFROM><BANKID>000000000</BANKID><ACCTID>2</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>")
+		assert.Contains(t, out, "<BALAMT>0.00</BALAMT>")
+		assertWellFormed(t, out)
+	})
+
+	t.Run("Long names are cut to the OFX limit", func(t *testing.T) {
+		txn := &repository.Transaction{ID: 1, OperationTypeID: 1, OperationType: strings.Repeat("é", 20), Amount: -1, EventDate: exportFrom}
+		out := encode(t, export.FormatOFX, &repository.Account{ID: 1, Product: "credit"}, 0, []*repository.Transaction{txn})
+
+		assert.Contains(t, out, "<NAME>"+strings.Repeat("é", 16)+"</NAME>")
+	})
+}
+
+// assertWellFormed parses the whole document as XML
+func assertWellFormed(t *testing.T, out string) {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(out))
+	for {
+		_, err := dec.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			return
+		}
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	_, err := export.NewEncoder("xlsx", &bytes.Buffer{}, export.Options{})
+	assert.ErrorContains(t, err, `unsupported export format "xlsx"`)
+	assert.Equal(t, "text/csv; charset=utf-8", export.ContentType(export.FormatCSV))
+}
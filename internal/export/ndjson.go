@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+// ndjsonLine is one transaction of an NDJSON export; amounts are numbers with two decimals
+type ndjsonLine struct {
+	TransactionID       int64       `json:"transaction_id"`
+	AccountID           int64       `json:"account_id"`
+	EventDate           string      `json:"event_date"`
+	OperationTypeID     int64       `json:"operation_type_id"`
+	OperationType       string      `json:"operation_type"`
+	Amount              json.Number `json:"amount"`
+	Balance             json.Number `json:"balance"`
+	MerchantReference   string      `json:"merchant_reference,omitempty"`
+	TransferID          int64       `json:"transfer_id,omitempty"`
+	ParentTransactionID int64       `json:"parent_transaction_id,omitempty"`
+	DuplicateOfID       int64       `json:"duplicate_of_transaction_id,omitempty"`
+}
+
+type ndjsonEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) *ndjsonEncoder {
+	bw := bufio.NewWriter(w)
+	return &ndjsonEncoder{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (e *ndjsonEncoder) Begin(*repository.Account, time.Time, time.Time, float64) error {
+	return nil
+}
+
+func (e *ndjsonEncoder) Write(txn *repository.Transaction) error {
+	return e.enc.Encode(ndjsonLine{
+		TransactionID:       txn.ID,
+		AccountID:           txn.AccountID,
+		EventDate:           txn.EventDate.UTC().Format(time.RFC3339Nano),
+		OperationTypeID:     txn.OperationTypeID,
+		OperationType:       txn.OperationType,
+		Amount:              json.Number(formatAmount(txn.Amount)),
+		Balance:             json.Number(formatAmount(txn.Balance)),
+		MerchantReference:   txn.MerchantReference,
+		TransferID:          txn.TransferID,
+		ParentTransactionID: txn.ParentTransactionID,
+		DuplicateOfID:       txn.DuplicateOfID,
+	})
+}
+
+func (e *ndjsonEncoder) End() error {
+	return e.w.Flush()
+}
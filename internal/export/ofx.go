@@ -0,0 +1,148 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+)
+
+// ofxHeader opens an OFX 2.2 file, the XML form read by personal-finance tools
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+// ofxNameMaxLen is the longest NAME OFX allows
+const ofxNameMaxLen = 32
+
+// ofxTransactionTypes are the OFX TRNTYPEs of the operation types; others are DEBIT or CREDIT by sign
+var ofxTransactionTypes = map[int64]string{
+	1:                                   "POS",
+	2:                                   "POS",
+	3:                                   "ATM",
+	4:                                   "CREDIT",
+	service.OperationTypeTransferDebit:  "XFER",
+	service.OperationTypeTransferCredit: "XFER",
+	service.OperationTypeInterest:       "INT",
+	service.OperationTypeLateFee:        "FEE",
+	service.OperationTypeFee:            "FEE",
+}
+
+// ofxEncoder writes a statement download: a credit card statement for credit accounts and a checking
+// account statement otherwise. Its ledger balance is the account's as of the end of the period: the opening
+// balance plus the net of the exported transactions.
+type ofxEncoder struct {
+	w       *bufio.Writer
+	opts    Options
+	balance float64
+	end     time.Time
+	credit  bool
+	err     error
+}
+
+func newOFXEncoder(w io.Writer, opts Options) *ofxEncoder {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &ofxEncoder{w: bufio.NewWriter(w), opts: opts}
+}
+
+func (e *ofxEncoder) Begin(account *repository.Account, from, to time.Time, opening float64) error {
+	now := e.opts.Now()
+	if from.IsZero() {
+		from = account.CreatedAt
+	}
+	if to.IsZero() {
+		to = now
+	}
+	e.end = to
+	e.balance = opening
+	e.credit = account.Product == service.ProductCredit
+
+	e.printf("%s<OFX>\n", ofxHeader)
+	e.printf("<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>")
+	e.printf("<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n", ofxDate(now))
+	accountID := strconv.FormatInt(account.ID, 10)
+	if e.credit {
+		e.printf("<CREDITCARDMSGSRSV1><CCSTMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+		e.printf("<CCSTMTRS><CURDEF>%s</CURDEF><CCACCTFROM><ACCTID>%s</ACCTID></CCACCTFROM>\n", ofxText(e.opts.Currency), accountID)
+	} else {
+		e.printf("<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+		e.printf("<STMTRS><CURDEF>%s</CURDEF><BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n",
+			ofxText(e.opts.Currency), ofxText(e.opts.BankID), accountID)
+	}
+	e.printf("<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", ofxDate(from), ofxDate(to))
+	return e.err
+}
+
+func (e *ofxEncoder) Write(txn *repository.Transaction) error {
+	trnType, ok := ofxTransactionTypes[txn.OperationTypeID]
+	if !ok {
+		trnType = "DEBIT"
+		if txn.Amount > 0 {
+			trnType = "CREDIT"
+		}
+	}
+	e.balance += txn.Amount
+
+	e.printf("<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%d</FITID><NAME>%s</NAME>",
+		trnType, ofxDate(txn.EventDate), formatAmount(txn.Amount), txn.ID, ofxText(ofxName(txn)))
+	if txn.MerchantReference != "" {
+		e.printf("<MEMO>%s</MEMO>", ofxText(txn.MerchantReference))
+	}
+	e.printf("</STMTTRN>\n")
+	return e.err
+}
+
+func (e *ofxEncoder) End() error {
+	e.printf("</BANKTRANLIST><LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", formatAmount(e.balance), ofxDate(e.end))
+	if e.credit {
+		e.printf("</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>\n")
+	} else {
+		e.printf("</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n")
+	}
+	e.printf("</OFX>\n")
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// printf writes to the output, keeping the first error
+func (e *ofxEncoder) printf(format string, args ...any) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+// ofxDate formats an instant as an OFX datetime in UTC
+func ofxDate(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:UTC]"
+}
+
+// ofxName is the payee of the transaction, cut to the length OFX allows
+func ofxName(txn *repository.Transaction) string {
+	name := txn.OperationType
+	if name == "" {
+		name = "Operation " + strconv.FormatInt(txn.OperationTypeID, 10)
+	}
+	for len(name) > ofxNameMaxLen {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// ofxText escapes text for an OFX element
+func ofxText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -27,7 +27,7 @@ func (h *AccountsHandler) CreateAccount(w http.ResponseWriter, r *http.Request)
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(&req); err != nil {
-		log.Error().Str("request_id", reqID).Err(err).Msg("error decoding create account request")
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("error decoding create account request")
 		writer.WriteError(
 			w, r.Context(),
 			http.StatusBadRequest,
@@ -39,7 +39,7 @@ func (h *AccountsHandler) CreateAccount(w http.ResponseWriter, r *http.Request)
 	}
 
 	if r.ContentLength <= 0 {
-		log.Error().Str("request_id", reqID).Err(fmt.Errorf("invalid create account request")).Msg("invalid request body")
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid create account request")).Msg("invalid request body")
 		writer.WriteError(
 			w, r.Context(),
 			http.StatusBadRequest,
@@ -50,11 +50,13 @@ func (h *AccountsHandler) CreateAccount(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	account, err := h.accountService.CreateAccount(r.Context(), req.DocumentNumber)
+	account, err := h.accountService.CreateAccount(r.Context(), req.CustomerID, req.DocumentNumber, req.Product)
 	if err != nil {
-		log.Error().Str("request_id", reqID).Err(err).Msg("failed to create account")
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to create account")
 		switch {
-		case errors.Is(err, service.ErrInvalidDocumentNumber):
+		case errors.Is(err, service.ErrInvalidDocumentNumber),
+			errors.Is(err, service.ErrDocumentNumberMismatch),
+			errors.Is(err, service.ErrInvalidProduct):
 			writer.WriteError(
 				w, r.Context(),
 				http.StatusBadRequest,
@@ -63,6 +65,15 @@ func (h *AccountsHandler) CreateAccount(w http.ResponseWriter, r *http.Request)
 				err.Error(),
 			)
 			return
+		case errors.Is(err, service.ErrCustomerNotFound):
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusNotFound,
+				ErrCodeInvalidRequest,
+				ErrTitleCustNotFound,
+				err.Error(),
+			)
+			return
 		case errors.Is(err, service.ErrAccountAlreadyExists):
 			writer.WriteError(
 				w, r.Context(),
@@ -84,7 +95,7 @@ func (h *AccountsHandler) CreateAccount(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	log.Info().Str("request_id", reqID).Int64("id", account.ID).Msg("account creation successful")
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Int64("id", account.ID).Msg("account creation successful")
 	writer.WriteJSON(w, http.StatusCreated, account)
 	return
 }
@@ -94,7 +105,7 @@ func (h *AccountsHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 	reqID := middleware.GetRequestIDFromContext(r.Context())
 
 	if r.ContentLength > 0 {
-		log.Error().Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request body")
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request body")
 		writer.WriteError(
 			w, r.Context(),
 			http.StatusBadRequest,
@@ -107,7 +118,7 @@ func (h *AccountsHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 
 	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		log.Error().Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
 		writer.WriteError(
 			w, r.Context(),
 			http.StatusBadRequest,
@@ -120,7 +131,7 @@ func (h *AccountsHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 
 	account, err := h.accountService.GetAccount(r.Context(), accountID)
 	if err != nil {
-		log.Error().Str("request_id", reqID).Err(err).Msg("failed to get account")
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to get account")
 		writer.WriteError(
 			w, r.Context(),
 			http.StatusNotFound,
@@ -131,7 +142,76 @@ func (h *AccountsHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Info().Str("request_id", reqID).Int64("id", account.ID).Msg("account retrieval successful")
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Int64("id", account.ID).Msg("account retrieval successful")
 	writer.WriteJSON(w, http.StatusOK, account)
 	return
 }
+
+// SetAccountStatus handles activating, blocking and closing an account
+func (h *AccountsHandler) SetAccountStatus(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidAccID,
+			err.Error(),
+		)
+		return
+	}
+
+	var req SetAccountStatusReq
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("error decoding set account status request")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			ErrInvalidReqBody,
+		)
+		return
+	}
+
+	account, err := h.accountService.SetAccountStatus(r.Context(), accountID, req.Status)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to set account status")
+		switch {
+		case errors.Is(err, service.ErrAccountNotFound):
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusNotFound,
+				ErrCodeInvalidRequest,
+				ErrTitleAccNotFound,
+				err.Error(),
+			)
+		case errors.Is(err, service.ErrAccountClosed):
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusConflict,
+				ErrCodeConflictErr,
+				ErrTitleConflict,
+				err.Error(),
+			)
+		default:
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusBadRequest,
+				ErrCodeInvalidRequest,
+				ErrTitleInvalidRequest,
+				err.Error(),
+			)
+		}
+		return
+	}
+
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Int64("id", account.ID).Str("status", account.Status).Msg("account status updated")
+	writer.WriteJSON(w, http.StatusOK, account)
+}
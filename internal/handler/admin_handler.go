@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/logging"
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/rs/zerolog/log"
+)
+
+func NewAdminHandler(adminToken string, auditService service.AuditService) *AdminHandler {
+	return &AdminHandler{adminToken: adminToken, auditService: auditService}
+}
+
+// RequireAdminToken rejects requests that do not carry the admin bearer token
+func (h *AdminHandler) RequireAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID := middleware.GetRequestIDFromContext(r.Context())
+
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if h.adminToken == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(h.adminToken)) != 1 {
+			log.Warn().Ctx(r.Context()).Str("request_id", reqID).Msg("rejected admin request")
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusUnauthorized,
+				ErrCodeUnauthorized,
+				ErrTitleUnauthorized,
+				ErrInvalidAdminToken,
+			)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(middleware.WithPrincipal(r.Context(), AdminPrincipal)))
+	})
+}
+
+// GetLogLevel returns the current global log level
+func (h *AdminHandler) GetLogLevel(w http.ResponseWriter, _ *http.Request) {
+	writer.WriteJSON(w, http.StatusOK, LogLevelResp{Level: logging.Level()})
+}
+
+// SetLogLevel changes the global log level without a restart and records the change in the audit log;
+// the level is restored if the change cannot be recorded
+func (h *AdminHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	var req LogLevelReq
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("error decoding log level request")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			ErrInvalidReqBody,
+		)
+		return
+	}
+
+	previous := logging.Level()
+	if err := logging.SetLevel(req.Level); err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("invalid log level")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			err.Error(),
+		)
+		return
+	}
+
+	if err := h.auditService.RecordConfigChange(r.Context(), "log_level", previous, logging.Level()); err != nil {
+		_ = logging.SetLevel(previous)
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to audit log level change")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusInternalServerError,
+			ErrCodeInvalidRequest,
+			ErrTitleAuditFailed,
+			err.Error(),
+		)
+		return
+	}
+
+	log.Warn().Ctx(r.Context()).Str("request_id", reqID).Str("previous", previous).Str("level", logging.Level()).Msg("log level changed")
+	writer.WriteJSON(w, http.StatusOK, LogLevelResp{Level: logging.Level()})
+}
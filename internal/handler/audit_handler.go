@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/rs/zerolog/log"
+)
+
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetAuditEvents lists the audit events matching the query filters, oldest first. A full page carries
+// next_after_id, to pass as after_id for the next one.
+func (h *AuditHandler) GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	filter, err := parseAuditEventFilter(r.URL.Query())
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("invalid audit event filter")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidAuditFilter,
+			err.Error(),
+		)
+		return
+	}
+
+	events, err := h.auditService.GetAuditEvents(r.Context(), filter)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to fetch audit events")
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidAuditFilter) {
+			status = http.StatusBadRequest
+		}
+		writer.WriteError(
+			w, r.Context(),
+			status,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidAuditFilter,
+			err.Error(),
+		)
+		return
+	}
+
+	resp := AuditEventsResp{Events: events}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = service.DefaultAuditEventsLimit
+	}
+	if len(events) > 0 && len(events) >= min(limit, service.MaxAuditEventsLimit) {
+		resp.NextAfterID = events[len(events)-1].ID
+	}
+	writer.WriteJSON(w, http.StatusOK, resp)
+}
+
+// parseAuditEventFilter reads the filters of an audit event listing; from and to are RFC 3339 instants
+func parseAuditEventFilter(query url.Values) (repository.AuditEventFilter, error) {
+	filter := repository.AuditEventFilter{
+		Action:     query.Get("action"),
+		EntityType: query.Get("entity_type"),
+		Principal:  query.Get("principal"),
+		RequestID:  query.Get("request_id"),
+	}
+
+	var err error
+	for name, target := range map[string]*int64{"entity_id": &filter.EntityID, "after_id": &filter.AfterID} {
+		if v := query.Get(name); v != "" {
+			if *target, err = strconv.ParseInt(v, 10, 64); err != nil || *target < 0 {
+				return filter, fmt.Errorf("invalid %s: must be a non-negative integer", name)
+			}
+		}
+	}
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := query.Get(name); v != "" {
+			if *target, err = time.Parse(time.RFC3339, v); err != nil {
+				return filter, fmt.Errorf("invalid %s: must be an RFC 3339 timestamp", name)
+			}
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 {
+			return filter, fmt.Errorf("invalid limit: must be between 1 and %d", service.MaxAuditEventsLimit)
+		}
+	}
+	return filter, nil
+}
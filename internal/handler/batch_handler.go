@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/rs/zerolog/log"
+)
+
+// ndjsonContentType is the media type of a batch sent as one transaction per line
+const ndjsonContentType = "application/x-ndjson"
+
+// CreateTransactionBatch posts a batch of transactions sent as a JSON array or, with Content-Type
+// application/x-ndjson, as one transaction per line. The mode query parameter picks atomic, the default, where
+// the first item that cannot be posted fails the batch and nothing is posted, or best_effort, where each item
+// is posted or rejected on its own.
+func (h *TransactionsHandler) CreateTransactionBatch(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = service.BatchModeAtomic
+	}
+
+	reqs, err := decodeBatch(r)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("error decoding create transaction batch request")
+		detail := ErrInvalidBatchBody
+		if errors.Is(err, service.ErrBatchTooLarge) || errors.Is(err, errBatchEventDate) {
+			detail = err.Error()
+		}
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			detail,
+		)
+		return
+	}
+
+	transactions := make([]service.NewTransaction, len(reqs))
+	for i, req := range reqs {
+		transactions[i] = service.NewTransaction{
+			AccountID:         req.AccountID,
+			OperationTypeID:   req.OperationTypeID,
+			Amount:            req.Amount,
+			MerchantReference: req.MerchantReference,
+		}
+	}
+
+	results, err := h.transactionService.CreateTransactionBatch(r.Context(), transactions, mode)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Str("mode", mode).Err(err).Msg("failed to create transaction batch")
+		status, code, title := http.StatusBadRequest, ErrCodeTransactionErr, ErrTitleTrxFailed
+		var denied *service.ScreeningDeniedError
+		switch {
+		case errors.Is(err, service.ErrDuplicateTransaction):
+			status, code, title = http.StatusConflict, ErrCodeDuplicateTrx, ErrTitleTrxDuplicate
+		case errors.As(err, &denied):
+			status, code, title = http.StatusUnprocessableEntity, denied.ReasonCodes[0], ErrTitleTrxDenied
+		case errors.Is(err, service.ErrEmptyBatch), errors.Is(err, service.ErrBatchTooLarge), errors.Is(err, service.ErrInvalidBatchMode):
+			code, title = ErrCodeInvalidRequest, ErrTitleInvalidRequest
+		}
+		writer.WriteError(w, r.Context(), status, code, title, err.Error())
+		return
+	}
+
+	resp := BatchResp{Mode: mode, Results: make([]BatchItemResp, len(results))}
+	for i, result := range results {
+		item := BatchItemResp{Index: result.Index}
+		if result.Err != nil {
+			item.Status = BatchItemRejected
+			item.Error = &BatchItemErrResp{Code: batchItemErrCode(result.Err), Detail: result.Err.Error()}
+			resp.Rejected++
+		} else {
+			txn := h.transactionResp(result.Transaction)
+			item.Status, item.Transaction = BatchItemPosted, &txn
+			resp.Posted++
+		}
+		resp.Results[i] = item
+	}
+
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Str("mode", mode).Int("posted", resp.Posted).Int("rejected", resp.Rejected).Msg("transaction batch processed")
+	status := http.StatusOK
+	if mode == service.BatchModeAtomic {
+		status = http.StatusCreated
+	}
+	writer.WriteJSON(w, status, resp)
+}
+
+// errBatchEventDate rejects a batch with a back-dated item, since batches are posted as of now
+var errBatchEventDate = errors.New(ErrBatchEventDate)
+
+// decodeBatch reads the transactions of a batch request, failing with service.ErrBatchTooLarge as soon as
+// there are more than service.MaxBatchItems of them
+func decodeBatch(r *http.Request) ([]CreateTransactionReq, error) {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	var reqs []CreateTransactionReq
+	next := func() error {
+		if len(reqs) == service.MaxBatchItems {
+			return service.ErrBatchTooLarge
+		}
+		var req CreateTransactionReq
+		if err := dec.Decode(&req); err != nil {
+			return err
+		}
+		if !req.EventDate.IsZero() {
+			return errBatchEventDate
+		}
+		reqs = append(reqs, req)
+		return nil
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == ndjsonContentType {
+		for dec.More() {
+			if err := next(); err != nil {
+				return nil, err
+			}
+		}
+		return reqs, nil
+	}
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("expected a JSON array: %v", err)
+	}
+	for dec.More() {
+		if err := next(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the JSON array")
+	}
+	return reqs, nil
+}
+
+// batchItemErrCode is the error code of a rejected item of a batch
+func batchItemErrCode(err error) string {
+	var denied *service.ScreeningDeniedError
+	switch {
+	case errors.Is(err, service.ErrDuplicateTransaction):
+		return ErrCodeDuplicateTrx
+	case errors.As(err, &denied):
+		return denied.ReasonCodes[0]
+	}
+	return ErrCodeTransactionErr
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func NewCustomersHandler(customerService service.CustomersService) *CustomersHandler {
+	return &CustomersHandler{customerService: customerService}
+}
+
+// CreateCustomer handles customer registration requests
+func (h *CustomersHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	var req CreateCustomerReq
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("error decoding create customer request")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			ErrInvalidReqBody,
+		)
+		return
+	}
+
+	customer, err := h.customerService.CreateCustomer(r.Context(), service.NewCustomer{
+		DocumentNumber: req.DocumentNumber,
+		Name:           req.Name,
+		BirthDate:      req.BirthDate,
+		Email:          req.Email,
+		Phone:          req.Phone,
+	})
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to create customer")
+		if errors.Is(err, service.ErrCustomerAlreadyExists) {
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusConflict,
+				ErrCodeConflictErr,
+				ErrTitleConflict,
+				err.Error(),
+			)
+			return
+		}
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			err.Error(),
+		)
+		return
+	}
+
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Int64("id", customer.ID).Msg("customer creation successful")
+	writer.WriteJSON(w, http.StatusCreated, customer)
+}
+
+// GetCustomer handles retrieving a customer by ID
+func (h *CustomersHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := h.customerID(w, r)
+	if !ok {
+		return
+	}
+
+	customer, err := h.customerService.GetCustomer(r.Context(), customerID)
+	if err != nil {
+		h.writeLookupError(w, r, err)
+		return
+	}
+
+	writer.WriteJSON(w, http.StatusOK, customer)
+}
+
+// GetCustomerAccounts handles listing the accounts held by a customer
+func (h *CustomersHandler) GetCustomerAccounts(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := h.customerID(w, r)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.customerService.GetCustomerAccounts(r.Context(), customerID)
+	if err != nil {
+		h.writeLookupError(w, r, err)
+		return
+	}
+
+	writer.WriteJSON(w, http.StatusOK, accounts)
+}
+
+// customerID parses the {id} URL parameter, writing a 400 response when it is invalid
+func (h *CustomersHandler) customerID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	customerID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidCustID,
+			err.Error(),
+		)
+		return 0, false
+	}
+	return customerID, true
+}
+
+func (h *CustomersHandler) writeLookupError(w http.ResponseWriter, r *http.Request, err error) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+	log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to get customer")
+
+	status, title := http.StatusInternalServerError, ErrTitleInvalidRequest
+	if errors.Is(err, service.ErrCustomerNotFound) {
+		status, title = http.StatusNotFound, ErrTitleCustNotFound
+	}
+	writer.WriteError(
+		w, r.Context(),
+		status,
+		ErrCodeInvalidRequest,
+		title,
+		err.Error(),
+	)
+}
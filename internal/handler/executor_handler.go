@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/executor"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+)
+
+func NewExecutorHandler(exec *executor.Executor) *ExecutorHandler {
+	return &ExecutorHandler{executor: exec}
+}
+
+// GetStats reports the depth of the executor's queues and the transactions it turned down
+func (h *ExecutorHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	writer.WriteJSON(w, http.StatusOK, h.executor.Stats())
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/export"
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/rs/zerolog/log"
+)
+
+func NewExportHandler(exportService service.ExportService, options export.Options) *ExportHandler {
+	return &ExportHandler{exportService: exportService, options: options}
+}
+
+// ExportTransactions handles downloading an account's transactions as CSV, NDJSON or OFX. Rows are
+// written as they are read, so errors after the first byte can only cut the download short.
+func (h *ExportHandler) ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	format, from, to, err := parseExportQuery(r.URL.Query())
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("invalid export request")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			err.Error(),
+		)
+		return
+	}
+
+	enc, err := export.NewEncoder(format, w, h.options)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("invalid export format")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			err.Error(),
+		)
+		return
+	}
+
+	// Large exports outlast the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to lift the write deadline of an export")
+	}
+
+	sink := &exportSink{Encoder: enc, w: w, format: format}
+	err = h.exportService.ExportTransactions(r.Context(), accountID, from, to, sink)
+	if err == nil {
+		return
+	}
+
+	log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Int64("id", accountID).Int("transactions", sink.count).Msg("failed to export account transactions")
+	if sink.begun {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrInvalidExportPeriod):
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			err.Error(),
+		)
+	case errors.Is(err, service.ErrAccountNotFound):
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusNotFound,
+			ErrCodeInvalidRequest,
+			ErrTitleAccNotFound,
+			err.Error(),
+		)
+	default:
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusInternalServerError,
+			ErrCodeInvalidRequest,
+			ErrTitleExportFailed,
+			err.Error(),
+		)
+	}
+}
+
+// exportSink sets the download headers when the export begins, after the account was found
+type exportSink struct {
+	export.Encoder
+	w      http.ResponseWriter
+	format string
+	begun  bool
+	count  int
+}
+
+func (s *exportSink) Begin(account *repository.Account, from, to time.Time, opening float64) error {
+	s.w.Header().Set("Content-Type", export.ContentType(s.format))
+	s.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%d-transactions.%s"`, account.ID, s.format))
+	s.w.Header().Set("Cache-Control", "no-store")
+	s.begun = true
+	return s.Encoder.Begin(account, from, to, opening)
+}
+
+func (s *exportSink) Write(txn *repository.Transaction) error {
+	s.count++
+	return s.Encoder.Write(txn)
+}
+
+// parseExportQuery reads the format, csv by default, and the exported period. Bounds are RFC 3339
+// timestamps or YYYY-MM-DD dates in UTC; a date as to includes the whole day.
+func parseExportQuery(query url.Values) (format string, from, to time.Time, err error) {
+	format = query.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if v := query.Get("from"); v != "" {
+		if from, err = parseExportBound(v, false); err != nil {
+			return "", from, to, errors.New("invalid from: must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = parseExportBound(v, true); err != nil {
+			return "", from, to, errors.New("invalid to: must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+	}
+	return format, from, to, nil
+}
+
+func parseExportBound(v string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return t, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
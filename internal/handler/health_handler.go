@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/health"
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/rs/zerolog/log"
+)
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Livez reports that the process is up and serving HTTP; it never checks dependencies
+func (h *HealthHandler) Livez(w http.ResponseWriter, _ *http.Request) {
+	writer.WriteJSON(w, http.StatusOK, HealthStatusResp{Status: health.StatusUp})
+}
+
+// Readyz reports whether the service should receive traffic
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Run(r.Context())
+	if report.Status != health.StatusUp {
+		log.Warn().Ctx(r.Context()).Str("request_id", middleware.GetRequestIDFromContext(r.Context())).Interface("checks", report.Checks).Msg("readiness check failed")
+		writer.WriteJSON(w, http.StatusServiceUnavailable, HealthStatusResp{Status: report.Status})
+		return
+	}
+	writer.WriteJSON(w, http.StatusOK, HealthStatusResp{Status: report.Status})
+}
+
+// Healthz reports every dependency check with its latency
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Run(r.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	writer.WriteJSON(w, status, report)
+}
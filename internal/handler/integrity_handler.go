@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/rs/zerolog/log"
+)
+
+func NewIntegrityHandler(integrityService service.IntegrityService) *IntegrityHandler {
+	return &IntegrityHandler{integrityService: integrityService}
+}
+
+// VerifyAccountChain handles recomputing an account's transaction hash chain and reporting the
+// first broken link, if any
+func (h *IntegrityHandler) VerifyAccountChain(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.integrityService.VerifyChain(r.Context(), accountID)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to verify account chain")
+		if errors.Is(err, service.ErrAccountNotFound) {
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusNotFound,
+				ErrCodeInvalidRequest,
+				ErrTitleAccNotFound,
+				err.Error(),
+			)
+			return
+		}
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusInternalServerError,
+			ErrCodeInvalidRequest,
+			ErrTitleIntegrityFailed,
+			err.Error(),
+		)
+		return
+	}
+
+	if !report.Intact {
+		log.Warn().Ctx(r.Context()).Str("request_id", reqID).Int64("id", accountID).Int64("transaction_id", report.Break.TransactionID).Str("reason", report.Break.Reason).Msg("account transaction chain is broken")
+	}
+	writer.WriteJSON(w, http.StatusOK, newIntegrityResp(report))
+}
+
+func newIntegrityResp(report *service.ChainReport) IntegrityResp {
+	resp := IntegrityResp{
+		AccountID: report.AccountID,
+		Intact:    report.Intact,
+		Length:    report.Length,
+		Unchained: report.Unchained,
+		Head:      report.Head,
+	}
+	if report.Break != nil {
+		resp.Break = &ChainBreakResp{
+			TransactionID: report.Break.TransactionID,
+			Reason:        report.Break.Reason,
+			Expected:      report.Break.Expected,
+			Actual:        report.Break.Actual,
+		}
+	}
+	return resp
+}
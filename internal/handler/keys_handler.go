@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/receipt"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+)
+
+func NewKeysHandler(keyring *receipt.Keyring) *KeysHandler {
+	return &KeysHandler{keyring: keyring}
+}
+
+// GetKeys handles publishing the receipt verification keys as a JSON Web Key Set, retired keys included
+func (h *KeysHandler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writer.WriteJSON(w, http.StatusOK, h.keyring.JWKSet())
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func NewLedgerHandler(ledgerService service.LedgerService) *LedgerHandler {
+	return &LedgerHandler{ledgerService: ledgerService}
+}
+
+// VerifyAccountLedger handles reporting an account's ledger balances and verifying its
+// transaction balances against them
+func (h *LedgerHandler) VerifyAccountLedger(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidAccID,
+			err.Error(),
+		)
+		return
+	}
+
+	report, err := h.ledgerService.VerifyAccount(r.Context(), accountID)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to verify account ledger")
+		if errors.Is(err, service.ErrAccountNotFound) {
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusNotFound,
+				ErrCodeInvalidRequest,
+				ErrTitleAccNotFound,
+				err.Error(),
+			)
+			return
+		}
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusInternalServerError,
+			ErrCodeInvalidRequest,
+			ErrTitleLedgerFailed,
+			err.Error(),
+		)
+		return
+	}
+
+	if !report.Consistent {
+		log.Warn().Ctx(r.Context()).Str("request_id", reqID).Int64("id", accountID).Int("mismatches", len(report.Mismatches)).Msg("account ledger is inconsistent")
+	}
+	writer.WriteJSON(w, http.StatusOK, newLedgerResp(report))
+}
+
+func newLedgerResp(report *service.LedgerReport) LedgerResp {
+	resp := LedgerResp{
+		AccountID:  report.AccountID,
+		Balances:   report.Balances,
+		Consistent: report.Consistent,
+		Mismatches: make([]LedgerMismatchResp, 0, len(report.Mismatches)),
+	}
+	if resp.Balances == nil {
+		resp.Balances = []*repository.LedgerAccountBalance{}
+	}
+	for _, m := range report.Mismatches {
+		resp.Mismatches = append(resp.Mismatches, LedgerMismatchResp{
+			TransactionID:    m.TransactionID,
+			ProjectedBalance: m.ProjectedBalance,
+			LedgerBalance:    m.LedgerBalance,
+		})
+	}
+	return resp
+}
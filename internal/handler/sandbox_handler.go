@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/rs/zerolog/log"
+)
+
+func NewSandboxHandler(sandboxService service.SandboxService) *SandboxHandler {
+	return &SandboxHandler{sandboxService: sandboxService}
+}
+
+// GetClock handles reporting the sandbox's virtual time
+func (h *SandboxHandler) GetClock(w http.ResponseWriter, r *http.Request) {
+	writer.WriteJSON(w, http.StatusOK, ClockResp{Now: h.sandboxService.Now()})
+}
+
+// AdvanceClock handles moving the sandbox's virtual time forward, running the daily jobs of each day crossed
+func (h *SandboxHandler) AdvanceClock(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	var req AdvanceClockReq
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("error decoding advance clock request")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			ErrInvalidReqBody,
+		)
+		return
+	}
+	if (req.Days == 0) == (req.To == nil) {
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			ErrInvalidClockReq,
+		)
+		return
+	}
+
+	to := h.sandboxService.Now().AddDate(0, 0, req.Days)
+	if req.To != nil {
+		to = *req.To
+	}
+
+	advance, err := h.sandboxService.AdvanceClock(r.Context(), to)
+	if errors.Is(err, service.ErrClockBackwards) || errors.Is(err, service.ErrClockAdvanceTooFar) {
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			err.Error(),
+		)
+		return
+	}
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to run sandbox jobs")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusInternalServerError,
+			ErrCodeTransactionErr,
+			ErrTitleSandboxFailed,
+			err.Error(),
+		)
+		return
+	}
+
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Time("now", advance.To).
+		Int("statements", len(advance.Statements)).Int("accrual_runs", len(advance.AccrualRuns)).Msg("sandbox clock advanced")
+	writer.WriteJSON(w, http.StatusOK, ClockAdvanceResp{
+		From:        advance.From,
+		To:          advance.To,
+		Statements:  advance.Statements,
+		AccrualRuns: advance.AccrualRuns,
+	})
+}
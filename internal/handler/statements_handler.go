@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func NewStatementsHandler(statementService service.StatementsService) *StatementsHandler {
+	return &StatementsHandler{statementService: statementService}
+}
+
+// GetBillingCycle handles retrieving the billing cycle of an account
+func (h *StatementsHandler) GetBillingCycle(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	cycle, err := h.statementService.GetBillingCycle(r.Context(), accountID)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to get billing cycle")
+		writeAccountLookupError(w, r, err)
+		return
+	}
+
+	writer.WriteJSON(w, http.StatusOK, cycle)
+}
+
+// SetBillingCycle handles configuring the billing cycle of a credit account
+func (h *StatementsHandler) SetBillingCycle(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetBillingCycleReq
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("error decoding set billing cycle request")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			ErrInvalidReqBody,
+		)
+		return
+	}
+
+	cycle, err := h.statementService.SetBillingCycle(r.Context(), accountID, req.ClosingDay, req.DueDayOffset)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to set billing cycle")
+		switch {
+		case errors.Is(err, service.ErrAccountNotFound):
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusNotFound,
+				ErrCodeInvalidRequest,
+				ErrTitleAccNotFound,
+				err.Error(),
+			)
+		case errors.Is(err, service.ErrBillingNotSupported):
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusUnprocessableEntity,
+				ErrCodeInvalidRequest,
+				ErrTitleInvalidRequest,
+				err.Error(),
+			)
+		default:
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusBadRequest,
+				ErrCodeInvalidRequest,
+				ErrTitleInvalidRequest,
+				err.Error(),
+			)
+		}
+		return
+	}
+
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Int64("id", accountID).Int("closing_day", cycle.ClosingDay).Msg("billing cycle updated")
+	writer.WriteJSON(w, http.StatusOK, cycle)
+}
+
+// GetStatements handles listing the statements of an account
+func (h *StatementsHandler) GetStatements(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+
+	statements, err := h.statementService.GetStatements(r.Context(), accountID)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to get statements")
+		writeAccountLookupError(w, r, err)
+		return
+	}
+
+	writer.WriteJSON(w, http.StatusOK, statements)
+}
+
+// GetStatement handles retrieving a statement of an account with its lines
+func (h *StatementsHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	accountID, ok := parseAccountID(w, r)
+	if !ok {
+		return
+	}
+	statementID, err := strconv.ParseInt(chi.URLParam(r, "sid"), 10, 64)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidStmtID,
+			err.Error(),
+		)
+		return
+	}
+
+	statement, err := h.statementService.GetStatement(r.Context(), accountID, statementID)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to get statement")
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrStatementNotFound) {
+			status = http.StatusNotFound
+		}
+		writer.WriteError(
+			w, r.Context(),
+			status,
+			ErrCodeInvalidRequest,
+			ErrTitleStmtNotFound,
+			err.Error(),
+		)
+		return
+	}
+
+	writer.WriteJSON(w, http.StatusOK, statement)
+}
+
+// parseAccountID reads the account id path parameter, writing the error response if it is invalid
+func parseAccountID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(r.Context())
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidAccID,
+			err.Error(),
+		)
+		return 0, false
+	}
+	return accountID, true
+}
+
+// writeAccountLookupError writes 404 for a missing account and 500 otherwise
+func writeAccountLookupError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, service.ErrAccountNotFound) {
+		status = http.StatusNotFound
+	}
+	writer.WriteError(
+		w, r.Context(),
+		status,
+		ErrCodeInvalidRequest,
+		ErrTitleAccNotFound,
+		err.Error(),
+	)
+}
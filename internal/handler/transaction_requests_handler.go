@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func NewTransactionRequestsHandler(requestsService service.TransactionRequestsService) *TransactionRequestsHandler {
+	return &TransactionRequestsHandler{requestsService: requestsService}
+}
+
+// GetTransactionRequest returns a queued transaction with, once processed, its transaction or error
+func (h *TransactionRequestsHandler) GetTransactionRequest(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	requestID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidTrxReqID,
+			err.Error(),
+		)
+		return
+	}
+
+	request, err := h.requestsService.GetTransactionRequest(r.Context(), requestID)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to get transaction request")
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTransactionRequestNotFound) {
+			status = http.StatusNotFound
+		}
+		writer.WriteError(
+			w, r.Context(),
+			status,
+			ErrCodeInvalidRequest,
+			ErrTitleTrxReqNotFound,
+			err.Error(),
+		)
+		return
+	}
+
+	writer.WriteJSON(w, http.StatusOK, newTransactionRequestResp(request))
+}
+
+// submitTransaction queues a transaction and answers with where to poll for its outcome
+func (h *TransactionsHandler) submitTransaction(w http.ResponseWriter, r *http.Request, req CreateTransactionReq) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	request, err := h.requestsService.SubmitTransaction(r.Context(), service.NewTransaction{
+		AccountID:         req.AccountID,
+		OperationTypeID:   req.OperationTypeID,
+		Amount:            req.Amount,
+		MerchantReference: req.MerchantReference,
+	})
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to queue transaction")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeTransactionErr,
+			ErrTitleTrxFailed,
+			err.Error(),
+		)
+		return
+	}
+
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Int64("transaction_request_id", request.ID).Msg("transaction queued")
+	w.Header().Set("Location", fmt.Sprintf("/v1/transaction-requests/%d", request.ID))
+	w.Header().Set("Preference-Applied", "respond-async")
+	writer.WriteJSON(w, http.StatusAccepted, newTransactionRequestResp(request))
+}
+
+// prefersAsync reports whether the client asked for respond-async in a Prefer header
+func prefersAsync(r *http.Request) bool {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			token, _, _ := strings.Cut(preference, "=")
+			if strings.EqualFold(strings.TrimSpace(token), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newTransactionRequestResp(request *repository.TransactionRequest) TransactionRequestResp {
+	resp := TransactionRequestResp{
+		ID:                request.ID,
+		Status:            request.Status,
+		AccountID:         request.AccountID,
+		OperationTypeID:   request.OperationTypeID,
+		Amount:            request.Amount,
+		MerchantReference: request.MerchantReference,
+		CreatedAt:         request.CreatedAt,
+		UpdatedAt:         request.UpdatedAt,
+	}
+	if request.Transaction != nil {
+		transaction := newTransactionDetailsResp(request.Transaction)
+		resp.Transaction = &transaction
+	}
+	if request.Status == repository.TransactionRequestFailed {
+		resp.Error = &TransactionRequestErrResp{Code: request.ErrorCode, Detail: request.ErrorDetail}
+	}
+	return resp
+}
@@ -2,29 +2,74 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/receipt"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
 	"github.com/ashwingopalsamy/transactions-service/internal/service"
 	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 )
 
-func NewTransactionHandler(transactionService service.TransactionsService) *TransactionsHandler {
-	return &TransactionsHandler{transactionService: transactionService}
+// NewTransactionHandler returns the transactions handler; created transactions come with a receipt
+// signed by keyring unless it is nil. Clients may ask for a transaction to be queued with
+// Prefer: respond-async unless requestsService is nil.
+func NewTransactionHandler(transactionService service.TransactionsService, requestsService service.TransactionRequestsService, keyring *receipt.Keyring) *TransactionsHandler {
+	return &TransactionsHandler{transactionService: transactionService, requestsService: requestsService, keyring: keyring}
 }
 
 // CreateTransaction creates new transaction
 func (h *TransactionsHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	h.createTransaction(w, r, false)
+}
+
+// CreateTransactionAsOperator creates a new transaction on behalf of an operator, who may pass force=true
+// to post a suspected duplicate anyway. It must be mounted behind the admin token.
+func (h *TransactionsHandler) CreateTransactionAsOperator(w http.ResponseWriter, r *http.Request) {
+	h.createTransaction(w, r, true)
+}
+
+func (h *TransactionsHandler) createTransaction(w http.ResponseWriter, r *http.Request, operator bool) {
 	reqID := middleware.GetRequestIDFromContext(r.Context())
 
+	var force bool
+	if v := r.URL.Query().Get("force"); v != "" {
+		var err error
+		if force, err = strconv.ParseBool(v); err != nil {
+			log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("invalid force param")
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusBadRequest,
+				ErrCodeInvalidRequest,
+				ErrTitleInvalidRequest,
+				ErrInvalidForceParam,
+			)
+			return
+		}
+	}
+	if force && !operator {
+		log.Warn().Ctx(r.Context()).Str("request_id", reqID).Msg("rejected forced transaction outside the admin API")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusForbidden,
+			ErrCodeForbidden,
+			ErrTitleForbidden,
+			ErrForceNotAllowed,
+		)
+		return
+	}
+
 	var req CreateTransactionReq
 
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(&req); err != nil {
-		log.Error().Str("request_id", reqID).Err(err).Msg("error decoding create transaction request")
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("error decoding create transaction request")
 		writer.WriteError(
 			w, r.Context(),
 			http.StatusBadRequest,
@@ -36,7 +81,7 @@ func (h *TransactionsHandler) CreateTransaction(w http.ResponseWriter, r *http.R
 	}
 
 	if r.ContentLength <= 0 {
-		log.Error().Str("request_id", reqID).Err(fmt.Errorf("invalid create transaction request")).Msg("invalid request body")
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid create transaction request")).Msg("invalid request body")
 		writer.WriteError(
 			w, r.Context(),
 			http.StatusBadRequest,
@@ -47,9 +92,59 @@ func (h *TransactionsHandler) CreateTransaction(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	transaction, err := h.transactionService.CreateTransaction(r.Context(), req.AccountID, req.OperationTypeID, req.Amount)
+	// Forced and back-dated transactions are always posted synchronously
+	if h.requestsService != nil && !force && req.EventDate.IsZero() && prefersAsync(r) {
+		h.submitTransaction(w, r, req)
+		return
+	}
+
+	transaction, err := h.transactionService.CreateTransaction(r.Context(), service.NewTransaction{
+		AccountID:         req.AccountID,
+		OperationTypeID:   req.OperationTypeID,
+		Amount:            req.Amount,
+		MerchantReference: req.MerchantReference,
+		EventDate:         req.EventDate,
+		Force:             force,
+	})
+	var duplicate *service.DuplicateTransactionError
+	if errors.As(err, &duplicate) {
+		log.Warn().Ctx(r.Context()).Str("request_id", reqID).Int64("original_id", duplicate.Original.ID).Msg("transaction rejected as a suspected duplicate")
+		w.Header().Set("Link", duplicateLink(duplicate.Original.ID))
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusConflict,
+			ErrCodeDuplicateTrx,
+			ErrTitleTrxDuplicate,
+			err.Error(),
+		)
+		return
+	}
+	var denied *service.ScreeningDeniedError
+	if errors.As(err, &denied) {
+		log.Warn().Ctx(r.Context()).Str("request_id", reqID).Strs("reason_codes", denied.ReasonCodes).Msg("transaction denied by screening")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusUnprocessableEntity,
+			denied.ReasonCodes[0],
+			ErrTitleTrxDenied,
+			err.Error(),
+		)
+		return
+	}
+	if errors.Is(err, service.ErrTrxQueueFull) || errors.Is(err, service.ErrTrxTimeout) {
+		log.Warn().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("transaction turned down by the executor")
+		w.Header().Set("Retry-After", "1")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusServiceUnavailable,
+			ErrCodeUnavailable,
+			ErrTitleUnavailable,
+			err.Error(),
+		)
+		return
+	}
 	if err != nil {
-		log.Error().Str("request_id", reqID).Err(err).Msg("failed to create transaction")
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to create transaction")
 		writer.WriteError(
 			w, r.Context(),
 			http.StatusBadRequest,
@@ -60,7 +155,103 @@ func (h *TransactionsHandler) CreateTransaction(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	log.Info().Str("request_id", reqID).Int64("id", transaction.ID).Msg("transaction successful")
-	writer.WriteJSON(w, http.StatusCreated, transaction)
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Int64("id", transaction.ID).Msg("transaction successful")
+	if transaction.DuplicateOfID != 0 {
+		w.Header().Set("Link", duplicateLink(transaction.DuplicateOfID))
+	}
+	writer.WriteJSON(w, http.StatusCreated, h.transactionResp(transaction))
 	return
 }
+
+// GetTransaction returns a posted transaction
+func (h *TransactionsHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	transactionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidTrxID,
+			err.Error(),
+		)
+		return
+	}
+
+	transaction, err := h.transactionService.GetTransaction(r.Context(), transactionID)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to get transaction")
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTransactionNotFound) {
+			status = http.StatusNotFound
+		}
+		writer.WriteError(
+			w, r.Context(),
+			status,
+			ErrCodeInvalidRequest,
+			ErrTitleTrxNotFound,
+			err.Error(),
+		)
+		return
+	}
+
+	writer.WriteJSON(w, http.StatusOK, newTransactionDetailsResp(transaction))
+}
+
+// duplicateLink is the Link header pointing to the transaction a new one looks like a resend of
+func duplicateLink(transactionID int64) string {
+	return fmt.Sprintf(`</v1/transactions/%d>; rel="duplicate"`, transactionID)
+}
+
+// transactionResp is the response for a created transaction, with its receipt when a keyring is configured
+func (h *TransactionsHandler) transactionResp(transaction *repository.Transaction) TransactionResp {
+	resp := newTransactionResp(transaction)
+	if h.keyring != nil {
+		resp.Receipt = &receipt.Receipt{
+			TransactionID:   transaction.ID,
+			AccountID:       transaction.AccountID,
+			OperationTypeID: transaction.OperationTypeID,
+			Amount:          transaction.Amount,
+			EventDate:       transaction.EventDate,
+		}
+		h.keyring.Sign(resp.Receipt)
+	}
+	return resp
+}
+
+func newTransactionDetailsResp(transaction *repository.Transaction) TransactionDetailsResp {
+	return TransactionDetailsResp{
+		ID:                  transaction.ID,
+		AccountID:           transaction.AccountID,
+		OperationTypeID:     transaction.OperationTypeID,
+		Amount:              transaction.Amount,
+		Balance:             transaction.Balance,
+		EventDate:           transaction.EventDate,
+		BookingDate:         transaction.BookingDate,
+		MerchantReference:   transaction.MerchantReference,
+		TransferID:          transaction.TransferID,
+		ParentTransactionID: transaction.ParentTransactionID,
+		DuplicateOf:         transaction.DuplicateOfID,
+		PrevHash:            transaction.PrevHash,
+		Hash:                transaction.Hash,
+	}
+}
+
+func newTransactionResp(transaction *repository.Transaction) TransactionResp {
+	resp := TransactionResp{ID: transaction.ID, EventDate: transaction.EventDate, BookingDate: transaction.BookingDate, DuplicateOf: transaction.DuplicateOfID}
+	if screening := transaction.Screening; screening != nil && screening.Outcome != repository.ScreeningOutcomeAllow {
+		resp.Screening = &ScreeningResp{Outcome: screening.Outcome, ReasonCodes: screening.ReasonCodes}
+	}
+	for _, fee := range transaction.Fees {
+		resp.Fees = append(resp.Fees, FeeResp{
+			TransactionID:       fee.ID,
+			ParentTransactionID: fee.ParentTransactionID,
+			OperationTypeID:     fee.OperationTypeID,
+			Amount:              fee.Amount,
+			EventDate:           fee.EventDate,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/ashwingopalsamy/transactions-service/internal/writer"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+func NewTransfersHandler(transferService service.TransfersService) *TransfersHandler {
+	return &TransfersHandler{transferService: transferService}
+}
+
+// CreateTransfer handles account-to-account transfer requests
+func (h *TransfersHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	var req CreateTransferReq
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("error decoding create transfer request")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidRequest,
+			ErrInvalidReqBody,
+		)
+		return
+	}
+
+	transfer, legs, err := h.transferService.CreateTransfer(r.Context(), req.SourceAccountID, req.DestinationAccountID, req.Amount)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to create transfer")
+		switch {
+		case errors.Is(err, service.ErrSourceAccountNotFound), errors.Is(err, service.ErrDestinationAccountNotFound):
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusNotFound,
+				ErrCodeInvalidRequest,
+				ErrTitleAccNotFound,
+				err.Error(),
+			)
+		case errors.Is(err, service.ErrAccountNotActive):
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusUnprocessableEntity,
+				ErrCodeTransactionErr,
+				ErrTitleTrfFailed,
+				err.Error(),
+			)
+		default:
+			writer.WriteError(
+				w, r.Context(),
+				http.StatusBadRequest,
+				ErrCodeTransactionErr,
+				ErrTitleTrfFailed,
+				err.Error(),
+			)
+		}
+		return
+	}
+
+	log.Info().Ctx(r.Context()).Str("request_id", reqID).Int64("id", transfer.ID).Msg("transfer successful")
+	writer.WriteJSON(w, http.StatusCreated, newTransferResp(transfer, legs))
+}
+
+// GetTransfer handles retrieving a transfer and its legs by ID
+func (h *TransfersHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	reqID := middleware.GetRequestIDFromContext(r.Context())
+
+	transferID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(fmt.Errorf("invalid request")).Msg("invalid request param")
+		writer.WriteError(
+			w, r.Context(),
+			http.StatusBadRequest,
+			ErrCodeInvalidRequest,
+			ErrTitleInvalidTrfID,
+			err.Error(),
+		)
+		return
+	}
+
+	transfer, legs, err := h.transferService.GetTransfer(r.Context(), transferID)
+	if err != nil {
+		log.Error().Ctx(r.Context()).Str("request_id", reqID).Err(err).Msg("failed to get transfer")
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTransferNotFound) {
+			status = http.StatusNotFound
+		}
+		writer.WriteError(
+			w, r.Context(),
+			status,
+			ErrCodeInvalidRequest,
+			ErrTitleTrfNotFound,
+			err.Error(),
+		)
+		return
+	}
+
+	writer.WriteJSON(w, http.StatusOK, newTransferResp(transfer, legs))
+}
+
+func newTransferResp(transfer *repository.Transfer, legs []*repository.Transaction) TransferResp {
+	resp := TransferResp{
+		ID:                   transfer.ID,
+		SourceAccountID:      transfer.SourceAccountID,
+		DestinationAccountID: transfer.DestinationAccountID,
+		Amount:               transfer.Amount,
+		CreatedAt:            transfer.CreatedAt,
+		Legs:                 make([]TransferLegResp, 0, len(legs)),
+	}
+	for _, leg := range legs {
+		resp.Legs = append(resp.Legs, TransferLegResp{
+			TransactionID:   leg.ID,
+			AccountID:       leg.AccountID,
+			OperationTypeID: leg.OperationTypeID,
+			Amount:          leg.Amount,
+			EventDate:       leg.EventDate,
+		})
+	}
+	return resp
+}
@@ -1,6 +1,13 @@
 package handler
 
 import (
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/executor"
+	"github.com/ashwingopalsamy/transactions-service/internal/export"
+	"github.com/ashwingopalsamy/transactions-service/internal/health"
+	"github.com/ashwingopalsamy/transactions-service/internal/receipt"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
 	"github.com/ashwingopalsamy/transactions-service/internal/service"
 )
 
@@ -9,30 +16,326 @@ const (
 	ErrCodeInvalidRequest = "invalid_request"
 	ErrCodeConflictErr    = "conflict_error"
 	ErrCodeTransactionErr = "transaction_error"
+	ErrCodeDuplicateTrx   = "duplicate_transaction"
+	ErrCodeUnauthorized   = "unauthorized"
+	ErrCodeForbidden      = "forbidden"
+	ErrCodeUnavailable    = "service_unavailable"
 
-	ErrTitleAccNotFound    = "Account Not Found"
-	ErrTitleConflict       = "Conflict"
-	ErrTitleInvalidAccID   = "Invalid Account ID"
-	ErrTitleInvalidRequest = "Invalid Request"
-	ErrTitleTrxFailed      = "Transaction Failed"
+	ErrTitleAccNotFound        = "Account Not Found"
+	ErrTitleConflict           = "Conflict"
+	ErrTitleForbidden          = "Forbidden"
+	ErrTitleCustNotFound       = "Customer Not Found"
+	ErrTitleInvalidAccID       = "Invalid Account ID"
+	ErrTitleInvalidAuditFilter = "Invalid Audit Filter"
+	ErrTitleInvalidCustID      = "Invalid Customer ID"
+	ErrTitleInvalidRequest     = "Invalid Request"
+	ErrTitleInvalidStmtID      = "Invalid Statement ID"
+	ErrTitleInvalidTrfID       = "Invalid Transfer ID"
+	ErrTitleInvalidTrxID       = "Invalid Transaction ID"
+	ErrTitleInvalidTrxReqID    = "Invalid Transaction Request ID"
+	ErrTitleIntegrityFailed    = "Integrity Verification Failed"
+	ErrTitleExportFailed       = "Export Failed"
+	ErrTitleLedgerFailed       = "Ledger Verification Failed"
+	ErrTitleSandboxFailed      = "Sandbox Jobs Failed"
+	ErrTitleStmtNotFound       = "Statement Not Found"
+	ErrTitleTrfFailed          = "Transfer Failed"
+	ErrTitleTrfNotFound        = "Transfer Not Found"
+	ErrTitleTrxDenied          = "Transaction Denied"
+	ErrTitleTrxDuplicate       = "Duplicate Transaction"
+	ErrTitleTrxFailed          = "Transaction Failed"
+	ErrTitleTrxNotFound        = "Transaction Not Found"
+	ErrTitleTrxReqNotFound     = "Transaction Request Not Found"
+	ErrTitleUnauthorized       = "Unauthorized"
+	ErrTitleUnavailable        = "Service Unavailable"
+	ErrTitleAuditFailed        = "Audit Failed"
 
-	ErrInvalidReqBody = "invalid request body"
+	ErrInvalidReqBody    = "invalid request body"
+	ErrInvalidAdminToken = "missing or invalid admin token"
+	ErrInvalidClockReq   = "exactly one of days and to is required"
+	ErrInvalidForceParam = "invalid force: must be true or false"
+	ErrForceNotAllowed   = "force is reserved to operators: use /admin/transactions with the admin token"
+	ErrInvalidBatchBody  = "invalid request body: must be a JSON array or NDJSON of transactions"
+	ErrBatchEventDate    = "invalid request body: event_date is not supported in batches"
 )
 
+// AdminPrincipal is the principal attributed to requests authenticated with the admin token
+const AdminPrincipal = "admin"
+
+type CustomersHandler struct {
+	customerService service.CustomersService
+}
+
 type AccountsHandler struct {
 	accountService service.AccountsService
 }
 
 type TransactionsHandler struct {
 	transactionService service.TransactionsService
+	requestsService    service.TransactionRequestsService
+	keyring            *receipt.Keyring
+}
+
+type TransactionRequestsHandler struct {
+	requestsService service.TransactionRequestsService
+}
+
+type TransfersHandler struct {
+	transferService service.TransfersService
+}
+
+type StatementsHandler struct {
+	statementService service.StatementsService
+}
+
+type LedgerHandler struct {
+	ledgerService service.LedgerService
+}
+
+type IntegrityHandler struct {
+	integrityService service.IntegrityService
+}
+
+type ExportHandler struct {
+	exportService service.ExportService
+	options       export.Options
+}
+
+type ExecutorHandler struct {
+	executor *executor.Executor
+}
+
+type KeysHandler struct {
+	keyring *receipt.Keyring
+}
+
+type SandboxHandler struct {
+	sandboxService service.SandboxService
+}
+
+type AuditHandler struct {
+	auditService service.AuditService
 }
 
+type AdminHandler struct {
+	adminToken   string
+	auditService service.AuditService
+}
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+type CreateCustomerReq struct {
+	DocumentNumber string `json:"document_number"`
+	Name           string `json:"name"`
+	BirthDate      string `json:"birth_date"`
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+}
+
+// CreateAccountReq identifies the holder by customer_id or, for older clients, by document_number
 type CreateAccountReq struct {
+	CustomerID     int64  `json:"customer_id"`
 	DocumentNumber string `json:"document_number"`
+	Product        string `json:"product"`
 }
 
 type CreateTransactionReq struct {
-	AccountID       int64   `json:"account_id"`
-	OperationTypeID int64   `json:"operation_type_id"`
-	Amount          float64 `json:"amount"`
+	AccountID         int64     `json:"account_id"`
+	OperationTypeID   int64     `json:"operation_type_id"`
+	Amount            float64   `json:"amount"`
+	MerchantReference string    `json:"merchant_reference"`
+	EventDate         time.Time `json:"event_date"` // when the transaction took place, if earlier than now
+}
+
+// TransactionResp is a created transaction with the fees posted along with it and, when flagged for review,
+// its screening; DuplicateOf is set when it was posted flagged as a suspected duplicate. Receipt is set when
+// receipt signing is configured.
+type TransactionResp struct {
+	ID          int64            `json:"id"`
+	EventDate   time.Time        `json:"event_date"`
+	BookingDate time.Time        `json:"booking_date"`
+	DuplicateOf int64            `json:"duplicate_of,omitempty"`
+	Fees        []FeeResp        `json:"fees,omitempty"`
+	Screening   *ScreeningResp   `json:"screening,omitempty"`
+	Receipt     *receipt.Receipt `json:"receipt,omitempty"`
+}
+
+// TransactionDetailsResp is a posted transaction as returned by GET /v1/transactions/{id}
+type TransactionDetailsResp struct {
+	ID                  int64     `json:"id"`
+	AccountID           int64     `json:"account_id"`
+	OperationTypeID     int64     `json:"operation_type_id"`
+	Amount              float64   `json:"amount"`
+	Balance             float64   `json:"balance"`
+	EventDate           time.Time `json:"event_date"`
+	BookingDate         time.Time `json:"booking_date"`
+	MerchantReference   string    `json:"merchant_reference,omitempty"`
+	TransferID          int64     `json:"transfer_id,omitempty"`
+	ParentTransactionID int64     `json:"parent_transaction_id,omitempty"`
+	DuplicateOf         int64     `json:"duplicate_of,omitempty"`
+	PrevHash            string    `json:"prev_hash,omitempty"`
+	Hash                string    `json:"hash,omitempty"`
+}
+
+// BatchResp is the outcome of a batch of transactions, with a result per item in item order
+type BatchResp struct {
+	Mode     string          `json:"mode"`
+	Posted   int             `json:"posted"`
+	Rejected int             `json:"rejected"`
+	Results  []BatchItemResp `json:"results"`
+}
+
+// BatchItemResp is the result of the item of a batch at Index: its transaction when posted, its error when
+// rejected
+type BatchItemResp struct {
+	Index       int               `json:"index"`
+	Status      string            `json:"status"`
+	Transaction *TransactionResp  `json:"transaction,omitempty"`
+	Error       *BatchItemErrResp `json:"error,omitempty"`
+}
+
+type BatchItemErrResp struct {
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// Statuses of the items of a batch
+const (
+	BatchItemPosted   = "posted"
+	BatchItemRejected = "rejected"
+)
+
+// TransactionRequestResp is a transaction queued with Prefer: respond-async. Transaction is set once it
+// succeeded, Error once it failed.
+type TransactionRequestResp struct {
+	ID                int64                      `json:"id"`
+	Status            string                     `json:"status"`
+	AccountID         int64                      `json:"account_id"`
+	OperationTypeID   int64                      `json:"operation_type_id"`
+	Amount            float64                    `json:"amount"`
+	MerchantReference string                     `json:"merchant_reference,omitempty"`
+	CreatedAt         time.Time                  `json:"created_at"`
+	UpdatedAt         time.Time                  `json:"updated_at"`
+	Transaction       *TransactionDetailsResp    `json:"transaction,omitempty"`
+	Error             *TransactionRequestErrResp `json:"error,omitempty"`
+}
+
+type TransactionRequestErrResp struct {
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+type ScreeningResp struct {
+	Outcome     string   `json:"outcome"`
+	ReasonCodes []string `json:"reason_codes"`
+}
+
+type FeeResp struct {
+	TransactionID       int64     `json:"transaction_id"`
+	ParentTransactionID int64     `json:"parent_transaction_id"`
+	OperationTypeID     int64     `json:"operation_type_id"`
+	Amount              float64   `json:"amount"`
+	EventDate           time.Time `json:"event_date"`
+}
+
+type SetAccountStatusReq struct {
+	Status string `json:"status"`
+}
+
+type SetBillingCycleReq struct {
+	ClosingDay   int `json:"closing_day"`
+	DueDayOffset int `json:"due_day_offset"`
+}
+
+type CreateTransferReq struct {
+	SourceAccountID      int64   `json:"source_account_id"`
+	DestinationAccountID int64   `json:"destination_account_id"`
+	Amount               float64 `json:"amount"`
+}
+
+// TransferResp is a transfer with its debit and credit legs
+type TransferResp struct {
+	ID                   int64             `json:"id"`
+	SourceAccountID      int64             `json:"source_account_id"`
+	DestinationAccountID int64             `json:"destination_account_id"`
+	Amount               float64           `json:"amount"`
+	CreatedAt            time.Time         `json:"created_at"`
+	Legs                 []TransferLegResp `json:"legs"`
+}
+
+type TransferLegResp struct {
+	TransactionID   int64     `json:"transaction_id"`
+	AccountID       int64     `json:"account_id"`
+	OperationTypeID int64     `json:"operation_type_id"`
+	Amount          float64   `json:"amount"`
+	EventDate       time.Time `json:"event_date"`
+}
+
+// LedgerResp reports an account's ledger balances and whether its transaction balances match them
+type LedgerResp struct {
+	AccountID  int64                              `json:"account_id"`
+	Balances   []*repository.LedgerAccountBalance `json:"balances"`
+	Consistent bool                               `json:"consistent"`
+	Mismatches []LedgerMismatchResp               `json:"mismatches"`
+}
+
+type LedgerMismatchResp struct {
+	TransactionID    int64   `json:"transaction_id"`
+	ProjectedBalance float64 `json:"projected_balance"`
+	LedgerBalance    float64 `json:"ledger_balance"`
+}
+
+// IntegrityResp reports whether an account's transaction hash chain holds; Break is the first link
+// that does not
+type IntegrityResp struct {
+	AccountID int64           `json:"account_id"`
+	Intact    bool            `json:"intact"`
+	Length    int             `json:"length"`
+	Unchained int             `json:"unchained"`
+	Head      string          `json:"head,omitempty"`
+	Break     *ChainBreakResp `json:"break,omitempty"`
+}
+
+type ChainBreakResp struct {
+	TransactionID int64  `json:"transaction_id"`
+	Reason        string `json:"reason"`
+	Expected      string `json:"expected"`
+	Actual        string `json:"actual"`
+}
+
+// AdvanceClockReq moves the sandbox clock forward by Days or to the instant To
+type AdvanceClockReq struct {
+	Days int        `json:"days"`
+	To   *time.Time `json:"to"`
+}
+
+type ClockResp struct {
+	Now time.Time `json:"now"`
+}
+
+// ClockAdvanceResp reports the jobs run on the days the sandbox clock crossed
+type ClockAdvanceResp struct {
+	From        time.Time                `json:"from"`
+	To          time.Time                `json:"to"`
+	Statements  []*repository.Statement  `json:"statements"`
+	AccrualRuns []*repository.AccrualRun `json:"accrual_runs"`
+}
+
+// AuditEventsResp is a page of audit events; NextAfterID is set when another page may follow
+type AuditEventsResp struct {
+	Events      []*repository.AuditEvent `json:"events"`
+	NextAfterID int64                    `json:"next_after_id,omitempty"`
+}
+
+type LogLevelReq struct {
+	Level string `json:"level"`
+}
+
+type LogLevelResp struct {
+	Level string `json:"level"`
+}
+
+type HealthStatusResp struct {
+	Status string `json:"status"`
 }
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Pinger is implemented by *pgxpool.Pool
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// RowQuerier is implemented by *pgxpool.Pool
+type RowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// DatabaseCheck verifies the database answers a ping
+func DatabaseCheck(db Pinger) CheckFunc {
+	return func(ctx context.Context) error {
+		if err := db.Ping(ctx); err != nil {
+			return fmt.Errorf("failed to ping database: %w", err)
+		}
+		return nil
+	}
+}
+
+// SchemaVersionCheck verifies goose has applied migrations up to at least expectedVersion
+func SchemaVersionCheck(db RowQuerier, expectedVersion int64) CheckFunc {
+	return func(ctx context.Context) error {
+		var current int64
+		query := `SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied`
+		if err := db.QueryRow(ctx, query).Scan(&current); err != nil {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+		if current < expectedVersion {
+			return fmt.Errorf("schema version %d is behind expected version %d", current, expectedVersion)
+		}
+		return nil
+	}
+}
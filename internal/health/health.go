@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// ErrShuttingDown is reported once graceful shutdown has started
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// CheckFunc reports the health of a single dependency
+type CheckFunc func(ctx context.Context) error
+
+// Checker runs the dependency checks that decide whether the service can take traffic
+type Checker struct {
+	timeout      time.Duration
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Report is the aggregated result of all checks
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// NewChecker creates a Checker whose checks each run within timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// AddCheck registers a named dependency check; it is not safe to call while serving requests
+func (c *Checker) AddCheck(name string, check CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// SetShuttingDown marks the service as draining, making every later report fail
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Run executes all checks concurrently and aggregates their results
+func (c *Checker) Run(ctx context.Context) Report {
+	results := make([]CheckResult, len(c.checks)+1)
+	results[0] = c.shutdownResult()
+
+	done := make(chan struct{}, len(c.checks))
+	for i, nc := range c.checks {
+		go func(i int, nc namedCheck) {
+			results[i+1] = c.runCheck(ctx, nc)
+			done <- struct{}{}
+		}(i, nc)
+	}
+	for range c.checks {
+		<-done
+	}
+
+	report := Report{Status: StatusUp, Checks: results}
+	for _, r := range results {
+		if r.Status != StatusUp {
+			report.Status = StatusDown
+			break
+		}
+	}
+	return report
+}
+
+func (c *Checker) shutdownResult() CheckResult {
+	result := CheckResult{Name: "shutdown", Status: StatusUp}
+	if c.shuttingDown.Load() {
+		result.Status = StatusDown
+		result.Error = ErrShuttingDown.Error()
+	}
+	return result
+}
+
+func (c *Checker) runCheck(ctx context.Context, nc namedCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := nc.check(ctx)
+	result := CheckResult{
+		Name:      nc.name,
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/health"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	t.Run("All checks passing reports up", func(t *testing.T) {
+		checker := health.NewChecker(time.Second)
+		checker.AddCheck("ok", func(ctx context.Context) error { return nil })
+
+		report := checker.Run(context.Background())
+		assert.Equal(t, health.StatusUp, report.Status)
+		assert.Len(t, report.Checks, 2)
+	})
+
+	t.Run("Failing check reports down with its error", func(t *testing.T) {
+		checker := health.NewChecker(time.Second)
+		checker.AddCheck("ok", func(ctx context.Context) error { return nil })
+		checker.AddCheck("broken", func(ctx context.Context) error { return errors.New("boom") })
+
+		report := checker.Run(context.Background())
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, "broken", report.Checks[2].Name)
+		assert.Equal(t, "boom", report.Checks[2].Error)
+	})
+
+	t.Run("Slow check is bounded by the timeout", func(t *testing.T) {
+		checker := health.NewChecker(10 * time.Millisecond)
+		checker.AddCheck("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		report := checker.Run(context.Background())
+		assert.Equal(t, health.StatusDown, report.Status)
+	})
+
+	t.Run("Shutting down reports down", func(t *testing.T) {
+		checker := health.NewChecker(time.Second)
+		checker.SetShuttingDown()
+
+		report := checker.Run(context.Background())
+		assert.Equal(t, health.StatusDown, report.Status)
+		assert.Equal(t, health.ErrShuttingDown.Error(), report.Checks[0].Error)
+	})
+}
+
+func TestSchemaVersionCheck(t *testing.T) {
+	query := `SELECT COALESCE\(MAX\(version_id\), 0\) FROM goose_db_version WHERE is_applied`
+
+	t.Run("Schema at expected version passes", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectQuery(query).WillReturnRows(pgxmock.NewRows([]string{"max"}).AddRow(int64(20250301090000)))
+
+		assert.NoError(t, health.SchemaVersionCheck(mockDB, 20250301090000)(context.Background()))
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Schema behind expected version fails", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectQuery(query).WillReturnRows(pgxmock.NewRows([]string{"max"}).AddRow(int64(20250213071634)))
+
+		err = health.SchemaVersionCheck(mockDB, 20250301090000)(context.Background())
+		assert.ErrorContains(t, err, "behind expected version")
+	})
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/config"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Supported log output formats, as validated by config
+const (
+	FormatJSON    = config.LogFormatJSON
+	FormatConsole = config.LogFormatConsole
+)
+
+// Setup configures the global zerolog logger with the given level and output format
+func Setup(level, format string) error {
+	return setup(os.Stderr, level, format)
+}
+
+func setup(out io.Writer, level, format string) error {
+	if err := SetLevel(level); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+	case FormatConsole:
+		out = zerolog.ConsoleWriter{Out: out}
+	default:
+		return fmt.Errorf("unsupported log format: %q", format)
+	}
+
+	log.Logger = zerolog.New(out).With().Timestamp().Logger().Hook(telemetry.TraceHook{})
+	return nil
+}
+
+// SetLevel changes the global log level; it is safe to call while serving requests
+func SetLevel(level string) error {
+	parsed, err := zerolog.ParseLevel(strings.ToLower(strings.TrimSpace(level)))
+	if err != nil || parsed == zerolog.NoLevel {
+		return fmt.Errorf("invalid log level: %q", level)
+	}
+	zerolog.SetGlobalLevel(parsed)
+	return nil
+}
+
+// Level returns the current global log level
+func Level() string {
+	return zerolog.GlobalLevel().String()
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLevel(t *testing.T) {
+	t.Cleanup(func() { zerolog.SetGlobalLevel(zerolog.InfoLevel) })
+
+	tests := []struct {
+		name          string
+		level         string
+		expectedLevel string
+		expectError   bool
+	}{
+		{"Lowercase level", "debug", "debug", false},
+		{"Uppercase level", "WARN", "warn", false},
+		{"Level with whitespace", " error ", "error", false},
+		{"Unknown level", "verbose", "", true},
+		{"Empty level", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetLevel(tt.level)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLevel, Level())
+		})
+	}
+}
+
+func TestSetup(t *testing.T) {
+	original := log.Logger
+	t.Cleanup(func() {
+		log.Logger = original
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	})
+
+	t.Run("JSON format honors level", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, setup(&buf, "warn", FormatJSON))
+
+		log.Info().Msg("suppressed")
+		log.Warn().Msg("emitted")
+
+		assert.NotContains(t, buf.String(), "suppressed")
+		assert.Contains(t, buf.String(), `"message":"emitted"`)
+	})
+
+	t.Run("Unsupported format should fail", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(t, setup(&buf, "info", "xml"))
+	})
+}
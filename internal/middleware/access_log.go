@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// AccessLog writes one structured log line per request once the response has been written
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		var event *zerolog.Event
+		switch {
+		case status >= http.StatusInternalServerError:
+			event = log.Error()
+		case status >= http.StatusBadRequest:
+			event = log.Warn()
+		default:
+			event = log.Info()
+		}
+
+		ctx := r.Context()
+		route := ""
+		if rctx := chi.RouteContext(ctx); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+
+		event.Ctx(ctx).
+			Str("request_id", GetRequestIDFromContext(ctx)).
+			Str("correlation_id", GetCorrelationIDFromContext(ctx)).
+			Str("principal", GetPrincipalFromContext(ctx)).
+			Str("method", r.Method).
+			Str("route", route).
+			Str("path", r.URL.Path).
+			Int("status", status).
+			Dur("latency_ms", time.Since(start)).
+			Int("bytes", ww.BytesWritten()).
+			Str("remote_addr", r.RemoteAddr).
+			Msg("request completed")
+	})
+}
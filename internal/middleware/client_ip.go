@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ForwardedForHeader carries the chain of client addresses, set by the API gateway
+const ForwardedForHeader = "X-Forwarded-For"
+
+var clientIPKey = key(4)
+
+// SetClientIPToContext stores the caller's IP in the context: the first X-Forwarded-For entry when one of the
+// trusted proxies set one, the connection's remote address otherwise
+func SetClientIPToContext(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var ip string
+			if fromTrustedProxy(r, trustedProxies) {
+				ip, _, _ = strings.Cut(r.Header.Get(ForwardedForHeader), ",")
+				ip = strings.TrimSpace(ip)
+			}
+			if net.ParseIP(ip) == nil {
+				ip = r.RemoteAddr
+				if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+					ip = host
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(WithClientIP(r.Context(), ip)))
+		})
+	}
+}
+
+// WithClientIP returns a copy of ctx carrying the given client IP
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// GetClientIPFromContext retrieves the client IP from context
+func GetClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
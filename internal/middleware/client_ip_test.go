@@ -0,0 +1,47 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetClientIPToContext(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	var got string
+	h := middleware.SetClientIPToContext(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.GetClientIPFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		expected     string
+	}{
+		{"Remote address without forwarding", "10.1.2.3:4711", "", "10.1.2.3"},
+		{"First forwarded address", "10.1.2.3:4711", "203.0.113.7, 10.0.0.1", "203.0.113.7"},
+		{"IPv6 forwarded address", "10.1.2.3:4711", "2001:db8::1", "2001:db8::1"},
+		{"Malformed forwarded address", "10.1.2.3:4711", "unknown", "10.1.2.3"},
+		{"Forged forwarded address from an untrusted peer", "192.0.2.1:1234", "203.0.113.7", "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set(middleware.ForwardedForHeader, tt.forwardedFor)
+			}
+
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
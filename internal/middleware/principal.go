@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// PrincipalHeader carries the authenticated caller, set by the API gateway
+const PrincipalHeader = "X-Principal"
+
+// UnauthenticatedPrincipal is recorded for requests whose principal no trusted proxy vouched for
+const UnauthenticatedPrincipal = "unauthenticated"
+
+var principalKey = key(2)
+
+// SetPrincipalToContext stores the caller in the context: the X-Principal header when the connection comes
+// from one of the trusted proxies, UnauthenticatedPrincipal otherwise. Admin routes replace it with the admin
+// principal once the admin token is checked.
+func SetPrincipalToContext(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := UnauthenticatedPrincipal
+			if fromTrustedProxy(r, trustedProxies) {
+				if p := strings.TrimSpace(r.Header.Get(PrincipalHeader)); p != "" {
+					principal = p
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// fromTrustedProxy reports whether the peer of the connection is in one of the trusted ranges
+func fromTrustedProxy(r *http.Request, trustedProxies []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal returns a copy of ctx carrying the given principal
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// GetPrincipalFromContext retrieves the principal from context
+func GetPrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey).(string)
+	return principal
+}
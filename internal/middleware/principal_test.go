@@ -0,0 +1,61 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetPrincipalToContext(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")}
+
+	var got string
+	h := middleware.SetPrincipalToContext(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = middleware.GetPrincipalFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		principal  string
+		expected   string
+	}{
+		{"Header from a trusted proxy", "10.1.2.3:4711", "ops@example.com", "ops@example.com"},
+		{"Header from a trusted IPv6 proxy", "[2001:db8::7]:4711", "ops@example.com", "ops@example.com"},
+		{"Header from an IPv4-mapped trusted proxy", "[::ffff:10.1.2.3]:4711", "ops@example.com", "ops@example.com"},
+		{"Header from an untrusted peer", "192.0.2.1:1234", "ops@example.com", middleware.UnauthenticatedPrincipal},
+		{"No header from a trusted proxy", "10.1.2.3:4711", "", middleware.UnauthenticatedPrincipal},
+		{"Blank header from a trusted proxy", "10.1.2.3:4711", "  ", middleware.UnauthenticatedPrincipal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.principal != "" {
+				req.Header.Set(middleware.PrincipalHeader, tt.principal)
+			}
+
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+
+	t.Run("No trusted proxies", func(t *testing.T) {
+		h := middleware.SetPrincipalToContext(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = middleware.GetPrincipalFromContext(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.1.2.3:4711"
+		req.Header.Set(middleware.PrincipalHeader, "ops@example.com")
+
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, middleware.UnauthenticatedPrincipal, got)
+	})
+}
@@ -8,15 +8,28 @@ import (
 	"github.com/google/uuid"
 )
 
+// CorrelationIDHeader carries the id that ties together every request of a business flow across services
+const CorrelationIDHeader = "X-Correlation-Id"
+
+// maxInboundIDLength bounds the size of caller-supplied request and correlation ids
+const maxInboundIDLength = 128
+
 type key int
 
-var requestIDKey = key(1)
+var (
+	requestIDKey     = key(1)
+	correlationIDKey = key(3)
+)
 
-// SetRequestIDToContext creates a UUIDv7-based requestID
+// SetRequestIDToContext honors a valid inbound X-Request-Id, e.g. from the API gateway,
+// and falls back to a UUIDv7-based requestID otherwise
 func SetRequestIDToContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		reqID, _ := uuid.NewV7()
-		reqIDStr := reqID.String()
+		reqIDStr := r.Header.Get(middleware.RequestIDHeader)
+		if !IsValidInboundID(reqIDStr) {
+			reqID, _ := uuid.NewV7()
+			reqIDStr = reqID.String()
+		}
 
 		// Set the header
 		w.Header().Set(middleware.RequestIDHeader, reqIDStr)
@@ -34,3 +47,47 @@ func GetRequestIDFromContext(ctx context.Context) string {
 	reqID, _ := ctx.Value(requestIDKey).(string)
 	return reqID
 }
+
+// SetCorrelationIDToContext propagates a valid inbound X-Correlation-Id, defaulting to the requestID
+// when the caller did not start a flow of its own. Must run after SetRequestIDToContext.
+func SetCorrelationIDToContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		correlationID := r.Header.Get(CorrelationIDHeader)
+		if !IsValidInboundID(correlationID) {
+			correlationID = GetRequestIDFromContext(r.Context())
+		}
+
+		w.Header().Set(CorrelationIDHeader, correlationID)
+
+		next.ServeHTTP(w, r.WithContext(WithCorrelationID(r.Context(), correlationID)))
+	})
+}
+
+// WithCorrelationID returns a copy of ctx carrying the given correlationID
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, correlationID)
+}
+
+// GetCorrelationIDFromContext retrieves the correlationID from context
+func GetCorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey).(string)
+	return correlationID
+}
+
+// IsValidInboundID reports whether a caller-supplied id is safe to adopt:
+// non-empty, at most 128 characters, and limited to [A-Za-z0-9._:-]
+func IsValidInboundID(id string) bool {
+	if id == "" || len(id) > maxInboundIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
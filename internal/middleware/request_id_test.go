@@ -0,0 +1,70 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidInboundID(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected bool
+	}{
+		{"UUID", "0194e9d1-7c3b-7a4e-9f0e-3f1c2b3a4d5e", true},
+		{"Gateway style id", "gw:eu-west_1.abc123", true},
+		{"Empty", "", false},
+		{"Too long", strings.Repeat("a", 129), false},
+		{"Whitespace", "abc def", false},
+		{"Header injection", "abc\r\nX-Evil: 1", false},
+		{"Non-ascii", "ïd", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, middleware.IsValidInboundID(tt.id))
+		})
+	}
+}
+
+func TestRequestAndCorrelationIDs(t *testing.T) {
+	var gotReqID, gotCorrelationID string
+	h := middleware.SetRequestIDToContext(middleware.SetCorrelationIDToContext(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotReqID = middleware.GetRequestIDFromContext(r.Context())
+			gotCorrelationID = middleware.GetCorrelationIDFromContext(r.Context())
+		}),
+	))
+
+	t.Run("Valid inbound ids are honored and echoed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(chimiddleware.RequestIDHeader, "gw-req-1")
+		req.Header.Set(middleware.CorrelationIDHeader, "flow-1")
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, "gw-req-1", gotReqID)
+		assert.Equal(t, "flow-1", gotCorrelationID)
+		assert.Equal(t, "gw-req-1", rec.Header().Get(chimiddleware.RequestIDHeader))
+		assert.Equal(t, "flow-1", rec.Header().Get(middleware.CorrelationIDHeader))
+	})
+
+	t.Run("Invalid inbound ids are replaced", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(chimiddleware.RequestIDHeader, "not valid!")
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+
+		assert.NotEqual(t, "not valid!", gotReqID)
+		assert.NotEmpty(t, gotReqID)
+		assert.Equal(t, gotReqID, gotCorrelationID)
+	})
+}
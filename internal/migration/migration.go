@@ -0,0 +1,72 @@
+package migration
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+
+	"github.com/ashwingopalsamy/transactions-service/schema"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// migrationsFS returns the embedded migrations rooted at the SQL files
+func migrationsFS() (fs.FS, error) {
+	fsys, err := fs.Sub(schema.Migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+	return fsys, nil
+}
+
+// OpenDB returns a database/sql handle, as required by goose, that shares the pgx pool
+func OpenDB(pool *pgxpool.Pool) *sql.DB {
+	return stdlib.OpenDBFromPool(pool)
+}
+
+// NewProvider returns a goose provider for the embedded migrations.
+// A Postgres advisory lock serializes concurrent migration runs, e.g. several replicas started with --migrate.
+func NewProvider(db *sql.DB) (*goose.Provider, error) {
+	fsys, err := migrationsFS()
+	if err != nil {
+		return nil, err
+	}
+
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration locker: %w", err)
+	}
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration provider: %w", err)
+	}
+	return provider, nil
+}
+
+// ExpectedVersion returns the latest embedded migration version, i.e. the schema version this binary requires
+func ExpectedVersion() (int64, error) {
+	fsys, err := migrationsFS()
+	if err != nil {
+		return 0, err
+	}
+
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list embedded migrations: %w", err)
+	}
+
+	var latest int64
+	for _, file := range files {
+		version, err := goose.NumericComponent(file)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %q: %w", file, err)
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,14 @@
+package migration_test
+
+import (
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/migration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	version, err := migration.ExpectedVersion()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, version, int64(20250301090000))
+}
@@ -0,0 +1,188 @@
+package receipt
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// KeyFileExt is the extension of key files; the rest of the file name is the key id
+const KeyFileExt = ".pem"
+
+// Keyring holds the receipt keys loaded from a directory. A PKCS#8 "PRIVATE KEY" file is a key that
+// can sign; a PKIX "PUBLIC KEY" file is a retired key, kept only to verify the receipts it signed.
+// Receipts are signed with the active key and every key is published.
+type Keyring struct {
+	activeKeyID string
+	signingKey  ed25519.PrivateKey
+	keys        map[string]ed25519.PublicKey
+}
+
+// LoadKeyring reads every <key id>.pem file in dir. The active key is activeKeyID or, when empty,
+// the signing key with the greatest id, so a key named after its creation date takes over once added.
+func LoadKeyring(dir, activeKeyID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+KeyFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list receipt keys: %w", err)
+	}
+
+	k := &Keyring{keys: make(map[string]ed25519.PublicKey)}
+	signingKeys := make(map[string]ed25519.PrivateKey)
+	for _, path := range paths {
+		keyID := strings.TrimSuffix(filepath.Base(path), KeyFileExt)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read receipt key %s: %w", keyID, err)
+		}
+		public, private, err := parseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid receipt key %s: %w", keyID, err)
+		}
+		k.keys[keyID] = public
+		if private != nil {
+			signingKeys[keyID] = private
+		}
+	}
+	if len(signingKeys) == 0 {
+		return nil, fmt.Errorf("no receipt signing key in %s", dir)
+	}
+
+	if activeKeyID == "" {
+		for keyID := range signingKeys {
+			if keyID > activeKeyID {
+				activeKeyID = keyID
+			}
+		}
+	}
+	signingKey, ok := signingKeys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active receipt key %q has no private key in %s", activeKeyID, dir)
+	}
+	k.activeKeyID = activeKeyID
+	k.signingKey = signingKey
+	return k, nil
+}
+
+// parseKey decodes a PEM Ed25519 key; private is nil for a public key
+func parseKey(data []byte) (public ed25519.PublicKey, private ed25519.PrivateKey, err error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("no PEM block")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		private, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, nil, errors.New("not an Ed25519 key")
+		}
+		return private.Public().(ed25519.PublicKey), private, nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		public, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, nil, errors.New("not an Ed25519 key")
+		}
+		return public, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+}
+
+// ActiveKeyID returns the id of the key receipts are signed with
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeKeyID
+}
+
+// Sign signs the receipt with the active key, setting its KeyID and Signature
+func (k *Keyring) Sign(r *Receipt) {
+	r.KeyID = k.activeKeyID
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(k.signingKey, r.Canonical()))
+}
+
+// VerificationKeys returns the public keys of the keyring by key id
+func (k *Keyring) VerificationKeys() map[string]ed25519.PublicKey {
+	keys := make(map[string]ed25519.PublicKey, len(k.keys))
+	for keyID, key := range k.keys {
+		keys[keyID] = key
+	}
+	return keys
+}
+
+// JWKSet returns the verification keys as a JSON Web Key Set, the active key first
+func (k *Keyring) JWKSet() JWKSet {
+	keyIDs := make([]string, 0, len(k.keys))
+	for keyID := range k.keys {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Slice(keyIDs, func(i, j int) bool {
+		if (keyIDs[i] == k.activeKeyID) != (keyIDs[j] == k.activeKeyID) {
+			return keyIDs[i] == k.activeKeyID
+		}
+		return keyIDs[i] > keyIDs[j]
+	})
+
+	set := JWKSet{Keys: make([]JWK, 0, len(keyIDs))}
+	for _, keyID := range keyIDs {
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			KeyID:     keyID,
+			Use:       "sig",
+			Algorithm: Algorithm,
+			X:         base64.RawURLEncoding.EncodeToString(k.keys[keyID]),
+		})
+	}
+	return set
+}
+
+// JWK is an Ed25519 verification key in JSON Web Key form (RFC 8037)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	X         string `json:"x"`
+}
+
+// JWKSet is a JSON Web Key Set, as published on /v1/keys
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParseJWKSet decodes a JSON Web Key Set into verification keys by key id, skipping keys other than
+// Ed25519 ones
+func ParseJWKSet(data []byte) (map[string]ed25519.PublicKey, error) {
+	var set JWKSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key set: key %q is not an Ed25519 public key", jwk.KeyID)
+		}
+		keys[jwk.KeyID] = ed25519.PublicKey(x)
+	}
+	return keys, nil
+}
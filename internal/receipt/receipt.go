@@ -0,0 +1,73 @@
+// Package receipt signs proofs that a transaction was accepted. A receipt carries the transaction's
+// id, account, operation type, amount and event date with a detached Ed25519 signature over its
+// canonical form, so merchants and customers can check it offline against the published keys.
+package receipt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Algorithm is the signature algorithm of receipts, as named in JWKs
+const Algorithm = "EdDSA"
+
+var (
+	ErrUnknownKey       = errors.New("receipt signed with an unknown key")
+	ErrInvalidSignature = errors.New("invalid receipt signature")
+)
+
+// Receipt is the proof that a transaction was accepted; Signature is the base64 Ed25519 signature of
+// the canonical form of the other fields by the key KeyID
+type Receipt struct {
+	TransactionID   int64     `json:"transaction_id"`
+	AccountID       int64     `json:"account_id"`
+	OperationTypeID int64     `json:"operation_type_id"`
+	Amount          float64   `json:"amount"`
+	EventDate       time.Time `json:"event_date"`
+	KeyID           string    `json:"key_id"`
+	Signature       string    `json:"signature"`
+}
+
+// canonicalReceipt fixes the field order and the event date format the signature covers
+type canonicalReceipt struct {
+	TransactionID   int64   `json:"transaction_id"`
+	AccountID       int64   `json:"account_id"`
+	OperationTypeID int64   `json:"operation_type_id"`
+	Amount          float64 `json:"amount"`
+	EventDate       string  `json:"event_date"`
+	KeyID           string  `json:"key_id"`
+}
+
+// Canonical returns the bytes the signature covers: the receipt without its signature as compact JSON
+// with the event date in UTC RFC 3339
+func (r *Receipt) Canonical() []byte {
+	b, _ := json.Marshal(canonicalReceipt{
+		TransactionID:   r.TransactionID,
+		AccountID:       r.AccountID,
+		OperationTypeID: r.OperationTypeID,
+		Amount:          r.Amount,
+		EventDate:       r.EventDate.UTC().Format(time.RFC3339Nano),
+		KeyID:           r.KeyID,
+	})
+	return b
+}
+
+// Verify checks the receipt's signature against the verification key it names
+func Verify(r *Receipt, keys map[string]ed25519.PublicKey) error {
+	key, ok := keys[r.KeyID]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, r.KeyID)
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if !ed25519.Verify(key, r.Canonical(), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package receipt_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/receipt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKey writes a new Ed25519 signing key named keyID to dir
+func writeKey(t *testing.T, dir, keyID string) ed25519.PrivateKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, keyID+receipt.KeyFileExt), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return private
+}
+
+// retireKey replaces the key file named keyID with the public half of private
+func retireKey(t *testing.T, dir, keyID string, private ed25519.PrivateKey) ed25519.PublicKey {
+	t.Helper()
+	public := private.Public().(ed25519.PublicKey)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, keyID+receipt.KeyFileExt), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	return public
+}
+
+func newReceipt() *receipt.Receipt {
+	return &receipt.Receipt{
+		TransactionID:   42,
+		AccountID:       1,
+		OperationTypeID: 1,
+		Amount:          -50.25,
+		EventDate:       time.Date(2025, 4, 30, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2025-04")
+	keyring, err := receipt.LoadKeyring(dir, "")
+	require.NoError(t, err)
+
+	r := newReceipt()
+	keyring.Sign(r)
+	assert.Equal(t, "2025-04", r.KeyID)
+	assert.NoError(t, receipt.Verify(r, keyring.VerificationKeys()))
+
+	t.Run("Receipt should survive a JSON round trip in another time zone", func(t *testing.T) {
+		r := *r
+		r.EventDate = r.EventDate.In(time.FixedZone("BRT", -3*3600))
+		data, err := json.Marshal(r)
+		require.NoError(t, err)
+
+		var decoded receipt.Receipt
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.NoError(t, receipt.Verify(&decoded, keyring.VerificationKeys()))
+	})
+
+	t.Run("Altered receipt should fail", func(t *testing.T) {
+		altered := *r
+		altered.Amount = -5.25
+		assert.ErrorIs(t, receipt.Verify(&altered, keyring.VerificationKeys()), receipt.ErrInvalidSignature)
+	})
+
+	t.Run("Unknown key should fail", func(t *testing.T) {
+		other := *r
+		other.KeyID = "2024-01"
+		assert.ErrorIs(t, receipt.Verify(&other, keyring.VerificationKeys()), receipt.ErrUnknownKey)
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	old := writeKey(t, dir, "2025-03")
+	keyring, err := receipt.LoadKeyring(dir, "")
+	require.NoError(t, err)
+	signedBefore := newReceipt()
+	keyring.Sign(signedBefore)
+
+	// A new key takes over and the old one is retired to its public half
+	retireKey(t, dir, "2025-03", old)
+	writeKey(t, dir, "2025-04")
+	keyring, err = receipt.LoadKeyring(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, "2025-04", keyring.ActiveKeyID())
+
+	signedAfter := newReceipt()
+	keyring.Sign(signedAfter)
+	assert.Equal(t, "2025-04", signedAfter.KeyID)
+	assert.NoError(t, receipt.Verify(signedBefore, keyring.VerificationKeys()))
+	assert.NoError(t, receipt.Verify(signedAfter, keyring.VerificationKeys()))
+}
+
+func TestLoadKeyring(t *testing.T) {
+	t.Run("Pinned active key", func(t *testing.T) {
+		dir := t.TempDir()
+		writeKey(t, dir, "a")
+		writeKey(t, dir, "b")
+
+		keyring, err := receipt.LoadKeyring(dir, "a")
+		require.NoError(t, err)
+		assert.Equal(t, "a", keyring.ActiveKeyID())
+	})
+
+	t.Run("Retired key cannot be active", func(t *testing.T) {
+		dir := t.TempDir()
+		retireKey(t, dir, "a", writeKey(t, dir, "a"))
+		writeKey(t, dir, "b")
+
+		_, err := receipt.LoadKeyring(dir, "a")
+		assert.ErrorContains(t, err, `active receipt key "a" has no private key`)
+	})
+
+	t.Run("Directory without signing keys", func(t *testing.T) {
+		dir := t.TempDir()
+		retireKey(t, dir, "a", writeKey(t, dir, "a"))
+
+		_, err := receipt.LoadKeyring(dir, "")
+		assert.ErrorContains(t, err, "no receipt signing key")
+	})
+
+	t.Run("Malformed key file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "bad"+receipt.KeyFileExt), []byte("not a key"), 0o600))
+
+		_, err := receipt.LoadKeyring(dir, "")
+		assert.ErrorContains(t, err, "invalid receipt key bad")
+	})
+}
+
+func TestJWKSet(t *testing.T) {
+	dir := t.TempDir()
+	retired := retireKey(t, dir, "2025-03", writeKey(t, dir, "2025-03"))
+	active := writeKey(t, dir, "2025-04").Public().(ed25519.PublicKey)
+	keyring, err := receipt.LoadKeyring(dir, "")
+	require.NoError(t, err)
+
+	set := keyring.JWKSet()
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, "2025-04", set.Keys[0].KeyID)
+	assert.Equal(t, "OKP", set.Keys[0].KeyType)
+	assert.Equal(t, "Ed25519", set.Keys[0].Curve)
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	keys, err := receipt.ParseJWKSet(data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ed25519.PublicKey{"2025-03": retired, "2025-04": active}, keys)
+
+	r := newReceipt()
+	keyring.Sign(r)
+	assert.NoError(t, receipt.Verify(r, keys))
+
+	_, err = receipt.ParseJWKSet([]byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"x","x":"short"}]}`))
+	assert.Error(t, err)
+}
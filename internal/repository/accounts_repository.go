@@ -5,22 +5,30 @@ import (
 	"fmt"
 
 	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
 )
 
+// accountColumns reads an account joined with its customer as c
+const accountColumns = `a.id, a.customer_id, c.document_number, a.product, a.status, a.created_at`
+
 func NewAccountsRepository(db PgxPoolIface) AccountsRepository {
 	return &accountsRepo{db: db}
 }
 
-// InsertAccount inserts a new account
-func (r *accountsRepo) InsertAccount(ctx context.Context, documentNumber string) (*Account, error) {
-	query := `INSERT INTO accounts (document_number) VALUES ($1) RETURNING id, document_number`
-	account := &Account{}
+// InsertAccount inserts a new account of the given product for the customer
+func (r *accountsRepo) InsertAccount(ctx context.Context, customerID int64, product string) (*Account, error) {
+	query := `WITH a AS (
+		INSERT INTO accounts (customer_id, product, correlation_id) VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING id, customer_id, product, status, created_at
+	)
+	SELECT ` + accountColumns + ` FROM a JOIN customers c ON c.id = a.customer_id`
+	account := &Account{CorrelationID: middleware.GetCorrelationIDFromContext(ctx)}
 
-	err := r.db.QueryRow(ctx, query, documentNumber).Scan(&account.ID, &account.DocumentNumber)
+	err := scanAccount(conn(ctx, r.db).QueryRow(ctx, query, customerID, product, account.CorrelationID), account)
 	if err != nil {
 		reqID := middleware.GetRequestIDFromContext(ctx)
-		log.Error().Str("request_id", reqID).Err(err).Msg("Database error: failed to insert account")
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert account")
 		return nil, fmt.Errorf("failed to insert account: %w", err)
 	}
 	return account, nil
@@ -28,14 +36,83 @@ func (r *accountsRepo) InsertAccount(ctx context.Context, documentNumber string)
 
 // GetAccountByID retrieves an account by accountID
 func (r *accountsRepo) GetAccountByID(ctx context.Context, accountID int64) (*Account, error) {
-	query := `SELECT id, document_number FROM accounts WHERE id = $1`
+	query := `SELECT ` + accountColumns + ` FROM accounts a JOIN customers c ON c.id = a.customer_id WHERE a.id = $1`
+	account := &Account{}
+
+	err := scanAccount(conn(ctx, r.db).QueryRow(ctx, query, accountID), account)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to retrieve account")
+		return nil, err
+	}
+	return account, nil
+}
+
+// GetAccountsByCustomerID retrieves every account of the customer, oldest first
+func (r *accountsRepo) GetAccountsByCustomerID(ctx context.Context, customerID int64) ([]*Account, error) {
+	query := `SELECT ` + accountColumns + ` FROM accounts a JOIN customers c ON c.id = a.customer_id WHERE a.customer_id = $1 ORDER BY a.id`
+
+	accounts, err := r.queryAccounts(ctx, query, customerID)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to retrieve customer accounts")
+		return nil, fmt.Errorf("failed to retrieve customer accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// LockAccounts locks the rows of the given accounts until the enclosing transaction ends and
+// returns the accounts found. Rows are locked in ascending id order, so concurrent callers
+// locking overlapping sets cannot deadlock.
+func (r *accountsRepo) LockAccounts(ctx context.Context, accountIDs ...int64) ([]*Account, error) {
+	query := `SELECT ` + accountColumns + ` FROM accounts a JOIN customers c ON c.id = a.customer_id
+		WHERE a.id = ANY($1) ORDER BY a.id FOR UPDATE OF a`
+
+	accounts, err := r.queryAccounts(ctx, query, accountIDs)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to lock accounts")
+		return nil, fmt.Errorf("failed to lock accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// UpdateAccountStatus sets the status of the account and returns the updated account
+func (r *accountsRepo) UpdateAccountStatus(ctx context.Context, accountID int64, status string) (*Account, error) {
+	query := `WITH a AS (
+		UPDATE accounts SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+		RETURNING id, customer_id, product, status, created_at
+	)
+	SELECT ` + accountColumns + ` FROM a JOIN customers c ON c.id = a.customer_id`
 	account := &Account{}
 
-	err := r.db.QueryRow(ctx, query, accountID).Scan(&account.ID, &account.DocumentNumber)
+	err := scanAccount(conn(ctx, r.db).QueryRow(ctx, query, accountID, status), account)
 	if err != nil {
 		reqID := middleware.GetRequestIDFromContext(ctx)
-		log.Error().Str("request_id", reqID).Err(err).Msg("Database error: failed to retrieve account")
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to update account status")
 		return nil, err
 	}
 	return account, nil
 }
+
+func (r *accountsRepo) queryAccounts(ctx context.Context, query string, args ...any) ([]*Account, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*Account
+	for rows.Next() {
+		account := &Account{}
+		if err := scanAccount(rows, account); err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+func scanAccount(row pgx.Row, account *Account) error {
+	return row.Scan(&account.ID, &account.CustomerID, &account.DocumentNumber, &account.Product, &account.Status, &account.CreatedAt)
+}
@@ -4,13 +4,20 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
 	"github.com/ashwingopalsamy/transactions-service/internal/repository"
 	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
 )
 
+var (
+	accountColumns   = []string{"id", "customer_id", "document_number", "product", "status", "created_at"}
+	accountCreatedAt = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+)
+
 func TestInsertAccount(t *testing.T) {
 	t.Run("Completely valid request", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
@@ -20,45 +27,49 @@ func TestInsertAccount(t *testing.T) {
 		repo := repository.NewAccountsRepository(mockDB)
 		ctx := context.Background()
 
-		rows := pgxmock.NewRows([]string{"id", "document_number"}).
-			AddRow(int64(1), "12345678900")
+		rows := pgxmock.NewRows(accountColumns).
+			AddRow(int64(1), int64(7), "12345678900", "credit", "active", accountCreatedAt)
 
-		mockDB.ExpectQuery(`INSERT INTO accounts`).
-			WithArgs("12345678900").
+		mockDB.ExpectQuery(`INSERT INTO accounts .* RETURNING id, customer_id, product, status, created_at`).
+			WithArgs(int64(7), "credit", "").
 			WillReturnRows(rows)
 
-		account, err := repo.InsertAccount(ctx, "12345678900")
+		account, err := repo.InsertAccount(ctx, 7, "credit")
 
 		assert.NoError(t, err)
 		assert.NotNil(t, account)
 		assert.Equal(t, int64(1), account.ID)
+		assert.Equal(t, int64(7), account.CustomerID)
 		assert.Equal(t, "12345678900", account.DocumentNumber)
+		assert.Equal(t, "credit", account.Product)
 
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
 
-	t.Run("Database error during insertion", func(t *testing.T) {
+	t.Run("Correlation id from context is stored", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
 		repo := repository.NewAccountsRepository(mockDB)
-		ctx := context.Background()
+		ctx := middleware.WithCorrelationID(context.Background(), "order-42")
+
+		rows := pgxmock.NewRows(accountColumns).
+			AddRow(int64(1), int64(7), "12345678900", "credit", "active", accountCreatedAt)
 
 		mockDB.ExpectQuery(`INSERT INTO accounts`).
-			WithArgs("12345678900").
-			WillReturnError(errors.New("database error"))
+			WithArgs(int64(7), "credit", "order-42").
+			WillReturnRows(rows)
 
-		account, err := repo.InsertAccount(ctx, "12345678900")
+		account, err := repo.InsertAccount(ctx, 7, "credit")
 
-		assert.Error(t, err)
-		assert.Nil(t, account)
-		assert.Contains(t, err.Error(), "database error")
+		assert.NoError(t, err)
+		assert.Equal(t, "order-42", account.CorrelationID)
 
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
 
-	t.Run("Empty document_number should fail", func(t *testing.T) {
+	t.Run("Database error during insertion", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
 		assert.NoError(t, err)
 		defer mockDB.Close()
@@ -67,19 +78,19 @@ func TestInsertAccount(t *testing.T) {
 		ctx := context.Background()
 
 		mockDB.ExpectQuery(`INSERT INTO accounts`).
-			WithArgs("").
-			WillReturnError(errors.New("null value in column \"document_number\" violates not-null constraint"))
+			WithArgs(int64(7), "credit", "").
+			WillReturnError(errors.New("database error"))
 
-		account, err := repo.InsertAccount(ctx, "")
+		account, err := repo.InsertAccount(ctx, 7, "credit")
 
 		assert.Error(t, err)
 		assert.Nil(t, account)
-		assert.Contains(t, err.Error(), "null value in column")
+		assert.Contains(t, err.Error(), "database error")
 
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
 
-	t.Run("Duplicate document_number should return error", func(t *testing.T) {
+	t.Run("Second account of the same product should return error", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
 		assert.NoError(t, err)
 		defer mockDB.Close()
@@ -88,10 +99,10 @@ func TestInsertAccount(t *testing.T) {
 		ctx := context.Background()
 
 		mockDB.ExpectQuery(`INSERT INTO accounts`).
-			WithArgs("12345678900").
-			WillReturnError(errors.New("duplicate key value violates unique constraint"))
+			WithArgs(int64(7), "credit", "").
+			WillReturnError(errors.New(`duplicate key value violates unique constraint "accounts_customer_id_product_key"`))
 
-		account, err := repo.InsertAccount(ctx, "12345678900")
+		account, err := repo.InsertAccount(ctx, 7, "credit")
 
 		assert.Error(t, err)
 		assert.Nil(t, account)
@@ -112,10 +123,10 @@ func TestGetAccountByID(t *testing.T) {
 
 		accountID := int64(1)
 
-		rows := pgxmock.NewRows([]string{"id", "document_number"}).
-			AddRow(accountID, "12345678900")
+		rows := pgxmock.NewRows(accountColumns).
+			AddRow(accountID, int64(7), "12345678900", "credit", "active", accountCreatedAt)
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
+		mockDB.ExpectQuery(`SELECT a.id, a.customer_id, c.document_number, a.product, a.status, a.created_at FROM accounts a .* WHERE a.id = \$1`).
 			WithArgs(accountID).
 			WillReturnRows(rows)
 
@@ -124,6 +135,7 @@ func TestGetAccountByID(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotNil(t, account)
 		assert.Equal(t, int64(1), account.ID)
+		assert.Equal(t, int64(7), account.CustomerID)
 		assert.Equal(t, "12345678900", account.DocumentNumber)
 
 		assert.NoError(t, mockDB.ExpectationsWereMet())
@@ -139,7 +151,7 @@ func TestGetAccountByID(t *testing.T) {
 
 		accountID := int64(999)
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
+		mockDB.ExpectQuery(`SELECT a.id, a.customer_id, c.document_number, a.product, a.status, a.created_at FROM accounts a .* WHERE a.id = \$1`).
 			WithArgs(accountID).
 			WillReturnError(pgx.ErrNoRows)
 
@@ -152,3 +164,90 @@ func TestGetAccountByID(t *testing.T) {
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
 }
+
+func TestGetAccountsByCustomerID(t *testing.T) {
+	t.Run("Returns every account of the customer", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAccountsRepository(mockDB)
+		ctx := context.Background()
+
+		rows := pgxmock.NewRows(accountColumns).
+			AddRow(int64(1), int64(7), "12345678900", "credit", "active", accountCreatedAt).
+			AddRow(int64(2), int64(7), "12345678900", "prepaid", "active", accountCreatedAt)
+
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* WHERE a.customer_id = \$1 ORDER BY a.id`).
+			WithArgs(int64(7)).
+			WillReturnRows(rows)
+
+		accounts, err := repo.GetAccountsByCustomerID(ctx, 7)
+
+		assert.NoError(t, err)
+		assert.Len(t, accounts, 2)
+		assert.Equal(t, "prepaid", accounts[1].Product)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAccountsRepository(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a`).
+			WithArgs(int64(7)).
+			WillReturnError(errors.New("database error"))
+
+		accounts, err := repo.GetAccountsByCustomerID(ctx, 7)
+
+		assert.Error(t, err)
+		assert.Nil(t, accounts)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestUpdateAccountStatus(t *testing.T) {
+	t.Run("Updated account is returned", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAccountsRepository(mockDB)
+
+		mockDB.ExpectQuery(`UPDATE accounts SET status = \$2, updated_at = CURRENT_TIMESTAMP WHERE id = \$1\s+RETURNING id, customer_id, product, status, created_at`).
+			WithArgs(int64(1), "blocked").
+			WillReturnRows(pgxmock.NewRows(accountColumns).
+				AddRow(int64(1), int64(7), "12345678900", "credit", "blocked", accountCreatedAt))
+
+		account, err := repo.UpdateAccountStatus(context.Background(), 1, "blocked")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "blocked", account.Status)
+		assert.Equal(t, accountCreatedAt, account.CreatedAt)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown account", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAccountsRepository(mockDB)
+
+		mockDB.ExpectQuery(`UPDATE accounts SET status`).
+			WithArgs(int64(999), "blocked").
+			WillReturnError(pgx.ErrNoRows)
+
+		account, err := repo.UpdateAccountStatus(context.Background(), 999, "blocked")
+
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.Nil(t, account)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
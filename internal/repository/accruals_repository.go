@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+func NewAccrualsRepository(db PgxPoolIface) AccrualsRepository {
+	return &accrualsRepo{db: db}
+}
+
+// GetAccrualPolicies retrieves the accrual policies of the product, the product-wide one first
+func (r *accrualsRepo) GetAccrualPolicies(ctx context.Context, product string) ([]*AccrualPolicy, error) {
+	query := `SELECT product, COALESCE(operation_type_id, 0), apr, late_fee_amount, late_fee_percent, grace_days
+		FROM accrual_policies WHERE product = $1
+		ORDER BY operation_type_id NULLS FIRST`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve accrual policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []*AccrualPolicy
+	for rows.Next() {
+		policy := &AccrualPolicy{}
+		if err := rows.Scan(&policy.Product, &policy.OperationTypeID, &policy.APR,
+			&policy.LateFeeAmount, &policy.LateFeePercent, &policy.GraceDays); err != nil {
+			return nil, fmt.Errorf("failed to scan accrual policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// GetPastDueAccountIDs retrieves the accounts, other than closed ones, with a statement due before date
+func (r *accrualsRepo) GetPastDueAccountIDs(ctx context.Context, date time.Time) ([]int64, error) {
+	query := `SELECT DISTINCT s.account_id
+		FROM statements s JOIN accounts a ON a.id = s.account_id
+		WHERE s.due_date < $1::date AND a.status <> 'closed'
+		ORDER BY s.account_id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve past-due accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accountIDs []int64
+	for rows.Next() {
+		var accountID int64
+		if err := rows.Scan(&accountID); err != nil {
+			return nil, fmt.Errorf("failed to scan past-due account: %w", err)
+		}
+		accountIDs = append(accountIDs, accountID)
+	}
+	return accountIDs, rows.Err()
+}
+
+// GetAccrualsByAccountID retrieves every charge levied on the account, oldest first
+func (r *accrualsRepo) GetAccrualsByAccountID(ctx context.Context, accountID int64) ([]*Accrual, error) {
+	query := `SELECT kind, transaction_id, COALESCE(statement_id, 0), amount
+		FROM accruals WHERE account_id = $1 ORDER BY id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve accruals: %w", err)
+	}
+	defer rows.Close()
+
+	var accruals []*Accrual
+	for rows.Next() {
+		accrual := &Accrual{}
+		if err := rows.Scan(&accrual.Kind, &accrual.TransactionID, &accrual.StatementID, &accrual.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan accrual: %w", err)
+		}
+		accruals = append(accruals, accrual)
+	}
+	return accruals, rows.Err()
+}
+
+// InsertAccrualRun records the run and its charges in a single statement. A second run for the
+// same account and day violates accrual_runs_pkey.
+func (r *accrualsRepo) InsertAccrualRun(ctx context.Context, run *AccrualRun) (*AccrualRun, error) {
+	query := `WITH r AS (
+		INSERT INTO accrual_runs (account_id, accrual_date, correlation_id)
+		VALUES ($1, $2::date, NULLIF($3, ''))
+		RETURNING account_id, accrual_date, created_at
+	), a AS (
+		INSERT INTO accruals (account_id, accrual_date, kind, transaction_id, statement_id, amount)
+		SELECT r.account_id, r.accrual_date, a.kind, a.transaction_id, NULLIF(a.statement_id, 0), a.amount
+		FROM r, unnest($4::text[], $5::bigint[], $6::bigint[], $7::numeric[])
+			WITH ORDINALITY AS a(kind, transaction_id, statement_id, amount, ord)
+		ORDER BY a.ord
+	)
+	SELECT created_at FROM r`
+
+	n := len(run.Accruals)
+	kinds, transactionIDs, statementIDs, amounts := make([]string, n), make([]int64, n), make([]int64, n), make([]float64, n)
+	for i, accrual := range run.Accruals {
+		kinds[i], transactionIDs[i], statementIDs[i], amounts[i] = accrual.Kind, accrual.TransactionID, accrual.StatementID, accrual.Amount
+	}
+
+	created := *run
+	created.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		run.AccountID, run.AccrualDate, created.CorrelationID, kinds, transactionIDs, statementIDs, amounts,
+	).Scan(&created.CreatedAt)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert accrual run")
+		return nil, fmt.Errorf("failed to insert accrual run: %w", err)
+	}
+	return &created, nil
+}
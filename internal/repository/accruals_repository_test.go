@@ -0,0 +1,125 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAccrualPolicies(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewAccrualsRepository(mockDB)
+
+	mockDB.ExpectQuery(`FROM accrual_policies WHERE product = \$1 ORDER BY operation_type_id NULLS FIRST`).
+		WithArgs("credit").
+		WillReturnRows(pgxmock.NewRows([]string{"product", "operation_type_id", "apr", "late_fee_amount", "late_fee_percent", "grace_days"}).
+			AddRow("credit", int64(0), 0.24, 25.0, 0.0, 0).
+			AddRow("credit", int64(3), 0.36, 0.0, 0.0, 0))
+
+	policies, err := repo.GetAccrualPolicies(context.Background(), "credit")
+
+	assert.NoError(t, err)
+	assert.Len(t, policies, 2)
+	assert.Equal(t, &repository.AccrualPolicy{Product: "credit", OperationTypeID: 3, APR: 0.36}, policies[1])
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetPastDueAccountIDs(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewAccrualsRepository(mockDB)
+	date := time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)
+
+	mockDB.ExpectQuery(`SELECT DISTINCT s.account_id .* WHERE s.due_date < \$1::date AND a.status <> 'closed'`).
+		WithArgs(date).
+		WillReturnRows(pgxmock.NewRows([]string{"account_id"}).AddRow(int64(1)).AddRow(int64(4)))
+
+	accountIDs, err := repo.GetPastDueAccountIDs(context.Background(), date)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 4}, accountIDs)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetAccrualsByAccountID(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewAccrualsRepository(mockDB)
+
+	mockDB.ExpectQuery(`FROM accruals WHERE account_id = \$1 ORDER BY id`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"kind", "transaction_id", "statement_id", "amount"}).
+			AddRow("interest", int64(9), int64(0), 0.12).
+			AddRow("late_fee", int64(10), int64(2), 25.0))
+
+	accruals, err := repo.GetAccrualsByAccountID(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, accruals, 2)
+	assert.Equal(t, int64(2), accruals[1].StatementID)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestInsertAccrualRun(t *testing.T) {
+	run := &repository.AccrualRun{
+		AccountID:   1,
+		AccrualDate: "2025-03-12",
+		Accruals: []repository.Accrual{
+			{Kind: "interest", TransactionID: 9, Amount: 0.12},
+			{Kind: "late_fee", TransactionID: 10, StatementID: 2, Amount: 25},
+		},
+	}
+
+	t.Run("Completely valid request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAccrualsRepository(mockDB)
+		ctx := middleware.WithCorrelationID(context.Background(), "accrual-1")
+
+		mockDB.ExpectQuery(`WITH r AS \(\s*INSERT INTO accrual_runs .* INSERT INTO accruals`).
+			WithArgs(int64(1), "2025-03-12", "accrual-1",
+				[]string{"interest", "late_fee"}, []int64{9, 10}, []int64{0, 2}, []float64{0.12, 25}).
+			WillReturnRows(pgxmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+
+		created, err := repo.InsertAccrualRun(ctx, run)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "accrual-1", created.CorrelationID)
+		assert.False(t, created.CreatedAt.IsZero())
+		assert.True(t, run.CreatedAt.IsZero())
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error during insertion", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAccrualsRepository(mockDB)
+
+		mockDB.ExpectQuery(`INSERT INTO accrual_runs`).
+			WithArgs(int64(1), "2025-03-12", "", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("database error"))
+
+		created, err := repo.InsertAccrualRun(context.Background(), run)
+
+		assert.Error(t, err)
+		assert.Nil(t, created)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
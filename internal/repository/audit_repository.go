@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+func NewAuditRepository(db PgxPoolIface) AuditRepository {
+	return &auditRepo{db: db}
+}
+
+// InsertAuditEvent appends an event to the audit log, attributed to the principal, request, correlation id
+// and IP of ctx
+func (r *auditRepo) InsertAuditEvent(ctx context.Context, event *AuditEvent) (*AuditEvent, error) {
+	query := `INSERT INTO audit_events (action, entity_type, entity_id, before, after, principal, request_id, correlation_id, ip)
+		VALUES ($1, $2, NULLIF($3::bigint, 0), NULLIF($4, '')::jsonb, NULLIF($5, '')::jsonb, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''))
+		RETURNING id, created_at`
+
+	created := *event
+	created.Principal = middleware.GetPrincipalFromContext(ctx)
+	created.RequestID = middleware.GetRequestIDFromContext(ctx)
+	created.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+	created.IP = middleware.GetClientIPFromContext(ctx)
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		created.Action, created.EntityType, created.EntityID, string(created.Before), string(created.After),
+		created.Principal, created.RequestID, created.CorrelationID, created.IP,
+	).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		log.Error().Ctx(ctx).Str("request_id", created.RequestID).Err(err).Msg("Database error: failed to insert audit event")
+		return nil, fmt.Errorf("failed to insert audit event: %w", err)
+	}
+	return &created, nil
+}
+
+// InsertAuditEvents appends the events to the audit log in order in a single statement, attributed as
+// InsertAuditEvent attributes them
+func (r *auditRepo) InsertAuditEvents(ctx context.Context, events []*AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO audit_events (action, entity_type, entity_id, before, after, principal, request_id, correlation_id, ip)
+		SELECT e.action, e.entity_type, NULLIF(e.entity_id, 0), NULLIF(e.before, '')::jsonb, NULLIF(e.after, '')::jsonb,
+			NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, '')
+		FROM unnest($1::text[], $2::text[], $3::bigint[], $4::text[], $5::text[]) WITH ORDINALITY AS e(action, entity_type, entity_id, before, after, n)
+		ORDER BY e.n`
+
+	actions, entityTypes, entityIDs := make([]string, len(events)), make([]string, len(events)), make([]int64, len(events))
+	befores, afters := make([]string, len(events)), make([]string, len(events))
+	for i, event := range events {
+		actions[i], entityTypes[i], entityIDs[i] = event.Action, event.EntityType, event.EntityID
+		befores[i], afters[i] = string(event.Before), string(event.After)
+	}
+
+	reqID := middleware.GetRequestIDFromContext(ctx)
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		actions, entityTypes, entityIDs, befores, afters,
+		middleware.GetPrincipalFromContext(ctx), reqID, middleware.GetCorrelationIDFromContext(ctx), middleware.GetClientIPFromContext(ctx),
+	)
+	if err != nil {
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert audit events")
+		return fmt.Errorf("failed to insert audit events: %w", err)
+	}
+	return nil
+}
+
+// GetAuditEvents retrieves the audit events matching the filter, oldest first
+func (r *auditRepo) GetAuditEvents(ctx context.Context, filter AuditEventFilter) ([]*AuditEvent, error) {
+	query := `SELECT id, action, entity_type, COALESCE(entity_id, 0), COALESCE(before::text, ''), COALESCE(after::text, ''),
+			COALESCE(principal, ''), COALESCE(request_id, ''), COALESCE(correlation_id, ''), COALESCE(ip, ''), created_at
+		FROM audit_events
+		WHERE ($1 = '' OR action = $1)
+		  AND ($2 = '' OR entity_type = $2)
+		  AND ($3::bigint = 0 OR entity_id = $3)
+		  AND ($4 = '' OR principal = $4)
+		  AND ($5 = '' OR request_id = $5)
+		  AND ($6::timestamp IS NULL OR created_at >= $6)
+		  AND ($7::timestamp IS NULL OR created_at < $7)
+		  AND id > $8
+		ORDER BY id
+		LIMIT NULLIF($9, 0)`
+
+	var from, to any
+	if !filter.From.IsZero() {
+		from = filter.From
+	}
+	if !filter.To.IsZero() {
+		to = filter.To
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query,
+		filter.Action, filter.EntityType, filter.EntityID, filter.Principal, filter.RequestID, from, to, filter.AfterID, filter.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*AuditEvent
+	for rows.Next() {
+		event := &AuditEvent{}
+		var before, after string
+		if err := rows.Scan(&event.ID, &event.Action, &event.EntityType, &event.EntityID, &before, &after,
+			&event.Principal, &event.RequestID, &event.CorrelationID, &event.IP, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		if before != "" {
+			event.Before = []byte(before)
+		}
+		if after != "" {
+			event.After = []byte(after)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
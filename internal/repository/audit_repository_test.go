@@ -0,0 +1,157 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertAuditEvent(t *testing.T) {
+	t.Run("Event attributed to the context", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAuditRepository(mockDB)
+		ctx := middleware.WithPrincipal(middleware.WithCorrelationID(context.Background(), "flow-1"), "admin")
+		ctx = middleware.WithClientIP(ctx, "203.0.113.7")
+		createdAt := time.Date(2025, 4, 20, 9, 0, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`INSERT INTO audit_events`).
+			WithArgs("account.status_changed", "account", int64(7), `{"status":"active"}`, `{"status":"blocked"}`, "admin", "", "flow-1", "203.0.113.7").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(3), createdAt))
+
+		event, err := repo.InsertAuditEvent(ctx, &repository.AuditEvent{
+			Action:     "account.status_changed",
+			EntityType: "account",
+			EntityID:   7,
+			Before:     []byte(`{"status":"active"}`),
+			After:      []byte(`{"status":"blocked"}`),
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), event.ID)
+		assert.Equal(t, "admin", event.Principal)
+		assert.Equal(t, "203.0.113.7", event.IP)
+		assert.Equal(t, createdAt, event.CreatedAt)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAuditRepository(mockDB)
+
+		mockDB.ExpectQuery(`INSERT INTO audit_events`).
+			WithArgs("config.updated", "config", int64(0), "", "", "", "", "", "").
+			WillReturnError(errors.New("db error"))
+
+		event, err := repo.InsertAuditEvent(context.Background(), &repository.AuditEvent{Action: "config.updated", EntityType: "config"})
+
+		assert.Nil(t, event)
+		assert.EqualError(t, err, "failed to insert audit event: db error")
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestInsertAuditEvents(t *testing.T) {
+	events := []*repository.AuditEvent{
+		{Action: "transaction.balance_updated", EntityType: "transaction", EntityID: 3, Before: []byte(`{"balance":-100}`), After: []byte(`{"balance":0}`)},
+		{Action: "transaction.balance_updated", EntityType: "transaction", EntityID: 9, Before: []byte(`{"balance":150}`), After: []byte(`{"balance":50}`)},
+	}
+
+	t.Run("Events attributed to the context in a single statement", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAuditRepository(mockDB)
+		ctx := middleware.WithPrincipal(middleware.WithCorrelationID(context.Background(), "flow-1"), "admin")
+
+		mockDB.ExpectExec(`INSERT INTO audit_events .* FROM unnest\(.*\) WITH ORDINALITY .* ORDER BY e.n`).
+			WithArgs([]string{"transaction.balance_updated", "transaction.balance_updated"}, []string{"transaction", "transaction"}, []int64{3, 9},
+				[]string{`{"balance":-100}`, `{"balance":150}`}, []string{`{"balance":0}`, `{"balance":50}`}, "admin", "", "flow-1", "").
+			WillReturnResult(pgxmock.NewResult("INSERT", 2))
+
+		assert.NoError(t, repo.InsertAuditEvents(ctx, events))
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAuditRepository(mockDB)
+
+		mockDB.ExpectExec(`INSERT INTO audit_events`).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "", "", "", "").
+			WillReturnError(errors.New("db error"))
+
+		err = repo.InsertAuditEvents(context.Background(), events)
+
+		assert.EqualError(t, err, "failed to insert audit events: db error")
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetAuditEvents(t *testing.T) {
+	columns := []string{"id", "action", "entity_type", "entity_id", "before", "after", "principal", "request_id", "correlation_id", "ip", "created_at"}
+
+	t.Run("Filtered events", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAuditRepository(mockDB)
+		from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+		createdAt := time.Date(2025, 4, 20, 9, 0, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`FROM audit_events WHERE .* ORDER BY id LIMIT NULLIF\(\$9, 0\)`).
+			WithArgs("", "account", int64(7), "", "", from, nil, int64(2), 50).
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(int64(3), "account.status_changed", "account", int64(7), `{"status": "active"}`, `{"status": "blocked"}`, "admin", "req-1", "", "203.0.113.7", createdAt).
+				AddRow(int64(4), "account.created", "account", int64(7), "", `{"id": 7}`, "", "", "", "", createdAt))
+
+		events, err := repo.GetAuditEvents(context.Background(), repository.AuditEventFilter{
+			EntityType: "account",
+			EntityID:   7,
+			From:       from,
+			AfterID:    2,
+			Limit:      50,
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, "admin", events[0].Principal)
+		assert.JSONEq(t, `{"status":"blocked"}`, string(events[0].After))
+		assert.Nil(t, events[1].Before)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewAuditRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM audit_events`).
+			WithArgs("", "", int64(0), "", "", nil, nil, int64(0), 0).
+			WillReturnError(errors.New("db error"))
+
+		events, err := repo.GetAuditEvents(context.Background(), repository.AuditEventFilter{})
+
+		assert.Nil(t, events)
+		assert.EqualError(t, err, "failed to retrieve audit events: db error")
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
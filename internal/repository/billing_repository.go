@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// statementColumns reads the statement dates as YYYY-MM-DD
+const statementColumns = `id, account_id, to_char(period_start, 'YYYY-MM-DD'), to_char(period_end, 'YYYY-MM-DD'),
+	opening_balance, closing_balance, minimum_payment, to_char(due_date, 'YYYY-MM-DD'), created_at`
+
+func NewBillingRepository(db PgxPoolIface) BillingRepository {
+	return &billingRepo{db: db}
+}
+
+// GetBillingCycle retrieves the billing cycle of the account, or the default one if it has not
+// configured any. It returns pgx.ErrNoRows if the account does not exist.
+func (r *billingRepo) GetBillingCycle(ctx context.Context, accountID int64) (*BillingCycle, error) {
+	query := `SELECT a.id, COALESCE(bc.closing_day, $2), COALESCE(bc.due_day_offset, $3)
+		FROM accounts a LEFT JOIN billing_cycles bc ON bc.account_id = a.id
+		WHERE a.id = $1`
+	cycle := &BillingCycle{}
+
+	err := conn(ctx, r.db).QueryRow(ctx, query, accountID, DefaultClosingDay, DefaultDueDayOffset).
+		Scan(&cycle.AccountID, &cycle.ClosingDay, &cycle.DueDayOffset)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to retrieve billing cycle")
+		return nil, err
+	}
+	return cycle, nil
+}
+
+// UpsertBillingCycle configures the billing cycle of the account
+func (r *billingRepo) UpsertBillingCycle(ctx context.Context, cycle *BillingCycle) (*BillingCycle, error) {
+	query := `INSERT INTO billing_cycles (account_id, closing_day, due_day_offset) VALUES ($1, $2, $3)
+		ON CONFLICT (account_id) DO UPDATE
+		SET closing_day = EXCLUDED.closing_day, due_day_offset = EXCLUDED.due_day_offset, updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, cycle.AccountID, cycle.ClosingDay, cycle.DueDayOffset); err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to upsert billing cycle")
+		return nil, fmt.Errorf("failed to upsert billing cycle: %w", err)
+	}
+	upserted := *cycle
+	return &upserted, nil
+}
+
+// GetBillingCyclesByClosingDay retrieves the billing cycles of the product's accounts that close on closingDay
+func (r *billingRepo) GetBillingCyclesByClosingDay(ctx context.Context, product string, closingDay int) ([]*BillingCycle, error) {
+	query := `SELECT a.id, COALESCE(bc.closing_day, $3), COALESCE(bc.due_day_offset, $4)
+		FROM accounts a LEFT JOIN billing_cycles bc ON bc.account_id = a.id
+		WHERE a.product = $1 AND COALESCE(bc.closing_day, $3) = $2
+		ORDER BY a.id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, product, closingDay, DefaultClosingDay, DefaultDueDayOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve billing cycles: %w", err)
+	}
+	defer rows.Close()
+
+	var cycles []*BillingCycle
+	for rows.Next() {
+		cycle := &BillingCycle{}
+		if err := rows.Scan(&cycle.AccountID, &cycle.ClosingDay, &cycle.DueDayOffset); err != nil {
+			return nil, fmt.Errorf("failed to scan billing cycle: %w", err)
+		}
+		cycles = append(cycles, cycle)
+	}
+	return cycles, rows.Err()
+}
+
+// InsertStatement inserts the statement and its lines in a single statement
+func (r *billingRepo) InsertStatement(ctx context.Context, statement *Statement) (*Statement, error) {
+	query := `WITH s AS (
+		INSERT INTO statements (account_id, period_start, period_end, opening_balance, closing_balance, minimum_payment, due_date, correlation_id)
+		VALUES ($1, $2::date, $3::date, $4, $5, $6, $7::date, NULLIF($8, ''))
+		RETURNING id, created_at
+	), l AS (
+		INSERT INTO statement_lines (statement_id, kind, transaction_id, related_transaction_id, amount, event_date)
+		SELECT s.id, l.kind, l.transaction_id, NULLIF(l.related_transaction_id, 0), l.amount, l.event_date
+		FROM s, unnest($9::text[], $10::bigint[], $11::bigint[], $12::numeric[], $13::timestamp[])
+			WITH ORDINALITY AS l(kind, transaction_id, related_transaction_id, amount, event_date, ord)
+		ORDER BY l.ord
+	)
+	SELECT id, created_at FROM s`
+
+	n := len(statement.Lines)
+	kinds, transactionIDs, relatedIDs := make([]string, n), make([]int64, n), make([]int64, n)
+	amounts, eventDates := make([]float64, n), make([]time.Time, n)
+	for i, line := range statement.Lines {
+		kinds[i], transactionIDs[i], relatedIDs[i] = line.Kind, line.TransactionID, line.RelatedTransactionID
+		amounts[i], eventDates[i] = line.Amount, line.EventDate
+	}
+
+	created := *statement
+	created.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		statement.AccountID, statement.PeriodStart, statement.PeriodEnd,
+		statement.OpeningBalance, statement.ClosingBalance, statement.MinimumPayment, statement.DueDate,
+		created.CorrelationID, kinds, transactionIDs, relatedIDs, amounts, eventDates,
+	).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert statement")
+		return nil, fmt.Errorf("failed to insert statement: %w", err)
+	}
+	return &created, nil
+}
+
+// GetLatestStatementByAccountID retrieves the account's statement with the latest period, without its lines
+func (r *billingRepo) GetLatestStatementByAccountID(ctx context.Context, accountID int64) (*Statement, error) {
+	query := `SELECT ` + statementColumns + ` FROM statements WHERE account_id = $1 ORDER BY period_end DESC LIMIT 1`
+	statement := &Statement{}
+
+	if err := scanStatement(conn(ctx, r.db).QueryRow(ctx, query, accountID), statement); err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+// GetStatementsByAccountID retrieves the account's statements, latest first, without their lines
+func (r *billingRepo) GetStatementsByAccountID(ctx context.Context, accountID int64) ([]*Statement, error) {
+	query := `SELECT ` + statementColumns + ` FROM statements WHERE account_id = $1 ORDER BY period_end DESC`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve statements: %w", err)
+	}
+	defer rows.Close()
+
+	var statements []*Statement
+	for rows.Next() {
+		statement := &Statement{}
+		if err := scanStatement(rows, statement); err != nil {
+			return nil, fmt.Errorf("failed to scan statement: %w", err)
+		}
+		statements = append(statements, statement)
+	}
+	return statements, rows.Err()
+}
+
+// GetStatementByID retrieves a statement of the account with its lines
+func (r *billingRepo) GetStatementByID(ctx context.Context, accountID, statementID int64) (*Statement, error) {
+	query := `SELECT ` + statementColumns + ` FROM statements WHERE id = $1 AND account_id = $2`
+	statement := &Statement{}
+
+	if err := scanStatement(conn(ctx, r.db).QueryRow(ctx, query, statementID, accountID), statement); err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to retrieve statement")
+		return nil, err
+	}
+
+	linesQuery := `SELECT kind, transaction_id, COALESCE(related_transaction_id, 0), amount, event_date
+		FROM statement_lines WHERE statement_id = $1 ORDER BY id`
+	rows, err := conn(ctx, r.db).Query(ctx, linesQuery, statementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve statement lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line StatementLine
+		if err := rows.Scan(&line.Kind, &line.TransactionID, &line.RelatedTransactionID, &line.Amount, &line.EventDate); err != nil {
+			return nil, fmt.Errorf("failed to scan statement line: %w", err)
+		}
+		statement.Lines = append(statement.Lines, line)
+	}
+	return statement, rows.Err()
+}
+
+func scanStatement(row pgx.Row, statement *Statement) error {
+	return row.Scan(
+		&statement.ID, &statement.AccountID, &statement.PeriodStart, &statement.PeriodEnd,
+		&statement.OpeningBalance, &statement.ClosingBalance, &statement.MinimumPayment, &statement.DueDate,
+		&statement.CreatedAt,
+	)
+}
@@ -0,0 +1,239 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var statementColumns = []string{"id", "account_id", "period_start", "period_end", "opening_balance", "closing_balance", "minimum_payment", "due_date", "created_at"}
+
+func TestGetBillingCycle(t *testing.T) {
+	t.Run("Completely valid request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewBillingRepository(mockDB)
+
+		mockDB.ExpectQuery(`SELECT a.id, COALESCE\(bc.closing_day, \$2\), COALESCE\(bc.due_day_offset, \$3\)`).
+			WithArgs(int64(1), repository.DefaultClosingDay, repository.DefaultDueDayOffset).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "closing_day", "due_day_offset"}).AddRow(int64(1), 15, 20))
+
+		cycle, err := repo.GetBillingCycle(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &repository.BillingCycle{AccountID: 1, ClosingDay: 15, DueDayOffset: 20}, cycle)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown account", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewBillingRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM accounts a LEFT JOIN billing_cycles`).
+			WithArgs(int64(999), repository.DefaultClosingDay, repository.DefaultDueDayOffset).
+			WillReturnError(pgx.ErrNoRows)
+
+		cycle, err := repo.GetBillingCycle(context.Background(), 999)
+
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.Nil(t, cycle)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestUpsertBillingCycle(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewBillingRepository(mockDB)
+	cycle := &repository.BillingCycle{AccountID: 1, ClosingDay: 5, DueDayOffset: 15}
+
+	mockDB.ExpectExec(`INSERT INTO billing_cycles .* ON CONFLICT \(account_id\) DO UPDATE`).
+		WithArgs(int64(1), 5, 15).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectExec(`INSERT INTO billing_cycles`).
+		WithArgs(int64(1), 5, 15).
+		WillReturnError(errors.New("database error"))
+
+	upserted, err := repo.UpsertBillingCycle(context.Background(), cycle)
+	assert.NoError(t, err)
+	assert.Equal(t, cycle, upserted)
+
+	upserted, err = repo.UpsertBillingCycle(context.Background(), cycle)
+	assert.Error(t, err)
+	assert.Nil(t, upserted)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetBillingCyclesByClosingDay(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewBillingRepository(mockDB)
+
+	mockDB.ExpectQuery(`WHERE a.product = \$1 AND COALESCE\(bc.closing_day, \$3\) = \$2`).
+		WithArgs("credit", 1, repository.DefaultClosingDay, repository.DefaultDueDayOffset).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "closing_day", "due_day_offset"}).
+			AddRow(int64(1), 1, 10).
+			AddRow(int64(3), 1, 25))
+
+	cycles, err := repo.GetBillingCyclesByClosingDay(context.Background(), "credit", 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, cycles, 2)
+	assert.Equal(t, 25, cycles[1].DueDayOffset)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestInsertStatement(t *testing.T) {
+	eventDate := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+	statement := &repository.Statement{
+		AccountID:      1,
+		PeriodStart:    "2025-02-02",
+		PeriodEnd:      "2025-03-01",
+		OpeningBalance: 0,
+		ClosingBalance: 50,
+		MinimumPayment: 25,
+		DueDate:        "2025-03-11",
+		Lines: []repository.StatementLine{
+			{Kind: "purchase", TransactionID: 5, Amount: -50, EventDate: eventDate},
+		},
+	}
+
+	t.Run("Completely valid request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewBillingRepository(mockDB)
+		ctx := middleware.WithCorrelationID(context.Background(), "close-1")
+
+		mockDB.ExpectQuery(`WITH s AS \(\s*INSERT INTO statements .* INSERT INTO statement_lines`).
+			WithArgs(int64(1), "2025-02-02", "2025-03-01", 0.0, 50.0, 25.0, "2025-03-11", "close-1",
+				[]string{"purchase"}, []int64{5}, []int64{0}, []float64{-50}, []time.Time{eventDate}).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(4), time.Now()))
+
+		created, err := repo.InsertStatement(ctx, statement)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), created.ID)
+		assert.Equal(t, "close-1", created.CorrelationID)
+		assert.Zero(t, statement.ID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error during insertion", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewBillingRepository(mockDB)
+
+		mockDB.ExpectQuery(`INSERT INTO statements`).
+			WithArgs(int64(1), "2025-02-02", "2025-03-01", 0.0, 50.0, 25.0, "2025-03-11", "",
+				pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("database error"))
+
+		created, err := repo.InsertStatement(context.Background(), statement)
+
+		assert.Error(t, err)
+		assert.Nil(t, created)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetStatements(t *testing.T) {
+	t.Run("Latest statement", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewBillingRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM statements WHERE account_id = \$1 ORDER BY period_end DESC LIMIT 1`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(statementColumns).
+				AddRow(int64(2), int64(1), "2025-03-02", "2025-04-01", 50.0, 80.0, 25.0, "2025-04-11", time.Now()))
+
+		statement, err := repo.GetLatestStatementByAccountID(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "2025-04-01", statement.PeriodEnd)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Statements of an account", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewBillingRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM statements WHERE account_id = \$1 ORDER BY period_end DESC`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(statementColumns).
+				AddRow(int64(2), int64(1), "2025-03-02", "2025-04-01", 50.0, 80.0, 25.0, "2025-04-11", time.Now()).
+				AddRow(int64(1), int64(1), "2025-02-02", "2025-03-01", 0.0, 50.0, 25.0, "2025-03-11", time.Now()))
+
+		statements, err := repo.GetStatementsByAccountID(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Len(t, statements, 2)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Statement with its lines", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewBillingRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM statements WHERE id = \$1 AND account_id = \$2`).
+			WithArgs(int64(1), int64(1)).
+			WillReturnRows(pgxmock.NewRows(statementColumns).
+				AddRow(int64(1), int64(1), "2025-02-02", "2025-03-01", 0.0, 50.0, 25.0, "2025-03-11", time.Now()))
+		mockDB.ExpectQuery(`FROM statement_lines WHERE statement_id = \$1 ORDER BY id`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"kind", "transaction_id", "related_transaction_id", "amount", "event_date"}).
+				AddRow("purchase", int64(5), int64(0), -50.0, time.Now()))
+
+		statement, err := repo.GetStatementByID(context.Background(), 1, 1)
+
+		assert.NoError(t, err)
+		assert.Len(t, statement.Lines, 1)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown statement", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewBillingRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM statements WHERE id = \$1 AND account_id = \$2`).
+			WithArgs(int64(9), int64(1)).
+			WillReturnError(pgx.ErrNoRows)
+
+		statement, err := repo.GetStatementByID(context.Background(), 1, 9)
+
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.Nil(t, statement)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// customerColumns reads optional columns as empty strings and the birth date as YYYY-MM-DD
+const customerColumns = `id, document_number, name, COALESCE(to_char(birth_date, 'YYYY-MM-DD'), ''), COALESCE(email, ''), COALESCE(phone, '')`
+
+func NewCustomersRepository(db PgxPoolIface) CustomersRepository {
+	return &customersRepo{db: db}
+}
+
+// InsertCustomer inserts a new customer
+func (r *customersRepo) InsertCustomer(ctx context.Context, customer *Customer) (*Customer, error) {
+	query := `INSERT INTO customers (document_number, name, birth_date, email, phone, correlation_id)
+		VALUES ($1, $2, NULLIF($3, '')::date, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''))
+		RETURNING ` + customerColumns
+	created := &Customer{CorrelationID: middleware.GetCorrelationIDFromContext(ctx)}
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		customer.DocumentNumber,
+		customer.Name,
+		customer.BirthDate,
+		customer.Email,
+		customer.Phone,
+		created.CorrelationID,
+	).Scan(&created.ID, &created.DocumentNumber, &created.Name, &created.BirthDate, &created.Email, &created.Phone)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert customer")
+		return nil, fmt.Errorf("failed to insert customer: %w", err)
+	}
+	return created, nil
+}
+
+// GetCustomerByID retrieves a customer by customerID
+func (r *customersRepo) GetCustomerByID(ctx context.Context, customerID int64) (*Customer, error) {
+	return r.getCustomer(ctx, `SELECT `+customerColumns+` FROM customers WHERE id = $1`, customerID)
+}
+
+// GetCustomerByDocumentNumber retrieves a customer by documentNumber
+func (r *customersRepo) GetCustomerByDocumentNumber(ctx context.Context, documentNumber string) (*Customer, error) {
+	return r.getCustomer(ctx, `SELECT `+customerColumns+` FROM customers WHERE document_number = $1`, documentNumber)
+}
+
+func (r *customersRepo) getCustomer(ctx context.Context, query string, arg any) (*Customer, error) {
+	customer := &Customer{}
+
+	err := conn(ctx, r.db).QueryRow(ctx, query, arg).
+		Scan(&customer.ID, &customer.DocumentNumber, &customer.Name, &customer.BirthDate, &customer.Email, &customer.Phone)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to retrieve customer")
+		return nil, err
+	}
+	return customer, nil
+}
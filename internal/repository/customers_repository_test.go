@@ -0,0 +1,111 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var customerColumns = []string{"id", "document_number", "name", "birth_date", "email", "phone"}
+
+func TestInsertCustomer(t *testing.T) {
+	t.Run("Completely valid request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewCustomersRepository(mockDB)
+		ctx := context.Background()
+
+		rows := pgxmock.NewRows(customerColumns).
+			AddRow(int64(1), "12345678900", "Maria Silva", "1990-05-17", "maria@example.com", "")
+
+		mockDB.ExpectQuery(`INSERT INTO customers`).
+			WithArgs("12345678900", "Maria Silva", "1990-05-17", "maria@example.com", "", "").
+			WillReturnRows(rows)
+
+		customer, err := repo.InsertCustomer(ctx, &repository.Customer{
+			DocumentNumber: "12345678900",
+			Name:           "Maria Silva",
+			BirthDate:      "1990-05-17",
+			Email:          "maria@example.com",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), customer.ID)
+		assert.Equal(t, "1990-05-17", customer.BirthDate)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Duplicate document_number should return error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewCustomersRepository(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`INSERT INTO customers`).
+			WithArgs("12345678900", "", "", "", "", "").
+			WillReturnError(errors.New(`duplicate key value violates unique constraint "customers_document_number_key"`))
+
+		customer, err := repo.InsertCustomer(ctx, &repository.Customer{DocumentNumber: "12345678900"})
+
+		assert.Error(t, err)
+		assert.Nil(t, customer)
+		assert.Contains(t, err.Error(), "customers_document_number_key")
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetCustomer(t *testing.T) {
+	t.Run("By id", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewCustomersRepository(mockDB)
+		ctx := context.Background()
+
+		rows := pgxmock.NewRows(customerColumns).
+			AddRow(int64(1), "12345678900", "Maria Silva", "", "", "")
+
+		mockDB.ExpectQuery(`SELECT .* FROM customers WHERE id = \$1`).
+			WithArgs(int64(1)).
+			WillReturnRows(rows)
+
+		customer, err := repo.GetCustomerByID(ctx, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Maria Silva", customer.Name)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("By document number, not found", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewCustomersRepository(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`SELECT .* FROM customers WHERE document_number = \$1`).
+			WithArgs("000").
+			WillReturnError(pgx.ErrNoRows)
+
+		customer, err := repo.GetCustomerByDocumentNumber(ctx, "000")
+
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.Nil(t, customer)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,165 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/migration"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// dischargeBenchmarkDebts is how many outstanding debts a single credit pays off in the discharge benchmarks
+const dischargeBenchmarkDebts = 200
+
+// dischargeRepos are the repositories a payment discharge writes to
+type dischargeRepos struct {
+	transactions repository.TransactionsRepository
+	ledger       repository.LedgerRepository
+	audit        repository.AuditRepository
+}
+
+func newDischargeRepos(db repository.PgxPoolIface) dischargeRepos {
+	return dischargeRepos{
+		transactions: repository.NewTransactionsRepository(db),
+		ledger:       repository.NewLedgerRepository(db),
+		audit:        repository.NewAuditRepository(db),
+	}
+}
+
+// dischargeLoop is processPaymentDischarge as it stood before DischargeTransactions: the debts are read, then
+// every one the credit reaches gets an UPDATE of its own, and the credit's balance a last one. It writes no
+// journal entries or audit events, so the comparison understates what the set-based discharge saves.
+func dischargeLoop(ctx context.Context, repos dischargeRepos, creditTxn *repository.Transaction) error {
+	creditedAmount := creditTxn.Amount
+
+	outstandingTxns, err := repos.transactions.GetOutstandingTransactionsByAccountID(ctx, creditTxn.AccountID)
+	if err != nil {
+		return err
+	}
+
+	var totalDischarge float64
+	for _, outstandingTxn := range outstandingTxns {
+		if creditedAmount <= 0 {
+			break
+		}
+
+		outstandingAbsValue := -outstandingTxn.Balance
+		dischargeableAmount := creditedAmount
+		if creditedAmount > outstandingAbsValue {
+			dischargeableAmount = outstandingAbsValue
+		}
+
+		newBalance := outstandingTxn.Balance + dischargeableAmount
+		if err := repos.transactions.UpdateTransactionBalance(ctx, outstandingTxn.ID, newBalance); err != nil {
+			return err
+		}
+
+		creditedAmount -= dischargeableAmount
+		totalDischarge += dischargeableAmount
+	}
+
+	return repos.transactions.UpdateTransactionBalance(ctx, creditTxn.ID, creditTxn.Amount-totalDischarge)
+}
+
+// dischargeSetBased discharges the credit with a constant number of statements however many debts it pays off
+func dischargeSetBased(ctx context.Context, repos dischargeRepos, credit *repository.Transaction) error {
+	discharges, err := repos.transactions.DischargeTransactions(ctx, credit)
+	if err != nil {
+		return err
+	}
+	entries := make([]*repository.JournalEntry, 0, len(discharges))
+	events := make([]*repository.AuditEvent, 0, len(discharges)+1)
+	remaining := credit.Balance
+	for _, discharge := range discharges {
+		entries = append(entries, dischargeJournalEntry(credit, discharge.TransactionID, discharge.Amount))
+		events = append(events, balanceUpdatedEvent(discharge.TransactionID, discharge.Balance, discharge.Balance+discharge.Amount))
+		remaining -= discharge.Amount
+	}
+	events = append(events, balanceUpdatedEvent(credit.ID, credit.Balance, remaining))
+	if err := repos.ledger.PostJournalEntries(ctx, entries); err != nil {
+		return err
+	}
+	return repos.audit.InsertAuditEvents(ctx, events)
+}
+
+func balanceUpdatedEvent(transactionID int64, before, after float64) *repository.AuditEvent {
+	return &repository.AuditEvent{
+		Action:     "transaction.balance_updated",
+		EntityType: "transaction",
+		EntityID:   transactionID,
+		Before:     []byte(fmt.Sprintf(`{"balance":%g}`, before)),
+		After:      []byte(fmt.Sprintf(`{"balance":%g}`, after)),
+	}
+}
+
+func dischargeJournalEntry(credit *repository.Transaction, debtID int64, amount float64) *repository.JournalEntry {
+	return &repository.JournalEntry{
+		Kind:          "discharge",
+		TransactionID: credit.ID,
+		CreatedAt:     credit.EventDate,
+		Postings: []repository.Posting{
+			{Account: repository.LedgerAccount{Code: fmt.Sprintf("customer_credit:%d", credit.AccountID), Type: "liability", AccountID: credit.AccountID}, TransactionID: credit.ID, Amount: amount},
+			{Account: repository.LedgerAccount{Code: fmt.Sprintf("customer_receivable:%d", credit.AccountID), Type: "asset", AccountID: credit.AccountID}, TransactionID: debtID, Amount: -amount},
+		},
+	}
+}
+
+// errRollback undoes a benchmarked discharge so that every iteration starts from the same debts
+var errRollback = errors.New("rollback")
+
+// BenchmarkPostgresDischarge compares the two discharges against the database at TEST_DB_DSN; it is skipped
+// when unset. Each iteration discharges the same debts within a unit of work that is rolled back.
+func BenchmarkPostgresDischarge(b *testing.B) {
+	dsn := os.Getenv(testDBDSNEnv)
+	if dsn == "" {
+		b.Skipf("%s not set, skipping Postgres discharge benchmark", testDBDSNEnv)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(b, err)
+	b.Cleanup(pool.Close)
+
+	provider, err := migration.NewProvider(migration.OpenDB(pool))
+	require.NoError(b, err)
+	_, err = provider.Up(ctx)
+	require.NoError(b, err)
+	require.NoError(b, resetTables(ctx, pool, `customers, accounts, transactions, ledger_accounts, journal_entries, postings, audit_events`))
+
+	customer, err := repository.NewCustomersRepository(pool).InsertCustomer(ctx, &repository.Customer{DocumentNumber: "1"})
+	require.NoError(b, err)
+	account, err := repository.NewAccountsRepository(pool).InsertAccount(ctx, customer.ID, "credit")
+	require.NoError(b, err)
+	transactions := repository.NewTransactionsRepository(pool)
+	for range dischargeBenchmarkDebts {
+		_, err := transactions.InsertTransaction(ctx, account.ID, 1, -10, -10, time.Now().UTC(), time.Now().UTC(), "", 0)
+		require.NoError(b, err)
+	}
+	credit, err := transactions.InsertTransaction(ctx, account.ID, 4, 10*dischargeBenchmarkDebts, 10*dischargeBenchmarkDebts, time.Now().UTC(), time.Now().UTC(), "", 0)
+	require.NoError(b, err)
+
+	transactor := repository.NewTransactor(pool)
+	for name, discharge := range map[string]func(context.Context, dischargeRepos, *repository.Transaction) error{
+		"loop":      dischargeLoop,
+		"set-based": dischargeSetBased,
+	} {
+		b.Run(name, func(b *testing.B) {
+			repos := newDischargeRepos(pool)
+			for range b.N {
+				err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+					if err := discharge(ctx, repos, credit); err != nil {
+						return err
+					}
+					return errRollback
+				})
+				require.ErrorIs(b, err, errRollback)
+			}
+		})
+	}
+}
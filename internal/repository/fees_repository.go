@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func NewFeesRepository(db PgxPoolIface) FeesRepository {
+	return &feesRepo{db: db}
+}
+
+// GetFeeRule retrieves the fee rule of the product's transactions of the operation type with its tiers,
+// bounded ones first. It returns pgx.ErrNoRows if such transactions carry no fee.
+func (r *feesRepo) GetFeeRule(ctx context.Context, product string, operationTypeID int64) (*FeeRule, error) {
+	query := `SELECT fr.id, fr.kind, fr.flat_amount, fr.percent, COALESCE(fr.min_amount, 0), COALESCE(fr.max_amount, 0),
+			t.id IS NOT NULL, COALESCE(t.up_to, 0), COALESCE(t.flat_amount, 0), COALESCE(t.percent, 0)
+		FROM fee_rules fr
+		LEFT JOIN fee_rule_tiers t ON t.fee_rule_id = fr.id
+		WHERE fr.product = $1 AND fr.operation_type_id = $2
+		ORDER BY t.up_to NULLS LAST`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, product, operationTypeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve fee rule: %w", err)
+	}
+	defer rows.Close()
+
+	var rule *FeeRule
+	for rows.Next() {
+		var fr FeeRule
+		var tier FeeTier
+		var hasTier bool
+		if err := rows.Scan(&fr.ID, &fr.Kind, &fr.FlatAmount, &fr.Percent, &fr.MinAmount, &fr.MaxAmount,
+			&hasTier, &tier.UpTo, &tier.FlatAmount, &tier.Percent); err != nil {
+			return nil, fmt.Errorf("failed to scan fee rule: %w", err)
+		}
+		if rule == nil {
+			fr.Product, fr.OperationTypeID = product, operationTypeID
+			rule = &fr
+		}
+		if hasTier {
+			rule.Tiers = append(rule.Tiers, tier)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve fee rule: %w", err)
+	}
+	if rule == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return rule, nil
+}
@@ -0,0 +1,85 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var feeRuleColumns = []string{"id", "kind", "flat_amount", "percent", "min_amount", "max_amount", "has_tier", "up_to", "tier_flat_amount", "tier_percent"}
+
+func TestGetFeeRule(t *testing.T) {
+	t.Run("Tiered rule with its tiers, bounded ones first", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewFeesRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM fee_rules fr LEFT JOIN fee_rule_tiers t .* WHERE fr.product = \$1 AND fr.operation_type_id = \$2 ORDER BY t.up_to NULLS LAST`).
+			WithArgs("prepaid", int64(3)).
+			WillReturnRows(pgxmock.NewRows(feeRuleColumns).
+				AddRow(int64(2), "tiered", 0.0, 0.0, 0.0, 10.0, true, 100.0, 1.0, 0.0).
+				AddRow(int64(2), "tiered", 0.0, 0.0, 0.0, 10.0, true, 0.0, 0.0, 0.01))
+
+		rule, err := repo.GetFeeRule(context.Background(), "prepaid", 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &repository.FeeRule{
+			ID:              2,
+			Product:         "prepaid",
+			OperationTypeID: 3,
+			Kind:            repository.FeeKindTiered,
+			MaxAmount:       10,
+			Tiers: []repository.FeeTier{
+				{UpTo: 100, FlatAmount: 1},
+				{Percent: 0.01},
+			},
+		}, rule)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Rule without tiers", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewFeesRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM fee_rules fr`).
+			WithArgs("credit", int64(3)).
+			WillReturnRows(pgxmock.NewRows(feeRuleColumns).
+				AddRow(int64(1), "percent", 0.0, 0.03, 5.0, 0.0, false, 0.0, 0.0, 0.0))
+
+		rule, err := repo.GetFeeRule(context.Background(), "credit", 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, repository.FeeKindPercent, rule.Kind)
+		assert.Equal(t, 0.03, rule.Percent)
+		assert.Equal(t, 5.0, rule.MinAmount)
+		assert.Empty(t, rule.Tiers)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Operation type without a fee", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewFeesRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM fee_rules fr`).
+			WithArgs("credit", int64(1)).
+			WillReturnRows(pgxmock.NewRows(feeRuleColumns))
+
+		rule, err := repo.GetFeeRule(context.Background(), "credit", 1)
+
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.Nil(t, rule)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+func NewLedgerRepository(db PgxPoolIface) LedgerRepository {
+	return &ledgerRepo{db: db}
+}
+
+// PostJournalEntry appends the entry and its postings in a single statement, creating the
+// ledger accounts it posts to on first use. The entry is dated with its CreatedAt. The database
+// rejects the entry at commit unless its postings sum to zero.
+func (r *ledgerRepo) PostJournalEntry(ctx context.Context, entry *JournalEntry) (*JournalEntry, error) {
+	query := `WITH la AS (
+		INSERT INTO ledger_accounts (code, type, account_id)
+		SELECT DISTINCT code, type, NULLIF(account_id, 0) FROM unnest($3::text[], $4::text[], $5::bigint[]) AS l(code, type, account_id)
+		ON CONFLICT (code) DO NOTHING
+	), je AS (
+		INSERT INTO journal_entries (kind, transaction_id, correlation_id, created_at) VALUES ($1, NULLIF($2, 0), NULLIF($6, ''), $9)
+		RETURNING id, created_at
+	), p AS (
+		INSERT INTO postings (journal_entry_id, ledger_account_code, transaction_id, amount)
+		SELECT je.id, p.code, NULLIF(p.transaction_id, 0), p.amount
+		FROM je, unnest($3::text[], $7::bigint[], $8::numeric[]) AS p(code, transaction_id, amount)
+	)
+	SELECT id, created_at FROM je`
+
+	n := len(entry.Postings)
+	codes, types, accountIDs := make([]string, n), make([]string, n), make([]int64, n)
+	transactionIDs, amounts := make([]int64, n), make([]float64, n)
+	for i, p := range entry.Postings {
+		codes[i], types[i], accountIDs[i] = p.Account.Code, p.Account.Type, p.Account.AccountID
+		transactionIDs[i], amounts[i] = p.TransactionID, p.Amount
+	}
+
+	posted := *entry
+	posted.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		entry.Kind, entry.TransactionID, codes, types, accountIDs, posted.CorrelationID, transactionIDs, amounts, entry.CreatedAt,
+	).Scan(&posted.ID, &posted.CreatedAt)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to post journal entry")
+		return nil, fmt.Errorf("failed to post journal entry: %w", err)
+	}
+	return &posted, nil
+}
+
+// PostJournalEntries appends the entries and their postings in a single statement, as PostJournalEntry
+// would one by one. Entry ids are drawn from the sequence up front so that postings can refer to them.
+func (r *ledgerRepo) PostJournalEntries(ctx context.Context, entries []*JournalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	query := `WITH la AS (
+		INSERT INTO ledger_accounts (code, type, account_id)
+		SELECT DISTINCT code, type, NULLIF(account_id, 0) FROM unnest($4::text[], $5::text[], $6::bigint[]) AS l(code, type, account_id)
+		ON CONFLICT (code) DO NOTHING
+	), e AS (
+		SELECT nextval('journal_entries_id_seq') AS id, e.n, e.kind, e.transaction_id, e.created_at
+		FROM unnest($1::text[], $2::bigint[], $3::timestamp[]) WITH ORDINALITY AS e(kind, transaction_id, created_at, n)
+	), je AS (
+		INSERT INTO journal_entries (id, kind, transaction_id, correlation_id, created_at)
+		SELECT id, kind, NULLIF(transaction_id, 0), NULLIF($10, ''), created_at FROM e ORDER BY n
+	)
+	INSERT INTO postings (journal_entry_id, ledger_account_code, transaction_id, amount)
+	SELECT e.id, p.code, NULLIF(p.transaction_id, 0), p.amount
+	FROM unnest($7::bigint[], $4::text[], $8::bigint[], $9::numeric[]) AS p(n, code, transaction_id, amount)
+	JOIN e ON e.n = p.n`
+
+	kinds, entryTransactionIDs, createdAts := make([]string, len(entries)), make([]int64, len(entries)), make([]time.Time, len(entries))
+	var codes, types []string
+	var accountIDs, entryNumbers, transactionIDs []int64
+	var amounts []float64
+	for i, entry := range entries {
+		kinds[i], entryTransactionIDs[i], createdAts[i] = entry.Kind, entry.TransactionID, entry.CreatedAt
+		for _, p := range entry.Postings {
+			codes, types, accountIDs = append(codes, p.Account.Code), append(types, p.Account.Type), append(accountIDs, p.Account.AccountID)
+			entryNumbers, transactionIDs, amounts = append(entryNumbers, int64(i+1)), append(transactionIDs, p.TransactionID), append(amounts, p.Amount)
+		}
+	}
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		kinds, entryTransactionIDs, createdAts, codes, types, accountIDs, entryNumbers, transactionIDs, amounts,
+		middleware.GetCorrelationIDFromContext(ctx),
+	)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to post journal entries")
+		return fmt.Errorf("failed to post journal entries: %w", err)
+	}
+	return nil
+}
+
+// GetLedgerAccountBalances retrieves the balance of every ledger account of the account
+func (r *ledgerRepo) GetLedgerAccountBalances(ctx context.Context, accountID int64) ([]*LedgerAccountBalance, error) {
+	query := `SELECT la.code, la.type, COALESCE(SUM(p.amount), 0)
+		FROM ledger_accounts la
+		LEFT JOIN postings p ON p.ledger_account_code = la.code
+		WHERE la.account_id = $1
+		GROUP BY la.code, la.type
+		ORDER BY la.code`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve ledger balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []*LedgerAccountBalance
+	for rows.Next() {
+		balance := &LedgerAccountBalance{LedgerAccount: LedgerAccount{AccountID: accountID}}
+		if err := rows.Scan(&balance.Code, &balance.Type, &balance.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger balance: %w", err)
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+// GetLedgerBalancesByTransaction derives each transaction's balance from the postings attributed
+// to it on the account's ledger accounts. Credits on those accounts are what the account holds,
+// so the derived balance is the negated sum, matching the sign of transactions.balance.
+func (r *ledgerRepo) GetLedgerBalancesByTransaction(ctx context.Context, accountID int64) (map[int64]float64, error) {
+	query := `SELECT p.transaction_id, -SUM(p.amount)
+		FROM postings p
+		JOIN ledger_accounts la ON la.code = p.ledger_account_code
+		WHERE la.account_id = $1 AND p.transaction_id IS NOT NULL
+		GROUP BY p.transaction_id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve ledger balances by transaction: %w", err)
+	}
+	defer rows.Close()
+
+	balances := map[int64]float64{}
+	for rows.Next() {
+		var transactionID int64
+		var balance float64
+		if err := rows.Scan(&transactionID, &balance); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger balance: %w", err)
+		}
+		balances[transactionID] = balance
+	}
+	return balances, rows.Err()
+}
+
+// GetDischargesByAccountID retrieves the discharge and discharge adjustment entries of the account posted in
+// [from, to), oldest first
+func (r *ledgerRepo) GetDischargesByAccountID(ctx context.Context, accountID int64, from, to time.Time) ([]*JournalEntry, error) {
+	query := `SELECT je.id, je.kind, COALESCE(je.transaction_id, 0), COALESCE(je.correlation_id, ''), je.created_at, p.ledger_account_code, la.type, COALESCE(la.account_id, 0), COALESCE(p.transaction_id, 0), p.amount
+		FROM journal_entries je
+		JOIN postings p ON p.journal_entry_id = je.id
+		JOIN ledger_accounts la ON la.code = p.ledger_account_code
+		WHERE je.kind IN ('discharge', 'discharge_adjustment') AND je.created_at >= $2 AND je.created_at < $3
+			AND je.id IN (SELECT p.journal_entry_id FROM postings p JOIN ledger_accounts la ON la.code = p.ledger_account_code WHERE la.account_id = $1)
+		ORDER BY je.id, p.id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, accountID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve discharges: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*JournalEntry
+	for rows.Next() {
+		var entry JournalEntry
+		var posting Posting
+		if err := rows.Scan(&entry.ID, &entry.Kind, &entry.TransactionID, &entry.CorrelationID, &entry.CreatedAt,
+			&posting.Account.Code, &posting.Account.Type, &posting.Account.AccountID, &posting.TransactionID, &posting.Amount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan discharge: %w", err)
+		}
+		if len(entries) == 0 || entries[len(entries)-1].ID != entry.ID {
+			entries = append(entries, &entry)
+		}
+		last := entries[len(entries)-1]
+		last.Postings = append(last.Postings, posting)
+	}
+	return entries, rows.Err()
+}
+
+// GetDischargeAllocations retrieves what each credit of the account has discharged of each of its debts, net of
+// discharge adjustments, by credit then debt
+func (r *ledgerRepo) GetDischargeAllocations(ctx context.Context, accountID int64) ([]*Allocation, error) {
+	query := `SELECT je.transaction_id, p.transaction_id, -SUM(p.amount)
+		FROM journal_entries je
+		JOIN postings p ON p.journal_entry_id = je.id
+		JOIN ledger_accounts la ON la.code = p.ledger_account_code
+		WHERE je.kind IN ('discharge', 'discharge_adjustment') AND la.account_id = $1 AND la.type = 'asset'
+		GROUP BY je.transaction_id, p.transaction_id
+		HAVING SUM(p.amount) <> 0
+		ORDER BY je.transaction_id, p.transaction_id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve discharge allocations: %w", err)
+	}
+	defer rows.Close()
+
+	var allocations []*Allocation
+	for rows.Next() {
+		allocation := &Allocation{}
+		if err := rows.Scan(&allocation.CreditTransactionID, &allocation.DebtTransactionID, &allocation.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan discharge allocation: %w", err)
+		}
+		allocations = append(allocations, allocation)
+	}
+	return allocations, rows.Err()
+}
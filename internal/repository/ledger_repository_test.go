@@ -0,0 +1,208 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostJournalEntry(t *testing.T) {
+	effective := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+	entry := &repository.JournalEntry{
+		Kind:          "purchase",
+		TransactionID: 7,
+		CreatedAt:     effective,
+		Postings: []repository.Posting{
+			{Account: repository.LedgerAccount{Code: "customer_receivable:1", Type: "asset", AccountID: 1}, TransactionID: 7, Amount: 50},
+			{Account: repository.LedgerAccount{Code: "cash_clearing", Type: "asset"}, Amount: -50},
+		},
+	}
+
+	t.Run("Completely valid request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewLedgerRepository(mockDB)
+		ctx := middleware.WithCorrelationID(context.Background(), "flow-1")
+
+		mockDB.ExpectQuery(`WITH la AS \(\s*INSERT INTO ledger_accounts .* INSERT INTO journal_entries .* INSERT INTO postings`).
+			WithArgs("purchase", int64(7),
+				[]string{"customer_receivable:1", "cash_clearing"}, []string{"asset", "asset"}, []int64{1, 0},
+				"flow-1", []int64{7, 0}, []float64{50, -50}, effective).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(3), effective))
+
+		posted, err := repo.PostJournalEntry(ctx, entry)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), posted.ID)
+		assert.Equal(t, "flow-1", posted.CorrelationID)
+		assert.Len(t, posted.Postings, 2)
+		assert.Equal(t, effective, posted.CreatedAt)
+		assert.Zero(t, entry.ID)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error during insertion", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewLedgerRepository(mockDB)
+
+		mockDB.ExpectQuery(`INSERT INTO journal_entries`).
+			WithArgs("purchase", int64(7), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "", pgxmock.AnyArg(), pgxmock.AnyArg(), effective).
+			WillReturnError(errors.New("database error"))
+
+		posted, err := repo.PostJournalEntry(context.Background(), entry)
+
+		assert.ErrorContains(t, err, "database error")
+		assert.Nil(t, posted)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestPostJournalEntries(t *testing.T) {
+	effective := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+	entries := []*repository.JournalEntry{
+		{
+			Kind: "discharge", TransactionID: 9, CreatedAt: effective,
+			Postings: []repository.Posting{
+				{Account: repository.LedgerAccount{Code: "customer_credit:1", Type: "liability", AccountID: 1}, TransactionID: 9, Amount: 100},
+				{Account: repository.LedgerAccount{Code: "customer_receivable:1", Type: "asset", AccountID: 1}, TransactionID: 3, Amount: -100},
+			},
+		},
+		{
+			Kind: "discharge", TransactionID: 9, CreatedAt: effective,
+			Postings: []repository.Posting{
+				{Account: repository.LedgerAccount{Code: "customer_credit:1", Type: "liability", AccountID: 1}, TransactionID: 9, Amount: 50},
+				{Account: repository.LedgerAccount{Code: "customer_receivable:1", Type: "asset", AccountID: 1}, TransactionID: 5, Amount: -50},
+			},
+		},
+	}
+
+	t.Run("Entries are posted in a single statement", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewLedgerRepository(mockDB)
+		ctx := middleware.WithCorrelationID(context.Background(), "flow-1")
+
+		mockDB.ExpectExec(`WITH la AS \(\s*INSERT INTO ledger_accounts .* nextval\('journal_entries_id_seq'\) .* INSERT INTO journal_entries .* INSERT INTO postings`).
+			WithArgs([]string{"discharge", "discharge"}, []int64{9, 9}, []time.Time{effective, effective},
+				[]string{"customer_credit:1", "customer_receivable:1", "customer_credit:1", "customer_receivable:1"},
+				[]string{"liability", "asset", "liability", "asset"}, []int64{1, 1, 1, 1},
+				[]int64{1, 1, 2, 2}, []int64{9, 3, 9, 5}, []float64{100, -100, 50, -50}, "flow-1").
+			WillReturnResult(pgxmock.NewResult("INSERT", 4))
+
+		assert.NoError(t, repo.PostJournalEntries(ctx, entries))
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("No entries should not reach the database", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewLedgerRepository(mockDB)
+
+		assert.NoError(t, repo.PostJournalEntries(context.Background(), nil))
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewLedgerRepository(mockDB)
+
+		mockDB.ExpectExec(`INSERT INTO postings`).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+				pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "").
+			WillReturnError(errors.New("database error"))
+
+		err = repo.PostJournalEntries(context.Background(), entries)
+
+		assert.EqualError(t, err, "failed to post journal entries: database error")
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetLedgerAccountBalances(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewLedgerRepository(mockDB)
+
+	mockDB.ExpectQuery(`SELECT la.code, la.type, COALESCE\(SUM\(p.amount\), 0\) FROM ledger_accounts la .* WHERE la.account_id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"code", "type", "balance"}).
+			AddRow("customer_credit:1", "liability", -10.0).
+			AddRow("customer_receivable:1", "asset", 0.0))
+
+	balances, err := repo.GetLedgerAccountBalances(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+	assert.Equal(t, "customer_credit:1", balances[0].Code)
+	assert.Equal(t, int64(1), balances[0].AccountID)
+	assert.Equal(t, -10.0, balances[0].Balance)
+
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetLedgerBalancesByTransaction(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewLedgerRepository(mockDB)
+
+	mockDB.ExpectQuery(`SELECT p.transaction_id, -SUM\(p.amount\) FROM postings p .* WHERE la.account_id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"transaction_id", "balance"}).
+			AddRow(int64(7), 0.0).
+			AddRow(int64(8), 10.0))
+
+	balances, err := repo.GetLedgerBalancesByTransaction(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]float64{7: 0, 8: 10}, balances)
+
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetDischargeAllocations(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewLedgerRepository(mockDB)
+
+	mockDB.ExpectQuery(`SELECT je.transaction_id, p.transaction_id, -SUM\(p.amount\) FROM journal_entries je .* WHERE je.kind IN \('discharge', 'discharge_adjustment'\) AND la.account_id = \$1 AND la.type = 'asset'`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"credit_transaction_id", "debt_transaction_id", "amount"}).
+			AddRow(int64(9), int64(3), 100.0).
+			AddRow(int64(9), int64(5), 50.0))
+
+	allocations, err := repo.GetDischargeAllocations(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*repository.Allocation{
+		{CreditTransactionID: 9, DebtTransactionID: 3, Amount: 100},
+		{CreditTransactionID: 9, DebtTransactionID: 5, Amount: 50},
+	}, allocations)
+
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type accountsRepo struct {
+	store *Store
+}
+
+func NewAccountsRepository(store *Store) repository.AccountsRepository {
+	return &accountsRepo{store: store}
+}
+
+// InsertAccount inserts a new account of the given product for the customer
+func (r *accountsRepo) InsertAccount(ctx context.Context, customerID int64, product string) (*repository.Account, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if _, ok := s.customers[customerID]; !ok {
+		return nil, fmt.Errorf("failed to insert account: %w", foreignKeyViolation("accounts", "accounts_customer_id_fkey"))
+	}
+	for _, account := range s.accounts {
+		if account.CustomerID == customerID && account.Product == product {
+			return nil, fmt.Errorf("failed to insert account: %w", uniqueViolation("accounts", "accounts_customer_id_product_key"))
+		}
+	}
+
+	s.accountSeq++
+	account := &repository.Account{
+		ID:            s.accountSeq,
+		CustomerID:    customerID,
+		Product:       product,
+		Status:        "active",
+		CorrelationID: middleware.GetCorrelationIDFromContext(ctx),
+		CreatedAt:     s.now(),
+	}
+	keepRow(ctx, s, s.accounts, account.ID)
+	s.accounts[account.ID] = account
+
+	return s.account(account), nil
+}
+
+// GetAccountByID retrieves an account by accountID
+func (r *accountsRepo) GetAccountByID(ctx context.Context, accountID int64) (*repository.Account, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	account, ok := s.accounts[accountID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	return s.account(account), nil
+}
+
+// GetAccountsByCustomerID retrieves every account of the customer, oldest first
+func (r *accountsRepo) GetAccountsByCustomerID(ctx context.Context, customerID int64) ([]*repository.Account, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var accounts []*repository.Account
+	for _, account := range s.accounts {
+		if account.CustomerID == customerID {
+			accounts = append(accounts, s.account(account))
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+	return accounts, nil
+}
+
+// LockAccounts returns the accounts found; rows need no locking as a unit of work holds the whole store
+func (r *accountsRepo) LockAccounts(ctx context.Context, accountIDs ...int64) ([]*repository.Account, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var accounts []*repository.Account
+	for _, accountID := range accountIDs {
+		if account, ok := s.accounts[accountID]; ok {
+			accounts = append(accounts, s.account(account))
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+	return accounts, nil
+}
+
+// UpdateAccountStatus sets the status of the account and returns the updated account
+func (r *accountsRepo) UpdateAccountStatus(ctx context.Context, accountID int64, status string) (*repository.Account, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	account, ok := s.accounts[accountID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	keepRow(ctx, s, s.accounts, accountID)
+	account.Status = status
+	return s.account(account), nil
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+type accrualsRepo struct {
+	store *Store
+}
+
+// accrualRunKey is the primary key of an accrual run
+type accrualRunKey struct {
+	accountID   int64
+	accrualDate string
+}
+
+func NewAccrualsRepository(store *Store) repository.AccrualsRepository {
+	return &accrualsRepo{store: store}
+}
+
+// GetAccrualPolicies retrieves the accrual policies of the product, the product-wide one first
+func (r *accrualsRepo) GetAccrualPolicies(ctx context.Context, product string) ([]*repository.AccrualPolicy, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var policies []*repository.AccrualPolicy
+	for _, policy := range s.accrualPolicies {
+		if policy.Product == product {
+			out := policy
+			policies = append(policies, &out)
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].OperationTypeID < policies[j].OperationTypeID })
+	return policies, nil
+}
+
+// GetPastDueAccountIDs retrieves the accounts, other than closed ones, with a statement due before date
+func (r *accrualsRepo) GetPastDueAccountIDs(ctx context.Context, date time.Time) ([]int64, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	day := date.Format(time.DateOnly)
+	pastDue := map[int64]bool{}
+	for _, statement := range s.statements {
+		if statement.DueDate < day && s.accounts[statement.AccountID].Status != "closed" {
+			pastDue[statement.AccountID] = true
+		}
+	}
+
+	accountIDs := make([]int64, 0, len(pastDue))
+	for accountID := range pastDue {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+	return accountIDs, nil
+}
+
+// GetAccrualsByAccountID retrieves every charge levied on the account, oldest first
+func (r *accrualsRepo) GetAccrualsByAccountID(ctx context.Context, accountID int64) ([]*repository.Accrual, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var runs []*repository.AccrualRun
+	for key, run := range s.accrualRuns {
+		if key.accountID == accountID {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].AccrualDate < runs[j].AccrualDate })
+
+	var accruals []*repository.Accrual
+	for _, run := range runs {
+		for _, accrual := range run.Accruals {
+			out := accrual
+			accruals = append(accruals, &out)
+		}
+	}
+	return accruals, nil
+}
+
+// InsertAccrualRun records the run and its charges. A second run for the same account and day
+// violates accrual_runs_pkey.
+func (r *accrualsRepo) InsertAccrualRun(ctx context.Context, run *repository.AccrualRun) (*repository.AccrualRun, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	key := accrualRunKey{accountID: run.AccountID, accrualDate: run.AccrualDate}
+	if _, ok := s.accounts[run.AccountID]; !ok {
+		return nil, fmt.Errorf("failed to insert accrual run: %w", foreignKeyViolation("accrual_runs", "accrual_runs_account_id_fkey"))
+	}
+	if _, ok := s.accrualRuns[key]; ok {
+		return nil, fmt.Errorf("failed to insert accrual run: %w", uniqueViolation("accrual_runs", "accrual_runs_pkey"))
+	}
+	for _, accrual := range run.Accruals {
+		if _, ok := s.transactions[accrual.TransactionID]; !ok {
+			return nil, fmt.Errorf("failed to insert accrual run: %w", foreignKeyViolation("accruals", "accruals_transaction_id_fkey"))
+		}
+		if accrual.StatementID == 0 {
+			continue
+		}
+		if _, ok := s.statements[accrual.StatementID]; !ok {
+			return nil, fmt.Errorf("failed to insert accrual run: %w", foreignKeyViolation("accruals", "accruals_statement_id_fkey"))
+		}
+		if accrual.Kind == "late_fee" && s.lateFeeLevied(accrual.StatementID) {
+			return nil, fmt.Errorf("failed to insert accrual run: %w", uniqueViolation("accruals", "accruals_statement_id_late_fee_key"))
+		}
+	}
+
+	created := *run
+	created.Accruals = append([]repository.Accrual(nil), run.Accruals...)
+	created.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+	created.CreatedAt = s.now()
+	keepRow(ctx, s, s.accrualRuns, key)
+	s.accrualRuns[key] = &created
+
+	out := created
+	return &out, nil
+}
+
+// lateFeeLevied reports whether a late fee was already levied for the statement
+func (s *Store) lateFeeLevied(statementID int64) bool {
+	for _, run := range s.accrualRuns {
+		for _, accrual := range run.Accruals {
+			if accrual.Kind == "late_fee" && accrual.StatementID == statementID {
+				return true
+			}
+		}
+	}
+	return false
+}
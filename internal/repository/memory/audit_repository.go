@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+type auditRepo struct {
+	store *Store
+}
+
+func NewAuditRepository(store *Store) repository.AuditRepository {
+	return &auditRepo{store: store}
+}
+
+// InsertAuditEvent appends an event to the audit log, attributed to the principal, request, correlation id
+// and IP of ctx
+func (r *auditRepo) InsertAuditEvent(ctx context.Context, event *repository.AuditEvent) (*repository.AuditEvent, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	out := *s.appendAuditEvent(ctx, event)
+	return &out, nil
+}
+
+// InsertAuditEvents appends the events to the audit log in order, attributed as InsertAuditEvent attributes
+// them
+func (r *auditRepo) InsertAuditEvents(ctx context.Context, events []*repository.AuditEvent) error {
+	s := r.store
+	defer s.lock(ctx)()
+
+	for _, event := range events {
+		s.appendAuditEvent(ctx, event)
+	}
+	return nil
+}
+
+func (s *Store) appendAuditEvent(ctx context.Context, event *repository.AuditEvent) *repository.AuditEvent {
+	created := *event
+	created.ID = int64(len(s.auditEvents)) + 1
+	created.Before = slices.Clone(event.Before)
+	created.After = slices.Clone(event.After)
+	created.Principal = middleware.GetPrincipalFromContext(ctx)
+	created.RequestID = middleware.GetRequestIDFromContext(ctx)
+	created.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+	created.IP = middleware.GetClientIPFromContext(ctx)
+	created.CreatedAt = s.now()
+	s.auditEvents = append(s.auditEvents, &created)
+	return &created
+}
+
+// GetAuditEvents retrieves the audit events matching the filter, oldest first
+func (r *auditRepo) GetAuditEvents(ctx context.Context, filter repository.AuditEventFilter) ([]*repository.AuditEvent, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var events []*repository.AuditEvent
+	for _, event := range s.auditEvents {
+		switch {
+		case event.ID <= filter.AfterID,
+			filter.Action != "" && event.Action != filter.Action,
+			filter.EntityType != "" && event.EntityType != filter.EntityType,
+			filter.EntityID != 0 && event.EntityID != filter.EntityID,
+			filter.Principal != "" && event.Principal != filter.Principal,
+			filter.RequestID != "" && event.RequestID != filter.RequestID,
+			!filter.From.IsZero() && event.CreatedAt.Before(filter.From),
+			!filter.To.IsZero() && !event.CreatedAt.Before(filter.To):
+			continue
+		}
+		out := *event
+		events = append(events, &out)
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+	}
+	return events, nil
+}
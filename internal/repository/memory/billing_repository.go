@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type billingRepo struct {
+	store *Store
+}
+
+func NewBillingRepository(store *Store) repository.BillingRepository {
+	return &billingRepo{store: store}
+}
+
+// GetBillingCycle retrieves the billing cycle of the account, or the default one if it has not
+// configured any. It returns pgx.ErrNoRows if the account does not exist.
+func (r *billingRepo) GetBillingCycle(ctx context.Context, accountID int64) (*repository.BillingCycle, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if _, ok := s.accounts[accountID]; !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return s.billingCycle(accountID), nil
+}
+
+// UpsertBillingCycle configures the billing cycle of the account
+func (r *billingRepo) UpsertBillingCycle(ctx context.Context, cycle *repository.BillingCycle) (*repository.BillingCycle, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if _, ok := s.accounts[cycle.AccountID]; !ok {
+		return nil, fmt.Errorf("failed to upsert billing cycle: %w", foreignKeyViolation("billing_cycles", "billing_cycles_account_id_fkey"))
+	}
+
+	stored := *cycle
+	keepRow(ctx, s, s.billingCycles, cycle.AccountID)
+	s.billingCycles[cycle.AccountID] = &stored
+
+	out := stored
+	return &out, nil
+}
+
+// GetBillingCyclesByClosingDay retrieves the billing cycles of the product's accounts that close on closingDay
+func (r *billingRepo) GetBillingCyclesByClosingDay(ctx context.Context, product string, closingDay int) ([]*repository.BillingCycle, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var cycles []*repository.BillingCycle
+	for _, account := range s.accounts {
+		if account.Product != product {
+			continue
+		}
+		if cycle := s.billingCycle(account.ID); cycle.ClosingDay == closingDay {
+			cycles = append(cycles, cycle)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i].AccountID < cycles[j].AccountID })
+	return cycles, nil
+}
+
+// billingCycle returns a copy of the account's billing cycle, or the default one
+func (s *Store) billingCycle(accountID int64) *repository.BillingCycle {
+	if cycle, ok := s.billingCycles[accountID]; ok {
+		out := *cycle
+		return &out
+	}
+	return &repository.BillingCycle{
+		AccountID:    accountID,
+		ClosingDay:   repository.DefaultClosingDay,
+		DueDayOffset: repository.DefaultDueDayOffset,
+	}
+}
+
+// InsertStatement inserts the statement and its lines
+func (r *billingRepo) InsertStatement(ctx context.Context, statement *repository.Statement) (*repository.Statement, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if _, ok := s.accounts[statement.AccountID]; !ok {
+		return nil, fmt.Errorf("failed to insert statement: %w", foreignKeyViolation("statements", "statements_account_id_fkey"))
+	}
+	for _, existing := range s.statements {
+		if existing.AccountID == statement.AccountID && existing.PeriodEnd == statement.PeriodEnd {
+			return nil, fmt.Errorf("failed to insert statement: %w", uniqueViolation("statements", "statements_account_id_period_end_key"))
+		}
+	}
+	for _, line := range statement.Lines {
+		if _, ok := s.transactions[line.TransactionID]; !ok {
+			return nil, fmt.Errorf("failed to insert statement: %w", foreignKeyViolation("statement_lines", "statement_lines_transaction_id_fkey"))
+		}
+	}
+
+	s.statementSeq++
+	created := *statement
+	created.ID = s.statementSeq
+	created.Lines = append([]repository.StatementLine(nil), statement.Lines...)
+	created.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+	created.CreatedAt = s.now()
+	keepRow(ctx, s, s.statements, created.ID)
+	s.statements[created.ID] = &created
+
+	out := created
+	return &out, nil
+}
+
+// GetLatestStatementByAccountID retrieves the account's statement with the latest period, without its lines
+func (r *billingRepo) GetLatestStatementByAccountID(ctx context.Context, accountID int64) (*repository.Statement, error) {
+	statements, err := r.GetStatementsByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return statements[0], nil
+}
+
+// GetStatementsByAccountID retrieves the account's statements, latest first, without their lines
+func (r *billingRepo) GetStatementsByAccountID(ctx context.Context, accountID int64) ([]*repository.Statement, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var statements []*repository.Statement
+	for _, statement := range s.statements {
+		if statement.AccountID == accountID {
+			out := *statement
+			out.Lines = nil
+			statements = append(statements, &out)
+		}
+	}
+	sort.Slice(statements, func(i, j int) bool { return statements[i].PeriodEnd > statements[j].PeriodEnd })
+	return statements, nil
+}
+
+// GetStatementByID retrieves a statement of the account with its lines
+func (r *billingRepo) GetStatementByID(ctx context.Context, accountID, statementID int64) (*repository.Statement, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	statement, ok := s.statements[statementID]
+	if !ok || statement.AccountID != accountID {
+		return nil, pgx.ErrNoRows
+	}
+	out := *statement
+	out.Lines = append([]repository.StatementLine(nil), statement.Lines...)
+	return &out, nil
+}
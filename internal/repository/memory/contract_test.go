@@ -0,0 +1,28 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/repositorytest"
+)
+
+func TestMemoryRepositoriesContract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		store := memory.NewStore()
+		return repositorytest.Repositories{
+			Customers:    memory.NewCustomersRepository(store),
+			Accounts:     memory.NewAccountsRepository(store),
+			Transactions: memory.NewTransactionsRepository(store),
+			Transfers:    memory.NewTransfersRepository(store),
+			Ledger:       memory.NewLedgerRepository(store),
+			Billing:      memory.NewBillingRepository(store),
+			Accruals:     memory.NewAccrualsRepository(store),
+			Fees:         memory.NewFeesRepository(store),
+			Screening:    memory.NewScreeningRepository(store),
+			Audit:        memory.NewAuditRepository(store),
+			Requests:     memory.NewTransactionRequestsRepository(store),
+			Transactor:   memory.NewTransactor(store),
+		}
+	})
+}
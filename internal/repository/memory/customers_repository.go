@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type customersRepo struct {
+	store *Store
+}
+
+func NewCustomersRepository(store *Store) repository.CustomersRepository {
+	return &customersRepo{store: store}
+}
+
+// InsertCustomer inserts a new customer
+func (r *customersRepo) InsertCustomer(ctx context.Context, customer *repository.Customer) (*repository.Customer, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if _, exists := s.documentIdx[customer.DocumentNumber]; exists {
+		return nil, fmt.Errorf("failed to insert customer: %w", uniqueViolation("customers", "customers_document_number_key"))
+	}
+
+	s.customerSeq++
+	created := &repository.Customer{
+		ID:             s.customerSeq,
+		DocumentNumber: customer.DocumentNumber,
+		Name:           customer.Name,
+		BirthDate:      customer.BirthDate,
+		Email:          customer.Email,
+		Phone:          customer.Phone,
+		CorrelationID:  middleware.GetCorrelationIDFromContext(ctx),
+		CreatedAt:      s.now(),
+	}
+	keepRow(ctx, s, s.customers, created.ID)
+	keepValue(ctx, s, s.documentIdx, created.DocumentNumber)
+	s.customers[created.ID] = created
+	s.documentIdx[created.DocumentNumber] = created.ID
+
+	out := *created
+	return &out, nil
+}
+
+// GetCustomerByID retrieves a customer by customerID
+func (r *customersRepo) GetCustomerByID(ctx context.Context, customerID int64) (*repository.Customer, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	customer, ok := s.customers[customerID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	out := *customer
+	return &out, nil
+}
+
+// GetCustomerByDocumentNumber retrieves a customer by documentNumber
+func (r *customersRepo) GetCustomerByDocumentNumber(ctx context.Context, documentNumber string) (*repository.Customer, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	customerID, ok := s.documentIdx[documentNumber]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	out := *s.customers[customerID]
+	return &out, nil
+}
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type feesRepo struct {
+	store *Store
+}
+
+func NewFeesRepository(store *Store) repository.FeesRepository {
+	return &feesRepo{store: store}
+}
+
+// GetFeeRule retrieves the fee rule of the product's transactions of the operation type with its tiers,
+// bounded ones first. It returns pgx.ErrNoRows if such transactions carry no fee.
+func (r *feesRepo) GetFeeRule(ctx context.Context, product string, operationTypeID int64) (*repository.FeeRule, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	for _, rule := range s.feeRules {
+		if rule.Product == product && rule.OperationTypeID == operationTypeID {
+			out := rule
+			out.Tiers = slices.Clone(rule.Tiers)
+			return &out, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+type ledgerRepo struct {
+	store *Store
+}
+
+func NewLedgerRepository(store *Store) repository.LedgerRepository {
+	return &ledgerRepo{store: store}
+}
+
+// PostJournalEntry appends the entry, creating the ledger accounts it posts to on first use.
+// Postgres checks the entry balances at commit; the store checks it immediately.
+func (r *ledgerRepo) PostJournalEntry(ctx context.Context, entry *repository.JournalEntry) (*repository.JournalEntry, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if err := s.checkJournalEntry(entry, s.journalSeq+1); err != nil {
+		return nil, err
+	}
+	out := *s.postJournalEntry(ctx, entry)
+	return &out, nil
+}
+
+// PostJournalEntries appends the entries in order as PostJournalEntry would, posting none of them unless all
+// of them can be
+func (r *ledgerRepo) PostJournalEntries(ctx context.Context, entries []*repository.JournalEntry) error {
+	s := r.store
+	defer s.lock(ctx)()
+
+	for i, entry := range entries {
+		if err := s.checkJournalEntry(entry, s.journalSeq+int64(i)+1); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		s.postJournalEntry(ctx, entry)
+	}
+	return nil
+}
+
+// checkJournalEntry enforces the constraints of the journal tables on the entry that would get the id
+func (s *Store) checkJournalEntry(entry *repository.JournalEntry, id int64) error {
+	if _, ok := s.transactions[entry.TransactionID]; entry.TransactionID != 0 && !ok {
+		return foreignKeyViolation("journal_entries", "journal_entries_transaction_id_fkey")
+	}
+
+	var total float64
+	for _, p := range entry.Postings {
+		if _, ok := s.accounts[p.Account.AccountID]; p.Account.AccountID != 0 && !ok {
+			return foreignKeyViolation("ledger_accounts", "ledger_accounts_account_id_fkey")
+		}
+		if _, ok := s.transactions[p.TransactionID]; p.TransactionID != 0 && !ok {
+			return foreignKeyViolation("postings", "postings_transaction_id_fkey")
+		}
+		if math.Round(p.Amount*100) == 0 {
+			return checkViolation("postings", "postings_amount_check", `new row for relation "postings" violates check constraint "postings_amount_check"`)
+		}
+		total += p.Amount
+	}
+	if math.Round(total*100) != 0 {
+		return checkViolation("postings", "postings_journal_entry_balanced",
+			fmt.Sprintf("journal entry %d does not balance: postings sum to %.2f", id, total))
+	}
+	return nil
+}
+
+// postJournalEntry records a checked entry, creating the ledger accounts it posts to on first use
+func (s *Store) postJournalEntry(ctx context.Context, entry *repository.JournalEntry) *repository.JournalEntry {
+	for _, p := range entry.Postings {
+		if _, ok := s.ledgerAccounts[p.Account.Code]; !ok {
+			keepValue(ctx, s, s.ledgerAccounts, p.Account.Code)
+			s.ledgerAccounts[p.Account.Code] = p.Account
+		}
+	}
+
+	s.journalSeq++
+	posted := *entry
+	posted.ID = s.journalSeq
+	posted.Postings = append([]repository.Posting(nil), entry.Postings...)
+	posted.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+	posted.CreatedAt = entry.CreatedAt.UTC()
+	keepRow(ctx, s, s.journalEntries, posted.ID)
+	s.journalEntries[posted.ID] = &posted
+	return &posted
+}
+
+// GetLedgerAccountBalances retrieves the balance of every ledger account of the account
+func (r *ledgerRepo) GetLedgerAccountBalances(ctx context.Context, accountID int64) ([]*repository.LedgerAccountBalance, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	byCode := map[string]*repository.LedgerAccountBalance{}
+	for code, account := range s.ledgerAccounts {
+		if account.AccountID == accountID {
+			byCode[code] = &repository.LedgerAccountBalance{LedgerAccount: account}
+		}
+	}
+	for _, entry := range s.journalEntries {
+		for _, p := range entry.Postings {
+			if balance, ok := byCode[p.Account.Code]; ok {
+				balance.Balance += p.Amount
+			}
+		}
+	}
+
+	var balances []*repository.LedgerAccountBalance
+	for _, balance := range byCode {
+		balance.Balance = math.Round(balance.Balance*100) / 100
+		balances = append(balances, balance)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Code < balances[j].Code })
+	return balances, nil
+}
+
+// GetLedgerBalancesByTransaction derives each transaction's balance from the postings attributed
+// to it on the account's ledger accounts, negated to match the sign of the transaction balance
+func (r *ledgerRepo) GetLedgerBalancesByTransaction(ctx context.Context, accountID int64) (map[int64]float64, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	balances := map[int64]float64{}
+	for _, entry := range s.journalEntries {
+		for _, p := range entry.Postings {
+			if p.TransactionID != 0 && s.ledgerAccounts[p.Account.Code].AccountID == accountID {
+				balances[p.TransactionID] -= p.Amount
+			}
+		}
+	}
+	for id, balance := range balances {
+		balances[id] = math.Round(balance*100) / 100
+	}
+	return balances, nil
+}
+
+// GetDischargesByAccountID retrieves the discharge and discharge adjustment entries of the account posted in
+// [from, to), oldest first
+func (r *ledgerRepo) GetDischargesByAccountID(ctx context.Context, accountID int64, from, to time.Time) ([]*repository.JournalEntry, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var entries []*repository.JournalEntry
+	for _, entry := range s.journalEntries {
+		if (entry.Kind != "discharge" && entry.Kind != "discharge_adjustment") || entry.CreatedAt.Before(from) || !entry.CreatedAt.Before(to) {
+			continue
+		}
+		for _, p := range entry.Postings {
+			if s.ledgerAccounts[p.Account.Code].AccountID == accountID {
+				out := *entry
+				entries = append(entries, &out)
+				break
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// GetDischargeAllocations retrieves what each credit of the account has discharged of each of its debts, net of
+// discharge adjustments, by credit then debt
+func (r *ledgerRepo) GetDischargeAllocations(ctx context.Context, accountID int64) ([]*repository.Allocation, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	type pair struct{ credit, debt int64 }
+	amounts := map[pair]float64{}
+	for _, entry := range s.journalEntries {
+		if entry.Kind != "discharge" && entry.Kind != "discharge_adjustment" {
+			continue
+		}
+		for _, p := range entry.Postings {
+			if account := s.ledgerAccounts[p.Account.Code]; account.AccountID == accountID && account.Type == "asset" {
+				amounts[pair{entry.TransactionID, p.TransactionID}] -= p.Amount
+			}
+		}
+	}
+
+	var allocations []*repository.Allocation
+	for p, amount := range amounts {
+		if amount := math.Round(amount*100) / 100; amount != 0 {
+			allocations = append(allocations, &repository.Allocation{CreditTransactionID: p.credit, DebtTransactionID: p.debt, Amount: amount})
+		}
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		if allocations[i].CreditTransactionID != allocations[j].CreditTransactionID {
+			return allocations[i].CreditTransactionID < allocations[j].CreditTransactionID
+		}
+		return allocations[i].DebtTransactionID < allocations[j].DebtTransactionID
+	})
+	return allocations, nil
+}
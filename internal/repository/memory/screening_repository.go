@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+type screeningRepo struct {
+	store *Store
+}
+
+func NewScreeningRepository(store *Store) repository.ScreeningRepository {
+	return &screeningRepo{store: store}
+}
+
+// GetScreeningRules retrieves the screening rules applying to accounts of the product, in the order they
+// were defined
+func (r *screeningRepo) GetScreeningRules(ctx context.Context, product string) ([]*repository.ScreeningRule, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var rules []*repository.ScreeningRule
+	for _, rule := range s.screeningRules {
+		if rule.Product == "" || rule.Product == product {
+			out := rule
+			out.OperationTypeIDs = slices.Clone(rule.OperationTypeIDs)
+			rules = append(rules, &out)
+		}
+	}
+	return rules, nil
+}
+
+// InsertScreeningEvaluation records the screening of a transaction
+func (r *screeningRepo) InsertScreeningEvaluation(ctx context.Context, evaluation *repository.ScreeningEvaluation) (*repository.ScreeningEvaluation, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if _, ok := s.accounts[evaluation.AccountID]; !ok {
+		return nil, fmt.Errorf("failed to insert screening evaluation: %w", foreignKeyViolation("screening_evaluations", "screening_evaluations_account_id_fkey"))
+	}
+	if _, ok := s.operationTypes[evaluation.OperationTypeID]; !ok {
+		return nil, fmt.Errorf("failed to insert screening evaluation: %w", foreignKeyViolation("screening_evaluations", "screening_evaluations_operation_type_id_fkey"))
+	}
+	if (evaluation.Outcome == repository.ScreeningOutcomeDeny) != (evaluation.TransactionID == 0) {
+		return nil, fmt.Errorf("failed to insert screening evaluation: %w", checkViolation("screening_evaluations", "screening_evaluations_check",
+			`new row for relation "screening_evaluations" violates check constraint "screening_evaluations_check"`))
+	}
+	if evaluation.TransactionID != 0 {
+		if _, ok := s.transactions[evaluation.TransactionID]; !ok {
+			return nil, fmt.Errorf("failed to insert screening evaluation: %w", foreignKeyViolation("screening_evaluations", "screening_evaluations_transaction_id_fkey"))
+		}
+		for _, existing := range s.screeningEvaluations {
+			if existing.TransactionID == evaluation.TransactionID {
+				return nil, fmt.Errorf("failed to insert screening evaluation: %w", uniqueViolation("screening_evaluations", "screening_evaluations_transaction_id_key"))
+			}
+		}
+	}
+
+	s.screeningEvaluationSeq++
+	created := *evaluation
+	created.ID = s.screeningEvaluationSeq
+	created.ReasonCodes = slices.Clone(evaluation.ReasonCodes)
+	if created.ReasonCodes == nil {
+		created.ReasonCodes = []string{}
+	}
+	created.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+	created.CreatedAt = s.now()
+	keepRow(ctx, s, s.screeningEvaluations, created.ID)
+	s.screeningEvaluations[created.ID] = &created
+
+	out := created
+	return &out, nil
+}
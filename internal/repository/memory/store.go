@@ -0,0 +1,219 @@
+// Package memory provides in-memory implementations of the repository interfaces.
+// They enforce the same unique and foreign key constraints as the Postgres schema
+// and report violations with the same pgconn errors, so services behave identically
+// against either backend.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres SQLSTATE codes reported by the store
+const (
+	codeUniqueViolation     = "23505"
+	codeForeignKeyViolation = "23503"
+	codeCheckViolation      = "23514"
+)
+
+// operationTypeFee is the operation type of fees, the only transactions with a parent
+const operationTypeFee = 9
+
+// Store holds every table; all repositories created from the same Store share its data.
+// A single mutex serializes access, which gives each repository call the atomicity of a
+// single SQL statement. A unit of work run by the store's Transactor holds the mutex for
+// its whole duration instead.
+type Store struct {
+	mu sync.Mutex
+	tables
+
+	// undo reverts, last first, the rows written by the unit of work in progress
+	undo []func()
+
+	now func() time.Time
+}
+
+// tables is the data of a Store. A unit of work restores its sequences and the audit log from a shallow
+// copy on rollback, and the rows of its maps from the undo log.
+type tables struct {
+	customers    map[int64]*repository.Customer
+	customerSeq  int64
+	documentIdx  map[string]int64
+	accounts     map[int64]*repository.Account
+	accountSeq   int64
+	transactions map[int64]*repository.Transaction
+	trxSeq       int64
+	transfers    map[int64]*repository.Transfer
+	transferSeq  int64
+
+	ledgerAccounts map[string]repository.LedgerAccount
+	journalEntries map[int64]*repository.JournalEntry
+	journalSeq     int64
+
+	billingCycles map[int64]*repository.BillingCycle
+	statements    map[int64]*repository.Statement
+	statementSeq  int64
+
+	accrualPolicies []repository.AccrualPolicy
+	accrualRuns     map[accrualRunKey]*repository.AccrualRun
+
+	feeRules []repository.FeeRule
+
+	screeningRules         []repository.ScreeningRule
+	screeningEvaluations   map[int64]*repository.ScreeningEvaluation
+	screeningEvaluationSeq int64
+
+	auditEvents []*repository.AuditEvent // append-only, in id order
+
+	transactionRequests   map[int64]*repository.TransactionRequest
+	transactionRequestSeq int64
+
+	operationTypes map[int64]string
+}
+
+// NewStore returns an empty store seeded like a freshly migrated database: operation types, global
+// ledger accounts, accrual policies, fee rules and screening rules
+func NewStore() *Store {
+	return &Store{
+		tables: tables{
+			customers:    map[int64]*repository.Customer{},
+			documentIdx:  map[string]int64{},
+			accounts:     map[int64]*repository.Account{},
+			transactions: map[int64]*repository.Transaction{},
+			transfers:    map[int64]*repository.Transfer{},
+			ledgerAccounts: map[string]repository.LedgerAccount{
+				"cash_clearing":              {Code: "cash_clearing", Type: "asset"},
+				"transfer_clearing":          {Code: "transfer_clearing", Type: "asset"},
+				"fee_income":                 {Code: "fee_income", Type: "income"},
+				"interest_income":            {Code: "interest_income", Type: "income"},
+				"opening_balance_adjustment": {Code: "opening_balance_adjustment", Type: "equity"},
+			},
+			journalEntries: map[int64]*repository.JournalEntry{},
+			billingCycles:  map[int64]*repository.BillingCycle{},
+			statements:     map[int64]*repository.Statement{},
+			accrualPolicies: []repository.AccrualPolicy{
+				{Product: "credit", APR: 0.24, LateFeeAmount: 25},
+				{Product: "credit", OperationTypeID: 3, APR: 0.36},
+			},
+			accrualRuns: map[accrualRunKey]*repository.AccrualRun{},
+			feeRules: []repository.FeeRule{
+				{ID: 1, Product: "credit", OperationTypeID: 3, Kind: "percent", Percent: 0.03, MinAmount: 5},
+				{ID: 2, Product: "prepaid", OperationTypeID: 3, Kind: "tiered", MaxAmount: 10, Tiers: []repository.FeeTier{
+					{UpTo: 100, FlatAmount: 1},
+					{Percent: 0.01},
+				}},
+			},
+			screeningRules: []repository.ScreeningRule{
+				{ID: 1, Code: "withdrawal_velocity", OperationTypeIDs: []int64{3}, Kind: "count", WindowHours: 24, Threshold: 5, Action: "deny"},
+				{ID: 2, Code: "daily_debit_limit", OperationTypeIDs: []int64{1, 2, 3, 5}, Kind: "amount", WindowHours: 24, Threshold: 5000, Action: "deny"},
+				{ID: 3, Code: "new_account_large_debit", OperationTypeIDs: []int64{1, 2, 3}, Kind: "single_amount", Threshold: 1000, MaxAccountAgeDays: 7, Action: "deny"},
+				{ID: 4, Code: "large_debit", OperationTypeIDs: []int64{1, 2, 3}, Kind: "single_amount", Threshold: 2500, Action: "review"},
+			},
+			screeningEvaluations: map[int64]*repository.ScreeningEvaluation{},
+			transactionRequests:  map[int64]*repository.TransactionRequest{},
+			operationTypes: map[int64]string{
+				1: "Normal Purchase",
+				2: "Purchase with Installments",
+				3: "Withdrawal",
+				4: "Credit Voucher",
+				5: "Transfer Debit",
+				6: "Transfer Credit",
+				7: "Interest Charge",
+				8: "Late Fee",
+				9: "Fee",
+			},
+		},
+		now: func() time.Time { return time.Now().UTC() },
+	}
+}
+
+type txKey struct{}
+
+// lock acquires the store mutex and returns its release function. Inside a unit of work
+// of this store the mutex is already held, so both are no-ops.
+func (s *Store) lock(ctx context.Context) func() {
+	if s.inUnitOfWork(ctx) {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// inUnitOfWork reports whether ctx is within a unit of work of this store
+func (s *Store) inUnitOfWork(ctx context.Context) bool {
+	owner, _ := ctx.Value(txKey{}).(*Store)
+	return owner == s
+}
+
+// keepRow logs how to restore rows[key] as it is now, should the unit of work of ctx roll back; outside one
+// it does nothing. Rows are restored in place, as repositories update them through their pointers.
+func keepRow[K comparable, T any](ctx context.Context, s *Store, rows map[K]*T, key K) {
+	if !s.inUnitOfWork(ctx) {
+		return
+	}
+	row, ok := rows[key]
+	if !ok {
+		s.undo = append(s.undo, func() { delete(rows, key) })
+		return
+	}
+	saved := *row
+	s.undo = append(s.undo, func() {
+		*row = saved
+		rows[key] = row
+	})
+}
+
+// keepValue is keepRow for the tables holding their rows by value
+func keepValue[K comparable, V any](ctx context.Context, s *Store, rows map[K]V, key K) {
+	if !s.inUnitOfWork(ctx) {
+		return
+	}
+	value, ok := rows[key]
+	if !ok {
+		s.undo = append(s.undo, func() { delete(rows, key) })
+		return
+	}
+	s.undo = append(s.undo, func() { rows[key] = value })
+}
+
+// account returns a copy of the account with the holder's document number filled in
+func (s *Store) account(account *repository.Account) *repository.Account {
+	out := *account
+	out.DocumentNumber = s.customers[account.CustomerID].DocumentNumber
+	return &out
+}
+
+func uniqueViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           codeUniqueViolation,
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func foreignKeyViolation(table, constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           codeForeignKeyViolation,
+		Message:        fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+func checkViolation(table, constraint, message string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           codeCheckViolation,
+		Message:        message,
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type transactionRequestsRepo struct {
+	store *Store
+}
+
+func NewTransactionRequestsRepository(store *Store) repository.TransactionRequestsRepository {
+	return &transactionRequestsRepo{store: store}
+}
+
+// InsertTransactionRequest queues a pending request attributed to the correlation id and principal of ctx
+func (r *transactionRequestsRepo) InsertTransactionRequest(ctx context.Context, request *repository.TransactionRequest) (*repository.TransactionRequest, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if _, ok := s.accounts[request.AccountID]; !ok {
+		return nil, foreignKeyViolation("transaction_requests", "transaction_requests_account_id_fkey")
+	}
+	if _, ok := s.operationTypes[request.OperationTypeID]; !ok {
+		return nil, foreignKeyViolation("transaction_requests", "transaction_requests_operation_type_id_fkey")
+	}
+	if request.Amount <= 0 {
+		return nil, checkViolation("transaction_requests", "transaction_requests_amount_check",
+			`new row for relation "transaction_requests" violates check constraint "transaction_requests_amount_check"`)
+	}
+
+	now := s.now()
+	s.transactionRequestSeq++
+	created := &repository.TransactionRequest{
+		ID:                s.transactionRequestSeq,
+		AccountID:         request.AccountID,
+		OperationTypeID:   request.OperationTypeID,
+		Amount:            request.Amount,
+		MerchantReference: request.MerchantReference,
+		Status:            repository.TransactionRequestPending,
+		CorrelationID:     middleware.GetCorrelationIDFromContext(ctx),
+		Principal:         middleware.GetPrincipalFromContext(ctx),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	keepRow(ctx, s, s.transactionRequests, created.ID)
+	s.transactionRequests[created.ID] = created
+
+	out := *created
+	return &out, nil
+}
+
+// GetTransactionRequestByID retrieves a request; it returns pgx.ErrNoRows when there is none
+func (r *transactionRequestsRepo) GetTransactionRequestByID(ctx context.Context, requestID int64) (*repository.TransactionRequest, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	request, ok := s.transactionRequests[requestID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	out := *request
+	return &out, nil
+}
+
+// ClaimTransactionRequest returns the oldest pending request of an account that has no older pending request.
+// Units of work hold the store exclusively, so a request claimed by another one is already processed or
+// back to pending; it returns pgx.ErrNoRows when there is none to claim.
+func (r *transactionRequestsRepo) ClaimTransactionRequest(ctx context.Context) (*repository.TransactionRequest, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	// The oldest pending request of every account is the oldest pending request overall, of its account
+	var claimed *repository.TransactionRequest
+	for _, request := range s.transactionRequests {
+		if request.Status == repository.TransactionRequestPending && (claimed == nil || request.ID < claimed.ID) {
+			claimed = request
+		}
+	}
+	if claimed == nil {
+		return nil, pgx.ErrNoRows
+	}
+	out := *claimed
+	return &out, nil
+}
+
+// CompleteTransactionRequest records the transaction a pending request posted; it returns pgx.ErrNoRows when
+// the request is not pending
+func (r *transactionRequestsRepo) CompleteTransactionRequest(ctx context.Context, requestID, transactionID int64) error {
+	s := r.store
+	defer s.lock(ctx)()
+
+	request, ok := s.transactionRequests[requestID]
+	if !ok || request.Status != repository.TransactionRequestPending {
+		return pgx.ErrNoRows
+	}
+	if _, ok := s.transactions[transactionID]; !ok {
+		return foreignKeyViolation("transaction_requests", "transaction_requests_transaction_id_fkey")
+	}
+	for _, other := range s.transactionRequests {
+		if other.TransactionID == transactionID {
+			return uniqueViolation("transaction_requests", "transaction_requests_transaction_id_key")
+		}
+	}
+
+	keepRow(ctx, s, s.transactionRequests, requestID)
+	request.Status = repository.TransactionRequestSucceeded
+	request.TransactionID = transactionID
+	request.UpdatedAt = s.now()
+	return nil
+}
+
+// FailTransactionRequest records why a pending request could not be processed; it returns pgx.ErrNoRows
+// when the request is not pending
+func (r *transactionRequestsRepo) FailTransactionRequest(ctx context.Context, requestID int64, errorCode, errorDetail string) error {
+	s := r.store
+	defer s.lock(ctx)()
+
+	request, ok := s.transactionRequests[requestID]
+	if !ok || request.Status != repository.TransactionRequestPending {
+		return pgx.ErrNoRows
+	}
+
+	keepRow(ctx, s, s.transactionRequests, requestID)
+	request.Status = repository.TransactionRequestFailed
+	request.ErrorCode = errorCode
+	request.ErrorDetail = errorDetail
+	request.UpdatedAt = s.now()
+	return nil
+}
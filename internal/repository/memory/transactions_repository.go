@@ -0,0 +1,475 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type transactionsRepo struct {
+	store *Store
+}
+
+func NewTransactionsRepository(store *Store) repository.TransactionsRepository {
+	return &transactionsRepo{store: store}
+}
+
+// InsertTransaction inserts a new transaction that took place at eventDate and was booked at bookingDate.
+// merchantReference and duplicateOfTransactionID are optional; the latter flags the transaction as a suspected
+// duplicate of an earlier one.
+func (r *transactionsRepo) InsertTransaction(ctx context.Context, accountID, operationTypeID int64, amount, balance float64, eventDate, bookingDate time.Time, merchantReference string, duplicateOfTransactionID int64) (*repository.Transaction, error) {
+	return r.insert(ctx, &repository.Transaction{
+		AccountID:         accountID,
+		OperationTypeID:   operationTypeID,
+		Amount:            amount,
+		Balance:           balance,
+		EventDate:         eventDate,
+		BookingDate:       bookingDate,
+		MerchantReference: merchantReference,
+		DuplicateOfID:     duplicateOfTransactionID,
+	})
+}
+
+// InsertTransactions inserts the transactions in order, booked at their event dates, filling in their IDs,
+// event dates and correlation IDs; either all of them are inserted or none
+func (r *transactionsRepo) InsertTransactions(ctx context.Context, transactions []*repository.Transaction) error {
+	return NewTransactor(r.store).WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, txn := range transactions {
+			inserted, err := r.insert(ctx, &repository.Transaction{
+				AccountID:         txn.AccountID,
+				OperationTypeID:   txn.OperationTypeID,
+				Amount:            txn.Amount,
+				Balance:           txn.Balance,
+				EventDate:         txn.EventDate,
+				MerchantReference: txn.MerchantReference,
+				DuplicateOfID:     txn.DuplicateOfID,
+			})
+			if err != nil {
+				return err
+			}
+			txn.ID, txn.EventDate, txn.BookingDate, txn.CorrelationID = inserted.ID, inserted.EventDate, inserted.BookingDate, inserted.CorrelationID
+		}
+		return nil
+	})
+}
+
+// InsertTransferLeg inserts a transaction posted and booked at eventDate as one leg of the transfer
+func (r *transactionsRepo) InsertTransferLeg(ctx context.Context, transferID, accountID, operationTypeID int64, amount, balance float64, eventDate time.Time) (*repository.Transaction, error) {
+	return r.insert(ctx, &repository.Transaction{
+		TransferID:      transferID,
+		AccountID:       accountID,
+		OperationTypeID: operationTypeID,
+		Amount:          amount,
+		Balance:         balance,
+		EventDate:       eventDate,
+	})
+}
+
+// InsertFeeTransaction inserts a fee posted at eventDate for the parent transaction, booked with it
+func (r *transactionsRepo) InsertFeeTransaction(ctx context.Context, parentTransactionID, accountID, operationTypeID int64, amount, balance float64, eventDate time.Time) (*repository.Transaction, error) {
+	return r.insert(ctx, &repository.Transaction{
+		ParentTransactionID: parentTransactionID,
+		AccountID:           accountID,
+		OperationTypeID:     operationTypeID,
+		Amount:              amount,
+		Balance:             balance,
+		EventDate:           eventDate,
+	})
+}
+
+// insert stores the transaction, assigning its ID, timestamps and correlation ID. Transactions are booked at
+// their event date unless given a booking date, fees along with their parent.
+func (r *transactionsRepo) insert(ctx context.Context, transaction *repository.Transaction) (*repository.Transaction, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if _, ok := s.transfers[transaction.TransferID]; transaction.TransferID != 0 && !ok {
+		return nil, foreignKeyViolation("transactions", "transactions_transfer_id_fkey")
+	}
+	if _, ok := s.transactions[transaction.ParentTransactionID]; transaction.ParentTransactionID != 0 && !ok {
+		return nil, foreignKeyViolation("transactions", "transactions_parent_transaction_id_fkey")
+	}
+	if _, ok := s.transactions[transaction.DuplicateOfID]; transaction.DuplicateOfID != 0 && !ok {
+		return nil, foreignKeyViolation("transactions", "transactions_duplicate_of_transaction_id_fkey")
+	}
+	if _, ok := s.accounts[transaction.AccountID]; !ok {
+		return nil, foreignKeyViolation("transactions", "transactions_account_id_fkey")
+	}
+	if _, ok := s.operationTypes[transaction.OperationTypeID]; !ok {
+		return nil, foreignKeyViolation("transactions", "transactions_operation_type_id_fkey")
+	}
+	if (transaction.OperationTypeID == operationTypeFee) != (transaction.ParentTransactionID != 0) {
+		return nil, checkViolation("transactions", "transactions_parent_transaction_id_check",
+			`new row for relation "transactions" violates check constraint "transactions_parent_transaction_id_check"`)
+	}
+
+	if parent := s.transactions[transaction.ParentTransactionID]; parent != nil {
+		transaction.BookingDate = parent.BookingDate
+	}
+	if transaction.BookingDate.IsZero() {
+		transaction.BookingDate = transaction.EventDate
+	}
+
+	now := s.now()
+	s.trxSeq++
+	transaction.ID = s.trxSeq
+	transaction.EventDate = transaction.EventDate.UTC()
+	transaction.BookingDate = transaction.BookingDate.UTC()
+	transaction.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+	transaction.CreatedAt = now
+	transaction.UpdatedAt = now
+	keepRow(ctx, s, s.transactions, transaction.ID)
+	s.transactions[transaction.ID] = transaction
+
+	out := *transaction
+	return &out, nil
+}
+
+// GetTransactionByID retrieves a transaction; it returns pgx.ErrNoRows when there is none
+func (r *transactionsRepo) GetTransactionByID(ctx context.Context, transactionID int64) (*repository.Transaction, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	txn, ok := s.transactions[transactionID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	out := *txn
+	return &out, nil
+}
+
+// GetDuplicateTransaction retrieves the latest transaction of the account with the operation type and amount and
+// an event date from since on. When merchantReference is set, the transaction must carry it too. It returns
+// pgx.ErrNoRows when there is none.
+func (r *transactionsRepo) GetDuplicateTransaction(ctx context.Context, accountID, operationTypeID int64, amount float64, merchantReference string, since time.Time) (*repository.Transaction, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var candidates []*repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.AccountID != accountID || txn.OperationTypeID != operationTypeID || txn.Amount != amount || txn.EventDate.Before(since) {
+			continue
+		}
+		if merchantReference != "" && txn.MerchantReference != merchantReference {
+			continue
+		}
+		candidates = append(candidates, txn)
+	}
+	if len(candidates) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	sortByEventDate(candidates)
+	out := *candidates[len(candidates)-1]
+	return &out, nil
+}
+
+// GetTransactionsByTransferID retrieves the legs of a transfer in posting order
+func (r *transactionsRepo) GetTransactionsByTransferID(ctx context.Context, transferID int64) ([]*repository.Transaction, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var transactions []*repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.TransferID == transferID {
+			out := *txn
+			transactions = append(transactions, &out)
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID < transactions[j].ID })
+	return transactions, nil
+}
+
+// GetTransactionsByAccountID retrieves every transaction of the account, oldest first
+func (r *transactionsRepo) GetTransactionsByAccountID(ctx context.Context, accountID int64) ([]*repository.Transaction, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var transactions []*repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.AccountID == accountID {
+			out := *txn
+			transactions = append(transactions, &out)
+		}
+	}
+	sortByEventDate(transactions)
+	return transactions, nil
+}
+
+// GetTransactionsByAccountIDInPeriod retrieves the transactions of the account with an event date
+// in [from, to), oldest first
+func (r *transactionsRepo) GetTransactionsByAccountIDInPeriod(ctx context.Context, accountID int64, from, to time.Time) ([]*repository.Transaction, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var transactions []*repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.AccountID == accountID && !txn.EventDate.Before(from) && txn.EventDate.Before(to) {
+			out := *txn
+			transactions = append(transactions, &out)
+		}
+	}
+	sortByEventDate(transactions)
+	return transactions, nil
+}
+
+// GetTransactionsByAccountIDSince retrieves the transactions of the account with an event date from since
+// on, oldest first
+func (r *transactionsRepo) GetTransactionsByAccountIDSince(ctx context.Context, accountID int64, since time.Time) ([]*repository.Transaction, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var transactions []*repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.AccountID == accountID && !txn.EventDate.Before(since) {
+			out := *txn
+			transactions = append(transactions, &out)
+		}
+	}
+	sortByEventDate(transactions)
+	return transactions, nil
+}
+
+// StreamTransactionsByAccountID passes the transactions of the account with an event date in [from, to) to fn,
+// oldest first; a zero bound is open. It stops at the first error of fn.
+func (r *transactionsRepo) StreamTransactionsByAccountID(ctx context.Context, accountID int64, from, to time.Time, fn func(*repository.Transaction) error) error {
+	transactions := r.snapshotAccountTransactions(ctx, accountID, from, to)
+	for _, txn := range transactions {
+		if err := fn(txn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNetAmountByAccountIDBefore sums the amounts of the account's transactions with an event date before the
+// given time
+func (r *transactionsRepo) GetNetAmountByAccountIDBefore(ctx context.Context, accountID int64, before time.Time) (float64, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var net float64
+	for _, txn := range s.transactions {
+		if txn.AccountID == accountID && txn.EventDate.Before(before) {
+			net += txn.Amount
+		}
+	}
+	return net, nil
+}
+
+// snapshotAccountTransactions copies the transactions to stream, so fn runs without holding the store
+func (r *transactionsRepo) snapshotAccountTransactions(ctx context.Context, accountID int64, from, to time.Time) []*repository.Transaction {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var transactions []*repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.AccountID != accountID || (!from.IsZero() && txn.EventDate.Before(from)) || (!to.IsZero() && !txn.EventDate.Before(to)) {
+			continue
+		}
+		out := *txn
+		out.OperationType = s.operationTypes[txn.OperationTypeID]
+		transactions = append(transactions, &out)
+	}
+	sortByEventDate(transactions)
+	return transactions
+}
+
+// GetOutstandingTransactionsByAccountID retrieves the account's purchases, withdrawals, transfer debits, charges
+// and fees with a negative balance, oldest first
+func (r *transactionsRepo) GetOutstandingTransactionsByAccountID(ctx context.Context, accountID int64) ([]*repository.Transaction, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var transactions []*repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.AccountID != accountID || txn.Balance >= 0 {
+			continue
+		}
+		switch txn.OperationTypeID {
+		case 1, 2, 3, 5, 7, 8, operationTypeFee:
+			out := *txn
+			transactions = append(transactions, &out)
+		}
+	}
+
+	sortByEventDate(transactions)
+	return transactions, nil
+}
+
+func sortByEventDate(transactions []*repository.Transaction) {
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].EventDate.Equal(transactions[j].EventDate) {
+			return transactions[i].ID < transactions[j].ID
+		}
+		return transactions[i].EventDate.Before(transactions[j].EventDate)
+	})
+}
+
+// UpdateTransactionBalance updates the balance for the provided transactionID
+func (r *transactionsRepo) UpdateTransactionBalance(ctx context.Context, transactionID int64, newBalance float64) error {
+	s := r.store
+	defer s.lock(ctx)()
+
+	txn, ok := s.transactions[transactionID]
+	if !ok {
+		return fmt.Errorf("failed to update transaction balance: unexpected number of rows affected: %d for transaction %d", 0, transactionID)
+	}
+
+	keepRow(ctx, s, s.transactions, transactionID)
+	txn.Balance = newBalance
+	txn.UpdatedAt = s.now()
+	return nil
+}
+
+// UpdateTransactionBalances sets the balances of the transactions; it fails, updating none, when any of them is
+// missing
+func (r *transactionsRepo) UpdateTransactionBalances(ctx context.Context, balances map[int64]float64) error {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var found int
+	for id := range balances {
+		if _, ok := s.transactions[id]; ok {
+			found++
+		}
+	}
+	if found != len(balances) {
+		return fmt.Errorf("failed to update transaction balances: unexpected number of rows affected: %d for %d transactions", found, len(balances))
+	}
+	now := s.now()
+	for id, balance := range balances {
+		keepRow(ctx, s, s.transactions, id)
+		s.transactions[id].Balance = balance
+		s.transactions[id].UpdatedAt = now
+	}
+	return nil
+}
+
+// DischargeTransactions applies the balance of the credit to the account's outstanding debts, oldest first,
+// and returns the discharges in that order; the credit is left untouched when there is nothing to discharge.
+// Amounts are computed in cents, as the database computes them in NUMERIC.
+func (r *transactionsRepo) DischargeTransactions(ctx context.Context, creditTxn *repository.Transaction) ([]*repository.Discharge, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	credit, ok := s.transactions[creditTxn.ID]
+	if !ok || credit.AccountID != creditTxn.AccountID || credit.Balance <= 0 {
+		return nil, nil
+	}
+
+	var debts []*repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.AccountID != credit.AccountID || txn.Balance >= 0 {
+			continue
+		}
+		switch txn.OperationTypeID {
+		case 1, 2, 3, 5, 7, 8, operationTypeFee:
+			debts = append(debts, txn)
+		}
+	}
+	sortByEventDate(debts)
+
+	now := s.now()
+	remaining := cents(credit.Balance)
+	var discharges []*repository.Discharge
+	for _, debt := range debts {
+		if remaining <= 0 {
+			break
+		}
+		amount := min(-cents(debt.Balance), remaining)
+		discharges = append(discharges, &repository.Discharge{TransactionID: debt.ID, Balance: debt.Balance, Amount: float64(amount) / 100})
+		keepRow(ctx, s, s.transactions, debt.ID)
+		debt.Balance = float64(cents(debt.Balance)+amount) / 100
+		debt.UpdatedAt = now
+		remaining -= amount
+	}
+	if len(discharges) > 0 {
+		keepRow(ctx, s, s.transactions, credit.ID)
+		credit.Balance = float64(remaining) / 100
+		credit.UpdatedAt = now
+	}
+	return discharges, nil
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// GetChainHead retrieves the hash of the latest chained transaction of the account; it returns
+// pgx.ErrNoRows when the account's chain has not started
+func (r *transactionsRepo) GetChainHead(ctx context.Context, accountID int64) (string, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var head *repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.AccountID == accountID && txn.Hash != "" && (head == nil || txn.ID > head.ID) {
+			head = txn
+		}
+	}
+	if head == nil {
+		return "", pgx.ErrNoRows
+	}
+	return head.Hash, nil
+}
+
+// SetTransactionHash links a transaction into its account's chain; a transaction is linked only once
+func (r *transactionsRepo) SetTransactionHash(ctx context.Context, transactionID int64, prevHash, hash string) error {
+	s := r.store
+	defer s.lock(ctx)()
+
+	txn, ok := s.transactions[transactionID]
+	if !ok || txn.Hash != "" {
+		return fmt.Errorf("failed to set transaction hash: transaction %d does not exist or is already chained", transactionID)
+	}
+	for _, other := range s.transactions {
+		if other.AccountID == txn.AccountID && other.Hash != "" && other.PrevHash == prevHash {
+			return fmt.Errorf("failed to set transaction hash: %w", uniqueViolation("transactions", "idx_transactions_chain_link"))
+		}
+	}
+
+	keepRow(ctx, s, s.transactions, transactionID)
+	txn.PrevHash = prevHash
+	txn.Hash = hash
+	return nil
+}
+
+// GetTransactionChain retrieves every transaction of the account with the fields its hash covers, in
+// posting order
+func (r *transactionsRepo) GetTransactionChain(ctx context.Context, accountID int64) ([]*repository.Transaction, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	var transactions []*repository.Transaction
+	for _, txn := range s.transactions {
+		if txn.AccountID == accountID {
+			out := *txn
+			transactions = append(transactions, &out)
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID < transactions[j].ID })
+	return transactions, nil
+}
+
+// GetChainedAccountIDs retrieves the ids of the accounts whose chain has started, in id order
+func (r *transactionsRepo) GetChainedAccountIDs(ctx context.Context) ([]int64, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	seen := make(map[int64]bool)
+	var accountIDs []int64
+	for _, txn := range s.transactions {
+		if txn.Hash != "" && !seen[txn.AccountID] {
+			seen[txn.AccountID] = true
+			accountIDs = append(accountIDs, txn.AccountID)
+		}
+	}
+	sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+	return accountIDs, nil
+}
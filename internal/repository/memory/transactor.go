@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+type transactor struct {
+	store *Store
+}
+
+// NewTransactor returns a Transactor whose units of work hold the store exclusively and
+// are rolled back by undoing the rows they wrote
+func NewTransactor(store *Store) repository.Transactor {
+	return &transactor{store: store}
+}
+
+// WithinTransaction runs fn atomically; calls nested in an existing unit of work join it
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	s := t.store
+	if owner, _ := ctx.Value(txKey{}).(*Store); owner == s {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The copy shares the maps, whose rows the undo log restores; it keeps the sequences and the audit log
+	saved := s.tables
+	defer func() { s.undo = nil }()
+	if err := fn(context.WithValue(ctx, txKey{}, s)); err != nil {
+		for i := len(s.undo) - 1; i >= 0; i-- {
+			s.undo[i]()
+		}
+		s.tables = saved
+		return err
+	}
+	return nil
+}
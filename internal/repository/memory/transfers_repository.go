@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type transfersRepo struct {
+	store *Store
+}
+
+func NewTransfersRepository(store *Store) repository.TransfersRepository {
+	return &transfersRepo{store: store}
+}
+
+// InsertTransfer inserts a new transfer; its legs are inserted separately
+func (r *transfersRepo) InsertTransfer(ctx context.Context, sourceAccountID, destinationAccountID int64, amount float64) (*repository.Transfer, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	if _, ok := s.accounts[sourceAccountID]; !ok {
+		return nil, foreignKeyViolation("transfers", "transfers_source_account_id_fkey")
+	}
+	if _, ok := s.accounts[destinationAccountID]; !ok {
+		return nil, foreignKeyViolation("transfers", "transfers_destination_account_id_fkey")
+	}
+
+	s.transferSeq++
+	transfer := &repository.Transfer{
+		ID:                   s.transferSeq,
+		SourceAccountID:      sourceAccountID,
+		DestinationAccountID: destinationAccountID,
+		Amount:               amount,
+		CorrelationID:        middleware.GetCorrelationIDFromContext(ctx),
+		CreatedAt:            s.now(),
+	}
+	keepRow(ctx, s, s.transfers, transfer.ID)
+	s.transfers[transfer.ID] = transfer
+
+	out := *transfer
+	return &out, nil
+}
+
+// GetTransferByID retrieves a transfer by transferID
+func (r *transfersRepo) GetTransferByID(ctx context.Context, transferID int64) (*repository.Transfer, error) {
+	s := r.store
+	defer s.lock(ctx)()
+
+	transfer, ok := s.transfers[transferID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	out := *transfer
+	return &out, nil
+}
@@ -0,0 +1,167 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/migration"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/repositorytest"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// testDBDSNEnv points the Postgres contract tests at a disposable database; they are skipped when unset
+const testDBDSNEnv = "TEST_DB_DSN"
+
+// appendOnlyTables are the tables whose triggers turn TRUNCATE down
+var appendOnlyTables = []string{"audit_events", "journal_entries", "postings"}
+
+// resetTables empties the tables, and those referencing them, for a test to start from scratch. The guards of
+// the append-only tables are lifted for the TRUNCATE only, within a unit of work no other session sees.
+func resetTables(ctx context.Context, pool *pgxpool.Pool, tables string) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for _, table := range appendOnlyTables {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %[1]s DISABLE TRIGGER %[1]s_no_truncate`, table)); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `TRUNCATE `+tables+` RESTART IDENTITY CASCADE`); err != nil {
+		return err
+	}
+	for _, table := range appendOnlyTables {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %[1]s ENABLE TRIGGER %[1]s_no_truncate`, table)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func TestPostgresRepositoriesContract(t *testing.T) {
+	dsn := os.Getenv(testDBDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set, skipping Postgres contract tests", testDBDSNEnv)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	provider, err := migration.NewProvider(migration.OpenDB(pool))
+	require.NoError(t, err)
+	_, err = provider.Up(ctx)
+	require.NoError(t, err)
+
+	t.Run("Audit events are append-only", func(t *testing.T) {
+		require.NoError(t, resetTables(ctx, pool, `audit_events`))
+		_, err := repository.NewAuditRepository(pool).InsertAuditEvent(ctx, &repository.AuditEvent{Action: "config.updated", EntityType: "config"})
+		require.NoError(t, err)
+
+		_, err = pool.Exec(ctx, `UPDATE audit_events SET action = 'tampered'`)
+		require.ErrorContains(t, err, "append-only")
+		_, err = pool.Exec(ctx, `DELETE FROM audit_events`)
+		require.ErrorContains(t, err, "append-only")
+		_, err = pool.Exec(ctx, `TRUNCATE audit_events`)
+		require.ErrorContains(t, err, "append-only")
+	})
+
+	t.Run("Ledger tables cannot be truncated", func(t *testing.T) {
+		for _, table := range []string{"journal_entries", "postings"} {
+			_, err := pool.Exec(ctx, `TRUNCATE `+table+` CASCADE`)
+			require.ErrorContains(t, err, "append-only")
+		}
+		_, err := pool.Exec(ctx, `TRUNCATE accounts CASCADE`)
+		require.ErrorContains(t, err, "append-only")
+	})
+
+	t.Run("Requests claimed by a unit of work are skipped by the others", func(t *testing.T) {
+		require.NoError(t, resetTables(ctx, pool, `customers, accounts, transaction_requests`))
+		customers := repository.NewCustomersRepository(pool)
+		accounts := repository.NewAccountsRepository(pool)
+		requests := repository.NewTransactionRequestsRepository(pool)
+		transactor := repository.NewTransactor(pool)
+
+		var requestIDs []int64
+		for _, document := range []string{"1", "2"} {
+			customer, err := customers.InsertCustomer(ctx, &repository.Customer{DocumentNumber: document})
+			require.NoError(t, err)
+			account, err := accounts.InsertAccount(ctx, customer.ID, "credit")
+			require.NoError(t, err)
+			for range 2 {
+				request, err := requests.InsertTransactionRequest(ctx, &repository.TransactionRequest{AccountID: account.ID, OperationTypeID: 1, Amount: 10})
+				require.NoError(t, err)
+				requestIDs = append(requestIDs, request.ID)
+			}
+		}
+
+		err = transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+			claimed, err := requests.ClaimTransactionRequest(txCtx)
+			require.NoError(t, err)
+			require.Equal(t, requestIDs[0], claimed.ID)
+
+			// The first account's later request waits for the claimed one; the second account's is free
+			return transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				claimed, err := requests.ClaimTransactionRequest(ctx)
+				require.NoError(t, err)
+				require.Equal(t, requestIDs[2], claimed.ID)
+				return nil
+			})
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Stream bounds do not depend on the session time zone", func(t *testing.T) {
+		require.NoError(t, resetTables(ctx, pool, `customers, accounts, transactions`))
+		config, err := pgxpool.ParseConfig(dsn)
+		require.NoError(t, err)
+		config.ConnConfig.RuntimeParams["timezone"] = "America/New_York"
+		nyPool, err := pgxpool.NewWithConfig(ctx, config)
+		require.NoError(t, err)
+		t.Cleanup(nyPool.Close)
+
+		customer, err := repository.NewCustomersRepository(nyPool).InsertCustomer(ctx, &repository.Customer{DocumentNumber: "1"})
+		require.NoError(t, err)
+		account, err := repository.NewAccountsRepository(nyPool).InsertAccount(ctx, customer.ID, "credit")
+		require.NoError(t, err)
+		transactions := repository.NewTransactionsRepository(nyPool)
+		eventDate := time.Date(2025, 3, 31, 22, 0, 0, 0, time.UTC)
+		txn, err := transactions.InsertTransaction(ctx, account.ID, 1, -10, -10, eventDate, eventDate, "", 0)
+		require.NoError(t, err)
+
+		var ids []int64
+		err = transactions.StreamTransactionsByAccountID(ctx, account.ID, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), func(txn *repository.Transaction) error {
+			ids = append(ids, txn.ID)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []int64{txn.ID}, ids)
+	})
+
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		require.NoError(t, resetTables(ctx, pool, `customers, accounts, transactions, transfers, ledger_accounts, journal_entries, postings, billing_cycles, statements, statement_lines, accrual_runs, accruals, screening_evaluations, audit_events, transaction_requests`))
+
+		return repositorytest.Repositories{
+			Customers:    repository.NewCustomersRepository(pool),
+			Accounts:     repository.NewAccountsRepository(pool),
+			Transactions: repository.NewTransactionsRepository(pool),
+			Transfers:    repository.NewTransfersRepository(pool),
+			Ledger:       repository.NewLedgerRepository(pool),
+			Billing:      repository.NewBillingRepository(pool),
+			Accruals:     repository.NewAccrualsRepository(pool),
+			Fees:         repository.NewFeesRepository(pool),
+			Screening:    repository.NewScreeningRepository(pool),
+			Audit:        repository.NewAuditRepository(pool),
+			Requests:     repository.NewTransactionRequestsRepository(pool),
+			Transactor:   repository.NewTransactor(pool),
+		}
+	})
+}
@@ -0,0 +1,1829 @@
+// Package repositorytest holds the behavioral contract every repository backend must satisfy.
+// Backends run it from their own tests with a factory that returns empty, isolated repositories.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Repositories groups the repositories of one backend that share the same underlying data
+type Repositories struct {
+	Customers    repository.CustomersRepository
+	Accounts     repository.AccountsRepository
+	Transactions repository.TransactionsRepository
+	Transfers    repository.TransfersRepository
+	Ledger       repository.LedgerRepository
+	Billing      repository.BillingRepository
+	Accruals     repository.AccrualsRepository
+	Fees         repository.FeesRepository
+	Screening    repository.ScreeningRepository
+	Audit        repository.AuditRepository
+	Requests     repository.TransactionRequestsRepository
+	Transactor   repository.Transactor
+}
+
+// Factory returns repositories over an empty data set
+type Factory func(t *testing.T) Repositories
+
+// Run executes the whole contract against the backend produced by newRepos
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("Customers", func(t *testing.T) { testCustomers(t, newRepos) })
+	t.Run("Accounts", func(t *testing.T) { testAccounts(t, newRepos) })
+	t.Run("Transactions", func(t *testing.T) { testTransactions(t, newRepos) })
+	t.Run("Transfers", func(t *testing.T) { testTransfers(t, newRepos) })
+	t.Run("Ledger", func(t *testing.T) { testLedger(t, newRepos) })
+	t.Run("Billing", func(t *testing.T) { testBilling(t, newRepos) })
+	t.Run("Accruals", func(t *testing.T) { testAccruals(t, newRepos) })
+	t.Run("Fees", func(t *testing.T) { testFees(t, newRepos) })
+	t.Run("Screening", func(t *testing.T) { testScreening(t, newRepos) })
+	t.Run("Audit", func(t *testing.T) { testAudit(t, newRepos) })
+	t.Run("TransactionRequests", func(t *testing.T) { testTransactionRequests(t, newRepos) })
+	t.Run("Transactor", func(t *testing.T) { testTransactor(t, newRepos) })
+}
+
+func testCustomers(t *testing.T, newRepos Factory) {
+	t.Run("Insert and get customer", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithCorrelationID(context.Background(), "flow-1")
+
+		created, err := repos.Customers.InsertCustomer(ctx, &repository.Customer{
+			DocumentNumber: "12345678900",
+			Name:           "Maria Silva",
+			BirthDate:      "1990-05-17",
+			Email:          "maria@example.com",
+		})
+		require.NoError(t, err)
+		assert.Positive(t, created.ID)
+		assert.Equal(t, "12345678900", created.DocumentNumber)
+		assert.Equal(t, "Maria Silva", created.Name)
+		assert.Equal(t, "1990-05-17", created.BirthDate)
+		assert.Equal(t, "maria@example.com", created.Email)
+		assert.Empty(t, created.Phone)
+		assert.Equal(t, "flow-1", created.CorrelationID)
+
+		byID, err := repos.Customers.GetCustomerByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, byID.ID)
+		assert.Equal(t, created.BirthDate, byID.BirthDate)
+
+		byDocument, err := repos.Customers.GetCustomerByDocumentNumber(ctx, "12345678900")
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, byDocument.ID)
+	})
+
+	t.Run("Duplicate document number violates unique constraint", func(t *testing.T) {
+		repos := newRepos(t)
+		mustInsertCustomer(t, repos, "12345678900")
+
+		_, err := repos.Customers.InsertCustomer(context.Background(), &repository.Customer{DocumentNumber: "12345678900"})
+		assertPgError(t, err, "23505", "customers_document_number_key")
+	})
+
+	t.Run("Missing customer returns no rows", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+
+		_, err := repos.Customers.GetCustomerByID(ctx, 999)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+		_, err = repos.Customers.GetCustomerByDocumentNumber(ctx, "000")
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("Concurrent inserts of the same document number create one customer", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+
+		const attempts = 10
+		var wg sync.WaitGroup
+		errs := make(chan error, attempts)
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repos.Customers.InsertCustomer(ctx, &repository.Customer{DocumentNumber: "98765432100"})
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+}
+
+func testAccounts(t *testing.T, newRepos Factory) {
+	t.Run("Insert and get account", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithCorrelationID(context.Background(), "flow-1")
+		customer := mustInsertCustomer(t, repos, "12345678900")
+
+		created, err := repos.Accounts.InsertAccount(ctx, customer.ID, "credit")
+		require.NoError(t, err)
+		assert.Positive(t, created.ID)
+		assert.Equal(t, customer.ID, created.CustomerID)
+		assert.Equal(t, "12345678900", created.DocumentNumber)
+		assert.Equal(t, "credit", created.Product)
+		assert.Equal(t, "flow-1", created.CorrelationID)
+
+		fetched, err := repos.Accounts.GetAccountByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, fetched.ID)
+		assert.Equal(t, created.CustomerID, fetched.CustomerID)
+		assert.Equal(t, created.DocumentNumber, fetched.DocumentNumber)
+		assert.Equal(t, created.Product, fetched.Product)
+		assert.Equal(t, "active", fetched.Status)
+	})
+
+	t.Run("Update account status", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+
+		updated, err := repos.Accounts.UpdateAccountStatus(ctx, account.ID, "blocked")
+		require.NoError(t, err)
+		assert.Equal(t, "blocked", updated.Status)
+		assert.Equal(t, "1", updated.DocumentNumber)
+
+		_, err = repos.Accounts.UpdateAccountStatus(ctx, 999, "blocked")
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("Lock accounts returns the accounts found in id order", func(t *testing.T) {
+		repos := newRepos(t)
+		first := mustInsertAccount(t, repos, "1")
+		second := mustInsertAccount(t, repos, "2")
+
+		err := repos.Transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			locked, err := repos.Accounts.LockAccounts(ctx, second.ID, 999, first.ID)
+			require.NoError(t, err)
+			require.Len(t, locked, 2)
+			assert.Equal(t, first.ID, locked[0].ID)
+			assert.Equal(t, second.ID, locked[1].ID)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("A customer holds one account per product", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		customer := mustInsertCustomer(t, repos, "12345678900")
+
+		_, err := repos.Accounts.InsertAccount(ctx, customer.ID, "credit")
+		require.NoError(t, err)
+		_, err = repos.Accounts.InsertAccount(ctx, customer.ID, "prepaid")
+		require.NoError(t, err)
+
+		_, err = repos.Accounts.InsertAccount(ctx, customer.ID, "credit")
+		assertPgError(t, err, "23505", "accounts_customer_id_product_key")
+	})
+
+	t.Run("Unknown customer violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Accounts.InsertAccount(context.Background(), 999, "credit")
+		assertPgError(t, err, "23503", "accounts_customer_id_fkey")
+	})
+
+	t.Run("Missing account returns no rows", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Accounts.GetAccountByID(context.Background(), 999)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("Accounts of a customer, oldest first", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		customer := mustInsertCustomer(t, repos, "1")
+		other := mustInsertCustomer(t, repos, "2")
+
+		credit, err := repos.Accounts.InsertAccount(ctx, customer.ID, "credit")
+		require.NoError(t, err)
+		_, err = repos.Accounts.InsertAccount(ctx, other.ID, "credit")
+		require.NoError(t, err)
+		prepaid, err := repos.Accounts.InsertAccount(ctx, customer.ID, "prepaid")
+		require.NoError(t, err)
+
+		accounts, err := repos.Accounts.GetAccountsByCustomerID(ctx, customer.ID)
+		require.NoError(t, err)
+		require.Len(t, accounts, 2)
+		assert.Equal(t, credit.ID, accounts[0].ID)
+		assert.Equal(t, prepaid.ID, accounts[1].ID)
+		assert.Equal(t, "1", accounts[1].DocumentNumber)
+
+		none, err := repos.Accounts.GetAccountsByCustomerID(ctx, 999)
+		require.NoError(t, err)
+		assert.Empty(t, none)
+	})
+}
+
+func testTransactions(t *testing.T, newRepos Factory) {
+	t.Run("Insert transaction", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		txn, err := repos.Transactions.InsertTransaction(ctx, account.ID, 1, -50.25, -50.25, eventDate, eventDate, "", 0)
+		require.NoError(t, err)
+		assert.Positive(t, txn.ID)
+		assert.Equal(t, account.ID, txn.AccountID)
+		assert.Equal(t, int64(1), txn.OperationTypeID)
+		assert.Equal(t, -50.25, txn.Amount)
+		assert.Equal(t, -50.25, txn.Balance)
+		assert.True(t, eventDate.Equal(txn.EventDate))
+		assert.True(t, eventDate.Equal(txn.BookingDate))
+	})
+
+	t.Run("Back-dated transaction is booked later than it took place", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+		bookingDate := eventDate.Add(48 * time.Hour)
+
+		txn, err := repos.Transactions.InsertTransaction(ctx, account.ID, 1, -50.25, -50.25, eventDate, bookingDate, "", 0)
+		require.NoError(t, err)
+		assert.True(t, bookingDate.Equal(txn.BookingDate))
+
+		got, err := repos.Transactions.GetTransactionByID(ctx, txn.ID)
+		require.NoError(t, err)
+		assert.True(t, eventDate.Equal(got.EventDate))
+		assert.True(t, bookingDate.Equal(got.BookingDate))
+	})
+
+	t.Run("Unknown account violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Transactions.InsertTransaction(context.Background(), 999, 1, -10, -10, time.Now().UTC(), time.Now().UTC(), "", 0)
+		assertPgError(t, err, "23503", "transactions_account_id_fkey")
+	})
+
+	t.Run("Unknown operation type violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+		account := mustInsertAccount(t, repos, "1")
+
+		_, err := repos.Transactions.InsertTransaction(context.Background(), account.ID, 99, -10, -10, time.Now().UTC(), time.Now().UTC(), "", 0)
+		assertPgError(t, err, "23503", "transactions_operation_type_id_fkey")
+	})
+
+	t.Run("Insert flagged duplicate with merchant reference", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		original, err := repos.Transactions.InsertTransaction(ctx, account.ID, 1, -20, -20, time.Now().UTC(), time.Now().UTC(), "ref-1", 0)
+		require.NoError(t, err)
+
+		duplicate, err := repos.Transactions.InsertTransaction(ctx, account.ID, 1, -20, -20, time.Now().UTC(), time.Now().UTC(), "ref-1", original.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "ref-1", duplicate.MerchantReference)
+		assert.Equal(t, original.ID, duplicate.DuplicateOfID)
+
+		got, err := repos.Transactions.GetTransactionByID(ctx, duplicate.ID)
+		require.NoError(t, err)
+		assert.Equal(t, account.ID, got.AccountID)
+		assert.Equal(t, int64(1), got.OperationTypeID)
+		assert.Equal(t, -20.0, got.Amount)
+		assert.Equal(t, "ref-1", got.MerchantReference)
+		assert.Equal(t, original.ID, got.DuplicateOfID)
+	})
+
+	t.Run("Unknown duplicated transaction violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+		account := mustInsertAccount(t, repos, "1")
+
+		_, err := repos.Transactions.InsertTransaction(context.Background(), account.ID, 1, -10, -10, time.Now().UTC(), time.Now().UTC(), "", 999)
+		assertPgError(t, err, "23503", "transactions_duplicate_of_transaction_id_fkey")
+	})
+
+	t.Run("Insert transactions in one batch", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		batch := []*repository.Transaction{
+			{AccountID: account.ID, OperationTypeID: 1, Amount: -50.25, Balance: -50.25, EventDate: eventDate, MerchantReference: "ref-1"},
+			{AccountID: account.ID, OperationTypeID: 4, Amount: 60, Balance: 60, EventDate: eventDate.Add(time.Second)},
+		}
+		require.NoError(t, repos.Transactions.InsertTransactions(ctx, batch))
+		assert.Positive(t, batch[0].ID)
+		assert.Greater(t, batch[1].ID, batch[0].ID)
+
+		got, err := repos.Transactions.GetTransactionByID(ctx, batch[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), got.OperationTypeID)
+		assert.Equal(t, -50.25, got.Amount)
+		assert.Equal(t, "ref-1", got.MerchantReference)
+		assert.True(t, eventDate.Equal(got.EventDate))
+
+		flagged := []*repository.Transaction{{AccountID: account.ID, OperationTypeID: 1, Amount: -50.25, Balance: -50.25, EventDate: eventDate, DuplicateOfID: batch[0].ID}}
+		require.NoError(t, repos.Transactions.InsertTransactions(ctx, flagged))
+		got, err = repos.Transactions.GetTransactionByID(ctx, flagged[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, batch[0].ID, got.DuplicateOfID)
+	})
+
+	t.Run("A failed batch inserts nothing", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+
+		err := repos.Transactions.InsertTransactions(ctx, []*repository.Transaction{
+			{AccountID: account.ID, OperationTypeID: 1, Amount: -10, Balance: -10, EventDate: time.Now().UTC()},
+			{AccountID: account.ID, OperationTypeID: 99, Amount: -10, Balance: -10, EventDate: time.Now().UTC()},
+		})
+		assertPgError(t, err, "23503", "transactions_operation_type_id_fkey")
+
+		transactions, err := repos.Transactions.GetTransactionsByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Empty(t, transactions)
+	})
+
+	t.Run("Unknown transaction is not found", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Transactions.GetTransactionByID(context.Background(), 999)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("Duplicate is the latest match within the window", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		other := mustInsertAccount(t, repos, "2")
+		now := time.Date(2025, 4, 15, 12, 0, 0, 0, time.UTC)
+		insert := func(accountID, operationTypeID int64, amount float64, at time.Time, merchantReference string) *repository.Transaction {
+			txn, err := repos.Transactions.InsertTransaction(ctx, accountID, operationTypeID, amount, amount, at, at, merchantReference, 0)
+			require.NoError(t, err)
+			return txn
+		}
+
+		insert(account.ID, 1, -20, now.Add(-time.Hour), "ref-1")
+		latest := insert(account.ID, 1, -20, now.Add(-time.Minute), "ref-2")
+		insert(account.ID, 2, -20, now, "")
+		insert(account.ID, 1, -21, now, "")
+		insert(other.ID, 1, -20, now, "")
+
+		duplicate, err := repos.Transactions.GetDuplicateTransaction(ctx, account.ID, 1, -20, "", now.Add(-2*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, latest.ID, duplicate.ID)
+		assert.Equal(t, "ref-2", duplicate.MerchantReference)
+
+		byReference, err := repos.Transactions.GetDuplicateTransaction(ctx, account.ID, 1, -20, "ref-1", now.Add(-2*time.Hour))
+		require.NoError(t, err)
+		assert.NotEqual(t, latest.ID, byReference.ID)
+		assert.Equal(t, "ref-1", byReference.MerchantReference)
+
+		_, err = repos.Transactions.GetDuplicateTransaction(ctx, account.ID, 1, -20, "ref-1", now.Add(-30*time.Minute))
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		_, err = repos.Transactions.GetDuplicateTransaction(ctx, account.ID, 1, -20, "ref-3", now.Add(-2*time.Hour))
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("Insert fee transaction", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		parent := mustInsertTransaction(t, repos, account.ID, 3, -400)
+
+		fee, err := repos.Transactions.InsertFeeTransaction(ctx, parent.ID, account.ID, 9, -12, -12, parent.EventDate)
+		require.NoError(t, err)
+		assert.Positive(t, fee.ID)
+		assert.Equal(t, parent.ID, fee.ParentTransactionID)
+		assert.Equal(t, int64(9), fee.OperationTypeID)
+		assert.True(t, parent.EventDate.Equal(fee.EventDate))
+		assert.True(t, parent.BookingDate.Equal(fee.BookingDate))
+
+		transactions, err := repos.Transactions.GetTransactionsByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, transactions, 2)
+		assert.Zero(t, transactions[0].ParentTransactionID)
+		assert.Equal(t, parent.ID, transactions[1].ParentTransactionID)
+	})
+
+	t.Run("Unknown parent violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+		account := mustInsertAccount(t, repos, "1")
+
+		_, err := repos.Transactions.InsertFeeTransaction(context.Background(), 999, account.ID, 9, -12, -12, time.Now().UTC())
+		assertPgError(t, err, "23503", "transactions_parent_transaction_id_fkey")
+	})
+
+	t.Run("Only fees have a parent", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		parent := mustInsertTransaction(t, repos, account.ID, 3, -400)
+
+		_, err := repos.Transactions.InsertFeeTransaction(ctx, parent.ID, account.ID, 1, -12, -12, time.Now().UTC())
+		assertPgError(t, err, "23514", "transactions_parent_transaction_id_check")
+		_, err = repos.Transactions.InsertTransaction(ctx, account.ID, 9, -12, -12, time.Now().UTC(), time.Now().UTC(), "", 0)
+		assertPgError(t, err, "23514", "transactions_parent_transaction_id_check")
+	})
+
+	t.Run("Outstanding transactions are unpaid debts of the account, oldest first", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		other := mustInsertAccount(t, repos, "2")
+
+		first := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		second := mustInsertTransaction(t, repos, account.ID, 3, -20)
+		paid := mustInsertTransaction(t, repos, account.ID, 2, -30)
+		mustInsertTransaction(t, repos, account.ID, 4, 40)
+		mustInsertTransaction(t, repos, other.ID, 1, -50)
+		require.NoError(t, repos.Transactions.UpdateTransactionBalance(ctx, paid.ID, 0))
+		transfer, err := repos.Transfers.InsertTransfer(ctx, account.ID, other.ID, 5)
+		require.NoError(t, err)
+		debit, err := repos.Transactions.InsertTransferLeg(ctx, transfer.ID, account.ID, 5, -5, -5, time.Now().UTC())
+		require.NoError(t, err)
+		_, err = repos.Transactions.InsertTransferLeg(ctx, transfer.ID, other.ID, 6, 5, 5, time.Now().UTC())
+		require.NoError(t, err)
+		interest := mustInsertTransaction(t, repos, account.ID, 7, -1.5)
+		fee, err := repos.Transactions.InsertFeeTransaction(ctx, second.ID, account.ID, 9, -5, -5, time.Now().UTC())
+		require.NoError(t, err)
+
+		outstanding, err := repos.Transactions.GetOutstandingTransactionsByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, outstanding, 5)
+		assert.Equal(t, first.ID, outstanding[0].ID)
+		assert.Equal(t, second.ID, outstanding[1].ID)
+		assert.Equal(t, int64(3), outstanding[1].OperationTypeID)
+		assert.Equal(t, -20.0, outstanding[1].Balance)
+		assert.Equal(t, debit.ID, outstanding[2].ID)
+		assert.Equal(t, interest.ID, outstanding[3].ID)
+		assert.Equal(t, int64(7), outstanding[3].OperationTypeID)
+		assert.Equal(t, fee.ID, outstanding[4].ID)
+	})
+
+	t.Run("Transactions of an account, oldest first", func(t *testing.T) {
+		repos := newRepos(t)
+		account := mustInsertAccount(t, repos, "1")
+		other := mustInsertAccount(t, repos, "2")
+
+		first := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		mustInsertTransaction(t, repos, other.ID, 1, -20)
+		second := mustInsertTransaction(t, repos, account.ID, 4, 30)
+
+		transactions, err := repos.Transactions.GetTransactionsByAccountID(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Len(t, transactions, 2)
+		assert.Equal(t, first.ID, transactions[0].ID)
+		assert.Equal(t, second.ID, transactions[1].ID)
+		assert.Equal(t, 30.0, transactions[1].Balance)
+	})
+
+	t.Run("Transactions of an account in a period", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		txn := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		from, to := txn.EventDate.Add(-time.Hour), txn.EventDate.Add(time.Hour)
+
+		transactions, err := repos.Transactions.GetTransactionsByAccountIDInPeriod(ctx, account.ID, from, to)
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		assert.Equal(t, txn.ID, transactions[0].ID)
+
+		transactions, err = repos.Transactions.GetTransactionsByAccountIDInPeriod(ctx, account.ID, to, to.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, transactions)
+	})
+
+	t.Run("Stream transactions of an account in a period", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		other := mustInsertAccount(t, repos, "2")
+		start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+		_, err := repos.Transactions.InsertTransaction(ctx, account.ID, 1, -10, -10, start.Add(-time.Hour), start.Add(-time.Hour), "", 0)
+		require.NoError(t, err)
+		later, err := repos.Transactions.InsertTransaction(ctx, account.ID, 4, 30, 30, start.Add(2*time.Hour), start.Add(2*time.Hour), "", 0)
+		require.NoError(t, err)
+		earlier, err := repos.Transactions.InsertTransaction(ctx, account.ID, 1, -20.5, -20.5, start.Add(time.Hour), start.Add(time.Hour), "ref-1", 0)
+		require.NoError(t, err)
+		_, err = repos.Transactions.InsertTransaction(ctx, other.ID, 1, -5, -5, start.Add(time.Hour), start.Add(time.Hour), "", 0)
+		require.NoError(t, err)
+
+		var streamed []*repository.Transaction
+		collect := func(txn *repository.Transaction) error {
+			streamed = append(streamed, txn)
+			return nil
+		}
+		require.NoError(t, repos.Transactions.StreamTransactionsByAccountID(ctx, account.ID, start, start.Add(24*time.Hour), collect))
+		require.Len(t, streamed, 2)
+		assert.Equal(t, earlier.ID, streamed[0].ID)
+		assert.Equal(t, "Normal Purchase", streamed[0].OperationType)
+		assert.Equal(t, -20.5, streamed[0].Amount)
+		assert.Equal(t, "ref-1", streamed[0].MerchantReference)
+		assert.Equal(t, later.ID, streamed[1].ID)
+		assert.Equal(t, "Credit Voucher", streamed[1].OperationType)
+
+		streamed = nil
+		require.NoError(t, repos.Transactions.StreamTransactionsByAccountID(ctx, account.ID, time.Time{}, time.Time{}, collect))
+		assert.Len(t, streamed, 3)
+
+		stop := errors.New("stop")
+		calls := 0
+		err = repos.Transactions.StreamTransactionsByAccountID(ctx, account.ID, time.Time{}, time.Time{}, func(*repository.Transaction) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Net amount of an account before a date", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		other := mustInsertAccount(t, repos, "2")
+		start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+		for _, txn := range []struct {
+			accountID       int64
+			operationTypeID int64
+			amount          float64
+			eventDate       time.Time
+		}{
+			{account.ID, 1, -10.25, start.Add(-2 * time.Hour)},
+			{account.ID, 4, 30, start.Add(-time.Hour)},
+			{account.ID, 1, -7, start},
+			{other.ID, 1, -5, start.Add(-time.Hour)},
+		} {
+			_, err := repos.Transactions.InsertTransaction(ctx, txn.accountID, txn.operationTypeID, txn.amount, txn.amount, txn.eventDate, txn.eventDate, "", 0)
+			require.NoError(t, err)
+		}
+
+		net, err := repos.Transactions.GetNetAmountByAccountIDBefore(ctx, account.ID, start)
+		require.NoError(t, err)
+		assert.InDelta(t, 19.75, net, 0.001)
+
+		net, err = repos.Transactions.GetNetAmountByAccountIDBefore(ctx, account.ID, start.Add(-2*time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, net)
+	})
+
+	t.Run("Transactions of an account since a date", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		now := time.Now().UTC()
+
+		_, err := repos.Transactions.InsertTransaction(ctx, account.ID, 1, -10, -10, now.Add(-2*time.Hour), now.Add(-2*time.Hour), "", 0)
+		require.NoError(t, err)
+		recent, err := repos.Transactions.InsertTransaction(ctx, account.ID, 3, -20, -20, now.Add(-time.Hour), now.Add(-time.Hour), "", 0)
+		require.NoError(t, err)
+
+		transactions, err := repos.Transactions.GetTransactionsByAccountIDSince(ctx, account.ID, recent.EventDate)
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		assert.Equal(t, recent.ID, transactions[0].ID)
+	})
+
+	t.Run("Update balance", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		txn := mustInsertTransaction(t, repos, account.ID, 1, -10)
+
+		require.NoError(t, repos.Transactions.UpdateTransactionBalance(ctx, txn.ID, -4))
+
+		outstanding, err := repos.Transactions.GetOutstandingTransactionsByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, outstanding, 1)
+		assert.Equal(t, -4.0, outstanding[0].Balance)
+	})
+
+	t.Run("Update balance of missing transaction fails", func(t *testing.T) {
+		repos := newRepos(t)
+
+		err := repos.Transactions.UpdateTransactionBalance(context.Background(), 999, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("Update balances in one go", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		first := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		second := mustInsertTransaction(t, repos, account.ID, 3, -20.5)
+		payment := mustInsertTransaction(t, repos, account.ID, 4, 30)
+
+		require.NoError(t, repos.Transactions.UpdateTransactionBalances(ctx, map[int64]float64{first.ID: 0, second.ID: -0.5, payment.ID: 0}))
+
+		outstanding, err := repos.Transactions.GetOutstandingTransactionsByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, outstanding, 1)
+		assert.Equal(t, second.ID, outstanding[0].ID)
+		assert.Equal(t, -0.5, outstanding[0].Balance)
+		credit, err := repos.Transactions.GetTransactionByID(ctx, payment.ID)
+		require.NoError(t, err)
+		assert.Zero(t, credit.Balance)
+	})
+
+	t.Run("Update balances of missing transaction fails within its unit of work", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		txn := mustInsertTransaction(t, repos, account.ID, 1, -10)
+
+		err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			return repos.Transactions.UpdateTransactionBalances(ctx, map[int64]float64{txn.ID: 0, 999: 0})
+		})
+		assert.Error(t, err)
+
+		got, err := repos.Transactions.GetTransactionByID(ctx, txn.ID)
+		require.NoError(t, err)
+		assert.Equal(t, -10.0, got.Balance)
+	})
+
+	t.Run("Discharge applies the credit to outstanding debts, oldest first", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		other := mustInsertAccount(t, repos, "2")
+
+		first := mustInsertTransaction(t, repos, account.ID, 1, -10.1)
+		second := mustInsertTransaction(t, repos, account.ID, 3, -20.2)
+		third := mustInsertTransaction(t, repos, account.ID, 1, -30)
+		fourth := mustInsertTransaction(t, repos, account.ID, 2, -5)
+		mustInsertTransaction(t, repos, other.ID, 1, -50)
+		payment := mustInsertTransaction(t, repos, account.ID, 4, 35.3)
+
+		discharges, err := repos.Transactions.DischargeTransactions(ctx, payment)
+		require.NoError(t, err)
+		assert.Equal(t, []*repository.Discharge{
+			{TransactionID: first.ID, Balance: -10.1, Amount: 10.1},
+			{TransactionID: second.ID, Balance: -20.2, Amount: 20.2},
+			{TransactionID: third.ID, Balance: -30, Amount: 5},
+		}, discharges)
+
+		outstanding, err := repos.Transactions.GetOutstandingTransactionsByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, outstanding, 2)
+		assert.Equal(t, third.ID, outstanding[0].ID)
+		assert.Equal(t, -25.0, outstanding[0].Balance)
+		assert.Equal(t, fourth.ID, outstanding[1].ID)
+
+		credit, err := repos.Transactions.GetTransactionByID(ctx, payment.ID)
+		require.NoError(t, err)
+		assert.Zero(t, credit.Balance)
+
+		debt, err := repos.Transactions.GetTransactionByID(ctx, first.ID)
+		require.NoError(t, err)
+		assert.Zero(t, debt.Balance)
+	})
+
+	t.Run("Discharge leaves what the debts do not take on the credit", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+
+		debt := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		payment := mustInsertTransaction(t, repos, account.ID, 4, 25.5)
+
+		discharges, err := repos.Transactions.DischargeTransactions(ctx, payment)
+		require.NoError(t, err)
+		assert.Equal(t, []*repository.Discharge{{TransactionID: debt.ID, Balance: -10, Amount: 10}}, discharges)
+
+		credit, err := repos.Transactions.GetTransactionByID(ctx, payment.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 15.5, credit.Balance)
+
+		discharges, err = repos.Transactions.DischargeTransactions(ctx, credit)
+		require.NoError(t, err)
+		assert.Empty(t, discharges)
+
+		credit, err = repos.Transactions.GetTransactionByID(ctx, payment.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 15.5, credit.Balance)
+	})
+
+	t.Run("Chain links transactions of an account", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		other := mustInsertAccount(t, repos, "2")
+		unchained := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		first := mustInsertTransaction(t, repos, account.ID, 1, -20)
+		second := mustInsertTransaction(t, repos, account.ID, 4, 30)
+		mustInsertTransaction(t, repos, other.ID, 1, -5)
+
+		_, err := repos.Transactions.GetChainHead(ctx, account.ID)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+		require.NoError(t, repos.Transactions.SetTransactionHash(ctx, first.ID, "genesis", "hash-1"))
+		require.NoError(t, repos.Transactions.SetTransactionHash(ctx, second.ID, "hash-1", "hash-2"))
+
+		head, err := repos.Transactions.GetChainHead(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "hash-2", head)
+		_, err = repos.Transactions.GetChainHead(ctx, other.ID)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+		chain, err := repos.Transactions.GetTransactionChain(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, chain, 3)
+		assert.Equal(t, []int64{unchained.ID, first.ID, second.ID}, []int64{chain[0].ID, chain[1].ID, chain[2].ID})
+		assert.Empty(t, chain[0].Hash)
+		assert.Equal(t, "genesis", chain[1].PrevHash)
+		assert.Equal(t, "hash-2", chain[2].Hash)
+		assert.Equal(t, 30.0, chain[2].Amount)
+
+		got, err := repos.Transactions.GetTransactionByID(ctx, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "hash-1", got.PrevHash)
+		assert.Equal(t, "hash-2", got.Hash)
+
+		accountIDs, err := repos.Transactions.GetChainedAccountIDs(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{account.ID}, accountIDs)
+	})
+
+	t.Run("Chained transaction cannot be relinked", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		txn := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		require.NoError(t, repos.Transactions.SetTransactionHash(ctx, txn.ID, "genesis", "hash-1"))
+
+		assert.Error(t, repos.Transactions.SetTransactionHash(ctx, txn.ID, "genesis", "hash-2"))
+		assert.Error(t, repos.Transactions.SetTransactionHash(ctx, 999, "genesis", "hash-3"))
+	})
+
+	t.Run("Chain link cannot be extended twice", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		first := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		second := mustInsertTransaction(t, repos, account.ID, 1, -20)
+		require.NoError(t, repos.Transactions.SetTransactionHash(ctx, first.ID, "genesis", "hash-1"))
+
+		err := repos.Transactions.SetTransactionHash(ctx, second.ID, "genesis", "hash-2")
+		assertPgError(t, err, "23505", "idx_transactions_chain_link")
+	})
+}
+
+func testTransfers(t *testing.T, newRepos Factory) {
+	t.Run("Insert transfer with its legs", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithCorrelationID(context.Background(), "flow-1")
+		source := mustInsertAccount(t, repos, "1")
+		destination := mustInsertAccount(t, repos, "2")
+
+		transfer, err := repos.Transfers.InsertTransfer(ctx, source.ID, destination.ID, 25.5)
+		require.NoError(t, err)
+		assert.Positive(t, transfer.ID)
+		assert.Equal(t, "flow-1", transfer.CorrelationID)
+		assert.False(t, transfer.CreatedAt.IsZero())
+
+		debit, err := repos.Transactions.InsertTransferLeg(ctx, transfer.ID, source.ID, 5, -25.5, -25.5, time.Now().UTC())
+		require.NoError(t, err)
+		assert.Equal(t, transfer.ID, debit.TransferID)
+		credit, err := repos.Transactions.InsertTransferLeg(ctx, transfer.ID, destination.ID, 6, 25.5, 25.5, time.Now().UTC())
+		require.NoError(t, err)
+		mustInsertTransaction(t, repos, source.ID, 1, -10)
+
+		fetched, err := repos.Transfers.GetTransferByID(ctx, transfer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, source.ID, fetched.SourceAccountID)
+		assert.Equal(t, destination.ID, fetched.DestinationAccountID)
+		assert.Equal(t, 25.5, fetched.Amount)
+
+		legs, err := repos.Transactions.GetTransactionsByTransferID(ctx, transfer.ID)
+		require.NoError(t, err)
+		require.Len(t, legs, 2)
+		assert.Equal(t, debit.ID, legs[0].ID)
+		assert.Equal(t, source.ID, legs[0].AccountID)
+		assert.Equal(t, int64(5), legs[0].OperationTypeID)
+		assert.Equal(t, -25.5, legs[0].Amount)
+		assert.Equal(t, credit.ID, legs[1].ID)
+		assert.Equal(t, 25.5, legs[1].Balance)
+	})
+
+	t.Run("Missing transfer returns no rows", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Transfers.GetTransferByID(context.Background(), 999)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("Leg of an unknown transfer violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+		account := mustInsertAccount(t, repos, "1")
+
+		_, err := repos.Transactions.InsertTransferLeg(context.Background(), 999, account.ID, 5, -1, -1, time.Now().UTC())
+		assertPgError(t, err, "23503", "transactions_transfer_id_fkey")
+	})
+}
+
+func testLedger(t *testing.T, newRepos Factory) {
+	receivable := func(accountID int64) repository.LedgerAccount {
+		return repository.LedgerAccount{Code: fmt.Sprintf("customer_receivable:%d", accountID), Type: "asset", AccountID: accountID}
+	}
+	credit := func(accountID int64) repository.LedgerAccount {
+		return repository.LedgerAccount{Code: fmt.Sprintf("customer_credit:%d", accountID), Type: "liability", AccountID: accountID}
+	}
+	cashClearing := repository.LedgerAccount{Code: "cash_clearing", Type: "asset"}
+
+	t.Run("Post entries and derive balances", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithCorrelationID(context.Background(), "flow-1")
+		account := mustInsertAccount(t, repos, "1")
+		purchase := mustInsertTransaction(t, repos, account.ID, 1, -50)
+		payment := mustInsertTransaction(t, repos, account.ID, 4, 60)
+		effective := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		entry, err := repos.Ledger.PostJournalEntry(ctx, &repository.JournalEntry{
+			Kind:          "purchase",
+			TransactionID: purchase.ID,
+			CreatedAt:     effective,
+			Postings: []repository.Posting{
+				{Account: receivable(account.ID), TransactionID: purchase.ID, Amount: 50},
+				{Account: cashClearing, Amount: -50},
+			},
+		})
+		require.NoError(t, err)
+		assert.Positive(t, entry.ID)
+		assert.Equal(t, "flow-1", entry.CorrelationID)
+		assert.True(t, effective.Equal(entry.CreatedAt))
+
+		_, err = repos.Ledger.PostJournalEntry(ctx, &repository.JournalEntry{
+			Kind:          "payment",
+			TransactionID: payment.ID,
+			CreatedAt:     effective,
+			Postings: []repository.Posting{
+				{Account: cashClearing, Amount: 60},
+				{Account: credit(account.ID), TransactionID: payment.ID, Amount: -60},
+			},
+		})
+		require.NoError(t, err)
+		_, err = repos.Ledger.PostJournalEntry(ctx, &repository.JournalEntry{
+			Kind:          "discharge",
+			TransactionID: payment.ID,
+			CreatedAt:     effective,
+			Postings: []repository.Posting{
+				{Account: credit(account.ID), TransactionID: payment.ID, Amount: 50},
+				{Account: receivable(account.ID), TransactionID: purchase.ID, Amount: -50},
+			},
+		})
+		require.NoError(t, err)
+
+		balances, err := repos.Ledger.GetLedgerAccountBalances(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, balances, 2)
+		assert.Equal(t, credit(account.ID).Code, balances[0].Code)
+		assert.Equal(t, "liability", balances[0].Type)
+		assert.Equal(t, -10.0, balances[0].Balance)
+		assert.Equal(t, receivable(account.ID).Code, balances[1].Code)
+		assert.Equal(t, 0.0, balances[1].Balance)
+
+		byTransaction, err := repos.Ledger.GetLedgerBalancesByTransaction(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[int64]float64{purchase.ID: 0, payment.ID: 10}, byTransaction)
+
+		discharges, err := repos.Ledger.GetDischargesByAccountID(ctx, account.ID, entry.CreatedAt.Add(-time.Hour), entry.CreatedAt.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, discharges, 1)
+		assert.Equal(t, "discharge", discharges[0].Kind)
+		assert.Equal(t, payment.ID, discharges[0].TransactionID)
+		require.Len(t, discharges[0].Postings, 2)
+		assert.Equal(t, purchase.ID, discharges[0].Postings[1].TransactionID)
+		assert.Equal(t, -50.0, discharges[0].Postings[1].Amount)
+		assert.Equal(t, account.ID, discharges[0].Postings[1].Account.AccountID)
+	})
+
+	t.Run("Unbalanced entry violates the balance constraint", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+
+		// Postgres checks the balance at commit, so post within a unit of work
+		err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := repos.Ledger.PostJournalEntry(ctx, &repository.JournalEntry{
+				Kind:      "purchase",
+				CreatedAt: time.Now().UTC(),
+				Postings: []repository.Posting{
+					{Account: receivable(account.ID), Amount: 50},
+					{Account: cashClearing, Amount: -49.99},
+				},
+			})
+			return err
+		})
+		assertPgError(t, err, "23514", "postings_journal_entry_balanced")
+
+		balances, err := repos.Ledger.GetLedgerAccountBalances(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Empty(t, balances)
+	})
+
+	t.Run("Post entries in one go", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithCorrelationID(context.Background(), "flow-1")
+		account := mustInsertAccount(t, repos, "1")
+		first := mustInsertTransaction(t, repos, account.ID, 1, -50)
+		second := mustInsertTransaction(t, repos, account.ID, 1, -20)
+		payment := mustInsertTransaction(t, repos, account.ID, 4, 60)
+		effective := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		err := repos.Ledger.PostJournalEntries(ctx, []*repository.JournalEntry{
+			{
+				Kind: "discharge", TransactionID: payment.ID, CreatedAt: effective,
+				Postings: []repository.Posting{
+					{Account: credit(account.ID), TransactionID: payment.ID, Amount: 50},
+					{Account: receivable(account.ID), TransactionID: first.ID, Amount: -50},
+				},
+			},
+			{
+				Kind: "discharge", TransactionID: payment.ID, CreatedAt: effective,
+				Postings: []repository.Posting{
+					{Account: credit(account.ID), TransactionID: payment.ID, Amount: 10},
+					{Account: receivable(account.ID), TransactionID: second.ID, Amount: -10},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		byTransaction, err := repos.Ledger.GetLedgerBalancesByTransaction(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[int64]float64{first.ID: 50, second.ID: 10, payment.ID: -60}, byTransaction)
+
+		discharges, err := repos.Ledger.GetDischargesByAccountID(ctx, account.ID, effective.Add(-time.Hour), effective.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, discharges, 2)
+		assert.Less(t, discharges[0].ID, discharges[1].ID)
+		assert.Equal(t, "flow-1", discharges[0].CorrelationID)
+		require.Len(t, discharges[0].Postings, 2)
+		assert.Equal(t, first.ID, discharges[0].Postings[1].TransactionID)
+		require.Len(t, discharges[1].Postings, 2)
+		assert.Equal(t, second.ID, discharges[1].Postings[1].TransactionID)
+		assert.Equal(t, -10.0, discharges[1].Postings[1].Amount)
+	})
+
+	t.Run("Discharge allocations are net of adjustments", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		other := mustInsertAccount(t, repos, "2")
+		first := mustInsertTransaction(t, repos, account.ID, 1, -50)
+		second := mustInsertTransaction(t, repos, account.ID, 1, -20)
+		payment := mustInsertTransaction(t, repos, account.ID, 4, 60)
+		otherDebt := mustInsertTransaction(t, repos, other.ID, 1, -5)
+		otherPayment := mustInsertTransaction(t, repos, other.ID, 4, 5)
+		effective := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+		allocate := func(kind string, creditTxn, debt *repository.Transaction, amount float64) *repository.JournalEntry {
+			return &repository.JournalEntry{
+				Kind: kind, TransactionID: creditTxn.ID, CreatedAt: effective,
+				Postings: []repository.Posting{
+					{Account: credit(creditTxn.AccountID), TransactionID: creditTxn.ID, Amount: amount},
+					{Account: receivable(creditTxn.AccountID), TransactionID: debt.ID, Amount: -amount},
+				},
+			}
+		}
+
+		require.NoError(t, repos.Ledger.PostJournalEntries(ctx, []*repository.JournalEntry{
+			allocate("discharge", payment, first, 50),
+			allocate("discharge", payment, second, 10),
+			allocate("discharge", otherPayment, otherDebt, 5),
+			allocate("discharge_adjustment", payment, first, -50),
+			allocate("discharge_adjustment", payment, second, 10),
+		}))
+
+		allocations, err := repos.Ledger.GetDischargeAllocations(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []*repository.Allocation{{CreditTransactionID: payment.ID, DebtTransactionID: second.ID, Amount: 20}}, allocations)
+	})
+
+	t.Run("Discharges of the account include adjustments", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		debt := mustInsertTransaction(t, repos, account.ID, 1, -50)
+		payment := mustInsertTransaction(t, repos, account.ID, 4, 50)
+		effective := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		require.NoError(t, repos.Ledger.PostJournalEntries(ctx, []*repository.JournalEntry{
+			{
+				Kind: "discharge", TransactionID: payment.ID, CreatedAt: effective,
+				Postings: []repository.Posting{
+					{Account: credit(account.ID), TransactionID: payment.ID, Amount: 50},
+					{Account: receivable(account.ID), TransactionID: debt.ID, Amount: -50},
+				},
+			},
+			{
+				Kind: "discharge_adjustment", TransactionID: payment.ID, CreatedAt: effective.Add(time.Minute),
+				Postings: []repository.Posting{
+					{Account: credit(account.ID), TransactionID: payment.ID, Amount: -20},
+					{Account: receivable(account.ID), TransactionID: debt.ID, Amount: 20},
+				},
+			},
+		}))
+
+		discharges, err := repos.Ledger.GetDischargesByAccountID(ctx, account.ID, effective, effective.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, discharges, 2)
+		assert.Equal(t, "discharge", discharges[0].Kind)
+		assert.Equal(t, "discharge_adjustment", discharges[1].Kind)
+		assert.Equal(t, payment.ID, discharges[1].TransactionID)
+		require.Len(t, discharges[1].Postings, 2)
+		assert.Equal(t, debt.ID, discharges[1].Postings[1].TransactionID)
+		assert.Equal(t, 20.0, discharges[1].Postings[1].Amount)
+	})
+
+	t.Run("Entries posted in one go are posted all or none", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+
+		err := repos.Ledger.PostJournalEntries(ctx, []*repository.JournalEntry{
+			{
+				Kind: "purchase", CreatedAt: time.Now().UTC(),
+				Postings: []repository.Posting{{Account: receivable(account.ID), Amount: 1}, {Account: cashClearing, Amount: -1}},
+			},
+			{
+				Kind: "purchase", CreatedAt: time.Now().UTC(),
+				Postings: []repository.Posting{{Account: receivable(999), Amount: 1}, {Account: cashClearing, Amount: -1}},
+			},
+		})
+		assertPgError(t, err, "23503", "ledger_accounts_account_id_fkey")
+
+		balances, err := repos.Ledger.GetLedgerAccountBalances(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Empty(t, balances)
+	})
+
+	t.Run("Posting for an unknown account violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Ledger.PostJournalEntry(context.Background(), &repository.JournalEntry{
+			Kind:      "purchase",
+			CreatedAt: time.Now().UTC(),
+			Postings: []repository.Posting{
+				{Account: receivable(999), Amount: 1},
+				{Account: cashClearing, Amount: -1},
+			},
+		})
+		assertPgError(t, err, "23503", "ledger_accounts_account_id_fkey")
+	})
+}
+
+func testBilling(t *testing.T, newRepos Factory) {
+	t.Run("Unconfigured accounts use the default billing cycle", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+
+		cycle, err := repos.Billing.GetBillingCycle(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, repository.BillingCycle{
+			AccountID:    account.ID,
+			ClosingDay:   repository.DefaultClosingDay,
+			DueDayOffset: repository.DefaultDueDayOffset,
+		}, *cycle)
+
+		_, err = repos.Billing.GetBillingCycle(ctx, 999)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("Configure billing cycles and find them by closing day", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		configured := mustInsertAccount(t, repos, "1")
+		defaulted := mustInsertAccount(t, repos, "2")
+		prepaid, err := repos.Accounts.InsertAccount(ctx, defaulted.CustomerID, "prepaid")
+		require.NoError(t, err)
+
+		_, err = repos.Billing.UpsertBillingCycle(ctx, &repository.BillingCycle{AccountID: configured.ID, ClosingDay: 5, DueDayOffset: 20})
+		require.NoError(t, err)
+		cycle, err := repos.Billing.UpsertBillingCycle(ctx, &repository.BillingCycle{AccountID: configured.ID, ClosingDay: 15, DueDayOffset: 7})
+		require.NoError(t, err)
+		assert.Equal(t, 15, cycle.ClosingDay)
+
+		fetched, err := repos.Billing.GetBillingCycle(ctx, configured.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 7, fetched.DueDayOffset)
+
+		closing, err := repos.Billing.GetBillingCyclesByClosingDay(ctx, "credit", 15)
+		require.NoError(t, err)
+		require.Len(t, closing, 1)
+		assert.Equal(t, configured.ID, closing[0].AccountID)
+
+		closing, err = repos.Billing.GetBillingCyclesByClosingDay(ctx, "credit", repository.DefaultClosingDay)
+		require.NoError(t, err)
+		require.Len(t, closing, 1)
+		assert.Equal(t, defaulted.ID, closing[0].AccountID)
+		assert.NotEqual(t, prepaid.ID, closing[0].AccountID)
+
+		_, err = repos.Billing.UpsertBillingCycle(ctx, &repository.BillingCycle{AccountID: 999, ClosingDay: 1, DueDayOffset: 1})
+		assertPgError(t, err, "23503", "billing_cycles_account_id_fkey")
+	})
+
+	t.Run("Insert and get statements", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithCorrelationID(context.Background(), "flow-1")
+		account := mustInsertAccount(t, repos, "1")
+		purchase := mustInsertTransaction(t, repos, account.ID, 1, -50)
+		payment := mustInsertTransaction(t, repos, account.ID, 4, 20)
+
+		first, err := repos.Billing.InsertStatement(ctx, &repository.Statement{
+			AccountID: account.ID, PeriodStart: "2025-01-02", PeriodEnd: "2025-02-01",
+			DueDate: "2025-02-11",
+		})
+		require.NoError(t, err)
+		created, err := repos.Billing.InsertStatement(ctx, &repository.Statement{
+			AccountID: account.ID, PeriodStart: "2025-02-02", PeriodEnd: "2025-03-01",
+			OpeningBalance: 0, ClosingBalance: 30, MinimumPayment: 25, DueDate: "2025-03-11",
+			Lines: []repository.StatementLine{
+				{Kind: "purchase", TransactionID: purchase.ID, Amount: -50, EventDate: purchase.EventDate},
+				{Kind: "payment", TransactionID: payment.ID, Amount: 20, EventDate: payment.EventDate},
+				{Kind: "discharge", TransactionID: purchase.ID, RelatedTransactionID: payment.ID, Amount: 20, EventDate: payment.EventDate},
+			},
+		})
+		require.NoError(t, err)
+		assert.Positive(t, created.ID)
+		assert.Equal(t, "flow-1", created.CorrelationID)
+		assert.False(t, created.CreatedAt.IsZero())
+
+		latest, err := repos.Billing.GetLatestStatementByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, latest.ID)
+		assert.Equal(t, "2025-03-01", latest.PeriodEnd)
+		assert.Empty(t, latest.Lines)
+
+		statements, err := repos.Billing.GetStatementsByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, statements, 2)
+		assert.Equal(t, created.ID, statements[0].ID)
+		assert.Equal(t, first.ID, statements[1].ID)
+
+		fetched, err := repos.Billing.GetStatementByID(ctx, account.ID, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "2025-02-02", fetched.PeriodStart)
+		assert.Equal(t, "2025-03-11", fetched.DueDate)
+		assert.Equal(t, 30.0, fetched.ClosingBalance)
+		assert.Equal(t, 25.0, fetched.MinimumPayment)
+		require.Len(t, fetched.Lines, 3)
+		assert.Equal(t, "purchase", fetched.Lines[0].Kind)
+		assert.Equal(t, -50.0, fetched.Lines[0].Amount)
+		assert.Zero(t, fetched.Lines[0].RelatedTransactionID)
+		assert.Equal(t, payment.ID, fetched.Lines[2].RelatedTransactionID)
+
+		_, err = repos.Billing.GetStatementByID(ctx, account.ID+1, created.ID)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("Closing the same period twice violates unique constraint", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		statement := &repository.Statement{AccountID: account.ID, PeriodStart: "2025-02-02", PeriodEnd: "2025-03-01", DueDate: "2025-03-11"}
+
+		_, err := repos.Billing.InsertStatement(ctx, statement)
+		require.NoError(t, err)
+		_, err = repos.Billing.InsertStatement(ctx, statement)
+		assertPgError(t, err, "23505", "statements_account_id_period_end_key")
+
+		_, err = repos.Billing.GetLatestStatementByAccountID(ctx, account.ID+1)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+}
+
+func testAccruals(t *testing.T, newRepos Factory) {
+	t.Run("Policies of a product, the product-wide one first", func(t *testing.T) {
+		repos := newRepos(t)
+
+		policies, err := repos.Accruals.GetAccrualPolicies(context.Background(), "credit")
+		require.NoError(t, err)
+		require.NotEmpty(t, policies)
+		assert.Zero(t, policies[0].OperationTypeID)
+		assert.Equal(t, "credit", policies[0].Product)
+	})
+
+	t.Run("Past-due accounts have a statement due before the date", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		due := mustInsertAccount(t, repos, "1")
+		notDue := mustInsertAccount(t, repos, "2")
+		closed := mustInsertAccount(t, repos, "3")
+		mustInsertAccount(t, repos, "4")
+
+		for _, statement := range []*repository.Statement{
+			{AccountID: due.ID, PeriodStart: "2025-01-02", PeriodEnd: "2025-02-01", DueDate: "2025-02-11"},
+			{AccountID: due.ID, PeriodStart: "2025-02-02", PeriodEnd: "2025-03-01", DueDate: "2025-03-11"},
+			{AccountID: notDue.ID, PeriodStart: "2025-02-02", PeriodEnd: "2025-03-01", DueDate: "2025-03-12"},
+			{AccountID: closed.ID, PeriodStart: "2025-02-02", PeriodEnd: "2025-03-01", DueDate: "2025-03-01"},
+		} {
+			_, err := repos.Billing.InsertStatement(ctx, statement)
+			require.NoError(t, err)
+		}
+		_, err := repos.Accounts.UpdateAccountStatus(ctx, closed.ID, "closed")
+		require.NoError(t, err)
+
+		accountIDs, err := repos.Accruals.GetPastDueAccountIDs(ctx, time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Equal(t, []int64{due.ID}, accountIDs)
+	})
+
+	t.Run("Insert runs and get their accruals", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithCorrelationID(context.Background(), "accrual-1")
+		account := mustInsertAccount(t, repos, "1")
+		statement, err := repos.Billing.InsertStatement(ctx, &repository.Statement{
+			AccountID: account.ID, PeriodStart: "2025-02-02", PeriodEnd: "2025-03-01", DueDate: "2025-03-11",
+		})
+		require.NoError(t, err)
+		interest := mustInsertTransaction(t, repos, account.ID, 7, -0.66)
+		fee := mustInsertTransaction(t, repos, account.ID, 8, -25)
+		nextInterest := mustInsertTransaction(t, repos, account.ID, 7, -0.65)
+
+		created, err := repos.Accruals.InsertAccrualRun(ctx, &repository.AccrualRun{
+			AccountID: account.ID, AccrualDate: "2025-03-12",
+			Accruals: []repository.Accrual{
+				{Kind: "interest", TransactionID: interest.ID, Amount: 0.66},
+				{Kind: "late_fee", TransactionID: fee.ID, StatementID: statement.ID, Amount: 25},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "accrual-1", created.CorrelationID)
+		assert.False(t, created.CreatedAt.IsZero())
+		_, err = repos.Accruals.InsertAccrualRun(ctx, &repository.AccrualRun{
+			AccountID: account.ID, AccrualDate: "2025-03-13",
+			Accruals: []repository.Accrual{{Kind: "interest", TransactionID: nextInterest.ID, Amount: 0.65}},
+		})
+		require.NoError(t, err)
+		_, err = repos.Accruals.InsertAccrualRun(ctx, &repository.AccrualRun{AccountID: account.ID, AccrualDate: "2025-03-14"})
+		require.NoError(t, err)
+
+		accruals, err := repos.Accruals.GetAccrualsByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, accruals, 3)
+		assert.Equal(t, repository.Accrual{Kind: "interest", TransactionID: interest.ID, Amount: 0.66}, *accruals[0])
+		assert.Equal(t, repository.Accrual{Kind: "late_fee", TransactionID: fee.ID, StatementID: statement.ID, Amount: 25}, *accruals[1])
+		assert.Equal(t, nextInterest.ID, accruals[2].TransactionID)
+	})
+
+	t.Run("Second run of the same day violates unique constraint", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		run := &repository.AccrualRun{AccountID: account.ID, AccrualDate: "2025-03-12"}
+
+		_, err := repos.Accruals.InsertAccrualRun(ctx, run)
+		require.NoError(t, err)
+		_, err = repos.Accruals.InsertAccrualRun(ctx, run)
+		assertPgError(t, err, "23505", "accrual_runs_pkey")
+	})
+
+	t.Run("Second late fee of a statement violates unique constraint", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		statement, err := repos.Billing.InsertStatement(ctx, &repository.Statement{
+			AccountID: account.ID, PeriodStart: "2025-02-02", PeriodEnd: "2025-03-01", DueDate: "2025-03-11",
+		})
+		require.NoError(t, err)
+		fee := mustInsertTransaction(t, repos, account.ID, 8, -25)
+		secondFee := mustInsertTransaction(t, repos, account.ID, 8, -25)
+
+		_, err = repos.Accruals.InsertAccrualRun(ctx, &repository.AccrualRun{
+			AccountID: account.ID, AccrualDate: "2025-03-12",
+			Accruals: []repository.Accrual{{Kind: "late_fee", TransactionID: fee.ID, StatementID: statement.ID, Amount: 25}},
+		})
+		require.NoError(t, err)
+		_, err = repos.Accruals.InsertAccrualRun(ctx, &repository.AccrualRun{
+			AccountID: account.ID, AccrualDate: "2025-03-13",
+			Accruals: []repository.Accrual{{Kind: "late_fee", TransactionID: secondFee.ID, StatementID: statement.ID, Amount: 25}},
+		})
+		assertPgError(t, err, "23505", "accruals_statement_id_late_fee_key")
+	})
+
+	t.Run("Charge of an unknown transaction violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+		account := mustInsertAccount(t, repos, "1")
+
+		_, err := repos.Accruals.InsertAccrualRun(context.Background(), &repository.AccrualRun{
+			AccountID: account.ID, AccrualDate: "2025-03-12",
+			Accruals: []repository.Accrual{{Kind: "interest", TransactionID: 999, Amount: 1}},
+		})
+		assertPgError(t, err, "23503", "accruals_transaction_id_fkey")
+	})
+}
+
+func testFees(t *testing.T, newRepos Factory) {
+	t.Run("Percent rule of a product", func(t *testing.T) {
+		repos := newRepos(t)
+
+		rule, err := repos.Fees.GetFeeRule(context.Background(), "credit", 3)
+		require.NoError(t, err)
+		assert.Equal(t, "credit", rule.Product)
+		assert.Equal(t, int64(3), rule.OperationTypeID)
+		assert.Equal(t, repository.FeeKindPercent, rule.Kind)
+		assert.Equal(t, 0.03, rule.Percent)
+		assert.Equal(t, 5.0, rule.MinAmount)
+		assert.Zero(t, rule.MaxAmount)
+		assert.Empty(t, rule.Tiers)
+	})
+
+	t.Run("Tiered rule with its tiers, bounded ones first", func(t *testing.T) {
+		repos := newRepos(t)
+
+		rule, err := repos.Fees.GetFeeRule(context.Background(), "prepaid", 3)
+		require.NoError(t, err)
+		assert.Equal(t, repository.FeeKindTiered, rule.Kind)
+		assert.Equal(t, 10.0, rule.MaxAmount)
+		assert.Equal(t, []repository.FeeTier{{UpTo: 100, FlatAmount: 1}, {Percent: 0.01}}, rule.Tiers)
+	})
+
+	t.Run("Operation type without a fee", func(t *testing.T) {
+		repos := newRepos(t)
+
+		rule, err := repos.Fees.GetFeeRule(context.Background(), "credit", 1)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.Nil(t, rule)
+	})
+}
+
+func testScreening(t *testing.T, newRepos Factory) {
+	t.Run("Rules of a product, in the order they were defined", func(t *testing.T) {
+		repos := newRepos(t)
+
+		rules, err := repos.Screening.GetScreeningRules(context.Background(), "credit")
+		require.NoError(t, err)
+		require.NotEmpty(t, rules)
+		for i, rule := range rules {
+			assert.Contains(t, []string{"", "credit"}, rule.Product)
+			assert.NotEmpty(t, rule.OperationTypeIDs)
+			if i > 0 {
+				assert.Greater(t, rule.ID, rules[i-1].ID)
+			}
+		}
+		assert.Equal(t, "withdrawal_velocity", rules[0].Code)
+		assert.Equal(t, []int64{3}, rules[0].OperationTypeIDs)
+	})
+
+	t.Run("Insert evaluations of denied and posted transactions", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithCorrelationID(context.Background(), "screening-1")
+		account := mustInsertAccount(t, repos, "1")
+		txn := mustInsertTransaction(t, repos, account.ID, 1, -3000)
+
+		denied, err := repos.Screening.InsertScreeningEvaluation(ctx, &repository.ScreeningEvaluation{
+			AccountID: account.ID, OperationTypeID: 3, Amount: -50, Outcome: "deny", ReasonCodes: []string{"withdrawal_velocity"},
+		})
+		require.NoError(t, err)
+		assert.Positive(t, denied.ID)
+		assert.Equal(t, "screening-1", denied.CorrelationID)
+		assert.False(t, denied.CreatedAt.IsZero())
+
+		reviewed, err := repos.Screening.InsertScreeningEvaluation(ctx, &repository.ScreeningEvaluation{
+			AccountID: account.ID, OperationTypeID: 1, Amount: -3000, Outcome: "review", ReasonCodes: []string{"large_debit"}, TransactionID: txn.ID,
+		})
+		require.NoError(t, err)
+		assert.Greater(t, reviewed.ID, denied.ID)
+		assert.Equal(t, txn.ID, reviewed.TransactionID)
+
+		_, err = repos.Screening.InsertScreeningEvaluation(ctx, &repository.ScreeningEvaluation{
+			AccountID: account.ID, OperationTypeID: 1, Amount: -3000, Outcome: "allow", TransactionID: txn.ID,
+		})
+		assertPgError(t, err, "23505", "screening_evaluations_transaction_id_key")
+	})
+
+	t.Run("Only denials have no transaction", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		txn := mustInsertTransaction(t, repos, account.ID, 1, -10)
+
+		_, err := repos.Screening.InsertScreeningEvaluation(ctx, &repository.ScreeningEvaluation{
+			AccountID: account.ID, OperationTypeID: 1, Amount: -10, Outcome: "allow",
+		})
+		assertPgError(t, err, "23514", "screening_evaluations_check")
+		_, err = repos.Screening.InsertScreeningEvaluation(ctx, &repository.ScreeningEvaluation{
+			AccountID: account.ID, OperationTypeID: 1, Amount: -10, Outcome: "deny", TransactionID: txn.ID,
+		})
+		assertPgError(t, err, "23514", "screening_evaluations_check")
+	})
+
+	t.Run("Unknown account violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Screening.InsertScreeningEvaluation(context.Background(), &repository.ScreeningEvaluation{
+			AccountID: 999, OperationTypeID: 1, Amount: -10, Outcome: "deny",
+		})
+		assertPgError(t, err, "23503", "screening_evaluations_account_id_fkey")
+	})
+}
+
+func testAudit(t *testing.T, newRepos Factory) {
+	t.Run("Insert event attributed to the context", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithPrincipal(middleware.WithCorrelationID(context.Background(), "flow-1"), "ops@example.com")
+		ctx = middleware.WithClientIP(ctx, "203.0.113.7")
+
+		event, err := repos.Audit.InsertAuditEvent(ctx, &repository.AuditEvent{
+			Action:     "account.status_changed",
+			EntityType: "account",
+			EntityID:   7,
+			Before:     []byte(`{"status":"active"}`),
+			After:      []byte(`{"status":"blocked"}`),
+		})
+		require.NoError(t, err)
+		assert.Positive(t, event.ID)
+		assert.Equal(t, "ops@example.com", event.Principal)
+		assert.Equal(t, "flow-1", event.CorrelationID)
+		assert.Equal(t, "203.0.113.7", event.IP)
+		assert.False(t, event.CreatedAt.IsZero())
+
+		events, err := repos.Audit.GetAuditEvents(context.Background(), repository.AuditEventFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, event.ID, events[0].ID)
+		assert.Equal(t, "account.status_changed", events[0].Action)
+		assert.Equal(t, int64(7), events[0].EntityID)
+		assert.JSONEq(t, `{"status":"active"}`, string(events[0].Before))
+		assert.JSONEq(t, `{"status":"blocked"}`, string(events[0].After))
+		assert.Equal(t, "ops@example.com", events[0].Principal)
+		assert.Equal(t, "203.0.113.7", events[0].IP)
+	})
+
+	t.Run("Insert events in order in one go", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithPrincipal(middleware.WithCorrelationID(context.Background(), "flow-1"), "ops@example.com")
+
+		err := repos.Audit.InsertAuditEvents(ctx, []*repository.AuditEvent{
+			{Action: "transaction.balance_updated", EntityType: "transaction", EntityID: 3, Before: []byte(`{"balance":-10}`), After: []byte(`{"balance":0}`)},
+			{Action: "transaction.balance_updated", EntityType: "transaction", EntityID: 1, Before: []byte(`{"balance":25}`), After: []byte(`{"balance":15}`)},
+			{Action: "config.updated", EntityType: "config"},
+		})
+		require.NoError(t, err)
+
+		events, err := repos.Audit.GetAuditEvents(context.Background(), repository.AuditEventFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+		assert.Equal(t, int64(3), events[0].EntityID)
+		assert.JSONEq(t, `{"balance":-10}`, string(events[0].Before))
+		assert.JSONEq(t, `{"balance":15}`, string(events[1].After))
+		assert.Equal(t, "config.updated", events[2].Action)
+		assert.Zero(t, events[2].EntityID)
+		assert.Nil(t, events[2].Before)
+		for _, event := range events {
+			assert.Equal(t, "ops@example.com", event.Principal)
+			assert.Equal(t, "flow-1", event.CorrelationID)
+		}
+	})
+
+	t.Run("Events without an entity id or state", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Audit.InsertAuditEvent(context.Background(), &repository.AuditEvent{Action: "config.updated", EntityType: "config"})
+		require.NoError(t, err)
+
+		events, err := repos.Audit.GetAuditEvents(context.Background(), repository.AuditEventFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Zero(t, events[0].EntityID)
+		assert.Nil(t, events[0].Before)
+		assert.Nil(t, events[0].After)
+		assert.Empty(t, events[0].Principal)
+	})
+
+	t.Run("Filter events, oldest first", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := middleware.WithPrincipal(context.Background(), "alice")
+		bob := middleware.WithPrincipal(context.Background(), "bob")
+		insert := func(ctx context.Context, action, entityType string, entityID int64) *repository.AuditEvent {
+			event, err := repos.Audit.InsertAuditEvent(ctx, &repository.AuditEvent{Action: action, EntityType: entityType, EntityID: entityID})
+			require.NoError(t, err)
+			return event
+		}
+
+		first := insert(alice, "account.created", "account", 1)
+		second := insert(bob, "transaction.created", "transaction", 1)
+		third := insert(alice, "account.status_changed", "account", 1)
+		insert(alice, "account.created", "account", 2)
+
+		byEntity, err := repos.Audit.GetAuditEvents(context.Background(), repository.AuditEventFilter{EntityType: "account", EntityID: 1})
+		require.NoError(t, err)
+		require.Len(t, byEntity, 2)
+		assert.Equal(t, first.ID, byEntity[0].ID)
+		assert.Equal(t, third.ID, byEntity[1].ID)
+
+		byPrincipal, err := repos.Audit.GetAuditEvents(context.Background(), repository.AuditEventFilter{Principal: "bob"})
+		require.NoError(t, err)
+		require.Len(t, byPrincipal, 1)
+		assert.Equal(t, second.ID, byPrincipal[0].ID)
+
+		byAction, err := repos.Audit.GetAuditEvents(context.Background(), repository.AuditEventFilter{Action: "account.created"})
+		require.NoError(t, err)
+		assert.Len(t, byAction, 2)
+
+		page, err := repos.Audit.GetAuditEvents(context.Background(), repository.AuditEventFilter{AfterID: first.ID, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page, 2)
+		assert.Equal(t, second.ID, page[0].ID)
+		assert.Equal(t, third.ID, page[1].ID)
+
+		none, err := repos.Audit.GetAuditEvents(context.Background(), repository.AuditEventFilter{To: first.CreatedAt.Add(-time.Hour)})
+		require.NoError(t, err)
+		assert.Empty(t, none)
+		all, err := repos.Audit.GetAuditEvents(context.Background(), repository.AuditEventFilter{From: first.CreatedAt.Add(-time.Hour)})
+		require.NoError(t, err)
+		assert.Len(t, all, 4)
+	})
+
+	t.Run("Events roll back with their unit of work", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+
+		err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if _, err := repos.Audit.InsertAuditEvent(ctx, &repository.AuditEvent{Action: "config.updated", EntityType: "config"}); err != nil {
+				return err
+			}
+			return errors.New("abort")
+		})
+		require.Error(t, err)
+
+		events, err := repos.Audit.GetAuditEvents(ctx, repository.AuditEventFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
+
+func testTransactionRequests(t *testing.T, newRepos Factory) {
+	t.Run("Insert and get request attributed to the context", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := middleware.WithPrincipal(middleware.WithCorrelationID(context.Background(), "flow-1"), "alice")
+		account := mustInsertAccount(t, repos, "1")
+
+		created, err := repos.Requests.InsertTransactionRequest(ctx, &repository.TransactionRequest{
+			AccountID: account.ID, OperationTypeID: 1, Amount: 12.5, MerchantReference: "order-1",
+		})
+		require.NoError(t, err)
+		assert.Positive(t, created.ID)
+		assert.Equal(t, repository.TransactionRequestPending, created.Status)
+		assert.Equal(t, "flow-1", created.CorrelationID)
+		assert.Equal(t, "alice", created.Principal)
+		assert.False(t, created.CreatedAt.IsZero())
+
+		got, err := repos.Requests.GetTransactionRequestByID(context.Background(), created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, account.ID, got.AccountID)
+		assert.Equal(t, int64(1), got.OperationTypeID)
+		assert.Equal(t, 12.5, got.Amount)
+		assert.Equal(t, "order-1", got.MerchantReference)
+		assert.Equal(t, repository.TransactionRequestPending, got.Status)
+		assert.Zero(t, got.TransactionID)
+		assert.Equal(t, "flow-1", got.CorrelationID)
+		assert.Equal(t, "alice", got.Principal)
+	})
+
+	t.Run("Unknown account violates foreign key", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Requests.InsertTransactionRequest(context.Background(), &repository.TransactionRequest{
+			AccountID: 999, OperationTypeID: 1, Amount: 10,
+		})
+		assertPgError(t, err, "23503", "transaction_requests_account_id_fkey")
+	})
+
+	t.Run("Missing request returns no rows", func(t *testing.T) {
+		repos := newRepos(t)
+
+		_, err := repos.Requests.GetTransactionRequestByID(context.Background(), 999)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("Requests of an account are claimed in order, one at a time", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		a := mustInsertAccount(t, repos, "1")
+		b := mustInsertAccount(t, repos, "2")
+		insert := func(accountID int64) *repository.TransactionRequest {
+			request, err := repos.Requests.InsertTransactionRequest(ctx, &repository.TransactionRequest{AccountID: accountID, OperationTypeID: 1, Amount: 10})
+			require.NoError(t, err)
+			return request
+		}
+		a1, a2, b1 := insert(a.ID), insert(a.ID), insert(b.ID)
+
+		claimed, err := repos.Requests.ClaimTransactionRequest(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, a1.ID, claimed.ID)
+		txn := mustInsertTransaction(t, repos, a.ID, 1, -10)
+		require.NoError(t, repos.Requests.CompleteTransactionRequest(ctx, a1.ID, txn.ID))
+
+		claimed, err = repos.Requests.ClaimTransactionRequest(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, a2.ID, claimed.ID)
+		require.NoError(t, repos.Requests.FailTransactionRequest(ctx, a2.ID, "transaction_error", "account is not active"))
+
+		claimed, err = repos.Requests.ClaimTransactionRequest(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, b1.ID, claimed.ID)
+		require.NoError(t, repos.Requests.FailTransactionRequest(ctx, b1.ID, "transaction_error", "account is not active"))
+
+		_, err = repos.Requests.ClaimTransactionRequest(ctx)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+		succeeded, err := repos.Requests.GetTransactionRequestByID(ctx, a1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, repository.TransactionRequestSucceeded, succeeded.Status)
+		assert.Equal(t, txn.ID, succeeded.TransactionID)
+		failed, err := repos.Requests.GetTransactionRequestByID(ctx, a2.ID)
+		require.NoError(t, err)
+		assert.Equal(t, repository.TransactionRequestFailed, failed.Status)
+		assert.Equal(t, "transaction_error", failed.ErrorCode)
+		assert.Equal(t, "account is not active", failed.ErrorDetail)
+	})
+
+	t.Run("Only pending requests are finished", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		txn := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		request, err := repos.Requests.InsertTransactionRequest(ctx, &repository.TransactionRequest{AccountID: account.ID, OperationTypeID: 1, Amount: 10})
+		require.NoError(t, err)
+		require.NoError(t, repos.Requests.CompleteTransactionRequest(ctx, request.ID, txn.ID))
+
+		assert.ErrorIs(t, repos.Requests.CompleteTransactionRequest(ctx, request.ID, txn.ID), pgx.ErrNoRows)
+		assert.ErrorIs(t, repos.Requests.FailTransactionRequest(ctx, request.ID, "transaction_error", "late"), pgx.ErrNoRows)
+		assert.ErrorIs(t, repos.Requests.FailTransactionRequest(ctx, 999, "transaction_error", "missing"), pgx.ErrNoRows)
+	})
+
+	t.Run("Finished requests roll back with their unit of work", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		request, err := repos.Requests.InsertTransactionRequest(ctx, &repository.TransactionRequest{AccountID: account.ID, OperationTypeID: 1, Amount: 10})
+		require.NoError(t, err)
+
+		err = repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			claimed, err := repos.Requests.ClaimTransactionRequest(ctx)
+			require.NoError(t, err)
+			require.NoError(t, repos.Requests.FailTransactionRequest(ctx, claimed.ID, "transaction_error", "aborted"))
+			return errors.New("abort")
+		})
+		require.Error(t, err)
+
+		claimed, err := repos.Requests.ClaimTransactionRequest(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, request.ID, claimed.ID)
+		assert.Equal(t, repository.TransactionRequestPending, claimed.Status)
+	})
+}
+
+func testTransactor(t *testing.T, newRepos Factory) {
+	t.Run("Committed work is visible", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+
+		var created *repository.Customer
+		err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			created, err = repos.Customers.InsertCustomer(ctx, &repository.Customer{DocumentNumber: "1"})
+			return err
+		})
+		require.NoError(t, err)
+
+		_, err = repos.Customers.GetCustomerByID(ctx, created.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Failed work is rolled back", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		txn := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		errAbort := errors.New("abort")
+
+		err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := repos.Customers.InsertCustomer(ctx, &repository.Customer{DocumentNumber: "2"})
+			require.NoError(t, err)
+			require.NoError(t, repos.Transactions.UpdateTransactionBalance(ctx, txn.ID, 0))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = repos.Customers.GetCustomerByDocumentNumber(ctx, "2")
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		outstanding, err := repos.Transactions.GetOutstandingTransactionsByAccountID(ctx, account.ID)
+		require.NoError(t, err)
+		require.Len(t, outstanding, 1)
+		assert.Equal(t, -10.0, outstanding[0].Balance)
+	})
+
+	t.Run("Failed work is rolled back in every table it wrote", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		account := mustInsertAccount(t, repos, "1")
+		txn := mustInsertTransaction(t, repos, account.ID, 1, -10)
+		errAbort := errors.New("abort")
+
+		err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := repos.Accounts.UpdateAccountStatus(ctx, account.ID, "blocked")
+			require.NoError(t, err)
+			require.NoError(t, repos.Transactions.UpdateTransactionBalance(ctx, txn.ID, -5))
+			require.NoError(t, repos.Transactions.UpdateTransactionBalances(ctx, map[int64]float64{txn.ID: 0}))
+			_, err = repos.Ledger.PostJournalEntry(ctx, &repository.JournalEntry{
+				Kind: "purchase", TransactionID: txn.ID, CreatedAt: time.Now().UTC(),
+				Postings: []repository.Posting{
+					{Account: repository.LedgerAccount{Code: fmt.Sprintf("customer_receivable:%d", account.ID), Type: "asset", AccountID: account.ID}, TransactionID: txn.ID, Amount: 10},
+					{Account: repository.LedgerAccount{Code: "cash_clearing", Type: "asset"}, Amount: -10},
+				},
+			})
+			require.NoError(t, err)
+			_, err = repos.Audit.InsertAuditEvent(ctx, &repository.AuditEvent{Action: "account.status_updated", EntityType: "account", EntityID: account.ID})
+			require.NoError(t, err)
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		stored, err := repos.Accounts.GetAccountByID(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "active", stored.Status)
+		txn, err = repos.Transactions.GetTransactionByID(ctx, txn.ID)
+		require.NoError(t, err)
+		assert.Equal(t, -10.0, txn.Balance)
+		balances, err := repos.Ledger.GetLedgerAccountBalances(ctx, account.ID)
+		require.NoError(t, err)
+		assert.Empty(t, balances)
+		events, err := repos.Audit.GetAuditEvents(ctx, repository.AuditEventFilter{})
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Nested work joins the enclosing unit of work", func(t *testing.T) {
+		repos := newRepos(t)
+		ctx := context.Background()
+		errAbort := errors.New("abort")
+
+		err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, err := repos.Customers.InsertCustomer(ctx, &repository.Customer{DocumentNumber: "1"})
+				return err
+			})
+			require.NoError(t, err)
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = repos.Customers.GetCustomerByDocumentNumber(ctx, "1")
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+}
+
+func mustInsertCustomer(t *testing.T, repos Repositories, documentNumber string) *repository.Customer {
+	t.Helper()
+	customer, err := repos.Customers.InsertCustomer(context.Background(), &repository.Customer{DocumentNumber: documentNumber})
+	require.NoError(t, err)
+	return customer
+}
+
+// mustInsertAccount creates a customer with the document number and a credit account for it
+func mustInsertAccount(t *testing.T, repos Repositories, documentNumber string) *repository.Account {
+	t.Helper()
+	customer := mustInsertCustomer(t, repos, documentNumber)
+	account, err := repos.Accounts.InsertAccount(context.Background(), customer.ID, "credit")
+	require.NoError(t, err)
+	return account
+}
+
+func mustInsertTransaction(t *testing.T, repos Repositories, accountID, operationTypeID int64, amount float64) *repository.Transaction {
+	t.Helper()
+	now := time.Now().UTC()
+	txn, err := repos.Transactions.InsertTransaction(context.Background(), accountID, operationTypeID, amount, amount, now, now, "", 0)
+	require.NoError(t, err)
+	return txn
+}
+
+func assertPgError(t *testing.T, err error, code, constraint string) {
+	t.Helper()
+	var pgErr *pgconn.PgError
+	if assert.True(t, errors.As(err, &pgErr), "expected a *pgconn.PgError, got %v", err) {
+		assert.Equal(t, code, pgErr.Code)
+		assert.Equal(t, constraint, pgErr.ConstraintName)
+	}
+}
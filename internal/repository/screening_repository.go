@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+func NewScreeningRepository(db PgxPoolIface) ScreeningRepository {
+	return &screeningRepo{db: db}
+}
+
+// GetScreeningRules retrieves the enabled screening rules applying to accounts of the product, in the
+// order they were defined
+func (r *screeningRepo) GetScreeningRules(ctx context.Context, product string) ([]*ScreeningRule, error) {
+	query := `SELECT id, code, COALESCE(product, ''), operation_type_ids, kind, window_hours, threshold,
+			COALESCE(max_account_age_days, 0), action
+		FROM screening_rules
+		WHERE enabled AND (product IS NULL OR product = $1)
+		ORDER BY id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve screening rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*ScreeningRule
+	for rows.Next() {
+		rule := &ScreeningRule{}
+		if err := rows.Scan(&rule.ID, &rule.Code, &rule.Product, &rule.OperationTypeIDs, &rule.Kind,
+			&rule.WindowHours, &rule.Threshold, &rule.MaxAccountAgeDays, &rule.Action); err != nil {
+			return nil, fmt.Errorf("failed to scan screening rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// InsertScreeningEvaluation records the screening of a transaction
+func (r *screeningRepo) InsertScreeningEvaluation(ctx context.Context, evaluation *ScreeningEvaluation) (*ScreeningEvaluation, error) {
+	query := `INSERT INTO screening_evaluations (account_id, operation_type_id, amount, outcome, reason_codes, transaction_id, correlation_id)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6::bigint, 0), NULLIF($7, ''))
+		RETURNING id, created_at`
+
+	created := *evaluation
+	created.CorrelationID = middleware.GetCorrelationIDFromContext(ctx)
+	if created.ReasonCodes == nil {
+		created.ReasonCodes = []string{}
+	}
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		created.AccountID, created.OperationTypeID, created.Amount, created.Outcome, created.ReasonCodes,
+		created.TransactionID, created.CorrelationID,
+	).Scan(&created.ID, &created.CreatedAt)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert screening evaluation")
+		return nil, fmt.Errorf("failed to insert screening evaluation: %w", err)
+	}
+	return &created, nil
+}
@@ -0,0 +1,112 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetScreeningRules(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewScreeningRepository(mockDB)
+
+	mockDB.ExpectQuery(`FROM screening_rules WHERE enabled AND \(product IS NULL OR product = \$1\) ORDER BY id`).
+		WithArgs("credit").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "code", "product", "operation_type_ids", "kind", "window_hours", "threshold", "max_account_age_days", "action"}).
+			AddRow(int64(1), "withdrawal_velocity", "", []int64{3}, "count", 24, 5.0, 0, "deny").
+			AddRow(int64(3), "new_account_large_debit", "", []int64{1, 2, 3}, "single_amount", 0, 1000.0, 7, "deny"))
+
+	rules, err := repo.GetScreeningRules(context.Background(), "credit")
+
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	assert.Equal(t, &repository.ScreeningRule{
+		ID:                3,
+		Code:              "new_account_large_debit",
+		OperationTypeIDs:  []int64{1, 2, 3},
+		Kind:              repository.ScreeningKindSingleAmount,
+		Threshold:         1000,
+		MaxAccountAgeDays: 7,
+		Action:            repository.ScreeningOutcomeDeny,
+	}, rules[1])
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestInsertScreeningEvaluation(t *testing.T) {
+	t.Run("Denial without a transaction", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewScreeningRepository(mockDB)
+		ctx := middleware.WithCorrelationID(context.Background(), "screening-1")
+		createdAt := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`INSERT INTO screening_evaluations`).
+			WithArgs(int64(1), int64(3), -50.0, "deny", []string{"withdrawal_velocity"}, int64(0), "screening-1").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(4), createdAt))
+
+		evaluation, err := repo.InsertScreeningEvaluation(ctx, &repository.ScreeningEvaluation{
+			AccountID:       1,
+			OperationTypeID: 3,
+			Amount:          -50,
+			Outcome:         repository.ScreeningOutcomeDeny,
+			ReasonCodes:     []string{"withdrawal_velocity"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), evaluation.ID)
+		assert.Equal(t, "screening-1", evaluation.CorrelationID)
+		assert.Equal(t, createdAt, evaluation.CreatedAt)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Allowed transaction without reasons", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewScreeningRepository(mockDB)
+
+		mockDB.ExpectQuery(`INSERT INTO screening_evaluations`).
+			WithArgs(int64(1), int64(1), -20.0, "allow", []string{}, int64(9), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(5), time.Now()))
+
+		evaluation, err := repo.InsertScreeningEvaluation(context.Background(), &repository.ScreeningEvaluation{
+			AccountID: 1, OperationTypeID: 1, Amount: -20, Outcome: repository.ScreeningOutcomeAllow, TransactionID: 9,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{}, evaluation.ReasonCodes)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewScreeningRepository(mockDB)
+
+		mockDB.ExpectQuery(`INSERT INTO screening_evaluations`).
+			WithArgs(int64(99), int64(1), -20.0, "allow", []string{}, int64(9), "").
+			WillReturnError(errors.New("violates foreign key constraint screening_evaluations_account_id_fkey"))
+
+		evaluation, err := repo.InsertScreeningEvaluation(context.Background(), &repository.ScreeningEvaluation{
+			AccountID: 99, OperationTypeID: 1, Amount: -20, Outcome: repository.ScreeningOutcomeAllow, TransactionID: 9,
+		})
+
+		assert.Error(t, err)
+		assert.Nil(t, evaluation)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// transactionRequestColumns are the columns scanned by scanTransactionRequest
+const transactionRequestColumns = `id, account_id, operation_type_id, amount, COALESCE(merchant_reference, ''), status,
+		COALESCE(transaction_id, 0), COALESCE(error_code, ''), COALESCE(error_detail, ''), COALESCE(correlation_id, ''),
+		COALESCE(principal, ''), created_at, updated_at`
+
+func NewTransactionRequestsRepository(db PgxPoolIface) TransactionRequestsRepository {
+	return &transactionRequestsRepo{db: db}
+}
+
+// InsertTransactionRequest queues a pending request attributed to the correlation id and principal of ctx
+func (r *transactionRequestsRepo) InsertTransactionRequest(ctx context.Context, request *TransactionRequest) (*TransactionRequest, error) {
+	query := `INSERT INTO transaction_requests (account_id, operation_type_id, amount, merchant_reference, correlation_id, principal)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''))
+		RETURNING ` + transactionRequestColumns
+
+	created := &TransactionRequest{}
+	err := scanTransactionRequest(conn(ctx, r.db).QueryRow(ctx, query,
+		request.AccountID, request.OperationTypeID, request.Amount, request.MerchantReference,
+		middleware.GetCorrelationIDFromContext(ctx), middleware.GetPrincipalFromContext(ctx),
+	), created)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Msg("Database error: failed to insert transaction request")
+		return nil, err
+	}
+	return created, nil
+}
+
+// GetTransactionRequestByID retrieves a request; it returns pgx.ErrNoRows when there is none
+func (r *transactionRequestsRepo) GetTransactionRequestByID(ctx context.Context, requestID int64) (*TransactionRequest, error) {
+	query := `SELECT ` + transactionRequestColumns + ` FROM transaction_requests WHERE id = $1`
+
+	request := &TransactionRequest{}
+	if err := scanTransactionRequest(conn(ctx, r.db).QueryRow(ctx, query, requestID), request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// ClaimTransactionRequest locks the oldest pending request of an account that has no older pending request
+// and that no other unit of work holds. Requests of an account are thus claimed one at a time and in order,
+// while those of other accounts are claimed concurrently. The request stays locked until the unit of work
+// ends; it returns pgx.ErrNoRows when there is none to claim.
+func (r *transactionRequestsRepo) ClaimTransactionRequest(ctx context.Context) (*TransactionRequest, error) {
+	query := `SELECT ` + transactionRequestColumns + `
+		FROM transaction_requests r
+		WHERE status = 'pending'
+		  AND NOT EXISTS (
+			SELECT 1 FROM transaction_requests older
+			WHERE older.account_id = r.account_id AND older.status = 'pending' AND older.id < r.id
+		  )
+		ORDER BY id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`
+
+	request := &TransactionRequest{}
+	if err := scanTransactionRequest(conn(ctx, r.db).QueryRow(ctx, query), request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// CompleteTransactionRequest records the transaction a pending request posted; it returns pgx.ErrNoRows when
+// the request is not pending
+func (r *transactionRequestsRepo) CompleteTransactionRequest(ctx context.Context, requestID, transactionID int64) error {
+	query := `UPDATE transaction_requests SET status = 'succeeded', transaction_id = $2 WHERE id = $1 AND status = 'pending'`
+
+	return r.finish(ctx, query, requestID, transactionID)
+}
+
+// FailTransactionRequest records why a pending request could not be processed; it returns pgx.ErrNoRows
+// when the request is not pending
+func (r *transactionRequestsRepo) FailTransactionRequest(ctx context.Context, requestID int64, errorCode, errorDetail string) error {
+	query := `UPDATE transaction_requests SET status = 'failed', error_code = $2, error_detail = $3 WHERE id = $1 AND status = 'pending'`
+
+	return r.finish(ctx, query, requestID, errorCode, errorDetail)
+}
+
+// finish runs the update of a pending request to its final status
+func (r *transactionRequestsRepo) finish(ctx context.Context, query string, requestID int64, args ...any) error {
+	res, err := conn(ctx, r.db).Exec(ctx, query, append([]any{requestID}, args...)...)
+	if err != nil {
+		log.Error().Ctx(ctx).Int64("transaction_request_id", requestID).Err(err).Msg("Database error: failed to finish transaction request")
+		return fmt.Errorf("failed to finish transaction request: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func scanTransactionRequest(row pgx.Row, request *TransactionRequest) error {
+	return row.Scan(
+		&request.ID,
+		&request.AccountID,
+		&request.OperationTypeID,
+		&request.Amount,
+		&request.MerchantReference,
+		&request.Status,
+		&request.TransactionID,
+		&request.ErrorCode,
+		&request.ErrorDetail,
+		&request.CorrelationID,
+		&request.Principal,
+		&request.CreatedAt,
+		&request.UpdatedAt,
+	)
+}
@@ -0,0 +1,206 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+var transactionRequestColumns = []string{"id", "account_id", "operation_type_id", "amount", "merchant_reference", "status",
+	"transaction_id", "error_code", "error_detail", "correlation_id", "principal", "created_at", "updated_at"}
+
+func TestInsertTransactionRequest(t *testing.T) {
+	createdAt := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("Request attributed to the context", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+		ctx := middleware.WithPrincipal(middleware.WithCorrelationID(context.Background(), "flow-1"), "alice")
+
+		mockDB.ExpectQuery(`INSERT INTO transaction_requests`).
+			WithArgs(int64(1), int64(1), 12.5, "order-1", "flow-1", "alice").
+			WillReturnRows(pgxmock.NewRows(transactionRequestColumns).
+				AddRow(int64(3), int64(1), int64(1), 12.5, "order-1", "pending", int64(0), "", "", "flow-1", "alice", createdAt, createdAt))
+
+		request, err := repo.InsertTransactionRequest(ctx, &repository.TransactionRequest{
+			AccountID: 1, OperationTypeID: 1, Amount: 12.5, MerchantReference: "order-1",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), request.ID)
+		assert.Equal(t, repository.TransactionRequestPending, request.Status)
+		assert.Equal(t, "alice", request.Principal)
+		assert.Equal(t, createdAt, request.CreatedAt)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+
+		mockDB.ExpectQuery(`INSERT INTO transaction_requests`).
+			WithArgs(int64(1), int64(1), 12.5, "", "", "").
+			WillReturnError(errors.New("db error"))
+
+		request, err := repo.InsertTransactionRequest(context.Background(), &repository.TransactionRequest{AccountID: 1, OperationTypeID: 1, Amount: 12.5})
+
+		assert.Nil(t, request)
+		assert.EqualError(t, err, "db error")
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetTransactionRequestByID(t *testing.T) {
+	t.Run("Succeeded request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+		createdAt := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`FROM transaction_requests WHERE id = \$1`).
+			WithArgs(int64(3)).
+			WillReturnRows(pgxmock.NewRows(transactionRequestColumns).
+				AddRow(int64(3), int64(1), int64(1), 12.5, "", "succeeded", int64(10), "", "", "", "", createdAt, createdAt.Add(time.Second)))
+
+		request, err := repo.GetTransactionRequestByID(context.Background(), 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, repository.TransactionRequestSucceeded, request.Status)
+		assert.Equal(t, int64(10), request.TransactionID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Missing request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM transaction_requests WHERE id = \$1`).
+			WithArgs(int64(3)).
+			WillReturnError(pgx.ErrNoRows)
+
+		request, err := repo.GetTransactionRequestByID(context.Background(), 3)
+
+		assert.Nil(t, request)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestClaimTransactionRequest(t *testing.T) {
+	t.Run("Oldest free request skipping locked ones", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+		createdAt := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`FROM transaction_requests r WHERE status = 'pending' AND NOT EXISTS .* ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED`).
+			WillReturnRows(pgxmock.NewRows(transactionRequestColumns).
+				AddRow(int64(3), int64(1), int64(4), 60.0, "", "pending", int64(0), "", "", "flow-1", "", createdAt, createdAt))
+
+		request, err := repo.ClaimTransactionRequest(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), request.ID)
+		assert.Equal(t, "flow-1", request.CorrelationID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Empty queue", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+
+		mockDB.ExpectQuery(`FOR UPDATE SKIP LOCKED`).WillReturnError(pgx.ErrNoRows)
+
+		request, err := repo.ClaimTransactionRequest(context.Background())
+
+		assert.Nil(t, request)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestFinishTransactionRequest(t *testing.T) {
+	t.Run("Complete pending request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+
+		mockDB.ExpectExec(`UPDATE transaction_requests SET status = 'succeeded', transaction_id = \$2 WHERE id = \$1 AND status = 'pending'`).
+			WithArgs(int64(3), int64(10)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		assert.NoError(t, repo.CompleteTransactionRequest(context.Background(), 3, 10))
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Fail pending request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+
+		mockDB.ExpectExec(`UPDATE transaction_requests SET status = 'failed', error_code = \$2, error_detail = \$3 WHERE id = \$1 AND status = 'pending'`).
+			WithArgs(int64(3), "transaction_error", "account is blocked or closed").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		assert.NoError(t, repo.FailTransactionRequest(context.Background(), 3, "transaction_error", "account is blocked or closed"))
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Request no longer pending", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+
+		mockDB.ExpectExec(`UPDATE transaction_requests SET status = 'succeeded'`).
+			WithArgs(int64(3), int64(10)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		assert.ErrorIs(t, repo.CompleteTransactionRequest(context.Background(), 3, 10), pgx.ErrNoRows)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionRequestsRepository(mockDB)
+
+		mockDB.ExpectExec(`UPDATE transaction_requests SET status = 'failed'`).
+			WithArgs(int64(3), "transaction_error", "late").
+			WillReturnError(errors.New("db error"))
+
+		err = repo.FailTransactionRequest(context.Background(), 3, "transaction_error", "late")
+		assert.EqualError(t, err, "failed to finish transaction request: db error")
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
@@ -3,8 +3,12 @@ package repository
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
 )
 
@@ -12,40 +16,295 @@ func NewTransactionsRepository(db PgxPoolIface) TransactionsRepository {
 	return &transactionsRepo{db: db}
 }
 
-// InsertTransaction inserts a new transaction
-func (r *transactionsRepo) InsertTransaction(ctx context.Context, accountID, operationTypeID int64, amount, balance float64) (*Transaction, error) {
-	query := `INSERT INTO transactions (account_id, operation_type_id, amount, balance) VALUES ($1, $2, $3, $4) RETURNING id, event_date, balance`
-	transaction := &Transaction{}
+// InsertTransaction inserts a new transaction that took place at eventDate and was booked at bookingDate.
+// merchantReference and duplicateOfTransactionID are optional; the latter flags the transaction as a suspected
+// duplicate of an earlier one.
+func (r *transactionsRepo) InsertTransaction(ctx context.Context, accountID, operationTypeID int64, amount, balance float64, eventDate, bookingDate time.Time, merchantReference string, duplicateOfTransactionID int64) (*Transaction, error) {
+	query := `INSERT INTO transactions (account_id, operation_type_id, amount, balance, event_date, booking_date, merchant_reference, duplicate_of_transaction_id, correlation_id) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8::bigint, 0), NULLIF($9, '')) RETURNING id, event_date, balance`
+	transaction := &Transaction{BookingDate: bookingDate, CorrelationID: middleware.GetCorrelationIDFromContext(ctx)}
 
-	err := r.db.QueryRow(ctx, query, accountID, operationTypeID, amount, balance).Scan(
+	err := conn(ctx, r.db).QueryRow(ctx, query, accountID, operationTypeID, amount, balance, eventDate, bookingDate, merchantReference, duplicateOfTransactionID, transaction.CorrelationID).Scan(
 		&transaction.ID,
 		&transaction.EventDate,
 		&transaction.Balance,
 	)
 	if err != nil {
 		reqID := middleware.GetRequestIDFromContext(ctx)
-		log.Error().Str("request_id", reqID).Err(err).Msg("Database error: failed to insert transaction")
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert transaction")
 		return nil, err
 	}
 
 	transaction.AccountID = accountID
 	transaction.Amount = amount
 	transaction.OperationTypeID = operationTypeID
+	transaction.MerchantReference = merchantReference
+	transaction.DuplicateOfID = duplicateOfTransactionID
 
 	return transaction, nil
 }
 
+// InsertTransactions inserts the transactions in order in one round trip, as a batch of the statements
+// InsertTransaction runs, booked at their event dates, filling in their IDs, event dates and correlation IDs.
+// The batch runs in one transaction, so either all of them are inserted or none.
+func (r *transactionsRepo) InsertTransactions(ctx context.Context, transactions []*Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	query := `INSERT INTO transactions (account_id, operation_type_id, amount, balance, event_date, booking_date, merchant_reference, duplicate_of_transaction_id, correlation_id) VALUES ($1, $2, $3, $4, $5, $5, NULLIF($6, ''), NULLIF($7::bigint, 0), NULLIF($8, '')) RETURNING id, event_date, balance`
+	correlationID := middleware.GetCorrelationIDFromContext(ctx)
+
+	batch := &pgx.Batch{}
+	for _, txn := range transactions {
+		txn.BookingDate, txn.CorrelationID = txn.EventDate, correlationID
+		batch.Queue(query, txn.AccountID, txn.OperationTypeID, txn.Amount, txn.Balance, txn.EventDate, txn.MerchantReference, txn.DuplicateOfID, correlationID).
+			QueryRow(func(row pgx.Row) error {
+				return row.Scan(&txn.ID, &txn.EventDate, &txn.Balance)
+			})
+	}
+	if err := conn(ctx, r.db).SendBatch(ctx, batch).Close(); err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Int("transactions", len(transactions)).Msg("Database error: failed to insert transactions")
+		return err
+	}
+	return nil
+}
+
+// InsertTransferLeg inserts a transaction posted and booked at eventDate as one leg of the transfer
+func (r *transactionsRepo) InsertTransferLeg(ctx context.Context, transferID, accountID, operationTypeID int64, amount, balance float64, eventDate time.Time) (*Transaction, error) {
+	query := `INSERT INTO transactions (transfer_id, account_id, operation_type_id, amount, balance, event_date, booking_date, correlation_id) VALUES ($1, $2, $3, $4, $5, $6, $6, NULLIF($7, '')) RETURNING id, event_date, balance`
+	transaction := &Transaction{BookingDate: eventDate, CorrelationID: middleware.GetCorrelationIDFromContext(ctx)}
+
+	err := conn(ctx, r.db).QueryRow(ctx, query, transferID, accountID, operationTypeID, amount, balance, eventDate, transaction.CorrelationID).Scan(
+		&transaction.ID,
+		&transaction.EventDate,
+		&transaction.Balance,
+	)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert transfer leg")
+		return nil, err
+	}
+
+	transaction.TransferID = transferID
+	transaction.AccountID = accountID
+	transaction.Amount = amount
+	transaction.OperationTypeID = operationTypeID
+
+	return transaction, nil
+}
+
+// InsertFeeTransaction inserts a fee posted at eventDate for the parent transaction, booked with it
+func (r *transactionsRepo) InsertFeeTransaction(ctx context.Context, parentTransactionID, accountID, operationTypeID int64, amount, balance float64, eventDate time.Time) (*Transaction, error) {
+	query := `INSERT INTO transactions (parent_transaction_id, account_id, operation_type_id, amount, balance, event_date, booking_date, correlation_id) VALUES ($1, $2, $3, $4, $5, $6, (SELECT booking_date FROM transactions WHERE id = $1), NULLIF($7, '')) RETURNING id, event_date, booking_date, balance`
+	transaction := &Transaction{CorrelationID: middleware.GetCorrelationIDFromContext(ctx)}
+
+	err := conn(ctx, r.db).QueryRow(ctx, query, parentTransactionID, accountID, operationTypeID, amount, balance, eventDate, transaction.CorrelationID).Scan(
+		&transaction.ID,
+		&transaction.EventDate,
+		&transaction.BookingDate,
+		&transaction.Balance,
+	)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert fee transaction")
+		return nil, err
+	}
+
+	transaction.ParentTransactionID = parentTransactionID
+	transaction.AccountID = accountID
+	transaction.Amount = amount
+	transaction.OperationTypeID = operationTypeID
+
+	return transaction, nil
+}
+
+// GetTransactionByID retrieves a transaction; it returns pgx.ErrNoRows when there is none
+func (r *transactionsRepo) GetTransactionByID(ctx context.Context, transactionID int64) (*Transaction, error) {
+	query := `SELECT id, account_id, operation_type_id, amount, balance, event_date, booking_date, COALESCE(transfer_id, 0),
+			COALESCE(parent_transaction_id, 0), COALESCE(merchant_reference, ''), COALESCE(duplicate_of_transaction_id, 0),
+			COALESCE(prev_hash, ''), COALESCE(hash, '')
+		FROM transactions
+		WHERE id = $1`
+
+	txn := &Transaction{}
+	err := conn(ctx, r.db).QueryRow(ctx, query, transactionID).Scan(
+		&txn.ID,
+		&txn.AccountID,
+		&txn.OperationTypeID,
+		&txn.Amount,
+		&txn.Balance,
+		&txn.EventDate,
+		&txn.BookingDate,
+		&txn.TransferID,
+		&txn.ParentTransactionID,
+		&txn.MerchantReference,
+		&txn.DuplicateOfID,
+		&txn.PrevHash,
+		&txn.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// GetDuplicateTransaction retrieves the latest transaction of the account with the operation type and amount and
+// an event date from since on. When merchantReference is set, the transaction must carry it too. It returns
+// pgx.ErrNoRows when there is none.
+func (r *transactionsRepo) GetDuplicateTransaction(ctx context.Context, accountID, operationTypeID int64, amount float64, merchantReference string, since time.Time) (*Transaction, error) {
+	query := `SELECT id, balance, event_date, COALESCE(merchant_reference, '')
+		FROM transactions
+		WHERE account_id = $1 AND operation_type_id = $2 AND amount = $3 AND event_date >= $4
+		  AND ($5 = '' OR merchant_reference = $5)
+		ORDER BY event_date DESC, id DESC
+		LIMIT 1`
+
+	txn := &Transaction{AccountID: accountID, OperationTypeID: operationTypeID, Amount: amount}
+	err := conn(ctx, r.db).QueryRow(ctx, query, accountID, operationTypeID, amount, since, merchantReference).Scan(
+		&txn.ID,
+		&txn.Balance,
+		&txn.EventDate,
+		&txn.MerchantReference,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// GetTransactionsByTransferID retrieves the legs of a transfer in posting order
+func (r *transactionsRepo) GetTransactionsByTransferID(ctx context.Context, transferID int64) ([]*Transaction, error) {
+	query := `SELECT id, account_id, operation_type_id, amount, balance, event_date
+		FROM transactions
+		WHERE transfer_id = $1
+		ORDER BY id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*Transaction
+	for rows.Next() {
+		txn := &Transaction{TransferID: transferID}
+		if err := rows.Scan(&txn.ID, &txn.AccountID, &txn.OperationTypeID, &txn.Amount, &txn.Balance, &txn.EventDate); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+	return transactions, rows.Err()
+}
+
+// GetTransactionsByAccountID retrieves every transaction of the account, oldest first
+func (r *transactionsRepo) GetTransactionsByAccountID(ctx context.Context, accountID int64) ([]*Transaction, error) {
+	query := `SELECT id, operation_type_id, amount, balance, event_date, COALESCE(transfer_id, 0), COALESCE(parent_transaction_id, 0)
+		FROM transactions
+		WHERE account_id = $1
+		ORDER BY event_date, id`
+	return r.queryAccountTransactions(ctx, accountID, query, accountID)
+}
+
+// GetTransactionsByAccountIDInPeriod retrieves the transactions of the account with an event date
+// in [from, to), oldest first
+func (r *transactionsRepo) GetTransactionsByAccountIDInPeriod(ctx context.Context, accountID int64, from, to time.Time) ([]*Transaction, error) {
+	query := `SELECT id, operation_type_id, amount, balance, event_date, COALESCE(transfer_id, 0), COALESCE(parent_transaction_id, 0)
+		FROM transactions
+		WHERE account_id = $1 AND event_date >= $2 AND event_date < $3
+		ORDER BY event_date, id`
+	return r.queryAccountTransactions(ctx, accountID, query, accountID, from, to)
+}
+
+// GetTransactionsByAccountIDSince retrieves the transactions of the account with an event date from since
+// on, oldest first
+func (r *transactionsRepo) GetTransactionsByAccountIDSince(ctx context.Context, accountID int64, since time.Time) ([]*Transaction, error) {
+	query := `SELECT id, operation_type_id, amount, balance, event_date, COALESCE(transfer_id, 0), COALESCE(parent_transaction_id, 0)
+		FROM transactions
+		WHERE account_id = $1 AND event_date >= $2
+		ORDER BY event_date, id`
+	return r.queryAccountTransactions(ctx, accountID, query, accountID, since)
+}
+
+// StreamTransactionsByAccountID passes the transactions of the account with an event date in [from, to) to fn,
+// oldest first, as they are read from the database; a zero bound is open. It stops at the first error of fn.
+func (r *transactionsRepo) StreamTransactionsByAccountID(ctx context.Context, accountID int64, from, to time.Time, fn func(*Transaction) error) error {
+	query := `SELECT t.id, t.operation_type_id, ot.description, t.amount, t.balance, t.event_date, COALESCE(t.transfer_id, 0),
+			COALESCE(t.parent_transaction_id, 0), COALESCE(t.merchant_reference, ''), COALESCE(t.duplicate_of_transaction_id, 0)
+		FROM transactions t
+		JOIN operation_types ot ON ot.id = t.operation_type_id
+		WHERE t.account_id = $1
+		  AND ($2::timestamp IS NULL OR t.event_date >= $2)
+		  AND ($3::timestamp IS NULL OR t.event_date < $3)
+		ORDER BY t.event_date, t.id`
+
+	var lower, upper any
+	if !from.IsZero() {
+		lower = from
+	}
+	if !to.IsZero() {
+		upper = to
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, accountID, lower, upper)
+	if err != nil {
+		return fmt.Errorf("failed to stream transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		txn := &Transaction{AccountID: accountID}
+		if err := rows.Scan(&txn.ID, &txn.OperationTypeID, &txn.OperationType, &txn.Amount, &txn.Balance, &txn.EventDate,
+			&txn.TransferID, &txn.ParentTransactionID, &txn.MerchantReference, &txn.DuplicateOfID); err != nil {
+			return fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		if err := fn(txn); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetNetAmountByAccountIDBefore sums the amounts of the account's transactions with an event date before the
+// given time, in the database
+func (r *transactionsRepo) GetNetAmountByAccountIDBefore(ctx context.Context, accountID int64, before time.Time) (float64, error) {
+	query := `SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE account_id = $1 AND event_date < $2`
+
+	var net float64
+	if err := conn(ctx, r.db).QueryRow(ctx, query, accountID, before).Scan(&net); err != nil {
+		return 0, fmt.Errorf("failed to sum transactions: %w", err)
+	}
+	return net, nil
+}
+
+func (r *transactionsRepo) queryAccountTransactions(ctx context.Context, accountID int64, query string, args ...any) ([]*Transaction, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*Transaction
+	for rows.Next() {
+		txn := &Transaction{AccountID: accountID}
+		if err := rows.Scan(&txn.ID, &txn.OperationTypeID, &txn.Amount, &txn.Balance, &txn.EventDate, &txn.TransferID, &txn.ParentTransactionID); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+	return transactions, rows.Err()
+}
+
 // GetOutstandingTransactionsByAccountID retrieves list of transactions for a given accountID
 func (r *transactionsRepo) GetOutstandingTransactionsByAccountID(ctx context.Context, accountID int64) ([]*Transaction, error) {
 	var transactions []*Transaction
-	query := `SELECT id, amount, balance, event_date 
+	query := `SELECT id, operation_type_id, amount, balance, event_date 
 		FROM transactions 
 		WHERE account_id = $1 
-		  AND operation_type_id IN (1,2,3) 
+		  AND operation_type_id IN (1,2,3,5,7,8,9) 
 		  AND balance < 0 
-		ORDER BY event_date`
+		ORDER BY event_date, id`
 
-	rows, err := r.db.Query(ctx,
+	rows, err := conn(ctx, r.db).Query(ctx,
 		query, accountID)
 	if err != nil {
 		return nil, err
@@ -53,8 +312,8 @@ func (r *transactionsRepo) GetOutstandingTransactionsByAccountID(ctx context.Con
 	defer rows.Close()
 
 	for rows.Next() {
-		txn := &Transaction{}
-		if err := rows.Scan(&txn.ID, &txn.Amount, &txn.Balance, &txn.EventDate); err != nil {
+		txn := &Transaction{AccountID: accountID}
+		if err := rows.Scan(&txn.ID, &txn.OperationTypeID, &txn.Amount, &txn.Balance, &txn.EventDate); err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, txn)
@@ -65,16 +324,165 @@ func (r *transactionsRepo) GetOutstandingTransactionsByAccountID(ctx context.Con
 // UpdateTransactionBalance updates the balance for the provided transactionID
 func (r *transactionsRepo) UpdateTransactionBalance(ctx context.Context, transactionID int64, newBalance float64) error {
 	query := `UPDATE transactions SET balance = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
-	res, err := r.db.Exec(ctx, query, newBalance, transactionID)
+	res, err := conn(ctx, r.db).Exec(ctx, query, newBalance, transactionID)
 	if err != nil {
 		return err
 	}
 	if res.RowsAffected() != 1 {
 		errMsg := fmt.Errorf("failed to update transaction balance: unexpected number of rows affected: %d for transaction %d", res.RowsAffected(), transactionID)
-		log.Error().Err(errMsg).Msg("Database error")
+		log.Error().Ctx(ctx).Err(errMsg).Msg("Database error")
 		return errMsg
 	}
 
-	log.Info().Msgf("Updated transaction %d with new balance %.2f", transactionID, newBalance)
+	log.Info().Ctx(ctx).Msgf("Updated transaction %d with new balance %.2f", transactionID, newBalance)
 	return nil
 }
+
+// DischargeTransactions applies the balance of the credit to the account's outstanding debts, oldest first, in
+// one statement: the running sum of what the debts owe tells how much of the credit is left for each, then
+// the debts and the credit are updated with what was applied. It returns the discharges in the order the
+// debts were discharged; the credit is left untouched when there is nothing to discharge.
+func (r *transactionsRepo) DischargeTransactions(ctx context.Context, creditTxn *Transaction) ([]*Discharge, error) {
+	query := `WITH credit AS (
+			SELECT id, balance FROM transactions WHERE id = $2 AND account_id = $1 AND balance > 0
+		), debts AS (
+			SELECT id, event_date, balance,
+				SUM(-balance) OVER (ORDER BY event_date, id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) + balance AS owed_before
+			FROM transactions
+			WHERE account_id = $1
+			  AND operation_type_id IN (1,2,3,5,7,8,9)
+			  AND balance < 0
+		), allocations AS (
+			SELECT d.id, d.event_date, d.balance, LEAST(-d.balance, c.balance - d.owed_before) AS amount
+			FROM debts d, credit c
+			WHERE d.owed_before < c.balance
+		), discharged AS (
+			UPDATE transactions t SET balance = t.balance + a.amount, updated_at = CURRENT_TIMESTAMP
+			FROM allocations a
+			WHERE t.id = a.id
+		), remaining AS (
+			UPDATE transactions t SET balance = c.balance - (SELECT SUM(amount) FROM allocations), updated_at = CURRENT_TIMESTAMP
+			FROM credit c
+			WHERE t.id = c.id AND EXISTS (SELECT 1 FROM allocations)
+		)
+		SELECT id, balance, amount FROM allocations ORDER BY event_date, id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, creditTxn.AccountID, creditTxn.ID)
+	if err != nil {
+		log.Error().Ctx(ctx).Int64("transaction_id", creditTxn.ID).Err(err).Msg("Database error: failed to discharge transactions")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var discharges []*Discharge
+	for rows.Next() {
+		discharge := &Discharge{}
+		if err := rows.Scan(&discharge.TransactionID, &discharge.Balance, &discharge.Amount); err != nil {
+			return nil, err
+		}
+		discharges = append(discharges, discharge)
+	}
+	return discharges, rows.Err()
+}
+
+// UpdateTransactionBalances sets the balances of the transactions in a single statement; it fails when any
+// of them is missing
+func (r *transactionsRepo) UpdateTransactionBalances(ctx context.Context, balances map[int64]float64) error {
+	if len(balances) == 0 {
+		return nil
+	}
+	query := `UPDATE transactions t SET balance = u.balance, updated_at = CURRENT_TIMESTAMP
+		FROM unnest($1::bigint[], $2::numeric[]) AS u(id, balance)
+		WHERE t.id = u.id`
+
+	transactionIDs := slices.Sorted(maps.Keys(balances))
+	newBalances := make([]float64, len(transactionIDs))
+	for i, id := range transactionIDs {
+		newBalances[i] = balances[id]
+	}
+
+	res, err := conn(ctx, r.db).Exec(ctx, query, transactionIDs, newBalances)
+	if err != nil {
+		log.Error().Ctx(ctx).Err(err).Int("transactions", len(transactionIDs)).Msg("Database error: failed to update transaction balances")
+		return fmt.Errorf("failed to update transaction balances: %w", err)
+	}
+	if res.RowsAffected() != int64(len(transactionIDs)) {
+		return fmt.Errorf("failed to update transaction balances: unexpected number of rows affected: %d for %d transactions", res.RowsAffected(), len(transactionIDs))
+	}
+	return nil
+}
+
+// GetChainHead retrieves the hash of the latest chained transaction of the account; it returns
+// pgx.ErrNoRows when the account's chain has not started
+func (r *transactionsRepo) GetChainHead(ctx context.Context, accountID int64) (string, error) {
+	query := `SELECT hash FROM transactions WHERE account_id = $1 AND hash IS NOT NULL ORDER BY id DESC LIMIT 1`
+
+	var hash string
+	if err := conn(ctx, r.db).QueryRow(ctx, query, accountID).Scan(&hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// SetTransactionHash links a transaction into its account's chain; a transaction is linked only once
+func (r *transactionsRepo) SetTransactionHash(ctx context.Context, transactionID int64, prevHash, hash string) error {
+	query := `UPDATE transactions SET prev_hash = $2, hash = $3 WHERE id = $1 AND hash IS NULL`
+	res, err := conn(ctx, r.db).Exec(ctx, query, transactionID, prevHash, hash)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to set transaction hash")
+		return fmt.Errorf("failed to set transaction hash: %w", err)
+	}
+	if res.RowsAffected() != 1 {
+		return fmt.Errorf("failed to set transaction hash: transaction %d does not exist or is already chained", transactionID)
+	}
+	return nil
+}
+
+// GetTransactionChain retrieves every transaction of the account with the fields its hash covers, in
+// posting order
+func (r *transactionsRepo) GetTransactionChain(ctx context.Context, accountID int64) ([]*Transaction, error) {
+	query := `SELECT id, operation_type_id, amount, balance, event_date, COALESCE(transfer_id, 0), COALESCE(parent_transaction_id, 0),
+			COALESCE(merchant_reference, ''), COALESCE(duplicate_of_transaction_id, 0), COALESCE(prev_hash, ''), COALESCE(hash, '')
+		FROM transactions
+		WHERE account_id = $1
+		ORDER BY id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*Transaction
+	for rows.Next() {
+		txn := &Transaction{AccountID: accountID}
+		if err := rows.Scan(&txn.ID, &txn.OperationTypeID, &txn.Amount, &txn.Balance, &txn.EventDate, &txn.TransferID,
+			&txn.ParentTransactionID, &txn.MerchantReference, &txn.DuplicateOfID, &txn.PrevHash, &txn.Hash); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+	return transactions, rows.Err()
+}
+
+// GetChainedAccountIDs retrieves the ids of the accounts whose chain has started, in id order
+func (r *transactionsRepo) GetChainedAccountIDs(ctx context.Context) ([]int64, error) {
+	query := `SELECT DISTINCT account_id FROM transactions WHERE hash IS NOT NULL ORDER BY account_id`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve chained accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accountIDs []int64
+	for rows.Next() {
+		var accountID int64
+		if err := rows.Scan(&accountID); err != nil {
+			return nil, fmt.Errorf("failed to scan account id: %w", err)
+		}
+		accountIDs = append(accountIDs, accountID)
+	}
+	return accountIDs, rows.Err()
+}
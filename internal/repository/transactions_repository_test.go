@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
 )
@@ -25,15 +26,16 @@ func TestInsertTransaction(t *testing.T) {
 		operationTypeID := int64(4)
 		amount := 100.50
 		balance := amount
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
 
 		rows := pgxmock.NewRows([]string{"id", "event_date", "balance"}).
-			AddRow(int64(1), time.Now(), balance)
+			AddRow(int64(1), eventDate, balance)
 
 		mockDB.ExpectQuery(`INSERT INTO transactions`).
-			WithArgs(accountID, operationTypeID, amount, balance).
+			WithArgs(accountID, operationTypeID, amount, balance, eventDate, eventDate, "", int64(0), "").
 			WillReturnRows(rows)
 
-		transaction, err := repo.InsertTransaction(ctx, accountID, operationTypeID, amount, balance)
+		transaction, err := repo.InsertTransaction(ctx, accountID, operationTypeID, amount, balance, eventDate, eventDate, "", 0)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, transaction)
@@ -54,12 +56,13 @@ func TestInsertTransaction(t *testing.T) {
 		operationTypeID := int64(4)
 		amount := 100.50
 		balance := amount
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
 
 		mockDB.ExpectQuery(`INSERT INTO transactions`).
-			WithArgs(accountID, operationTypeID, amount, balance).
+			WithArgs(accountID, operationTypeID, amount, balance, eventDate, eventDate, "", int64(0), "").
 			WillReturnError(errors.New("database error"))
 
-		transaction, err := repo.InsertTransaction(ctx, accountID, operationTypeID, amount, balance)
+		transaction, err := repo.InsertTransaction(ctx, accountID, operationTypeID, amount, balance, eventDate, eventDate, "", 0)
 
 		assert.Error(t, err)
 		assert.Nil(t, transaction)
@@ -80,12 +83,13 @@ func TestInsertTransaction(t *testing.T) {
 		operationTypeID := int64(4)
 		amount := 100.50
 		balance := amount
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
 
 		mockDB.ExpectQuery(`INSERT INTO transactions`).
-			WithArgs(invalidAccountID, operationTypeID, amount, balance).
+			WithArgs(invalidAccountID, operationTypeID, amount, balance, eventDate, eventDate, "", int64(0), "").
 			WillReturnError(errors.New("violates foreign key constraint \"transactions_account_id_fkey\""))
 
-		transaction, err := repo.InsertTransaction(ctx, invalidAccountID, operationTypeID, amount, balance)
+		transaction, err := repo.InsertTransaction(ctx, invalidAccountID, operationTypeID, amount, balance, eventDate, eventDate, "", 0)
 
 		assert.Error(t, err)
 		assert.Nil(t, transaction)
@@ -106,12 +110,13 @@ func TestInsertTransaction(t *testing.T) {
 		invalidOperationTypeID := int64(99)
 		amount := 100.50
 		balance := amount
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
 
 		mockDB.ExpectQuery(`INSERT INTO transactions`).
-			WithArgs(accountID, invalidOperationTypeID, amount, balance).
+			WithArgs(accountID, invalidOperationTypeID, amount, balance, eventDate, eventDate, "", int64(0), "").
 			WillReturnError(errors.New("violates foreign key constraint \"transactions_operation_type_id_fkey\""))
 
-		transaction, err := repo.InsertTransaction(ctx, accountID, invalidOperationTypeID, amount, balance)
+		transaction, err := repo.InsertTransaction(ctx, accountID, invalidOperationTypeID, amount, balance, eventDate, eventDate, "", 0)
 
 		assert.Error(t, err)
 		assert.Nil(t, transaction)
@@ -119,6 +124,124 @@ func TestInsertTransaction(t *testing.T) {
 
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
+	t.Run("Flagged duplicate with merchant reference", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`INSERT INTO transactions \(account_id, operation_type_id, amount, balance, event_date, booking_date, merchant_reference, duplicate_of_transaction_id, correlation_id\)`).
+			WithArgs(int64(1), int64(1), -20.0, -20.0, eventDate, eventDate, "ref-1", int64(7), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(8), eventDate, -20.0))
+
+		transaction, err := repo.InsertTransaction(context.Background(), 1, 1, -20, -20, eventDate, eventDate, "ref-1", 7)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(8), transaction.ID)
+		assert.Equal(t, "ref-1", transaction.MerchantReference)
+		assert.Equal(t, int64(7), transaction.DuplicateOfID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestInsertTransactions(t *testing.T) {
+	eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+	t.Run("Transactions should be inserted in one batch", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		batch := mockDB.ExpectBatch()
+		batch.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(1), -50.25, -50.25, eventDate, "ref-1", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(7), eventDate, -50.25))
+		batch.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(4), 60.0, 60.0, eventDate, "", int64(7), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(8), eventDate, 60.0))
+
+		transactions := []*repository.Transaction{
+			{AccountID: 1, OperationTypeID: 1, Amount: -50.25, Balance: -50.25, EventDate: eventDate, MerchantReference: "ref-1"},
+			{AccountID: 1, OperationTypeID: 4, Amount: 60, Balance: 60, EventDate: eventDate, DuplicateOfID: 7},
+		}
+		err = repository.NewTransactionsRepository(mockDB).InsertTransactions(context.Background(), transactions)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), transactions[0].ID)
+		assert.Equal(t, int64(8), transactions[1].ID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("An empty batch should not reach the database", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		err = repository.NewTransactionsRepository(mockDB).InsertTransactions(context.Background(), nil)
+		assert.NoError(t, err)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error should fail the batch", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectBatch().ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(99), -10.0, -10.0, eventDate, "", int64(0), "").
+			WillReturnError(errors.New("database error"))
+
+		err = repository.NewTransactionsRepository(mockDB).InsertTransactions(context.Background(), []*repository.Transaction{
+			{AccountID: 1, OperationTypeID: 99, Amount: -10, Balance: -10, EventDate: eventDate},
+		})
+		assert.ErrorContains(t, err, "database error")
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestInsertFeeTransaction(t *testing.T) {
+	t.Run("Fee linked to its parent", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`INSERT INTO transactions \(parent_transaction_id, account_id, operation_type_id, amount, balance, event_date, booking_date, correlation_id\)`).
+			WithArgs(int64(5), int64(1), int64(9), -12.0, -12.0, eventDate, "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "booking_date", "balance"}).AddRow(int64(6), eventDate, eventDate.Add(time.Hour), -12.0))
+
+		fee, err := repo.InsertFeeTransaction(context.Background(), 5, 1, 9, -12, -12, eventDate)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), fee.ID)
+		assert.Equal(t, int64(5), fee.ParentTransactionID)
+		assert.Equal(t, int64(9), fee.OperationTypeID)
+		assert.Equal(t, -12.0, fee.Amount)
+		assert.Equal(t, eventDate.Add(time.Hour), fee.BookingDate)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown parent should return error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(99), int64(1), int64(9), -12.0, -12.0, eventDate, "").
+			WillReturnError(errors.New("violates foreign key constraint transactions_parent_transaction_id_fkey"))
+
+		fee, err := repo.InsertFeeTransaction(context.Background(), 99, 1, 9, -12, -12, eventDate)
+
+		assert.Error(t, err)
+		assert.Nil(t, fee)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
 }
 
 func TestGetOutstandingTransactionsByAccountID(t *testing.T) {
@@ -134,10 +257,10 @@ func TestGetOutstandingTransactionsByAccountID(t *testing.T) {
 
 		now := time.Now()
 		later := now.Add(10 * time.Second)
-		rows := pgxmock.NewRows([]string{"id", "amount", "balance", "event_date"}).AddRow(int64(1), 100.00, 100.00, now).
-			AddRow(int64(2), 100.00, 100.00, later)
+		rows := pgxmock.NewRows([]string{"id", "operation_type_id", "amount", "balance", "event_date"}).AddRow(int64(1), int64(1), 100.00, 100.00, now).
+			AddRow(int64(2), int64(7), 100.00, 100.00, later)
 
-		mockDB.ExpectQuery(`SELECT id, amount, balance, event_date FROM transactions WHERE account_id = \$1`).WithArgs(accountID).WillReturnRows(rows)
+		mockDB.ExpectQuery(`SELECT id, operation_type_id, amount, balance, event_date FROM transactions WHERE account_id = \$1`).WithArgs(accountID).WillReturnRows(rows)
 
 		txns, err := repo.GetOutstandingTransactionsByAccountID(ctx, accountID)
 		assert.NoError(t, err)
@@ -148,6 +271,95 @@ func TestGetOutstandingTransactionsByAccountID(t *testing.T) {
 
 }
 
+func TestGetTransactionsByAccountIDSince(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewTransactionsRepository(mockDB)
+	since := time.Date(2025, 3, 14, 8, 30, 0, 0, time.UTC)
+
+	mockDB.ExpectQuery(`FROM transactions WHERE account_id = \$1 AND event_date >= \$2 ORDER BY event_date, id`).
+		WithArgs(int64(1), since).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "operation_type_id", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id"}).
+			AddRow(int64(4), int64(3), -50.0, -50.0, since.Add(time.Hour), int64(0), int64(0)).
+			AddRow(int64(5), int64(9), -5.0, -5.0, since.Add(time.Hour), int64(0), int64(4)))
+
+	transactions, err := repo.GetTransactionsByAccountIDSince(context.Background(), 1, since)
+
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, int64(1), transactions[1].AccountID)
+	assert.Equal(t, int64(4), transactions[1].ParentTransactionID)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetTransactionByID(t *testing.T) {
+	t.Run("Existing transaction", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+		eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`FROM transactions WHERE id = \$1`).
+			WithArgs(int64(8)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "account_id", "operation_type_id", "amount", "balance", "event_date", "booking_date", "transfer_id", "parent_transaction_id", "merchant_reference", "duplicate_of_transaction_id", "prev_hash", "hash"}).
+				AddRow(int64(8), int64(1), int64(1), -20.0, -20.0, eventDate, eventDate.Add(time.Hour), int64(0), int64(0), "ref-1", int64(7), "prev", "hash"))
+
+		transaction, err := repo.GetTransactionByID(context.Background(), 8)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), transaction.AccountID)
+		assert.Equal(t, eventDate.Add(time.Hour), transaction.BookingDate)
+		assert.Equal(t, "ref-1", transaction.MerchantReference)
+		assert.Equal(t, int64(7), transaction.DuplicateOfID)
+		assert.Equal(t, "hash", transaction.Hash)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown transaction", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectQuery(`FROM transactions WHERE id = \$1`).
+			WithArgs(int64(999)).
+			WillReturnError(pgx.ErrNoRows)
+
+		transaction, err := repo.GetTransactionByID(context.Background(), 999)
+
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.Nil(t, transaction)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetDuplicateTransaction(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewTransactionsRepository(mockDB)
+	since := time.Date(2025, 3, 15, 8, 25, 0, 0, time.UTC)
+
+	mockDB.ExpectQuery(`FROM transactions WHERE account_id = \$1 AND operation_type_id = \$2 AND amount = \$3 AND event_date >= \$4 AND \(\$5 = '' OR merchant_reference = \$5\) ORDER BY event_date DESC, id DESC LIMIT 1`).
+		WithArgs(int64(1), int64(1), -20.0, since, "ref-1").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "balance", "event_date", "merchant_reference"}).
+			AddRow(int64(7), -20.0, since.Add(time.Minute), "ref-1"))
+
+	duplicate, err := repo.GetDuplicateTransaction(context.Background(), 1, 1, -20, "ref-1", since)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), duplicate.ID)
+	assert.Equal(t, int64(1), duplicate.AccountID)
+	assert.Equal(t, "ref-1", duplicate.MerchantReference)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
 func TestUpdateTransactionBalance(t *testing.T) {
 	t.Run("Successful update returns nil error", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
@@ -193,3 +405,271 @@ func TestUpdateTransactionBalance(t *testing.T) {
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
 }
+
+func TestDischargeTransactions(t *testing.T) {
+	creditTxn := &repository.Transaction{ID: 9, AccountID: 1, OperationTypeID: 4, Amount: 150, Balance: 150}
+
+	t.Run("Discharges are returned oldest debt first", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectQuery(`WITH credit AS .* SUM\(-balance\) OVER \(ORDER BY event_date, id .* UPDATE transactions .* UPDATE transactions .* SELECT id, balance, amount FROM allocations ORDER BY event_date, id`).
+			WithArgs(int64(1), int64(9)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "balance", "amount"}).
+				AddRow(int64(3), -100.0, 100.0).
+				AddRow(int64(5), -80.0, 50.0))
+
+		discharges, err := repo.DischargeTransactions(context.Background(), creditTxn)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []*repository.Discharge{
+			{TransactionID: 3, Balance: -100, Amount: 100},
+			{TransactionID: 5, Balance: -80, Amount: 50},
+		}, discharges)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectQuery(`WITH credit AS`).
+			WithArgs(int64(1), int64(9)).
+			WillReturnError(errors.New("db error"))
+
+		discharges, err := repo.DischargeTransactions(context.Background(), creditTxn)
+
+		assert.EqualError(t, err, "db error")
+		assert.Nil(t, discharges)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestUpdateTransactionBalances(t *testing.T) {
+	t.Run("Balances are set in one statement", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectExec(`UPDATE transactions t SET balance = u.balance, updated_at = CURRENT_TIMESTAMP FROM unnest\(\$1::bigint\[\], \$2::numeric\[\]\) AS u\(id, balance\) WHERE t.id = u.id`).
+			WithArgs([]int64{3, 5, 9}, []float64{0, -30, 20}).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+
+		err = repo.UpdateTransactionBalances(context.Background(), map[int64]float64{9: 20, 3: 0, 5: -30})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("No balances need no statement", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		assert.NoError(t, repo.UpdateTransactionBalances(context.Background(), nil))
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Missing transaction returns error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectExec(`UPDATE transactions t SET balance`).
+			WithArgs([]int64{3, 999}, []float64{0, 0}).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		err = repo.UpdateTransactionBalances(context.Background(), map[int64]float64{3: 0, 999: 0})
+
+		assert.EqualError(t, err, "failed to update transaction balances: unexpected number of rows affected: 1 for 2 transactions")
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetChainHead(t *testing.T) {
+	t.Run("Started chain", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectQuery(`SELECT hash FROM transactions WHERE account_id = \$1 AND hash IS NOT NULL ORDER BY id DESC LIMIT 1`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"hash"}).AddRow("abc"))
+
+		head, err := repo.GetChainHead(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", head)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Chain not started", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectQuery(`SELECT hash FROM transactions`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"hash"}))
+
+		_, err = repo.GetChainHead(context.Background(), 1)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestSetTransactionHash(t *testing.T) {
+	t.Run("Unchained transaction", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectExec(`UPDATE transactions SET prev_hash = \$2, hash = \$3 WHERE id = \$1 AND hash IS NULL`).
+			WithArgs(int64(8), "prev", "hash").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		err = repo.SetTransactionHash(context.Background(), 8, "prev", "hash")
+		assert.NoError(t, err)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Already chained transaction", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectExec(`UPDATE transactions SET prev_hash`).
+			WithArgs(int64(8), "prev", "hash").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		err = repo.SetTransactionHash(context.Background(), 8, "prev", "hash")
+		assert.EqualError(t, err, "failed to set transaction hash: transaction 8 does not exist or is already chained")
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetTransactionChain(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewTransactionsRepository(mockDB)
+	eventDate := time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+	mockDB.ExpectQuery(`FROM transactions\s+WHERE account_id = \$1\s+ORDER BY id`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "operation_type_id", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id", "merchant_reference", "duplicate_of_transaction_id", "prev_hash", "hash"}).
+			AddRow(int64(1), int64(1), -20.0, -20.0, eventDate, int64(0), int64(0), "", int64(0), "", "").
+			AddRow(int64(2), int64(4), 30.0, 10.0, eventDate, int64(0), int64(0), "", int64(0), "prev", "hash"))
+
+	chain, err := repo.GetTransactionChain(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, chain, 2)
+	assert.Equal(t, int64(1), chain[1].AccountID)
+	assert.Equal(t, "prev", chain[1].PrevHash)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestGetChainedAccountIDs(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewTransactionsRepository(mockDB)
+
+	mockDB.ExpectQuery(`SELECT DISTINCT account_id FROM transactions WHERE hash IS NOT NULL ORDER BY account_id`).
+		WillReturnRows(pgxmock.NewRows([]string{"account_id"}).AddRow(int64(1)).AddRow(int64(3)))
+
+	accountIDs, err := repo.GetChainedAccountIDs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 3}, accountIDs)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestStreamTransactionsByAccountID(t *testing.T) {
+	t.Run("Rows are passed as they are read", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+		from := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+		mockDB.ExpectQuery(`FROM transactions t\s+JOIN operation_types ot .* \(\$2::timestamp IS NULL .* \(\$3::timestamp IS NULL .* ORDER BY t.event_date, t.id`).
+			WithArgs(int64(1), from, nil).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "operation_type_id", "description", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id", "merchant_reference", "duplicate_of_transaction_id"}).
+				AddRow(int64(7), int64(1), "Normal Purchase", -20.0, -20.0, from, int64(0), int64(0), "ref-1", int64(0)).
+				AddRow(int64(8), int64(4), "Credit Voucher", 30.0, 10.0, from, int64(0), int64(0), "", int64(0)))
+
+		var ids []int64
+		err = repo.StreamTransactionsByAccountID(context.Background(), 1, from, time.Time{}, func(txn *repository.Transaction) error {
+			ids = append(ids, txn.ID)
+			assert.Equal(t, int64(1), txn.AccountID)
+			assert.NotEmpty(t, txn.OperationType)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{7, 8}, ids)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Callback error stops the stream", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransactionsRepository(mockDB)
+		stop := errors.New("client gone")
+
+		mockDB.ExpectQuery(`FROM transactions t`).
+			WithArgs(int64(1), nil, nil).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "operation_type_id", "description", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id", "merchant_reference", "duplicate_of_transaction_id"}).
+				AddRow(int64(7), int64(1), "Normal Purchase", -20.0, -20.0, time.Now(), int64(0), int64(0), "", int64(0)).
+				AddRow(int64(8), int64(4), "Credit Voucher", 30.0, 10.0, time.Now(), int64(0), int64(0), "", int64(0)))
+
+		calls := 0
+		err = repo.StreamTransactionsByAccountID(context.Background(), 1, time.Time{}, time.Time{}, func(*repository.Transaction) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestGetNetAmountByAccountIDBefore(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := repository.NewTransactionsRepository(mockDB)
+	before := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	mockDB.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM transactions WHERE account_id = \$1 AND event_date < \$2`).
+		WithArgs(int64(1), before).
+		WillReturnRows(pgxmock.NewRows([]string{"sum"}).AddRow(19.75))
+
+	net, err := repo.GetNetAmountByAccountIDBefore(context.Background(), 1, before)
+	assert.NoError(t, err)
+	assert.Equal(t, 19.75, net)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxBeginner starts database transactions; *pgxpool.Pool satisfies it
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type txKey struct{}
+
+type transactor struct {
+	db TxBeginner
+}
+
+// NewTransactor returns a Transactor running units of work in a Postgres transaction.
+// Repositories called with the context handed to fn execute their statements in that transaction.
+func NewTransactor(db TxBeginner) Transactor {
+	return &transactor{db: db}
+}
+
+// WithinTransaction runs fn in a transaction, committing when it returns nil and rolling back otherwise.
+// Calls nested in an existing unit of work join it.
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+				err = errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rbErr))
+			}
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// conn returns the transaction bound to ctx, or db when there is none
+func conn(ctx context.Context, db PgxPoolIface) PgxPoolIface {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return db
+}
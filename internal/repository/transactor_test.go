@@ -0,0 +1,91 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithinTransaction(t *testing.T) {
+	t.Run("Statements run in the transaction, which is committed", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transactor := repository.NewTransactor(mockDB)
+		repo := repository.NewTransactionsRepository(mockDB)
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec(`UPDATE transactions SET balance`).
+			WithArgs(0.0, int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDB.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return repo.UpdateTransactionBalance(ctx, 1, 0)
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("An error rolls the transaction back", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transactor := repository.NewTransactor(mockDB)
+		errAbort := errors.New("abort")
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return errAbort
+		})
+
+		assert.ErrorIs(t, err, errAbort)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Nested calls join the enclosing transaction", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transactor := repository.NewTransactor(mockDB)
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			return transactor.WithinTransaction(ctx, func(ctx context.Context) error { return nil })
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Failing to begin is reported", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transactor := repository.NewTransactor(mockDB)
+
+		mockDB.ExpectBegin().WillReturnError(errors.New("connection refused"))
+
+		called := false
+		err = transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+			called = true
+			return nil
+		})
+
+		assert.ErrorContains(t, err, "failed to begin transaction")
+		assert.False(t, called)
+	})
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+func NewTransfersRepository(db PgxPoolIface) TransfersRepository {
+	return &transfersRepo{db: db}
+}
+
+// InsertTransfer inserts a new transfer; its legs are inserted separately
+func (r *transfersRepo) InsertTransfer(ctx context.Context, sourceAccountID, destinationAccountID int64, amount float64) (*Transfer, error) {
+	query := `INSERT INTO transfers (source_account_id, destination_account_id, amount, correlation_id) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id, created_at`
+	transfer := &Transfer{
+		SourceAccountID:      sourceAccountID,
+		DestinationAccountID: destinationAccountID,
+		Amount:               amount,
+		CorrelationID:        middleware.GetCorrelationIDFromContext(ctx),
+	}
+
+	err := conn(ctx, r.db).QueryRow(ctx, query, sourceAccountID, destinationAccountID, amount, transfer.CorrelationID).
+		Scan(&transfer.ID, &transfer.CreatedAt)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to insert transfer")
+		return nil, fmt.Errorf("failed to insert transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// GetTransferByID retrieves a transfer by transferID
+func (r *transfersRepo) GetTransferByID(ctx context.Context, transferID int64) (*Transfer, error) {
+	query := `SELECT id, source_account_id, destination_account_id, amount, created_at FROM transfers WHERE id = $1`
+	transfer := &Transfer{}
+
+	err := conn(ctx, r.db).QueryRow(ctx, query, transferID).
+		Scan(&transfer.ID, &transfer.SourceAccountID, &transfer.DestinationAccountID, &transfer.Amount, &transfer.CreatedAt)
+	if err != nil {
+		reqID := middleware.GetRequestIDFromContext(ctx)
+		log.Error().Ctx(ctx).Str("request_id", reqID).Err(err).Msg("Database error: failed to retrieve transfer")
+		return nil, err
+	}
+	return transfer, nil
+}
@@ -0,0 +1,77 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertTransfer(t *testing.T) {
+	t.Run("Completely valid request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransfersRepository(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`INSERT INTO transfers`).
+			WithArgs(int64(1), int64(2), 25.5, "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(10), time.Now()))
+
+		transfer, err := repo.InsertTransfer(ctx, 1, 2, 25.5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), transfer.ID)
+		assert.Equal(t, int64(2), transfer.DestinationAccountID)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error during insertion", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransfersRepository(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`INSERT INTO transfers`).
+			WithArgs(int64(1), int64(2), 25.5, "").
+			WillReturnError(errors.New("database error"))
+
+		transfer, err := repo.InsertTransfer(ctx, 1, 2, 25.5)
+
+		assert.ErrorContains(t, err, "database error")
+		assert.Nil(t, transfer)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetTransferByID(t *testing.T) {
+	t.Run("Transfer not found", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		repo := repository.NewTransfersRepository(mockDB)
+
+		mockDB.ExpectQuery(`SELECT .* FROM transfers WHERE id = \$1`).
+			WithArgs(int64(999)).
+			WillReturnError(pgx.ErrNoRows)
+
+		transfer, err := repo.GetTransferByID(context.Background(), 999)
+
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+		assert.Nil(t, transfer)
+
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
@@ -2,28 +2,121 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+type CustomersRepository interface {
+	InsertCustomer(ctx context.Context, customer *Customer) (*Customer, error)
+	GetCustomerByID(ctx context.Context, customerID int64) (*Customer, error)
+	GetCustomerByDocumentNumber(ctx context.Context, documentNumber string) (*Customer, error)
+}
+
 type AccountsRepository interface {
-	InsertAccount(ctx context.Context, documentNumber string) (*Account, error)
+	InsertAccount(ctx context.Context, customerID int64, product string) (*Account, error)
 	GetAccountByID(ctx context.Context, accountID int64) (*Account, error)
+	GetAccountsByCustomerID(ctx context.Context, customerID int64) ([]*Account, error)
+	LockAccounts(ctx context.Context, accountIDs ...int64) ([]*Account, error)
+	UpdateAccountStatus(ctx context.Context, accountID int64, status string) (*Account, error)
 }
 
 type TransactionsRepository interface {
-	InsertTransaction(ctx context.Context, accountID, operationTypeID int64, amount, balance float64) (*Transaction, error)
+	InsertTransaction(ctx context.Context, accountID, operationTypeID int64, amount, balance float64, eventDate, bookingDate time.Time, merchantReference string, duplicateOfTransactionID int64) (*Transaction, error)
+	InsertTransactions(ctx context.Context, transactions []*Transaction) error
+	InsertTransferLeg(ctx context.Context, transferID, accountID, operationTypeID int64, amount, balance float64, eventDate time.Time) (*Transaction, error)
+	InsertFeeTransaction(ctx context.Context, parentTransactionID, accountID, operationTypeID int64, amount, balance float64, eventDate time.Time) (*Transaction, error)
+	GetTransactionByID(ctx context.Context, transactionID int64) (*Transaction, error)
+	GetDuplicateTransaction(ctx context.Context, accountID, operationTypeID int64, amount float64, merchantReference string, since time.Time) (*Transaction, error)
+	GetTransactionsByTransferID(ctx context.Context, transferID int64) ([]*Transaction, error)
+	GetTransactionsByAccountID(ctx context.Context, accountID int64) ([]*Transaction, error)
+	GetTransactionsByAccountIDInPeriod(ctx context.Context, accountID int64, from, to time.Time) ([]*Transaction, error)
+	GetTransactionsByAccountIDSince(ctx context.Context, accountID int64, since time.Time) ([]*Transaction, error)
+	StreamTransactionsByAccountID(ctx context.Context, accountID int64, from, to time.Time, fn func(*Transaction) error) error
+	GetNetAmountByAccountIDBefore(ctx context.Context, accountID int64, before time.Time) (float64, error)
 
 	GetOutstandingTransactionsByAccountID(ctx context.Context, accountID int64) ([]*Transaction, error)
 	UpdateTransactionBalance(ctx context.Context, transactionID int64, amount float64) error
+	DischargeTransactions(ctx context.Context, creditTxn *Transaction) ([]*Discharge, error)
+	UpdateTransactionBalances(ctx context.Context, balances map[int64]float64) error
+
+	GetChainHead(ctx context.Context, accountID int64) (string, error)
+	SetTransactionHash(ctx context.Context, transactionID int64, prevHash, hash string) error
+	GetTransactionChain(ctx context.Context, accountID int64) ([]*Transaction, error)
+	GetChainedAccountIDs(ctx context.Context) ([]int64, error)
+}
+
+type TransfersRepository interface {
+	InsertTransfer(ctx context.Context, sourceAccountID, destinationAccountID int64, amount float64) (*Transfer, error)
+	GetTransferByID(ctx context.Context, transferID int64) (*Transfer, error)
+}
+
+type LedgerRepository interface {
+	PostJournalEntry(ctx context.Context, entry *JournalEntry) (*JournalEntry, error)
+	PostJournalEntries(ctx context.Context, entries []*JournalEntry) error
+	GetLedgerAccountBalances(ctx context.Context, accountID int64) ([]*LedgerAccountBalance, error)
+	GetLedgerBalancesByTransaction(ctx context.Context, accountID int64) (map[int64]float64, error)
+	GetDischargesByAccountID(ctx context.Context, accountID int64, from, to time.Time) ([]*JournalEntry, error)
+	GetDischargeAllocations(ctx context.Context, accountID int64) ([]*Allocation, error)
+}
+
+type BillingRepository interface {
+	GetBillingCycle(ctx context.Context, accountID int64) (*BillingCycle, error)
+	UpsertBillingCycle(ctx context.Context, cycle *BillingCycle) (*BillingCycle, error)
+	GetBillingCyclesByClosingDay(ctx context.Context, product string, closingDay int) ([]*BillingCycle, error)
+	InsertStatement(ctx context.Context, statement *Statement) (*Statement, error)
+	GetLatestStatementByAccountID(ctx context.Context, accountID int64) (*Statement, error)
+	GetStatementsByAccountID(ctx context.Context, accountID int64) ([]*Statement, error)
+	GetStatementByID(ctx context.Context, accountID, statementID int64) (*Statement, error)
+}
+
+type AccrualsRepository interface {
+	GetAccrualPolicies(ctx context.Context, product string) ([]*AccrualPolicy, error)
+	GetPastDueAccountIDs(ctx context.Context, date time.Time) ([]int64, error)
+	GetAccrualsByAccountID(ctx context.Context, accountID int64) ([]*Accrual, error)
+	InsertAccrualRun(ctx context.Context, run *AccrualRun) (*AccrualRun, error)
+}
+
+type FeesRepository interface {
+	GetFeeRule(ctx context.Context, product string, operationTypeID int64) (*FeeRule, error)
+}
+
+type ScreeningRepository interface {
+	GetScreeningRules(ctx context.Context, product string) ([]*ScreeningRule, error)
+	InsertScreeningEvaluation(ctx context.Context, evaluation *ScreeningEvaluation) (*ScreeningEvaluation, error)
+}
+
+type AuditRepository interface {
+	InsertAuditEvent(ctx context.Context, event *AuditEvent) (*AuditEvent, error)
+	InsertAuditEvents(ctx context.Context, events []*AuditEvent) error
+	GetAuditEvents(ctx context.Context, filter AuditEventFilter) ([]*AuditEvent, error)
+}
+
+type TransactionRequestsRepository interface {
+	InsertTransactionRequest(ctx context.Context, request *TransactionRequest) (*TransactionRequest, error)
+	GetTransactionRequestByID(ctx context.Context, requestID int64) (*TransactionRequest, error)
+	ClaimTransactionRequest(ctx context.Context) (*TransactionRequest, error)
+	CompleteTransactionRequest(ctx context.Context, requestID, transactionID int64) error
+	FailTransactionRequest(ctx context.Context, requestID int64, errorCode, errorDetail string) error
+}
+
+// Transactor runs a unit of work atomically; repositories called with the context
+// handed to fn take part in it
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type PgxPoolIface interface {
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+type customersRepo struct {
+	db PgxPoolIface
 }
 
 type accountsRepo struct {
@@ -34,9 +127,59 @@ type transactionsRepo struct {
 	db PgxPoolIface
 }
 
+type transfersRepo struct {
+	db PgxPoolIface
+}
+
+type ledgerRepo struct {
+	db PgxPoolIface
+}
+
+type billingRepo struct {
+	db PgxPoolIface
+}
+
+type accrualsRepo struct {
+	db PgxPoolIface
+}
+
+type feesRepo struct {
+	db PgxPoolIface
+}
+
+type screeningRepo struct {
+	db PgxPoolIface
+}
+
+type auditRepo struct {
+	db PgxPoolIface
+}
+
+type transactionRequestsRepo struct {
+	db PgxPoolIface
+}
+
+// Customer is the person holding one or more accounts. BirthDate is a YYYY-MM-DD date
+// and optional fields are empty when unset.
+type Customer struct {
+	ID             int64     `json:"id"`
+	DocumentNumber string    `json:"document_number"`
+	Name           string    `json:"name"`
+	BirthDate      string    `json:"birth_date,omitempty"`
+	Email          string    `json:"email,omitempty"`
+	Phone          string    `json:"phone,omitempty"`
+	CorrelationID  string    `json:"-"`
+	CreatedAt      time.Time `json:"-"`
+}
+
+// Account belongs to a customer; DocumentNumber is the holder's, read through the customer
 type Account struct {
 	ID             int64     `json:"id"`
+	CustomerID     int64     `json:"customer_id"`
 	DocumentNumber string    `json:"document_number"`
+	Product        string    `json:"product"`
+	Status         string    `json:"status"`
+	CorrelationID  string    `json:"-"`
 	CreatedAt      time.Time `json:"-"`
 }
 
@@ -45,12 +188,288 @@ type Account struct {
 // ref: https://stackoverflow.com/questions/3730019/why-not-use-double-or-float-to-represent-currency
 // To satisfy the tech-case requirements, the amount is defined in float64
 type Transaction struct {
+	ID                  int64                `json:"id"`
+	AccountID           int64                `json:"-"`
+	OperationTypeID     int64                `json:"-"`
+	OperationType       string               `json:"-"` // the operation type description, when streamed
+	Amount              float64              `json:"-"`
+	Balance             float64              `json:"-"`
+	EventDate           time.Time            `json:"event_date"`
+	BookingDate         time.Time            `json:"-"` // when the service posted it; later than EventDate when back-dated
+	TransferID          int64                `json:"-"` // zero unless the transaction is a leg of a transfer
+	ParentTransactionID int64                `json:"-"` // zero unless the transaction is a fee
+	MerchantReference   string               `json:"-"` // the processor's reference of the purchase, if any
+	DuplicateOfID       int64                `json:"-"` // the transaction it was posted despite duplicating, if flagged
+	PrevHash            string               `json:"-"` // the hash of the account's previous transaction in the chain
+	Hash                string               `json:"-"` // empty for transactions posted before the chain existed
+	Fees                []*Transaction       `json:"-"` // the fees posted with the transaction, when just created
+	Screening           *ScreeningEvaluation `json:"-"` // the screening of the transaction, when just created
+	CorrelationID       string               `json:"-"`
+	CreatedAt           time.Time            `json:"-"`
+	UpdatedAt           time.Time            `json:"-"`
+}
+
+// Discharge is the part of a credit applied to an outstanding debt: Amount of the debt's balance, which was
+// Balance before
+type Discharge struct {
+	TransactionID int64
+	Balance       float64
+	Amount        float64
+}
+
+// Allocation is what a credit has discharged of a debt in all, adjustments included
+type Allocation struct {
+	CreditTransactionID int64
+	DebtTransactionID   int64
+	Amount              float64
+}
+
+// Transfer moves Amount from the source to the destination account; its legs are
+// the transactions carrying its ID
+type Transfer struct {
+	ID                   int64     `json:"id"`
+	SourceAccountID      int64     `json:"source_account_id"`
+	DestinationAccountID int64     `json:"destination_account_id"`
+	Amount               float64   `json:"amount"`
+	CorrelationID        string    `json:"-"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// LedgerAccount is an account of the double-entry ledger. Customer ledger accounts carry the
+// AccountID they belong to; global ones such as cash_clearing have none.
+type LedgerAccount struct {
+	Code      string `json:"code"`
+	Type      string `json:"type"`
+	AccountID int64  `json:"-"`
+}
+
+// JournalEntry is an immutable, balanced set of postings. TransactionID is the transaction
+// that caused the entry, if any, and CreatedAt is when the entry takes effect.
+type JournalEntry struct {
+	ID            int64
+	Kind          string
+	TransactionID int64
+	Postings      []Posting
+	CorrelationID string
+	CreatedAt     time.Time
+}
+
+// Posting moves Amount into a ledger account: debits are positive and credits negative.
+// TransactionID attributes the posting to the transaction whose balance it affects, if any.
+type Posting struct {
+	Account       LedgerAccount
+	TransactionID int64
+	Amount        float64
+}
+
+// LedgerAccountBalance is the sum of the postings of a ledger account
+type LedgerAccountBalance struct {
+	LedgerAccount
+	Balance float64 `json:"balance"`
+}
+
+// Billing cycle of credit accounts that have not configured one
+const (
+	DefaultClosingDay   = 1
+	DefaultDueDayOffset = 10
+)
+
+// BillingCycle closes an account's statement on ClosingDay of every month, payable DueDayOffset days later
+type BillingCycle struct {
+	AccountID    int64 `json:"account_id"`
+	ClosingDay   int   `json:"closing_day"`
+	DueDayOffset int   `json:"due_day_offset"`
+}
+
+// Statement is the snapshot of a closed billing cycle. Dates are formatted as YYYY-MM-DD and the
+// period includes both PeriodStart and PeriodEnd. Balances are what the account owes.
+type Statement struct {
+	ID             int64           `json:"id"`
+	AccountID      int64           `json:"account_id"`
+	PeriodStart    string          `json:"period_start"`
+	PeriodEnd      string          `json:"period_end"`
+	OpeningBalance float64         `json:"opening_balance"`
+	ClosingBalance float64         `json:"closing_balance"`
+	MinimumPayment float64         `json:"minimum_payment"`
+	DueDate        string          `json:"due_date"`
+	Lines          []StatementLine `json:"lines,omitempty"`
+	CorrelationID  string          `json:"-"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// StatementLine is a transaction of the period or a discharge of one transaction by another.
+// RelatedTransactionID is the credit that paid a discharge, or the transaction that incurred a fee.
+type StatementLine struct {
+	Kind                 string    `json:"kind"`
+	TransactionID        int64     `json:"transaction_id"`
+	RelatedTransactionID int64     `json:"related_transaction_id,omitempty"`
+	Amount               float64   `json:"amount"`
+	EventDate            time.Time `json:"event_date"`
+}
+
+// AccrualPolicy prices the past-due debts of a product. A policy with an OperationTypeID applies to
+// debts of that type; the product's policy without one applies to its other debts and to late fees.
+// APR and LateFeePercent are fractions, so 0.24 is 24%.
+type AccrualPolicy struct {
+	Product         string  `json:"product"`
+	OperationTypeID int64   `json:"operation_type_id,omitempty"`
+	APR             float64 `json:"apr"`
+	LateFeeAmount   float64 `json:"late_fee_amount"`
+	LateFeePercent  float64 `json:"late_fee_percent"`
+	GraceDays       int     `json:"grace_days"`
+}
+
+// AccrualRun records the charges levied on an account for a day; AccrualDate is formatted as YYYY-MM-DD
+type AccrualRun struct {
+	AccountID     int64     `json:"account_id"`
+	AccrualDate   string    `json:"accrual_date"`
+	Accruals      []Accrual `json:"accruals"`
+	CorrelationID string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Accrual is a charge levied by an accrual run: a day of interest, or the late fee of the statement
+// StatementID. TransactionID is the charge's transaction and Amount what it charged.
+type Accrual struct {
+	Kind          string  `json:"kind"`
+	TransactionID int64   `json:"transaction_id"`
+	StatementID   int64   `json:"statement_id,omitempty"`
+	Amount        float64 `json:"amount"`
+}
+
+// Fee rule kinds
+const (
+	FeeKindFlat    = "flat"
+	FeeKindPercent = "percent"
+	FeeKindTiered  = "tiered"
+)
+
+// FeeRule prices the fee of the transactions of an operation type on accounts of a product: a flat rule
+// charges FlatAmount, a percent rule Percent of the amount, and a tiered rule prices the amount by the
+// first of its Tiers it falls in. The fee is then bounded by MinAmount and MaxAmount, zero meaning
+// unbounded. Percents are fractions, so 0.03 is 3%.
+type FeeRule struct {
+	ID              int64     `json:"id"`
+	Product         string    `json:"product"`
+	OperationTypeID int64     `json:"operation_type_id"`
+	Kind            string    `json:"kind"`
+	FlatAmount      float64   `json:"flat_amount"`
+	Percent         float64   `json:"percent"`
+	MinAmount       float64   `json:"min_amount,omitempty"`
+	MaxAmount       float64   `json:"max_amount,omitempty"`
+	Tiers           []FeeTier `json:"tiers,omitempty"`
+}
+
+// FeeTier prices the amounts up to UpTo at FlatAmount plus Percent of the amount; the last tier of a
+// rule has no bound and a zero UpTo
+type FeeTier struct {
+	UpTo       float64 `json:"up_to,omitempty"`
+	FlatAmount float64 `json:"flat_amount"`
+	Percent    float64 `json:"percent"`
+}
+
+// Screening rule kinds
+const (
+	ScreeningKindCount        = "count"
+	ScreeningKindAmount       = "amount"
+	ScreeningKindSingleAmount = "single_amount"
+)
+
+// Screening outcomes; rules deny or flag for review, and a transaction no rule trips on is allowed
+const (
+	ScreeningOutcomeAllow  = "allow"
+	ScreeningOutcomeDeny   = "deny"
+	ScreeningOutcomeReview = "review"
+)
+
+// ScreeningRule screens the transactions of OperationTypeIDs on accounts of Product, or of every product
+// when empty, and younger than MaxAccountAgeDays when set. A count rule trips when the transaction would
+// make more than Threshold such transactions of the account within the last WindowHours, an amount rule
+// when it would bring their amounts above Threshold, and a single amount rule when the transaction alone
+// is above Threshold. A tripped rule takes its Action and reports its Code as the reason.
+type ScreeningRule struct {
+	ID                int64   `json:"id"`
+	Code              string  `json:"code"`
+	Product           string  `json:"product,omitempty"`
+	OperationTypeIDs  []int64 `json:"operation_type_ids"`
+	Kind              string  `json:"kind"`
+	WindowHours       int     `json:"window_hours,omitempty"`
+	Threshold         float64 `json:"threshold"`
+	MaxAccountAgeDays int     `json:"max_account_age_days,omitempty"`
+	Action            string  `json:"action"`
+}
+
+// ScreeningEvaluation records the screening of a transaction for audit. Amount is signed like the
+// transaction's, ReasonCodes are the codes of the rules that tripped and TransactionID is zero when
+// the transaction was denied.
+type ScreeningEvaluation struct {
 	ID              int64     `json:"id"`
-	AccountID       int64     `json:"-"`
-	OperationTypeID int64     `json:"-"`
-	Amount          float64   `json:"-"`
-	Balance         float64   `json:"-"`
-	EventDate       time.Time `json:"event_date"`
-	CreatedAt       time.Time `json:"-"`
-	UpdatedAt       time.Time `json:"-"`
+	AccountID       int64     `json:"account_id"`
+	OperationTypeID int64     `json:"operation_type_id"`
+	Amount          float64   `json:"amount"`
+	Outcome         string    `json:"outcome"`
+	ReasonCodes     []string  `json:"reason_codes"`
+	TransactionID   int64     `json:"transaction_id,omitempty"`
+	CorrelationID   string    `json:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// AuditEvent is an entry of the append-only audit log: Action done to the entity of EntityType and EntityID,
+// zero for service-wide entities, with its JSON state Before and After where they apply. The principal,
+// request and correlation ids and IP are those of the context the event is recorded with.
+type AuditEvent struct {
+	ID            int64           `json:"id"`
+	Action        string          `json:"action"`
+	EntityType    string          `json:"entity_type"`
+	EntityID      int64           `json:"entity_id,omitempty"`
+	Before        json.RawMessage `json:"before,omitempty"`
+	After         json.RawMessage `json:"after,omitempty"`
+	Principal     string          `json:"principal,omitempty"`
+	RequestID     string          `json:"request_id,omitempty"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	IP            string          `json:"ip,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// AuditEventFilter selects audit events; zero fields match every event. Events with a created_at in
+// [From, To) are returned oldest first, starting after the event AfterID, at most Limit of them when set.
+type AuditEventFilter struct {
+	Action     string
+	EntityType string
+	EntityID   int64
+	Principal  string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+	AfterID    int64
+	Limit      int
+}
+
+// Statuses of a transaction request; a pending request is processed once, then succeeded or failed
+const (
+	TransactionRequestPending   = "pending"
+	TransactionRequestSucceeded = "succeeded"
+	TransactionRequestFailed    = "failed"
+)
+
+// TransactionRequest is a transaction submitted for asynchronous processing. Amount is unsigned, as
+// submitted. A succeeded request has the TransactionID it posted, a failed one its ErrorCode and ErrorDetail.
+// CorrelationID and Principal are those of the submission. Transaction is the posted transaction, filled in
+// by the service when the request is retrieved.
+type TransactionRequest struct {
+	ID                int64     `json:"id"`
+	AccountID         int64     `json:"account_id"`
+	OperationTypeID   int64     `json:"operation_type_id"`
+	Amount            float64   `json:"amount"`
+	MerchantReference string    `json:"merchant_reference,omitempty"`
+	Status            string    `json:"status"`
+	TransactionID     int64     `json:"transaction_id,omitempty"`
+	ErrorCode         string    `json:"error_code,omitempty"`
+	ErrorDetail       string    `json:"error_detail,omitempty"`
+	CorrelationID     string    `json:"-"`
+	Principal         string    `json:"-"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	Transaction *Transaction `json:"-"`
 }
@@ -9,24 +9,90 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
-func NewAccountsService(accRepo repository.AccountsRepository) AccountsService {
-	return &accountsService{accRepo: accRepo}
+func NewAccountsService(
+	accRepo repository.AccountsRepository,
+	custRepo repository.CustomersRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+) AccountsService {
+	return &accountsService{accRepo: accRepo, custRepo: custRepo, auditRepo: auditRepo, transactor: transactor}
 }
 
-// CreateAccount creates a new account
-func (s *accountsService) CreateAccount(ctx context.Context, documentNumber string) (*repository.Account, error) {
-	if strings.TrimSpace(documentNumber) == "" {
-		return nil, ErrInvalidDocumentNumber
+// CreateAccount opens an account of the given product, credit by default, for a customer, and records
+// its opening in the audit log.
+// The customer is identified by customerID; when it is zero, the customer holding documentNumber
+// is used and registered on the fly if unknown, which keeps document-only requests working.
+func (s *accountsService) CreateAccount(ctx context.Context, customerID int64, documentNumber, product string) (*repository.Account, error) {
+	if product == "" {
+		product = ProductCredit
+	}
+	if product != ProductCredit && product != ProductPrepaid {
+		return nil, ErrInvalidProduct
 	}
 
-	account, err := s.accRepo.InsertAccount(ctx, documentNumber)
-	if err != nil {
-		return nil, determinePgxError(err)
+	var customer *repository.Customer
+	var err error
+	if customerID != 0 {
+		customer, err = s.custRepo.GetCustomerByID(ctx, customerID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrCustomerNotFound
+			}
+			return nil, ErrFailedToFetchCustomer
+		}
+		if documentNumber != "" && documentNumber != customer.DocumentNumber {
+			return nil, ErrDocumentNumberMismatch
+		}
+	} else {
+		if strings.TrimSpace(documentNumber) == "" {
+			return nil, ErrInvalidDocumentNumber
+		}
+		customer, err = s.customerByDocument(ctx, documentNumber)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	var account *repository.Account
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		account, err = s.accRepo.InsertAccount(ctx, customer.ID, product)
+		if err != nil {
+			return determinePgxError(err)
+		}
+		return recordAudit(ctx, s.auditRepo, AuditActionAccountCreated, AuditEntityAccount, account.ID, nil, account)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return account, nil
 }
 
+// customerByDocument returns the customer holding documentNumber, registering one if there is none.
+// The registration is a unit of work of its own, so that losing a race to register the same document
+// leaves no aborted transaction behind for the lookup that follows.
+func (s *accountsService) customerByDocument(ctx context.Context, documentNumber string) (*repository.Customer, error) {
+	customer, err := s.custRepo.GetCustomerByDocumentNumber(ctx, documentNumber)
+	if err == nil {
+		return customer, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrFailedToFetchCustomer
+	}
+
+	customer, err = registerCustomer(ctx, s.custRepo, s.auditRepo, s.transactor, &repository.Customer{DocumentNumber: documentNumber})
+	if err == nil {
+		return customer, nil
+	}
+	// A concurrent request registered the same document first
+	if errors.Is(err, ErrCustomerAlreadyExists) {
+		customer, err = s.custRepo.GetCustomerByDocumentNumber(ctx, documentNumber)
+		if err == nil {
+			return customer, nil
+		}
+	}
+	return nil, ErrFailedToFetchCustomer
+}
+
 // GetAccount retrieves an account by accountID
 func (s *accountsService) GetAccount(ctx context.Context, accountID int64) (*repository.Account, error) {
 	account, err := s.accRepo.GetAccountByID(ctx, accountID)
@@ -38,3 +104,36 @@ func (s *accountsService) GetAccount(ctx context.Context, accountID int64) (*rep
 	}
 	return account, nil
 }
+
+// SetAccountStatus activates, blocks or closes an account; a closed account cannot change status again
+func (s *accountsService) SetAccountStatus(ctx context.Context, accountID int64, status string) (*repository.Account, error) {
+	switch status {
+	case AccountStatusActive, AccountStatusBlocked, AccountStatusClosed:
+	default:
+		return nil, ErrInvalidAccountStatus
+	}
+
+	var account *repository.Account
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		locked, err := s.accRepo.LockAccounts(ctx, accountID)
+		if err != nil {
+			return ErrFailedToFetchAccount
+		}
+		if len(locked) == 0 {
+			return ErrAccountNotFound
+		}
+		if locked[0].Status == AccountStatusClosed && status != AccountStatusClosed {
+			return ErrAccountClosed
+		}
+
+		account, err = s.accRepo.UpdateAccountStatus(ctx, accountID, status)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo, AuditActionAccountStatusChanged, AuditEntityAccount, accountID, locked[0], account)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
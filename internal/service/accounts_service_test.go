@@ -4,29 +4,125 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/ashwingopalsamy/transactions-service/internal/repository"
 	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
 )
 
+var (
+	accountColumns  = []string{"id", "customer_id", "document_number", "product", "status", "created_at"}
+	customerColumns = []string{"id", "document_number", "name", "birth_date", "email", "phone"}
+
+	accountCreatedAt = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+)
+
+func newAccountsService(mockDB pgxmock.PgxPoolIface) service.AccountsService {
+	return service.NewAccountsService(repository.NewAccountsRepository(mockDB), repository.NewCustomersRepository(mockDB), repository.NewAuditRepository(mockDB), repository.NewTransactor(mockDB))
+}
+
 func TestCreateAccount(t *testing.T) {
-	t.Run("Valid document number should create account", func(t *testing.T) {
+	t.Run("Existing customer should get a new account", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		accService := newAccountsService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`SELECT .* FROM customers WHERE id = \$1`).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows(customerColumns).AddRow(int64(7), "12345678900", "Maria Silva", "", "", ""))
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`INSERT INTO accounts`).WithArgs(int64(7), "prepaid", "").
+			WillReturnRows(pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(7), "12345678900", "prepaid", "active", accountCreatedAt))
+		expectAuditEvent(mockDB, service.AuditActionAccountCreated, service.AuditEntityAccount, 1)
+		mockDB.ExpectCommit()
+
+		account, err := accService.CreateAccount(ctx, 7, "", "prepaid")
+		assert.NoError(t, err)
+		assert.Equal(t, "prepaid", account.Product)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Valid document number should register the customer and create a credit account", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		repo := repository.NewAccountsRepository(mockDB)
-		accService := service.NewAccountsService(repo)
+		accService := newAccountsService(mockDB)
 		ctx := context.Background()
 
-		rows := pgxmock.NewRows([]string{"id", "document_number"}).AddRow(int64(1), "12345678900")
-		mockDB.ExpectQuery(`INSERT INTO accounts`).WithArgs("12345678900").WillReturnRows(rows)
+		mockDB.ExpectQuery(`SELECT .* FROM customers WHERE document_number = \$1`).WithArgs("12345678900").
+			WillReturnError(pgx.ErrNoRows)
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`INSERT INTO customers`).WithArgs("12345678900", "", "", "", "", "").
+			WillReturnRows(pgxmock.NewRows(customerColumns).AddRow(int64(7), "12345678900", "", "", "", ""))
+		expectAuditEvent(mockDB, service.AuditActionCustomerCreated, service.AuditEntityCustomer, 7)
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`INSERT INTO accounts`).WithArgs(int64(7), "credit", "").
+			WillReturnRows(pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(7), "12345678900", "credit", "active", accountCreatedAt))
+		expectAuditEvent(mockDB, service.AuditActionAccountCreated, service.AuditEntityAccount, 1)
+		mockDB.ExpectCommit()
 
-		account, err := accService.CreateAccount(ctx, "12345678900")
+		account, err := accService.CreateAccount(ctx, 0, "12345678900", "")
 		assert.NoError(t, err)
 		assert.NotNil(t, account)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Same product twice should conflict", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		accService := newAccountsService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`SELECT .* FROM customers WHERE document_number = \$1`).WithArgs("12345678900").
+			WillReturnRows(pgxmock.NewRows(customerColumns).AddRow(int64(7), "12345678900", "", "", "", ""))
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`INSERT INTO accounts`).WithArgs(int64(7), "credit", "").
+			WillReturnError(errors.New(`duplicate key value violates unique constraint "accounts_customer_id_product_key"`))
+		mockDB.ExpectRollback()
+
+		account, err := accService.CreateAccount(ctx, 0, "12345678900", "credit")
+		assert.ErrorIs(t, err, service.ErrAccountAlreadyExists)
+		assert.Nil(t, account)
+	})
+
+	t.Run("Unknown customer should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		accService := newAccountsService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`SELECT .* FROM customers WHERE id = \$1`).WithArgs(int64(999)).WillReturnError(pgx.ErrNoRows)
+
+		account, err := accService.CreateAccount(ctx, 999, "", "")
+		assert.ErrorIs(t, err, service.ErrCustomerNotFound)
+		assert.Nil(t, account)
+	})
+
+	t.Run("Document number of another customer should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		accService := newAccountsService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`SELECT .* FROM customers WHERE id = \$1`).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows(customerColumns).AddRow(int64(7), "12345678900", "", "", "", ""))
+
+		account, err := accService.CreateAccount(ctx, 7, "000", "")
+		assert.ErrorIs(t, err, service.ErrDocumentNumberMismatch)
+		assert.Nil(t, account)
 	})
 
 	t.Run("Empty document number should fail", func(t *testing.T) {
@@ -34,12 +130,24 @@ func TestCreateAccount(t *testing.T) {
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		repo := repository.NewAccountsRepository(mockDB)
-		accService := service.NewAccountsService(repo)
+		accService := newAccountsService(mockDB)
 		ctx := context.Background()
 
-		account, err := accService.CreateAccount(ctx, "")
-		assert.Error(t, err)
+		account, err := accService.CreateAccount(ctx, 0, "", "")
+		assert.ErrorIs(t, err, service.ErrInvalidDocumentNumber)
+		assert.Nil(t, account)
+	})
+
+	t.Run("Unknown product should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		accService := newAccountsService(mockDB)
+		ctx := context.Background()
+
+		account, err := accService.CreateAccount(ctx, 0, "12345678900", "debit")
+		assert.ErrorIs(t, err, service.ErrInvalidProduct)
 		assert.Nil(t, account)
 	})
 }
@@ -50,12 +158,11 @@ func TestGetAccount(t *testing.T) {
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		repo := repository.NewAccountsRepository(mockDB)
-		accService := service.NewAccountsService(repo)
+		accService := newAccountsService(mockDB)
 		ctx := context.Background()
 
-		rows := pgxmock.NewRows([]string{"id", "document_number"}).AddRow(int64(1), "12345678900")
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).WithArgs(int64(1)).WillReturnRows(rows)
+		rows := pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(7), "12345678900", "credit", "active", accountCreatedAt)
+		mockDB.ExpectQuery(`SELECT a.id, a.customer_id, c.document_number, a.product, a.status, a.created_at FROM accounts a .* WHERE a.id = \$1`).WithArgs(int64(1)).WillReturnRows(rows)
 
 		account, err := accService.GetAccount(ctx, 1)
 		assert.NoError(t, err)
@@ -67,14 +174,94 @@ func TestGetAccount(t *testing.T) {
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		repo := repository.NewAccountsRepository(mockDB)
-		accService := service.NewAccountsService(repo)
+		accService := newAccountsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).WithArgs(int64(999)).WillReturnError(errors.New("no rows in result set"))
+		mockDB.ExpectQuery(`SELECT a.id, a.customer_id, c.document_number, a.product, a.status, a.created_at FROM accounts a .* WHERE a.id = \$1`).WithArgs(int64(999)).WillReturnError(errors.New("no rows in result set"))
 
 		account, err := accService.GetAccount(ctx, 999)
 		assert.Error(t, err)
 		assert.Nil(t, account)
 	})
 }
+
+func TestSetAccountStatus(t *testing.T) {
+	t.Run("Active account should be blocked", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		accService := newAccountsService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).WithArgs([]int64{1}).
+			WillReturnRows(pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(7), "12345678900", "credit", "active", accountCreatedAt))
+		mockDB.ExpectQuery(`UPDATE accounts SET status`).WithArgs(int64(1), "blocked").
+			WillReturnRows(pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(7), "12345678900", "credit", "blocked", accountCreatedAt))
+		expectAuditEventStates(mockDB, service.AuditActionAccountStatusChanged, service.AuditEntityAccount, 1,
+			`{"id":1,"customer_id":7,"document_number":"12345678900","product":"credit","status":"active"}`,
+			`{"id":1,"customer_id":7,"document_number":"12345678900","product":"credit","status":"blocked"}`)
+		mockDB.ExpectCommit()
+
+		account, err := accService.SetAccountStatus(ctx, 1, "blocked")
+		assert.NoError(t, err)
+		assert.Equal(t, "blocked", account.Status)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Status change that cannot be audited should roll back", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		accService := newAccountsService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).WithArgs([]int64{1}).
+			WillReturnRows(pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(7), "12345678900", "credit", "active", accountCreatedAt))
+		mockDB.ExpectQuery(`UPDATE accounts SET status`).WithArgs(int64(1), "blocked").
+			WillReturnRows(pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(7), "12345678900", "credit", "blocked", accountCreatedAt))
+		mockDB.ExpectQuery(`INSERT INTO audit_events`).
+			WithArgs(service.AuditActionAccountStatusChanged, service.AuditEntityAccount, int64(1), pgxmock.AnyArg(), pgxmock.AnyArg(), "", "", "", "").
+			WillReturnError(errors.New("db error"))
+		mockDB.ExpectRollback()
+
+		account, err := accService.SetAccountStatus(ctx, 1, "blocked")
+		assert.ErrorContains(t, err, "failed to record audit event")
+		assert.Nil(t, account)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Closed account cannot be reopened", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		accService := newAccountsService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).WithArgs([]int64{1}).
+			WillReturnRows(pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(7), "12345678900", "credit", "closed", accountCreatedAt))
+		mockDB.ExpectRollback()
+
+		account, err := accService.SetAccountStatus(ctx, 1, "active")
+		assert.ErrorIs(t, err, service.ErrAccountClosed)
+		assert.Nil(t, account)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown status should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		accService := newAccountsService(mockDB)
+
+		account, err := accService.SetAccountStatus(context.Background(), 1, "frozen")
+		assert.ErrorIs(t, err, service.ErrInvalidAccountStatus)
+		assert.Nil(t, account)
+	})
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// daysPerYear turns an APR into a daily rate
+const daysPerYear = 365
+
+// Kinds of the charges levied by an accrual run
+const (
+	accrualKindInterest = "interest"
+	accrualKindLateFee  = "late_fee"
+)
+
+func NewAccrualsService(
+	accrualRepo repository.AccrualsRepository,
+	billingRepo repository.BillingRepository,
+	accRepo repository.AccountsRepository,
+	trxRepo repository.TransactionsRepository,
+	trxService TransactionsService,
+	transactor repository.Transactor,
+	clock clock.Clock,
+) AccrualsService {
+	return &accrualsService{
+		accrualRepo: accrualRepo,
+		billingRepo: billingRepo,
+		accRepo:     accRepo,
+		trxRepo:     trxRepo,
+		trxService:  trxService,
+		transactor:  transactor,
+		clock:       clock,
+	}
+}
+
+// Accrue levies today's interest and the late fees now due on every past-due account, today being the
+// date of the service's clock. A debt is past due once its statement's due date and the grace days of
+// its policy have passed. Each account is charged in its own unit of work and at most once per day:
+// accounts already charged today are skipped.
+func (s *accrualsService) Accrue(ctx context.Context) (_ []*repository.AccrualRun, err error) {
+	now := s.clock.Now().UTC()
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	ctx, span := telemetry.Tracer().Start(ctx, "AccrualsService.Accrue", trace.WithAttributes(
+		attribute.String("accrual.date", date.Format(dateLayout)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	accountIDs, err := s.accrualRepo.GetPastDueAccountIDs(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch past-due accounts: %w", err)
+	}
+
+	runs := []*repository.AccrualRun{}
+	var errs []error
+	for _, accountID := range accountIDs {
+		run, err := s.accrueAccount(ctx, accountID, date)
+		if errors.Is(err, ErrAccrualAlreadyRun) {
+			continue
+		}
+		if err != nil {
+			log.Error().Ctx(ctx).Err(err).Int64("account_id", accountID).Msg("failed to accrue account")
+			errs = append(errs, fmt.Errorf("account %d: %w", accountID, err))
+			continue
+		}
+		runs = append(runs, run)
+	}
+
+	span.SetAttributes(attribute.Int("accrual.runs", len(runs)))
+	return runs, errors.Join(errs...)
+}
+
+// accrueAccount charges the account for date and records the run, all with the account locked.
+// Recording the run fails with ErrAccrualAlreadyRun, undoing the charges, if date was already accrued.
+func (s *accrualsService) accrueAccount(ctx context.Context, accountID int64, date time.Time) (*repository.AccrualRun, error) {
+	var run *repository.AccrualRun
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		accounts, err := s.accRepo.LockAccounts(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch account: %w", err)
+		}
+		if len(accounts) == 0 {
+			return ErrInvalidAccountID
+		}
+
+		policies, err := s.accrualRepo.GetAccrualPolicies(ctx, accounts[0].Product)
+		if err != nil {
+			return fmt.Errorf("failed to fetch accrual policies: %w", err)
+		}
+		statements, err := s.billingRepo.GetStatementsByAccountID(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch statements: %w", err)
+		}
+		outstanding, err := s.trxRepo.GetOutstandingTransactionsByAccountID(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch outstanding transactions: %w", err)
+		}
+		levied, err := s.accrualRepo.GetAccrualsByAccountID(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch accruals: %w", err)
+		}
+
+		charges := accrualCharges(date, policies, statements, outstanding, levied)
+		for i, charge := range charges {
+			operationTypeID := OperationTypeInterest
+			if charge.Kind == accrualKindLateFee {
+				operationTypeID = OperationTypeLateFee
+			}
+			txn, err := s.trxService.CreateCharge(ctx, accountID, operationTypeID, charge.Amount)
+			if err != nil {
+				return fmt.Errorf("failed to charge %s: %w", charge.Kind, err)
+			}
+			charges[i].TransactionID = txn.ID
+		}
+
+		run, err = s.accrualRepo.InsertAccrualRun(ctx, &repository.AccrualRun{
+			AccountID:   accountID,
+			AccrualDate: date.Format(dateLayout),
+			Accruals:    charges,
+		})
+		if err != nil {
+			return determinePgxError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// accrualCharges prices what the account owes for date: one day of interest over its past-due debts,
+// and a late fee for each statement with debts still unpaid after its due date and grace days that was
+// not charged one yet. Charges themselves accrue neither interest nor fees. statements are latest first.
+func accrualCharges(
+	date time.Time,
+	policies []*repository.AccrualPolicy,
+	statements []*repository.Statement,
+	outstanding []*repository.Transaction,
+	levied []*repository.Accrual,
+) []repository.Accrual {
+	var productPolicy *repository.AccrualPolicy
+	typePolicies := map[int64]*repository.AccrualPolicy{}
+	for _, policy := range policies {
+		if policy.OperationTypeID == 0 {
+			productPolicy = policy
+		} else {
+			typePolicies[policy.OperationTypeID] = policy
+		}
+	}
+
+	feeCharged := map[int64]bool{}
+	for _, accrual := range levied {
+		if accrual.Kind == accrualKindLateFee {
+			feeCharged[accrual.StatementID] = true
+		}
+	}
+
+	var interest float64
+	pastDue := map[int64]float64{}
+	var pastDueStatements []*repository.Statement
+	for _, debt := range outstanding {
+		if debt.OperationTypeID == OperationTypeInterest || debt.OperationTypeID == OperationTypeLateFee {
+			continue
+		}
+		statement := billingStatement(statements, debt.EventDate)
+		if statement == nil {
+			continue
+		}
+		dueDate, err := time.Parse(dateLayout, statement.DueDate)
+		if err != nil {
+			continue
+		}
+
+		policy := productPolicy
+		if typePolicy, ok := typePolicies[debt.OperationTypeID]; ok {
+			policy = typePolicy
+		}
+		if policy != nil && date.After(dueDate.AddDate(0, 0, policy.GraceDays)) {
+			interest += -debt.Balance * policy.APR / daysPerYear
+		}
+		if productPolicy != nil && date.After(dueDate.AddDate(0, 0, productPolicy.GraceDays)) && !feeCharged[statement.ID] {
+			if _, ok := pastDue[statement.ID]; !ok {
+				pastDueStatements = append(pastDueStatements, statement)
+			}
+			pastDue[statement.ID] += -debt.Balance
+		}
+	}
+
+	var charges []repository.Accrual
+	if interest = FormatAmount(interest); interest > 0 {
+		charges = append(charges, repository.Accrual{Kind: accrualKindInterest, Amount: interest})
+	}
+	for _, statement := range pastDueStatements {
+		fee := FormatAmount(productPolicy.LateFeeAmount + productPolicy.LateFeePercent*pastDue[statement.ID])
+		if fee > 0 {
+			charges = append(charges, repository.Accrual{Kind: accrualKindLateFee, StatementID: statement.ID, Amount: fee})
+		}
+	}
+	return charges
+}
+
+// billingStatement is the first statement whose period ends on or after eventDate: the one that billed
+// a debt of that date. Debts older than the first statement are billed by it as its opening balance.
+func billingStatement(statements []*repository.Statement, eventDate time.Time) *repository.Statement {
+	day := eventDate.UTC().Format(dateLayout)
+	for i := len(statements) - 1; i >= 0; i-- {
+		if statements[i].PeriodEnd >= day {
+			return statements[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccrue(t *testing.T) {
+	store := memory.NewStore()
+	trxRepo := memory.NewTransactionsRepository(store)
+	accRepo := memory.NewAccountsRepository(store)
+	ledgerRepo := memory.NewLedgerRepository(store)
+	billingRepo := memory.NewBillingRepository(store)
+	accrualRepo := memory.NewAccrualsRepository(store)
+	transactor := memory.NewTransactor(store)
+	ctx := context.Background()
+
+	accService := service.NewAccountsService(accRepo, memory.NewCustomersRepository(store), memory.NewAuditRepository(store), transactor)
+	trxService := service.NewTransactionsService(trxRepo, accRepo, ledgerRepo, memory.NewFeesRepository(store), memory.NewScreeningRepository(store), memory.NewAuditRepository(store), memory.NewBillingRepository(store), transactor, clock.System(), service.DuplicatePolicy{}, service.BackdatingPolicy{})
+	ledgerService := service.NewLedgerService(ledgerRepo, trxRepo, accRepo)
+	accrualServiceAt := func(days int) service.AccrualsService {
+		today := time.Now().UTC()
+		return service.NewAccrualsService(accrualRepo, billingRepo, accRepo, trxRepo, trxService, transactor, clock.Fixed(today.AddDate(0, 0, days)))
+	}
+
+	account, err := accService.CreateAccount(ctx, 0, "1", service.ProductCredit)
+	require.NoError(t, err)
+	_, err = trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: account.ID, OperationTypeID: 1, Amount: 100})
+	require.NoError(t, err)
+	_, err = trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: account.ID, OperationTypeID: 3, Amount: 50})
+	require.NoError(t, err)
+
+	today := time.Now().UTC()
+	statement, err := billingRepo.InsertStatement(ctx, &repository.Statement{
+		AccountID:      account.ID,
+		PeriodStart:    today.AddDate(0, -1, 1).Format(time.DateOnly),
+		PeriodEnd:      today.Format(time.DateOnly),
+		ClosingBalance: 155,
+		MinimumPayment: 25,
+		DueDate:        today.AddDate(0, 0, 10).Format(time.DateOnly),
+	})
+	require.NoError(t, err)
+
+	// Nothing accrues until the day after the due date
+	runs, err := accrualServiceAt(10).Accrue(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	// 100 and the withdrawal's 5 fee at 24% and 50 at the 36% of withdrawals for a day, plus the product's late fee
+	runs, err = accrualServiceAt(11).Accrue(ctx)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Len(t, runs[0].Accruals, 2)
+	assert.Equal(t, "interest", runs[0].Accruals[0].Kind)
+	assert.Equal(t, 0.12, runs[0].Accruals[0].Amount)
+	assert.Equal(t, "late_fee", runs[0].Accruals[1].Kind)
+	assert.Equal(t, statement.ID, runs[0].Accruals[1].StatementID)
+	assert.Equal(t, 25.0, runs[0].Accruals[1].Amount)
+
+	// Running the same day again charges nothing
+	runs, err = accrualServiceAt(11).Accrue(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	// The late fee is levied once; interest does not compound on charges
+	runs, err = accrualServiceAt(12).Accrue(ctx)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Len(t, runs[0].Accruals, 1)
+	assert.Equal(t, 0.12, runs[0].Accruals[0].Amount)
+
+	outstanding, err := trxRepo.GetOutstandingTransactionsByAccountID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Len(t, outstanding, 6)
+
+	// Charges are debts like any other: a credit voucher discharges them
+	_, err = trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: account.ID, OperationTypeID: 4, Amount: 180.24})
+	require.NoError(t, err)
+	outstanding, err = trxRepo.GetOutstandingTransactionsByAccountID(ctx, account.ID)
+	require.NoError(t, err)
+	assert.Empty(t, outstanding)
+
+	report, err := ledgerService.VerifyAccount(ctx, account.ID)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent)
+
+	runs, err = accrualServiceAt(13).Accrue(ctx)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Empty(t, runs[0].Accruals)
+}
+
+func TestAccrueSkipsAccountsAlreadyAccrued(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	trxService := newTransactionsService(mockDB)
+	accrualService := service.NewAccrualsService(
+		repository.NewAccrualsRepository(mockDB),
+		repository.NewBillingRepository(mockDB),
+		repository.NewAccountsRepository(mockDB),
+		repository.NewTransactionsRepository(mockDB),
+		trxService,
+		repository.NewTransactor(mockDB),
+		clock.Fixed(time.Date(2025, 3, 12, 18, 0, 0, 0, time.UTC)),
+	)
+	date := time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)
+
+	mockDB.ExpectQuery(`SELECT DISTINCT s.account_id FROM statements s`).
+		WithArgs(date).
+		WillReturnRows(pgxmock.NewRows([]string{"account_id"}).AddRow(int64(1)))
+	mockDB.ExpectBegin()
+	expectLockAccount(mockDB, "active")
+	mockDB.ExpectQuery(`FROM accrual_policies WHERE product = \$1`).
+		WithArgs("credit").
+		WillReturnRows(pgxmock.NewRows([]string{"product", "operation_type_id", "apr", "late_fee_amount", "late_fee_percent", "grace_days"}).
+			AddRow("credit", int64(0), 0.365, 0.0, 0.0, 0))
+	mockDB.ExpectQuery(`FROM statements WHERE account_id = \$1 ORDER BY period_end DESC`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(statementColumns).
+			AddRow(int64(1), int64(1), "2025-02-02", "2025-03-01", 0.0, 100.0, 25.0, "2025-03-11", time.Now()))
+	mockDB.ExpectQuery(`SELECT id, operation_type_id, amount, balance, event_date FROM transactions`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "operation_type_id", "amount", "balance", "event_date"}).
+			AddRow(int64(5), int64(1), -100.0, -100.0, time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)))
+	mockDB.ExpectQuery(`FROM accruals WHERE account_id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"kind", "transaction_id", "statement_id", "amount"}))
+	expectLockAccount(mockDB, "active")
+	mockDB.ExpectQuery(`INSERT INTO transactions`).
+		WithArgs(int64(1), service.OperationTypeInterest, -0.1, -0.1, testNow, testNow, "", int64(0), "").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(9), time.Now(), -0.1))
+	expectChainLink(mockDB, 9)
+	expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 9)
+	expectJournalEntry(mockDB, "interest", 9, "customer_receivable:1", "interest_income", []int64{9, 0}, 0.1)
+	expectNoFeeRule(mockDB, service.OperationTypeInterest)
+	mockDB.ExpectQuery(`INSERT INTO accrual_runs`).
+		WithArgs(int64(1), "2025-03-12", "", []string{"interest"}, []int64{9}, []int64{0}, []float64{0.1}).
+		WillReturnError(&pgconn.PgError{Code: "23505", Message: `duplicate key value violates unique constraint "accrual_runs_pkey"`})
+	mockDB.ExpectRollback()
+
+	runs, err := accrualService.Accrue(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, runs)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// GetAuditEvents retrieves the audit events matching the filter, oldest first, in pages of at most
+// MaxAuditEventsLimit events
+func (s *auditService) GetAuditEvents(ctx context.Context, filter repository.AuditEventFilter) ([]*repository.AuditEvent, error) {
+	if filter.Limit < 0 || filter.AfterID < 0 || filter.EntityID < 0 {
+		return nil, ErrInvalidAuditFilter
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, ErrInvalidAuditFilter
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultAuditEventsLimit
+	}
+	filter.Limit = min(filter.Limit, MaxAuditEventsLimit)
+
+	events, err := s.auditRepo.GetAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, ErrFailedToFetchAuditEvents
+	}
+	if events == nil {
+		events = []*repository.AuditEvent{}
+	}
+	return events, nil
+}
+
+// RecordConfigChange records a change of a runtime setting from before to after
+func (s *auditService) RecordConfigChange(ctx context.Context, setting string, before, after any) error {
+	return recordAudit(ctx, s.auditRepo, AuditActionConfigUpdated, AuditEntityConfig, 0,
+		map[string]any{setting: before}, map[string]any{setting: after})
+}
+
+// recordAudit appends an event to the audit log with the entity as it was before and after the action;
+// a nil state is left out, such as the state before a creation
+func recordAudit(ctx context.Context, auditRepo repository.AuditRepository, action, entityType string, entityID int64, before, after any) error {
+	event := &repository.AuditEvent{Action: action, EntityType: entityType, EntityID: entityID}
+
+	var err error
+	if before != nil {
+		if event.Before, err = json.Marshal(before); err != nil {
+			return fmt.Errorf("failed to encode audit state: %w", err)
+		}
+	}
+	if after != nil {
+		if event.After, err = json.Marshal(after); err != nil {
+			return fmt.Errorf("failed to encode audit state: %w", err)
+		}
+	}
+
+	if _, err := auditRepo.InsertAuditEvent(ctx, event); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// transactionState is a transaction as recorded in the audit log; unlike the API representation it
+// carries the amounts and links of the transaction
+type transactionState struct {
+	ID                  int64     `json:"id"`
+	AccountID           int64     `json:"account_id"`
+	OperationTypeID     int64     `json:"operation_type_id"`
+	Amount              float64   `json:"amount"`
+	Balance             float64   `json:"balance"`
+	EventDate           time.Time `json:"event_date"`
+	TransferID          int64     `json:"transfer_id,omitempty"`
+	ParentTransactionID int64     `json:"parent_transaction_id,omitempty"`
+	MerchantReference   string    `json:"merchant_reference,omitempty"`
+	DuplicateOfID       int64     `json:"duplicate_of_transaction_id,omitempty"`
+	Hash                string    `json:"hash,omitempty"`
+}
+
+// balanceState is the part of a transaction a discharge changes
+type balanceState struct {
+	Balance float64 `json:"balance"`
+}
+
+// auditTransactionCreated records the creation of a transaction, fee, charge or transfer leg
+func auditTransactionCreated(ctx context.Context, auditRepo repository.AuditRepository, txn *repository.Transaction) error {
+	return recordAudit(ctx, auditRepo, AuditActionTransactionCreated, AuditEntityTransaction, txn.ID, nil, transactionState{
+		ID:                  txn.ID,
+		AccountID:           txn.AccountID,
+		OperationTypeID:     txn.OperationTypeID,
+		Amount:              txn.Amount,
+		Balance:             txn.Balance,
+		EventDate:           txn.EventDate,
+		TransferID:          txn.TransferID,
+		ParentTransactionID: txn.ParentTransactionID,
+		MerchantReference:   txn.MerchantReference,
+		DuplicateOfID:       txn.DuplicateOfID,
+		Hash:                txn.Hash,
+	})
+}
+
+// balanceUpdate is a discharge changing the balance of a transaction from before to after
+type balanceUpdate struct {
+	transactionID int64
+	before, after float64
+}
+
+// auditBalancesUpdated records the balance updates of a discharge in a single statement
+func auditBalancesUpdated(ctx context.Context, auditRepo repository.AuditRepository, updates []balanceUpdate) error {
+	events := make([]*repository.AuditEvent, 0, len(updates))
+	for _, update := range updates {
+		event := &repository.AuditEvent{Action: AuditActionTransactionBalanceUpdated, EntityType: AuditEntityTransaction, EntityID: update.transactionID}
+		var err error
+		if event.Before, err = json.Marshal(balanceState{Balance: update.before}); err != nil {
+			return fmt.Errorf("failed to encode audit state: %w", err)
+		}
+		if event.After, err = json.Marshal(balanceState{Balance: update.after}); err != nil {
+			return fmt.Errorf("failed to encode audit state: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := auditRepo.InsertAuditEvents(ctx, events); err != nil {
+		return fmt.Errorf("failed to record audit events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var auditEventColumns = []string{"id", "action", "entity_type", "entity_id", "before", "after", "principal", "request_id", "correlation_id", "ip", "created_at"}
+
+func TestGetAuditEvents(t *testing.T) {
+	t.Run("Unbounded listing should get the default page size", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		auditService := service.NewAuditService(repository.NewAuditRepository(mockDB))
+
+		mockDB.ExpectQuery(`FROM audit_events`).
+			WithArgs("", "account", int64(1), "", "", nil, nil, int64(0), service.DefaultAuditEventsLimit).
+			WillReturnRows(pgxmock.NewRows(auditEventColumns))
+
+		events, err := auditService.GetAuditEvents(context.Background(), repository.AuditEventFilter{EntityType: "account", EntityID: 1})
+		assert.NoError(t, err)
+		assert.NotNil(t, events)
+		assert.Empty(t, events)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Page size should be capped", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		auditService := service.NewAuditService(repository.NewAuditRepository(mockDB))
+
+		mockDB.ExpectQuery(`FROM audit_events`).
+			WithArgs("", "", int64(0), "", "", nil, nil, int64(0), service.MaxAuditEventsLimit).
+			WillReturnRows(pgxmock.NewRows(auditEventColumns).
+				AddRow(int64(1), "config.updated", "config", int64(0), `{"log_level": "info"}`, `{"log_level": "debug"}`, "admin", "", "", "", testNow))
+
+		events, err := auditService.GetAuditEvents(context.Background(), repository.AuditEventFilter{Limit: 10000})
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Empty time range should be rejected", func(t *testing.T) {
+		auditService := service.NewAuditService(memory.NewAuditRepository(memory.NewStore()))
+
+		_, err := auditService.GetAuditEvents(context.Background(), repository.AuditEventFilter{From: testNow, To: testNow.Add(-time.Hour)})
+		assert.ErrorIs(t, err, service.ErrInvalidAuditFilter)
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		auditService := service.NewAuditService(repository.NewAuditRepository(mockDB))
+
+		mockDB.ExpectQuery(`FROM audit_events`).WillReturnError(errors.New("db error"))
+
+		events, err := auditService.GetAuditEvents(context.Background(), repository.AuditEventFilter{})
+		assert.ErrorIs(t, err, service.ErrFailedToFetchAuditEvents)
+		assert.Nil(t, events)
+	})
+}
+
+func TestRecordConfigChange(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	auditService := service.NewAuditService(repository.NewAuditRepository(mockDB))
+
+	mockDB.ExpectQuery(`INSERT INTO audit_events`).
+		WithArgs(service.AuditActionConfigUpdated, service.AuditEntityConfig, int64(0), `{"log_level":"info"}`, `{"log_level":"debug"}`, "admin", "", "", "").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), testNow))
+
+	err = auditService.RecordConfigChange(middleware.WithPrincipal(context.Background(), "admin"), "log_level", "info", "debug")
+	assert.NoError(t, err)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestAuditLogRecordsMutations(t *testing.T) {
+	store := memory.NewStore()
+	trxRepo := memory.NewTransactionsRepository(store)
+	accRepo := memory.NewAccountsRepository(store)
+	auditRepo := memory.NewAuditRepository(store)
+	transactor := memory.NewTransactor(store)
+	ctx := middleware.WithClientIP(middleware.WithPrincipal(context.Background(), "ops@example.com"), "203.0.113.7")
+
+	accService := service.NewAccountsService(accRepo, memory.NewCustomersRepository(store), auditRepo, transactor)
+	trxService := service.NewTransactionsService(trxRepo, accRepo, memory.NewLedgerRepository(store), memory.NewFeesRepository(store), memory.NewScreeningRepository(store), auditRepo, memory.NewBillingRepository(store), transactor, clock.System(), service.DuplicatePolicy{}, service.BackdatingPolicy{})
+	auditService := service.NewAuditService(auditRepo)
+
+	account, err := accService.CreateAccount(ctx, 0, "1", service.ProductCredit)
+	require.NoError(t, err)
+	purchase, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: account.ID, OperationTypeID: 1, Amount: 50})
+	require.NoError(t, err)
+	voucher, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: account.ID, OperationTypeID: 4, Amount: 80})
+	require.NoError(t, err)
+	_, err = accService.SetAccountStatus(ctx, account.ID, service.AccountStatusBlocked)
+	require.NoError(t, err)
+
+	events, err := auditService.GetAuditEvents(context.Background(), repository.AuditEventFilter{})
+	require.NoError(t, err)
+
+	type entry struct {
+		action   string
+		entityID int64
+	}
+	var entries []entry
+	for _, event := range events {
+		entries = append(entries, entry{event.Action, event.EntityID})
+		assert.Equal(t, "ops@example.com", event.Principal)
+		assert.Equal(t, "203.0.113.7", event.IP)
+	}
+	assert.Equal(t, []entry{
+		{service.AuditActionCustomerCreated, 1},
+		{service.AuditActionAccountCreated, account.ID},
+		{service.AuditActionTransactionCreated, purchase.ID},
+		{service.AuditActionTransactionCreated, voucher.ID},
+		{service.AuditActionTransactionBalanceUpdated, purchase.ID},
+		{service.AuditActionTransactionBalanceUpdated, voucher.ID},
+		{service.AuditActionAccountStatusChanged, account.ID},
+	}, entries)
+	assert.JSONEq(t, `{"balance":-50}`, string(events[4].Before))
+	assert.JSONEq(t, `{"balance":0}`, string(events[4].After))
+	assert.JSONEq(t, `{"balance":30}`, string(events[5].After))
+}
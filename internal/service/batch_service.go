@@ -0,0 +1,452 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// batchItem is an item of a batch being posted
+type batchItem struct {
+	index     int
+	txn       NewTransaction
+	amount    float64 // signed, with two decimal places
+	screening *repository.ScreeningEvaluation
+	posted    *repository.Transaction
+}
+
+// CreateTransactionBatch posts a batch of transactions dated with the service's clock, each validated,
+// screened and checked for duplicates as by CreateTransaction. Items are grouped per account: each account
+// is locked once, its items are inserted in one round trip and its credits discharge its debts in one pass,
+// with the balances posting them one by one would leave. Earlier items of the batch count as the account's
+// activity and as originals of duplicates.
+//
+// In atomic mode the batch is one unit of work, which the first item that cannot be posted fails with a
+// *BatchItemError. In best-effort mode each account's items are a unit of work of their own: an item that is
+// invalid, of an inactive account, a rejected duplicate or denied by screening is reported in its result and
+// the others are posted, while an unexpected failure fails every item of the account. Results are in item
+// order.
+func (s *transactionsService) CreateTransactionBatch(ctx context.Context, transactions []NewTransaction, mode string) (_ []BatchItemResult, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransactionsService.CreateTransactionBatch", trace.WithAttributes(
+		attribute.Int("batch.items", len(transactions)),
+		attribute.String("batch.mode", mode),
+	))
+	defer func() { endSpan(span, err) }()
+
+	switch {
+	case mode != BatchModeAtomic && mode != BatchModeBestEffort:
+		return nil, ErrInvalidBatchMode
+	case len(transactions) == 0:
+		return nil, ErrEmptyBatch
+	case len(transactions) > MaxBatchItems:
+		return nil, ErrBatchTooLarge
+	}
+	atomic := mode == BatchModeAtomic
+
+	results := make([]BatchItemResult, len(transactions))
+	groups := make(map[int64][]*batchItem)
+	for i, txn := range transactions {
+		results[i].Index = i
+
+		amount, err := signedAmount(txn.OperationTypeID, txn.Amount)
+		if postedInternally(txn.OperationTypeID) {
+			err = ErrInvalidOperationType
+		}
+		if err != nil {
+			if atomic {
+				return nil, &BatchItemError{Index: i, Err: err}
+			}
+			results[i].Err = err
+			continue
+		}
+		groups[txn.AccountID] = append(groups[txn.AccountID], &batchItem{index: i, txn: txn, amount: amount})
+	}
+
+	// Accounts are locked in id order, so concurrent batches cannot deadlock
+	accountIDs := slices.Sorted(maps.Keys(groups))
+
+	if atomic {
+		err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			for _, accountID := range accountIDs {
+				if err := s.postBatchItems(ctx, accountID, groups[accountID], true, results); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	for _, accountID := range accountIDs {
+		items := groups[accountID]
+		err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			return s.postBatchItems(ctx, accountID, items, false, results)
+		})
+		if err == nil {
+			continue
+		}
+		log.Error().Ctx(ctx).Err(err).Int64("account_id", accountID).Int("items", len(items)).Msg("failed to post batch items of account")
+		for _, item := range items {
+			if result := &results[item.index]; result.Err == nil {
+				result.Transaction, result.Err = nil, err
+			}
+		}
+	}
+	return results, nil
+}
+
+// postBatchItems posts the items of a batch for the account in item order. Rejected items fail an atomic
+// batch and are recorded in their result otherwise; posted ones are recorded once all of them are.
+func (s *transactionsService) postBatchItems(ctx context.Context, accountID int64, items []*batchItem, atomic bool, results []BatchItemResult) (err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransactionsService.postBatchItems", trace.WithAttributes(
+		attribute.Int64("account.id", accountID),
+		attribute.Int("batch.items", len(items)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	reject := func(item *batchItem, err error) error {
+		if atomic {
+			return &BatchItemError{Index: item.index, Err: err}
+		}
+		results[item.index].Err = err
+		return nil
+	}
+
+	accounts, err := s.accRepo.LockAccounts(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch account: %w", err)
+	}
+	var accountErr error
+	switch {
+	case len(accounts) == 0:
+		accountErr = ErrInvalidAccountID
+	case accounts[0].Status != AccountStatusActive:
+		accountErr = ErrAccountNotActive
+	}
+	if accountErr != nil {
+		for _, item := range items {
+			if err := reject(item, accountErr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	account := accounts[0]
+
+	screener, err := s.newBatchScreener(ctx, account, items, s.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	// Accepted items are inserted in runs. A run is cut short before an item flagged as a duplicate of an
+	// item of the run, which needs the id of its original.
+	var accepted []*batchItem
+	var inserted int
+	insert := func() error {
+		run := make([]*repository.Transaction, 0, len(accepted)-inserted)
+		for _, item := range accepted[inserted:] {
+			run = append(run, item.posted)
+		}
+		if err := s.trxRepo.InsertTransactions(ctx, run); err != nil {
+			return determinePgxError(err)
+		}
+		inserted = len(accepted)
+		return nil
+	}
+
+	for _, item := range items {
+		now := s.clock.Now()
+
+		original, err := s.findDuplicate(ctx, item.txn, item.amount, now)
+		var duplicate *DuplicateTransactionError
+		if errors.As(err, &duplicate) {
+			if err := reject(item, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var batchOriginal *batchItem
+		if original == nil {
+			batchOriginal = s.findBatchDuplicate(ctx, item, accepted, now)
+			if batchOriginal != nil && s.duplicates.Action == DuplicateActionReject {
+				if err := reject(item, fmt.Errorf("%w: matches item %d of the batch", ErrDuplicateTransaction, batchOriginal.index)); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		item.screening = screener.screen(ctx, item.txn.OperationTypeID, item.amount, now)
+		if item.screening != nil && item.screening.Outcome == repository.ScreeningOutcomeDeny {
+			// The denial is recorded for audit, unless the atomic batch it fails is rolled back
+			if _, err := s.screeningRepo.InsertScreeningEvaluation(ctx, item.screening); err != nil {
+				return err
+			}
+			if err := reject(item, &ScreeningDeniedError{ReasonCodes: item.screening.ReasonCodes}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		item.posted = &repository.Transaction{
+			AccountID:         accountID,
+			OperationTypeID:   item.txn.OperationTypeID,
+			Amount:            item.amount,
+			Balance:           item.amount,
+			EventDate:         now,
+			MerchantReference: item.txn.MerchantReference,
+		}
+		switch {
+		case original != nil:
+			item.posted.DuplicateOfID = original.ID
+		case batchOriginal != nil:
+			if batchOriginal.posted.ID == 0 {
+				if err := insert(); err != nil {
+					return err
+				}
+			}
+			item.posted.DuplicateOfID = batchOriginal.posted.ID
+		}
+		accepted = append(accepted, item)
+		screener.record(item.posted)
+	}
+	if len(accepted) == 0 {
+		return nil
+	}
+	if err := insert(); err != nil {
+		return err
+	}
+
+	posted := make([]*repository.Transaction, len(accepted))
+	for i, item := range accepted {
+		posted[i] = item.posted
+	}
+
+	// The transactions are linked, audited and journaled in id order, then their fees are posted, whose ids
+	// follow theirs
+	if err := s.chainTransactions(ctx, posted); err != nil {
+		return err
+	}
+	for _, item := range accepted {
+		txn := item.posted
+		if err := auditTransactionCreated(ctx, s.auditRepo, txn); err != nil {
+			return err
+		}
+		if item.screening != nil {
+			item.screening.TransactionID = txn.ID
+			if txn.Screening, err = s.screeningRepo.InsertScreeningEvaluation(ctx, item.screening); err != nil {
+				return err
+			}
+		}
+		if err := s.postJournalEntry(ctx, transactionEntry(txn)); err != nil {
+			return err
+		}
+	}
+	for _, txn := range posted {
+		fee, err := s.postFee(ctx, account, txn)
+		if err != nil {
+			return err
+		}
+		if fee != nil {
+			txn.Fees = []*repository.Transaction{fee}
+		}
+	}
+
+	if err := s.dischargeBatch(ctx, posted); err != nil {
+		return fmt.Errorf("payment discharge error: %w", err)
+	}
+
+	for _, item := range accepted {
+		results[item.index].Transaction = item.posted
+	}
+	log.Info().Ctx(ctx).Int64("account_id", accountID).Int("items", len(items)).Int("posted", len(posted)).Msg("batch items of account posted")
+	return nil
+}
+
+// findBatchDuplicate looks for the latest item of the batch accepted so far that the item looks like a resend
+// of under the duplicate policy
+func (s *transactionsService) findBatchDuplicate(ctx context.Context, item *batchItem, accepted []*batchItem, now time.Time) *batchItem {
+	if s.duplicates.Window <= 0 || item.txn.Force {
+		return nil
+	}
+	for i := len(accepted) - 1; i >= 0; i-- {
+		original := accepted[i]
+		if original.txn.OperationTypeID != item.txn.OperationTypeID || original.amount != item.amount {
+			continue
+		}
+		if item.txn.MerchantReference != "" && original.txn.MerchantReference != item.txn.MerchantReference {
+			continue
+		}
+		if original.posted.EventDate.Before(now.Add(-s.duplicates.Window)) {
+			return nil
+		}
+		log.Warn().Ctx(ctx).Int64("account_id", item.txn.AccountID).Int64("operation_type_id", item.txn.OperationTypeID).Float64("amount", item.amount).
+			Int("item", item.index).Int("original_item", original.index).Str("action", s.duplicates.Action).Msg("suspected duplicate transaction in batch")
+		return original
+	}
+	return nil
+}
+
+// batchScreener screens the items of an account's batch against its screening rules and activity, read once
+// for all of them. The items accepted so far count as activity.
+type batchScreener struct {
+	account  *repository.Account
+	rules    []*repository.ScreeningRule
+	activity []*repository.Transaction
+}
+
+// newBatchScreener reads the rules applying to any of the items at now and the account's activity within
+// their windows
+func (s *transactionsService) newBatchScreener(ctx context.Context, account *repository.Account, items []*batchItem, now time.Time) (*batchScreener, error) {
+	rules, err := s.screeningRepo.GetScreeningRules(ctx, account.Product)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch screening rules: %w", err)
+	}
+	rules = slices.DeleteFunc(rules, func(rule *repository.ScreeningRule) bool {
+		return !slices.ContainsFunc(items, func(item *batchItem) bool {
+			return screeningRuleApplies(rule, account, item.txn.OperationTypeID, now)
+		})
+	})
+	screener := &batchScreener{account: account, rules: rules}
+
+	var windowHours int
+	for _, rule := range rules {
+		windowHours = max(windowHours, rule.WindowHours)
+	}
+	if windowHours > 0 {
+		screener.activity, err = s.trxRepo.GetTransactionsByAccountIDSince(ctx, account.ID, now.Add(-time.Duration(windowHours)*time.Hour))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch account activity: %w", err)
+		}
+	}
+	return screener, nil
+}
+
+// screen evaluates the rules applying to a transaction of amount at now; it returns nil when none does
+func (b *batchScreener) screen(ctx context.Context, operationTypeID int64, amount float64, now time.Time) *repository.ScreeningEvaluation {
+	rules := slices.DeleteFunc(slices.Clone(b.rules), func(rule *repository.ScreeningRule) bool {
+		return !screeningRuleApplies(rule, b.account, operationTypeID, now)
+	})
+	if len(rules) == 0 {
+		return nil
+	}
+	return screeningEvaluation(ctx, rules, b.account, b.activity, operationTypeID, amount, now)
+}
+
+// record counts an accepted transaction as activity of the account
+func (b *batchScreener) record(txn *repository.Transaction) {
+	if len(b.rules) > 0 {
+		b.activity = append(b.activity, txn)
+	}
+}
+
+// dischargeBatch applies the credit vouchers among the transactions posted by an account's batch against its
+// outstanding debts, reading them once and writing the changed balances, the discharge entries and their
+// audit events in one statement each. A credit only discharges the debts outstanding when it was posted, the
+// fees of the items up to it included, so balances end up as posting the items one by one would leave them.
+func (s *transactionsService) dischargeBatch(ctx context.Context, posted []*repository.Transaction) (err error) {
+	position := make(map[int64]int)
+	var credits []int
+	for i, txn := range posted {
+		position[txn.ID] = i
+		for _, fee := range txn.Fees {
+			position[fee.ID] = i
+		}
+		if txn.OperationTypeID == 4 {
+			credits = append(credits, i)
+		}
+	}
+	if len(credits) == 0 {
+		return nil
+	}
+
+	accountID := posted[0].AccountID
+	ctx, span := telemetry.Tracer().Start(ctx, "TransactionsService.dischargeBatch", trace.WithAttributes(
+		attribute.Int64("account.id", accountID),
+		attribute.Int("discharge.credits", len(credits)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	outstanding, err := s.trxRepo.GetOutstandingTransactionsByAccountID(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch outstanding transactions: %w", err)
+	}
+
+	// Balances of the debts before the discharges, kept for the ones changed. Amounts are worked out in
+	// cents, so that no floating point residue is left to discharge.
+	before := make(map[int64]float64)
+	balances := make(map[int64]int64, len(outstanding))
+	updated := make(map[int64]float64)
+	var entries []*repository.JournalEntry
+	var audits []balanceUpdate
+	for _, debt := range outstanding {
+		balances[debt.ID] = cents(debt.Balance)
+	}
+	for _, i := range credits {
+		credit := posted[i]
+		remaining := cents(credit.Amount)
+		var discharged int64
+		for _, debt := range outstanding {
+			if remaining <= 0 {
+				break
+			}
+			if p, ok := position[debt.ID]; balances[debt.ID] >= 0 || ok && p > i {
+				continue
+			}
+
+			amount := min(remaining, -balances[debt.ID])
+			if _, ok := before[debt.ID]; !ok {
+				before[debt.ID] = debt.Balance
+			}
+			balances[debt.ID] += amount
+			debt.Balance = float64(balances[debt.ID]) / 100
+			entries = append(entries, dischargeEntry(credit, debt, float64(amount)/100))
+			remaining -= amount
+			discharged += amount
+		}
+		if discharged == 0 {
+			continue
+		}
+
+		balance := float64(cents(credit.Amount)-discharged) / 100
+		updated[credit.ID] = balance
+		audits = append(audits, balanceUpdate{credit.ID, credit.Balance, balance})
+		credit.Balance = balance
+	}
+	for _, debt := range outstanding {
+		if balance, ok := before[debt.ID]; ok {
+			updated[debt.ID] = debt.Balance
+			audits = append(audits, balanceUpdate{debt.ID, balance, debt.Balance})
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := s.trxRepo.UpdateTransactionBalances(ctx, updated); err != nil {
+		return fmt.Errorf("failed to update transaction balances: %w", err)
+	}
+	if err := s.postJournalEntries(ctx, entries); err != nil {
+		return err
+	}
+	if err := auditBalancesUpdated(ctx, s.auditRepo, audits); err != nil {
+		return err
+	}
+	log.Info().Ctx(ctx).Int64("account_id", accountID).Int("credits", len(credits)).Int("debts", len(before)).Msg("batch payment discharge completed")
+	return nil
+}
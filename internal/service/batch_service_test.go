@@ -0,0 +1,317 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTransactionBatch(t *testing.T) {
+	t.Run("Atomic batch should insert an account's items in one round trip and discharge in one pass", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
+		batch := mockDB.ExpectBatch()
+		batch.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(1), -50.0, -50.0, testNow, "", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(1), testNow, -50.0))
+		batch.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(4), 60.0, 60.0, testNow, "", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(2), testNow, 60.0))
+		expectChainLink(mockDB, 1)
+		mockDB.ExpectExec(`UPDATE transactions SET prev_hash`).
+			WithArgs(int64(2), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 1)
+		expectJournalEntry(mockDB, "purchase", 1, "customer_receivable:1", "cash_clearing", []int64{1, 0}, 50)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 2)
+		expectJournalEntry(mockDB, "payment", 2, "customer_credit:1", "cash_clearing", []int64{2, 0}, -60)
+		expectNoFeeRule(mockDB, 1)
+		expectNoFeeRule(mockDB, 4)
+		mockDB.ExpectQuery(`SELECT id, operation_type_id, amount, balance, event_date FROM transactions WHERE account_id = \$1`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "operation_type_id", "amount", "balance", "event_date"}).
+				AddRow(int64(1), int64(1), -50.0, -50.0, testNow))
+		mockDB.ExpectExec(`UPDATE transactions t SET balance = u.balance.* FROM unnest`).
+			WithArgs([]int64{1, 2}, []float64{0, 10}).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+		mockDB.ExpectExec(`INSERT INTO postings`).
+			WithArgs([]string{"discharge"}, pgxmock.AnyArg(), pgxmock.AnyArg(), []string{"customer_credit:1", "customer_receivable:1"},
+				pgxmock.AnyArg(), pgxmock.AnyArg(), []int64{1, 1}, []int64{2, 1}, []float64{50, -50}, "").
+			WillReturnResult(pgxmock.NewResult("INSERT", 2))
+		mockDB.ExpectExec(`INSERT INTO audit_events .* FROM unnest`).
+			WithArgs([]string{service.AuditActionTransactionBalanceUpdated, service.AuditActionTransactionBalanceUpdated},
+				[]string{service.AuditEntityTransaction, service.AuditEntityTransaction}, []int64{2, 1},
+				[]string{`{"balance":60}`, `{"balance":-50}`}, []string{`{"balance":10}`, `{"balance":0}`}, "", "", "", "").
+			WillReturnResult(pgxmock.NewResult("INSERT", 2))
+		mockDB.ExpectCommit()
+
+		results, err := newTransactionsService(mockDB).CreateTransactionBatch(context.Background(), []service.NewTransaction{
+			{AccountID: 1, OperationTypeID: 1, Amount: 50},
+			{AccountID: 1, OperationTypeID: 4, Amount: 60},
+		}, service.BatchModeAtomic)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, int64(1), results[0].Transaction.ID)
+		assert.Equal(t, 10.0, results[1].Transaction.Balance)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid items should fail an atomic batch before it starts", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		_, err = newTransactionsService(mockDB).CreateTransactionBatch(context.Background(), []service.NewTransaction{
+			{AccountID: 1, OperationTypeID: 1, Amount: 50},
+			{AccountID: 1, OperationTypeID: 1, Amount: 0},
+		}, service.BatchModeAtomic)
+		var itemErr *service.BatchItemError
+		require.ErrorAs(t, err, &itemErr)
+		assert.Equal(t, 1, itemErr.Index)
+		assert.ErrorIs(t, err, service.ErrInvalidAmount)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Items of an inactive account should be reported in a best-effort batch", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "blocked")
+		mockDB.ExpectCommit()
+
+		results, err := newTransactionsService(mockDB).CreateTransactionBatch(context.Background(), []service.NewTransaction{
+			{AccountID: 1, OperationTypeID: 1, Amount: 50},
+			{AccountID: 1, OperationTypeID: 4, Amount: 60},
+		}, service.BatchModeBestEffort)
+		require.NoError(t, err)
+		for _, result := range results {
+			assert.ErrorIs(t, result.Err, service.ErrAccountNotActive)
+			assert.Nil(t, result.Transaction)
+		}
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid batches should be rejected", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+		trxService := newTransactionsService(mockDB)
+
+		_, err = trxService.CreateTransactionBatch(context.Background(), nil, service.BatchModeAtomic)
+		assert.ErrorIs(t, err, service.ErrEmptyBatch)
+		_, err = trxService.CreateTransactionBatch(context.Background(), make([]service.NewTransaction, service.MaxBatchItems+1), service.BatchModeAtomic)
+		assert.ErrorIs(t, err, service.ErrBatchTooLarge)
+		_, err = trxService.CreateTransactionBatch(context.Background(), []service.NewTransaction{{AccountID: 1, OperationTypeID: 1, Amount: 1}}, "partial")
+		assert.ErrorIs(t, err, service.ErrInvalidBatchMode)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+// batchFixture is a transactions service on a memory store with two fresh credit accounts
+type batchFixture struct {
+	trxService service.TransactionsService
+	trxRepo    repository.TransactionsRepository
+	ledger     service.LedgerService
+	integrity  service.IntegrityService
+	accounts   [2]int64
+}
+
+func newBatchFixture(t *testing.T, duplicates service.DuplicatePolicy) *batchFixture {
+	store := memory.NewStore()
+	trxRepo := memory.NewTransactionsRepository(store)
+	accRepo := memory.NewAccountsRepository(store)
+	ledgerRepo := memory.NewLedgerRepository(store)
+	auditRepo := memory.NewAuditRepository(store)
+	transactor := memory.NewTransactor(store)
+
+	f := &batchFixture{
+		trxService: service.NewTransactionsService(trxRepo, accRepo, ledgerRepo, memory.NewFeesRepository(store), memory.NewScreeningRepository(store), auditRepo, memory.NewBillingRepository(store), transactor, clock.System(), duplicates, service.BackdatingPolicy{}),
+		trxRepo:    trxRepo,
+		ledger:     service.NewLedgerService(ledgerRepo, trxRepo, accRepo),
+		integrity:  service.NewIntegrityService(trxRepo, accRepo),
+	}
+	accService := service.NewAccountsService(accRepo, memory.NewCustomersRepository(store), auditRepo, transactor)
+	for i, document := range []string{"1", "2"} {
+		account, err := accService.CreateAccount(context.Background(), 0, document, service.ProductCredit)
+		require.NoError(t, err)
+		f.accounts[i] = account.ID
+	}
+	return f
+}
+
+// postedTransaction is what a posted transaction leaves behind, whatever its id
+type postedTransaction struct {
+	OperationTypeID int64
+	Amount          float64
+	Balance         float64
+}
+
+func (f *batchFixture) posted(t *testing.T, accountID int64) []postedTransaction {
+	transactions, err := f.trxRepo.GetTransactionsByAccountID(context.Background(), accountID)
+	require.NoError(t, err)
+	var posted []postedTransaction
+	for _, txn := range transactions {
+		posted = append(posted, postedTransaction{txn.OperationTypeID, txn.Amount, txn.Balance})
+	}
+	return posted
+}
+
+func TestTransactionBatchPosting(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Batches should leave the balances posting items one by one leaves", func(t *testing.T) {
+		items := func(accounts [2]int64) []service.NewTransaction {
+			a, b := accounts[0], accounts[1]
+			return []service.NewTransaction{
+				{AccountID: a, OperationTypeID: 1, Amount: 50},
+				{AccountID: b, OperationTypeID: 1, Amount: 20},
+				{AccountID: a, OperationTypeID: 3, Amount: 100},
+				{AccountID: a, OperationTypeID: 4, Amount: 120},
+				{AccountID: a, OperationTypeID: 1, Amount: 30},
+				{AccountID: b, OperationTypeID: 4, Amount: 10},
+				{AccountID: a, OperationTypeID: 4, Amount: 100},
+			}
+		}
+
+		oneByOne := newBatchFixture(t, service.DuplicatePolicy{})
+		for _, txn := range items(oneByOne.accounts) {
+			_, err := oneByOne.trxService.CreateTransaction(ctx, txn)
+			require.NoError(t, err)
+		}
+
+		batched := newBatchFixture(t, service.DuplicatePolicy{})
+		results, err := batched.trxService.CreateTransactionBatch(ctx, items(batched.accounts), service.BatchModeAtomic)
+		require.NoError(t, err)
+		require.Len(t, results, 7)
+		require.Len(t, results[2].Transaction.Fees, 1)
+		assert.Equal(t, -5.0, results[2].Transaction.Fees[0].Amount)
+		assert.Equal(t, 35.0, results[6].Transaction.Balance)
+
+		for i := range batched.accounts {
+			assert.ElementsMatch(t, oneByOne.posted(t, oneByOne.accounts[i]), batched.posted(t, batched.accounts[i]))
+
+			report, err := batched.ledger.VerifyAccount(ctx, batched.accounts[i])
+			require.NoError(t, err)
+			assert.True(t, report.Consistent)
+			chain, err := batched.integrity.VerifyChain(ctx, batched.accounts[i])
+			require.NoError(t, err)
+			assert.True(t, chain.Intact)
+		}
+	})
+
+	t.Run("Batch discharges should leave no sub-cent residue", func(t *testing.T) {
+		f := newBatchFixture(t, service.DuplicatePolicy{})
+		a := f.accounts[0]
+		// Summed in floating point, 0.30 less three 0.10 debts leaves a residue of about 5.6e-17
+		results, err := f.trxService.CreateTransactionBatch(ctx, []service.NewTransaction{
+			{AccountID: a, OperationTypeID: 1, Amount: 0.10},
+			{AccountID: a, OperationTypeID: 1, Amount: 0.10},
+			{AccountID: a, OperationTypeID: 1, Amount: 0.10},
+			{AccountID: a, OperationTypeID: 1, Amount: 0.70},
+			{AccountID: a, OperationTypeID: 4, Amount: 0.30},
+			{AccountID: a, OperationTypeID: 4, Amount: 0.80},
+		}, service.BatchModeAtomic)
+		require.NoError(t, err)
+		require.Len(t, results, 6)
+
+		assert.Equal(t, []postedTransaction{
+			{1, -0.10, 0}, {1, -0.10, 0}, {1, -0.10, 0}, {1, -0.70, 0}, {4, 0.30, 0}, {4, 0.80, 0.10},
+		}, f.posted(t, a))
+		report, err := f.ledger.VerifyAccount(ctx, a)
+		require.NoError(t, err)
+		assert.True(t, report.Consistent, "%+v", report.Mismatches)
+	})
+
+	t.Run("Best-effort batches should post the items that pass and report the others", func(t *testing.T) {
+		f := newBatchFixture(t, service.DuplicatePolicy{Window: 10 * time.Minute, Action: service.DuplicateActionReject})
+		a := f.accounts[0]
+
+		results, err := f.trxService.CreateTransactionBatch(ctx, []service.NewTransaction{
+			{AccountID: a, OperationTypeID: 1, Amount: 50, MerchantReference: "order-1"},
+			{AccountID: a, OperationTypeID: 1, Amount: 50, MerchantReference: "order-1"},
+			{AccountID: 999, OperationTypeID: 1, Amount: 10},
+			{AccountID: a, OperationTypeID: 1, Amount: 0},
+			{AccountID: a, OperationTypeID: service.OperationTypeTransferDebit, Amount: 10},
+			{AccountID: a, OperationTypeID: 1, Amount: 1500},
+			{AccountID: a, OperationTypeID: 4, Amount: 20},
+		}, service.BatchModeBestEffort)
+		require.NoError(t, err)
+		require.Len(t, results, 7)
+
+		require.NotNil(t, results[0].Transaction)
+		assert.ErrorIs(t, results[1].Err, service.ErrDuplicateTransaction)
+		assert.ErrorContains(t, results[1].Err, "matches item 0 of the batch")
+		assert.ErrorIs(t, results[2].Err, service.ErrInvalidAccountID)
+		assert.ErrorIs(t, results[3].Err, service.ErrInvalidAmount)
+		assert.ErrorIs(t, results[4].Err, service.ErrInvalidOperationType)
+		assert.ErrorIs(t, results[5].Err, service.ErrTransactionDenied)
+		require.NotNil(t, results[6].Transaction)
+		assert.Zero(t, results[6].Transaction.Balance)
+		for i, result := range results {
+			assert.Equal(t, i, result.Index)
+		}
+		assert.ElementsMatch(t, []postedTransaction{{1, -50, -30}, {4, 20, 0}}, f.posted(t, a))
+
+		// A resend of the batch matches the posted transactions
+		results, err = f.trxService.CreateTransactionBatch(ctx, []service.NewTransaction{
+			{AccountID: a, OperationTypeID: 1, Amount: 50, MerchantReference: "order-1"},
+		}, service.BatchModeBestEffort)
+		require.NoError(t, err)
+		var duplicate *service.DuplicateTransactionError
+		require.True(t, errors.As(results[0].Err, &duplicate))
+		assert.Equal(t, results[0].Index, 0)
+	})
+
+	t.Run("Atomic batches should post nothing when an item is rejected", func(t *testing.T) {
+		f := newBatchFixture(t, service.DuplicatePolicy{})
+		a, b := f.accounts[0], f.accounts[1]
+
+		_, err := f.trxService.CreateTransactionBatch(ctx, []service.NewTransaction{
+			{AccountID: a, OperationTypeID: 1, Amount: 50},
+			{AccountID: b, OperationTypeID: 1, Amount: 20},
+			{AccountID: b, OperationTypeID: 1, Amount: 1500},
+		}, service.BatchModeAtomic)
+		var itemErr *service.BatchItemError
+		require.ErrorAs(t, err, &itemErr)
+		assert.Equal(t, 2, itemErr.Index)
+		assert.ErrorIs(t, err, service.ErrTransactionDenied)
+		assert.Empty(t, f.posted(t, a))
+		assert.Empty(t, f.posted(t, b))
+	})
+
+	t.Run("Flagged duplicates should reference their original in the batch", func(t *testing.T) {
+		f := newBatchFixture(t, service.DuplicatePolicy{Window: 10 * time.Minute, Action: service.DuplicateActionFlag})
+		a := f.accounts[0]
+
+		results, err := f.trxService.CreateTransactionBatch(ctx, []service.NewTransaction{
+			{AccountID: a, OperationTypeID: 1, Amount: 50, MerchantReference: "order-1"},
+			{AccountID: a, OperationTypeID: 1, Amount: 50, MerchantReference: "order-1"},
+			{AccountID: a, OperationTypeID: 1, Amount: 50, MerchantReference: "order-2"},
+		}, service.BatchModeAtomic)
+		require.NoError(t, err)
+		assert.Zero(t, results[0].Transaction.DuplicateOfID)
+		assert.Equal(t, results[0].Transaction.ID, results[1].Transaction.DuplicateOfID)
+		assert.Zero(t, results[2].Transaction.DuplicateOfID)
+
+		chain, err := f.integrity.VerifyChain(ctx, a)
+		require.NoError(t, err)
+		assert.True(t, chain.Intact)
+		assert.Equal(t, 3, chain.Length)
+	})
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+func NewCustomersService(
+	custRepo repository.CustomersRepository,
+	accRepo repository.AccountsRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+	clock clock.Clock,
+) CustomersService {
+	return &customersService{custRepo: custRepo, accRepo: accRepo, auditRepo: auditRepo, transactor: transactor, clock: clock}
+}
+
+// CreateCustomer registers a new customer; the document number must not belong to another customer
+func (s *customersService) CreateCustomer(ctx context.Context, customer NewCustomer) (*repository.Customer, error) {
+	if strings.TrimSpace(customer.DocumentNumber) == "" {
+		return nil, ErrInvalidDocumentNumber
+	}
+	if strings.TrimSpace(customer.Name) == "" {
+		return nil, ErrInvalidCustomerName
+	}
+	if customer.BirthDate != "" {
+		birthDate, err := time.Parse(dateLayout, customer.BirthDate)
+		if err != nil || !birthDate.Before(s.clock.Now()) {
+			return nil, ErrInvalidBirthDate
+		}
+	}
+	if customer.Email != "" {
+		if _, err := mail.ParseAddress(customer.Email); err != nil {
+			return nil, ErrInvalidEmail
+		}
+	}
+
+	return registerCustomer(ctx, s.custRepo, s.auditRepo, s.transactor, &repository.Customer{
+		DocumentNumber: customer.DocumentNumber,
+		Name:           customer.Name,
+		BirthDate:      customer.BirthDate,
+		Email:          customer.Email,
+		Phone:          customer.Phone,
+	})
+}
+
+// registerCustomer inserts the customer and records its creation in the audit log in one unit of work
+func registerCustomer(ctx context.Context, custRepo repository.CustomersRepository, auditRepo repository.AuditRepository, transactor repository.Transactor, customer *repository.Customer) (*repository.Customer, error) {
+	var created *repository.Customer
+	err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = custRepo.InsertCustomer(ctx, customer)
+		if err != nil {
+			return determinePgxError(err)
+		}
+		return recordAudit(ctx, auditRepo, AuditActionCustomerCreated, AuditEntityCustomer, created.ID, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// GetCustomer retrieves a customer by customerID
+func (s *customersService) GetCustomer(ctx context.Context, customerID int64) (*repository.Customer, error) {
+	customer, err := s.custRepo.GetCustomerByID(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, ErrFailedToFetchCustomer
+	}
+	return customer, nil
+}
+
+// GetCustomerAccounts retrieves the accounts held by an existing customer
+func (s *customersService) GetCustomerAccounts(ctx context.Context, customerID int64) ([]*repository.Account, error) {
+	if _, err := s.GetCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.accRepo.GetAccountsByCustomerID(ctx, customerID)
+	if err != nil {
+		return nil, ErrFailedToFetchAccount
+	}
+	if accounts == nil {
+		accounts = []*repository.Account{}
+	}
+	return accounts, nil
+}
@@ -0,0 +1,131 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCustomersService(mockDB pgxmock.PgxPoolIface) service.CustomersService {
+	return service.NewCustomersService(repository.NewCustomersRepository(mockDB), repository.NewAccountsRepository(mockDB), repository.NewAuditRepository(mockDB), repository.NewTransactor(mockDB), clock.Fixed(testNow))
+}
+
+func TestCreateCustomer(t *testing.T) {
+	t.Run("Valid customer should be created", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		custService := newCustomersService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`INSERT INTO customers`).
+			WithArgs("12345678900", "Maria Silva", "1990-05-17", "maria@example.com", "+5511999999999", "").
+			WillReturnRows(pgxmock.NewRows(customerColumns).
+				AddRow(int64(1), "12345678900", "Maria Silva", "1990-05-17", "maria@example.com", "+5511999999999"))
+		expectAuditEventStates(mockDB, service.AuditActionCustomerCreated, service.AuditEntityCustomer, 1, "",
+			`{"id":1,"document_number":"12345678900","name":"Maria Silva","birth_date":"1990-05-17","email":"maria@example.com","phone":"+5511999999999"}`)
+		mockDB.ExpectCommit()
+
+		customer, err := custService.CreateCustomer(ctx, service.NewCustomer{
+			DocumentNumber: "12345678900",
+			Name:           "Maria Silva",
+			BirthDate:      "1990-05-17",
+			Email:          "maria@example.com",
+			Phone:          "+5511999999999",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), customer.ID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Duplicate document number should conflict", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		custService := newCustomersService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`INSERT INTO customers`).
+			WithArgs("12345678900", "Maria Silva", "", "", "", "").
+			WillReturnError(errors.New(`duplicate key value violates unique constraint "customers_document_number_key"`))
+		mockDB.ExpectRollback()
+
+		customer, err := custService.CreateCustomer(ctx, service.NewCustomer{DocumentNumber: "12345678900", Name: "Maria Silva"})
+		assert.ErrorIs(t, err, service.ErrCustomerAlreadyExists)
+		assert.Nil(t, customer)
+	})
+
+	t.Run("Invalid input should fail before reaching the database", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		custService := newCustomersService(mockDB)
+		ctx := context.Background()
+
+		cases := []struct {
+			customer service.NewCustomer
+			err      error
+		}{
+			{service.NewCustomer{Name: "Maria Silva"}, service.ErrInvalidDocumentNumber},
+			{service.NewCustomer{DocumentNumber: "1"}, service.ErrInvalidCustomerName},
+			{service.NewCustomer{DocumentNumber: "1", Name: "Maria", BirthDate: "17/05/1990"}, service.ErrInvalidBirthDate},
+			{service.NewCustomer{DocumentNumber: "1", Name: "Maria", BirthDate: "2999-01-01"}, service.ErrInvalidBirthDate},
+			{service.NewCustomer{DocumentNumber: "1", Name: "Maria", BirthDate: "2025-06-01"}, service.ErrInvalidBirthDate},
+			{service.NewCustomer{DocumentNumber: "1", Name: "Maria", Email: "not-an-email"}, service.ErrInvalidEmail},
+		}
+		for _, c := range cases {
+			customer, err := custService.CreateCustomer(ctx, c.customer)
+			assert.ErrorIs(t, err, c.err)
+			assert.Nil(t, customer)
+		}
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetCustomerAccounts(t *testing.T) {
+	t.Run("Existing customer should return its accounts", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		custService := newCustomersService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`SELECT .* FROM customers WHERE id = \$1`).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows(customerColumns).AddRow(int64(7), "12345678900", "Maria Silva", "", "", ""))
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* WHERE a.customer_id = \$1`).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows(accountColumns))
+
+		accounts, err := custService.GetCustomerAccounts(ctx, 7)
+		assert.NoError(t, err)
+		assert.NotNil(t, accounts)
+		assert.Empty(t, accounts)
+	})
+
+	t.Run("Unknown customer should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		custService := newCustomersService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`SELECT .* FROM customers WHERE id = \$1`).WithArgs(int64(999)).WillReturnError(pgx.ErrNoRows)
+
+		accounts, err := custService.GetCustomerAccounts(ctx, 999)
+		assert.ErrorIs(t, err, service.ErrCustomerNotFound)
+		assert.Nil(t, accounts)
+	})
+}
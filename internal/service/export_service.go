@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func NewExportService(trxRepo repository.TransactionsRepository, accRepo repository.AccountsRepository) ExportService {
+	return &exportService{trxRepo: trxRepo, accRepo: accRepo}
+}
+
+// ExportTransactions streams the account's transactions with an event date in [from, to) into sink, oldest
+// first, without holding them in memory; a zero bound is open. Errors returned by the sink are passed through
+// unchanged, so callers can tell a failed write from a failed read.
+func (s *exportService) ExportTransactions(ctx context.Context, accountID int64, from, to time.Time, sink TransactionSink) (err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "ExportService.ExportTransactions", trace.WithAttributes(
+		attribute.Int64("account.id", accountID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return ErrInvalidExportPeriod
+	}
+
+	account, err := s.accRepo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAccountNotFound
+		}
+		return ErrFailedToFetchAccount
+	}
+
+	// The period opens with the net of the transactions before it, summed by the repository
+	var opening float64
+	if !from.IsZero() {
+		if opening, err = s.trxRepo.GetNetAmountByAccountIDBefore(ctx, accountID, from); err != nil {
+			return ErrFailedToExport
+		}
+	}
+
+	if err := sink.Begin(account, from, to, FormatAmount(opening)); err != nil {
+		return err
+	}
+
+	var count int
+	var sinkErr error
+	err = s.trxRepo.StreamTransactionsByAccountID(ctx, accountID, from, to, func(txn *repository.Transaction) error {
+		if sinkErr = sink.Write(txn); sinkErr != nil {
+			return sinkErr
+		}
+		count++
+		return nil
+	})
+	span.SetAttributes(attribute.Int("export.transactions", count))
+	if sinkErr != nil {
+		return sinkErr
+	}
+	if err != nil {
+		return ErrFailedToExport
+	}
+	return sink.End()
+}
@@ -0,0 +1,144 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportColumns = []string{"id", "operation_type_id", "description", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id", "merchant_reference", "duplicate_of_transaction_id"}
+
+// recordingSink keeps what an export passes to it, failing writes after failAfter transactions when set
+type recordingSink struct {
+	account      *repository.Account
+	opening      float64
+	transactions []*repository.Transaction
+	ended        bool
+	failAfter    int
+}
+
+var errSinkClosed = errors.New("sink closed")
+
+func (s *recordingSink) Begin(account *repository.Account, _, _ time.Time, opening float64) error {
+	s.account, s.opening = account, opening
+	return nil
+}
+
+func (s *recordingSink) Write(txn *repository.Transaction) error {
+	if s.failAfter > 0 && len(s.transactions) == s.failAfter {
+		return errSinkClosed
+	}
+	s.transactions = append(s.transactions, txn)
+	return nil
+}
+
+func (s *recordingSink) End() error {
+	s.ended = true
+	return nil
+}
+
+func newExportService(mockDB pgxmock.PgxPoolIface) service.ExportService {
+	return service.NewExportService(repository.NewTransactionsRepository(mockDB), repository.NewAccountsRepository(mockDB))
+}
+
+func exportRows() *pgxmock.Rows {
+	return pgxmock.NewRows(exportColumns).
+		AddRow(int64(2), int64(1), "Normal Purchase", -50.0, 0.0, testNow, int64(0), int64(0), "", int64(0)).
+		AddRow(int64(3), int64(4), "Credit Voucher", 60.0, 10.0, testNow, int64(0), int64(0), "", int64(0))
+}
+
+func TestExportTransactions(t *testing.T) {
+	from := testNow.AddDate(0, -1, 0)
+
+	t.Run("Transactions should stream into the sink", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		expectChainAccount(mockDB)
+		mockDB.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM transactions WHERE account_id = \$1 AND event_date < \$2`).
+			WithArgs(int64(1), from).
+			WillReturnRows(pgxmock.NewRows([]string{"sum"}).AddRow(-20.0))
+		mockDB.ExpectQuery(`FROM transactions t\s+JOIN operation_types ot`).
+			WithArgs(int64(1), from, nil).
+			WillReturnRows(exportRows())
+
+		sink := &recordingSink{}
+		err = newExportService(mockDB).ExportTransactions(context.Background(), 1, from, time.Time{}, sink)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), sink.account.ID)
+		assert.Equal(t, -20.0, sink.opening)
+		require.Len(t, sink.transactions, 2)
+		assert.Equal(t, "Credit Voucher", sink.transactions[1].OperationType)
+		assert.True(t, sink.ended)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Empty or reversed periods should be rejected", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		err = newExportService(mockDB).ExportTransactions(context.Background(), 1, testNow, testNow, &recordingSink{})
+		assert.ErrorIs(t, err, service.ErrInvalidExportPeriod)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown accounts should not begin an export", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectQuery(`SELECT a.id, .* FROM accounts a .* WHERE a.id = \$1`).
+			WithArgs(int64(1)).
+			WillReturnError(pgx.ErrNoRows)
+
+		sink := &recordingSink{}
+		err = newExportService(mockDB).ExportTransactions(context.Background(), 1, time.Time{}, time.Time{}, sink)
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+		assert.Nil(t, sink.account)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Sink errors should stop the export and pass through", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		expectChainAccount(mockDB)
+		mockDB.ExpectQuery(`FROM transactions t\s+JOIN operation_types ot`).
+			WithArgs(int64(1), nil, nil).
+			WillReturnRows(exportRows())
+
+		sink := &recordingSink{failAfter: 1}
+		err = newExportService(mockDB).ExportTransactions(context.Background(), 1, time.Time{}, time.Time{}, sink)
+		assert.ErrorIs(t, err, errSinkClosed)
+		assert.Len(t, sink.transactions, 1)
+		assert.False(t, sink.ended)
+	})
+
+	t.Run("Read errors should fail the export", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		expectChainAccount(mockDB)
+		mockDB.ExpectQuery(`FROM transactions t\s+JOIN operation_types ot`).
+			WithArgs(int64(1), nil, nil).
+			WillReturnError(errors.New("connection reset"))
+
+		sink := &recordingSink{}
+		err = newExportService(mockDB).ExportTransactions(context.Background(), 1, time.Time{}, time.Time{}, sink)
+		assert.ErrorIs(t, err, service.ErrFailedToExport)
+		assert.False(t, sink.ended)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GenesisHash is the previous hash of the first link of every account's chain
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+func NewIntegrityService(trxRepo repository.TransactionsRepository, accRepo repository.AccountsRepository) IntegrityService {
+	return &integrityService{trxRepo: trxRepo, accRepo: accRepo}
+}
+
+// chainRecord is the canonical form of a transaction its hash covers. The balance is left out, as
+// discharges keep changing it after posting.
+type chainRecord struct {
+	ID                  int64   `json:"id"`
+	AccountID           int64   `json:"account_id"`
+	OperationTypeID     int64   `json:"operation_type_id"`
+	Amount              float64 `json:"amount"`
+	EventDate           string  `json:"event_date"`
+	TransferID          int64   `json:"transfer_id"`
+	ParentTransactionID int64   `json:"parent_transaction_id"`
+	MerchantReference   string  `json:"merchant_reference"`
+	DuplicateOfID       int64   `json:"duplicate_of_transaction_id"`
+	PrevHash            string  `json:"prev_hash"`
+}
+
+// TransactionHash computes the hex SHA-256 of the transaction's canonical record linked to prevHash
+func TransactionHash(txn *repository.Transaction, prevHash string) string {
+	record, _ := json.Marshal(chainRecord{
+		ID:                  txn.ID,
+		AccountID:           txn.AccountID,
+		OperationTypeID:     txn.OperationTypeID,
+		Amount:              txn.Amount,
+		EventDate:           txn.EventDate.UTC().Format(time.RFC3339Nano),
+		TransferID:          txn.TransferID,
+		ParentTransactionID: txn.ParentTransactionID,
+		MerchantReference:   txn.MerchantReference,
+		DuplicateOfID:       txn.DuplicateOfID,
+		PrevHash:            prevHash,
+	})
+	sum := sha256.Sum256(record)
+	return hex.EncodeToString(sum[:])
+}
+
+// chainTransaction links a just inserted transaction to the head of its account's chain. Callers hold
+// the account lock, so no other transaction can extend the chain in between.
+func (s *transactionsService) chainTransaction(ctx context.Context, txn *repository.Transaction) error {
+	return s.chainTransactions(ctx, []*repository.Transaction{txn})
+}
+
+// chainTransactions links just inserted transactions of one account to its chain in order, reading the
+// head once. They must be in id order, the order chains are verified in.
+func (s *transactionsService) chainTransactions(ctx context.Context, transactions []*repository.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	prevHash, err := s.trxRepo.GetChainHead(ctx, transactions[0].AccountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		prevHash = GenesisHash
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch chain head: %w", err)
+	}
+
+	for _, txn := range transactions {
+		hash := TransactionHash(txn, prevHash)
+		if err := s.trxRepo.SetTransactionHash(ctx, txn.ID, prevHash, hash); err != nil {
+			return err
+		}
+		txn.PrevHash = prevHash
+		txn.Hash = hash
+		prevHash = hash
+	}
+	return nil
+}
+
+// VerifyChain recomputes the account's transaction hash chain from the genesis hash and reports the
+// first link that does not hold
+func (s *integrityService) VerifyChain(ctx context.Context, accountID int64) (_ *ChainReport, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "IntegrityService.VerifyChain", trace.WithAttributes(
+		attribute.Int64("account.id", accountID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.accRepo.GetAccountByID(ctx, accountID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, ErrFailedToFetchAccount
+	}
+
+	report, err := s.verifyChain(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Bool("chain.intact", report.Intact))
+	return report, nil
+}
+
+// VerifyChains verifies the chain of every account whose chain has started, in account id order
+func (s *integrityService) VerifyChains(ctx context.Context) (_ []*ChainReport, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "IntegrityService.VerifyChains")
+	defer func() { endSpan(span, err) }()
+
+	accountIDs, err := s.trxRepo.GetChainedAccountIDs(ctx)
+	if err != nil {
+		return nil, ErrFailedToFetchChain
+	}
+
+	reports := make([]*ChainReport, 0, len(accountIDs))
+	for _, accountID := range accountIDs {
+		report, err := s.verifyChain(ctx, accountID)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// verifyChain walks the account's transactions in posting order. Transactions posted before the chain
+// started are counted as unchained; once it has started, every transaction must link to the previous one.
+func (s *integrityService) verifyChain(ctx context.Context, accountID int64) (*ChainReport, error) {
+	transactions, err := s.trxRepo.GetTransactionChain(ctx, accountID)
+	if err != nil {
+		return nil, ErrFailedToFetchChain
+	}
+
+	report := &ChainReport{AccountID: accountID, Intact: true}
+	prevHash := ""
+	for _, txn := range transactions {
+		if prevHash == "" {
+			if txn.Hash == "" {
+				report.Unchained++
+				continue
+			}
+			prevHash = GenesisHash
+		}
+
+		var brk *ChainBreak
+		switch expected := TransactionHash(txn, prevHash); {
+		case txn.Hash == "":
+			brk = &ChainBreak{TransactionID: txn.ID, Reason: ChainBreakMissingHash, Expected: expected}
+		case txn.PrevHash != prevHash:
+			brk = &ChainBreak{TransactionID: txn.ID, Reason: ChainBreakPrevHashMismatch, Expected: prevHash, Actual: txn.PrevHash}
+		case txn.Hash != expected:
+			brk = &ChainBreak{TransactionID: txn.ID, Reason: ChainBreakHashMismatch, Expected: expected, Actual: txn.Hash}
+		}
+		if brk != nil {
+			report.Intact = false
+			report.Break = brk
+			return report, nil
+		}
+
+		report.Length++
+		report.Head = txn.Hash
+		prevHash = txn.Hash
+	}
+	return report, nil
+}
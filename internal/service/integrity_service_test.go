@@ -0,0 +1,195 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var chainColumns = []string{"id", "operation_type_id", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id", "merchant_reference", "duplicate_of_transaction_id", "prev_hash", "hash"}
+
+func newIntegrityService(mockDB pgxmock.PgxPoolIface) service.IntegrityService {
+	return service.NewIntegrityService(repository.NewTransactionsRepository(mockDB), repository.NewAccountsRepository(mockDB))
+}
+
+// chainRows links the transactions of account 1 from the genesis hash, after an unchained one
+func chainRows(transactions ...*repository.Transaction) *pgxmock.Rows {
+	rows := pgxmock.NewRows(chainColumns).
+		AddRow(int64(1), int64(1), -5.0, -5.0, testNow, int64(0), int64(0), "", int64(0), "", "")
+	prevHash := service.GenesisHash
+	for _, txn := range transactions {
+		txn.AccountID = 1
+		txn.EventDate = testNow
+		hash := service.TransactionHash(txn, prevHash)
+		rows.AddRow(txn.ID, txn.OperationTypeID, txn.Amount, txn.Balance, testNow, int64(0), int64(0), "", int64(0), prevHash, hash)
+		prevHash = hash
+	}
+	return rows
+}
+
+func expectChainAccount(mockDB pgxmock.PgxPoolIface) {
+	mockDB.ExpectQuery(`SELECT a.id, .* FROM accounts a .* WHERE a.id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(accountColumns).
+			AddRow(int64(1), int64(1), "12345678900", "credit", "active", accountCreatedAt))
+}
+
+func TestTransactionHash(t *testing.T) {
+	txn := &repository.Transaction{ID: 7, AccountID: 1, OperationTypeID: 1, Amount: -50, Balance: -50, EventDate: testNow}
+
+	hash := service.TransactionHash(txn, service.GenesisHash)
+	assert.Len(t, hash, 64)
+
+	txn.Balance = 0
+	assert.Equal(t, hash, service.TransactionHash(txn, service.GenesisHash), "discharges must not change the hash")
+	assert.NotEqual(t, hash, service.TransactionHash(txn, hash))
+	txn.Amount = -5
+	assert.NotEqual(t, hash, service.TransactionHash(txn, service.GenesisHash))
+}
+
+func TestVerifyChain(t *testing.T) {
+	t.Run("Recomputed links should make the chain intact", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		expectChainAccount(mockDB)
+		mockDB.ExpectQuery(`FROM transactions\s+WHERE account_id = \$1\s+ORDER BY id`).
+			WithArgs(int64(1)).
+			WillReturnRows(chainRows(
+				&repository.Transaction{ID: 2, OperationTypeID: 1, Amount: -50, Balance: 0},
+				&repository.Transaction{ID: 3, OperationTypeID: 4, Amount: 60, Balance: 10},
+			))
+
+		report, err := newIntegrityService(mockDB).VerifyChain(context.Background(), 1)
+		require.NoError(t, err)
+		assert.True(t, report.Intact)
+		assert.Equal(t, 2, report.Length)
+		assert.Equal(t, 1, report.Unchained)
+		assert.Len(t, report.Head, 64)
+		assert.Nil(t, report.Break)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Altered amount should break its link", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		first := &repository.Transaction{ID: 2, AccountID: 1, OperationTypeID: 1, Amount: -50, EventDate: testNow}
+		firstHash := service.TransactionHash(first, service.GenesisHash)
+		expectChainAccount(mockDB)
+		mockDB.ExpectQuery(`FROM transactions\s+WHERE account_id = \$1\s+ORDER BY id`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(chainColumns).
+				AddRow(int64(2), int64(1), -500.0, -500.0, testNow, int64(0), int64(0), "", int64(0), service.GenesisHash, firstHash))
+
+		report, err := newIntegrityService(mockDB).VerifyChain(context.Background(), 1)
+		require.NoError(t, err)
+		assert.False(t, report.Intact)
+		require.NotNil(t, report.Break)
+		assert.Equal(t, int64(2), report.Break.TransactionID)
+		assert.Equal(t, service.ChainBreakHashMismatch, report.Break.Reason)
+		assert.Equal(t, firstHash, report.Break.Actual)
+		assert.Zero(t, report.Length)
+	})
+
+	t.Run("Unknown account should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectQuery(`FROM accounts a`).WithArgs(int64(999)).WillReturnError(pgx.ErrNoRows)
+
+		_, err = newIntegrityService(mockDB).VerifyChain(context.Background(), 999)
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		expectChainAccount(mockDB)
+		mockDB.ExpectQuery(`FROM transactions`).WithArgs(int64(1)).WillReturnError(errors.New("db error"))
+
+		_, err = newIntegrityService(mockDB).VerifyChain(context.Background(), 1)
+		assert.ErrorIs(t, err, service.ErrFailedToFetchChain)
+	})
+}
+
+func TestVerifyChains(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	mockDB.ExpectQuery(`SELECT DISTINCT account_id FROM transactions`).
+		WillReturnRows(pgxmock.NewRows([]string{"account_id"}).AddRow(int64(1)))
+	mockDB.ExpectQuery(`FROM transactions\s+WHERE account_id = \$1\s+ORDER BY id`).
+		WithArgs(int64(1)).
+		WillReturnRows(chainRows(&repository.Transaction{ID: 2, OperationTypeID: 1, Amount: -50, Balance: -50}))
+
+	reports, err := newIntegrityService(mockDB).VerifyChains(context.Background())
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.True(t, reports[0].Intact)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestTransactionChainAcrossPostings(t *testing.T) {
+	store := memory.NewStore()
+	trxRepo := memory.NewTransactionsRepository(store)
+	accRepo := memory.NewAccountsRepository(store)
+	ledgerRepo := memory.NewLedgerRepository(store)
+	auditRepo := memory.NewAuditRepository(store)
+	transactor := memory.NewTransactor(store)
+	ctx := context.Background()
+
+	accService := service.NewAccountsService(accRepo, memory.NewCustomersRepository(store), auditRepo, transactor)
+	trxService := service.NewTransactionsService(trxRepo, accRepo, ledgerRepo, memory.NewFeesRepository(store), memory.NewScreeningRepository(store), auditRepo, memory.NewBillingRepository(store), transactor, clock.System(), service.DuplicatePolicy{}, service.BackdatingPolicy{})
+	transferService := service.NewTransfersService(memory.NewTransfersRepository(store), trxRepo, accRepo, ledgerRepo, auditRepo, transactor, clock.System())
+	integrityService := service.NewIntegrityService(trxRepo, accRepo)
+
+	source, err := accService.CreateAccount(ctx, 0, "1", service.ProductCredit)
+	require.NoError(t, err)
+	destination, err := accService.CreateAccount(ctx, 0, "2", service.ProductCredit)
+	require.NoError(t, err)
+
+	purchase, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: destination.ID, OperationTypeID: 1, Amount: 50})
+	require.NoError(t, err)
+	assert.Equal(t, service.GenesisHash, purchase.PrevHash)
+	_, _, err = transferService.CreateTransfer(ctx, source.ID, destination.ID, 80)
+	require.NoError(t, err)
+
+	reports, err := integrityService.VerifyChains(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.True(t, reports[0].Intact)
+	assert.Equal(t, 1, reports[0].Length)
+	assert.True(t, reports[1].Intact)
+	assert.Equal(t, 2, reports[1].Length)
+
+	// A discharge rewrites the purchase balance, which the hash leaves out
+	stored, err := trxRepo.GetTransactionByID(ctx, purchase.ID)
+	require.NoError(t, err)
+	assert.Zero(t, stored.Balance)
+	assert.Equal(t, purchase.Hash, stored.Hash)
+
+	// A transaction slipped in without a link breaks the chain
+	_, err = trxRepo.InsertTransaction(ctx, destination.ID, 4, 1000, 1000, testNow, testNow, "", 0)
+	require.NoError(t, err)
+	report, err := integrityService.VerifyChain(ctx, destination.ID)
+	require.NoError(t, err)
+	assert.False(t, report.Intact)
+	assert.Equal(t, service.ChainBreakMissingHash, report.Break.Reason)
+	assert.Equal(t, 2, report.Length)
+}
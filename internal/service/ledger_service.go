@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func NewLedgerService(ledgerRepo repository.LedgerRepository, trxRepo repository.TransactionsRepository, accRepo repository.AccountsRepository) LedgerService {
+	return &ledgerService{ledgerRepo: ledgerRepo, trxRepo: trxRepo, accRepo: accRepo}
+}
+
+// VerifyAccount compares every transaction balance of the account, which is a projection kept
+// up to date by discharges, against the balance derived from the ledger postings attributed to it.
+func (s *ledgerService) VerifyAccount(ctx context.Context, accountID int64) (_ *LedgerReport, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "LedgerService.VerifyAccount", trace.WithAttributes(
+		attribute.Int64("account.id", accountID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.accRepo.GetAccountByID(ctx, accountID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, ErrFailedToFetchAccount
+	}
+
+	balances, err := s.ledgerRepo.GetLedgerAccountBalances(ctx, accountID)
+	if err != nil {
+		return nil, ErrFailedToFetchLedger
+	}
+	ledgerBalances, err := s.ledgerRepo.GetLedgerBalancesByTransaction(ctx, accountID)
+	if err != nil {
+		return nil, ErrFailedToFetchLedger
+	}
+	transactions, err := s.trxRepo.GetTransactionsByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, ErrFailedToFetchLedger
+	}
+
+	report := &LedgerReport{AccountID: accountID, Balances: balances, Mismatches: []LedgerMismatch{}}
+	for _, txn := range transactions {
+		ledgerBalance := ledgerBalances[txn.ID]
+		if math.Round(txn.Balance*100) != math.Round(ledgerBalance*100) {
+			report.Mismatches = append(report.Mismatches, LedgerMismatch{
+				TransactionID:    txn.ID,
+				ProjectedBalance: txn.Balance,
+				LedgerBalance:    ledgerBalance,
+			})
+		}
+	}
+	report.Consistent = len(report.Mismatches) == 0
+
+	span.SetAttributes(attribute.Bool("ledger.consistent", report.Consistent))
+	return report, nil
+}
+
+// Ledger account codes; customer ledger accounts are suffixed with the account id
+const (
+	ledgerCashClearing       = "cash_clearing"
+	ledgerTransferClearing   = "transfer_clearing"
+	ledgerCustomerReceivable = "customer_receivable"
+	ledgerCustomerCredit     = "customer_credit"
+	ledgerInterestIncome     = "interest_income"
+	ledgerFeeIncome          = "fee_income"
+)
+
+// Journal entry kinds by the operation type of the transaction that caused them
+var journalEntryKinds = map[int64]string{
+	1:                           "purchase",
+	2:                           "purchase",
+	3:                           "withdrawal",
+	4:                           "payment",
+	OperationTypeTransferDebit:  "transfer_debit",
+	OperationTypeTransferCredit: "transfer_credit",
+	OperationTypeInterest:       "interest",
+	OperationTypeLateFee:        "late_fee",
+	OperationTypeFee:            "fee",
+}
+
+// customerReceivable is the asset holding what the account owes for its debits
+func customerReceivable(accountID int64) repository.LedgerAccount {
+	return repository.LedgerAccount{Code: fmt.Sprintf("%s:%d", ledgerCustomerReceivable, accountID), Type: "asset", AccountID: accountID}
+}
+
+// customerCredit is the liability holding what the account was credited and not yet spent
+func customerCredit(accountID int64) repository.LedgerAccount {
+	return repository.LedgerAccount{Code: fmt.Sprintf("%s:%d", ledgerCustomerCredit, accountID), Type: "liability", AccountID: accountID}
+}
+
+// clearingAccount is the counterparty of a transaction: transfer legs settle against each other
+// through transfer_clearing, charges and fees are earned as income, everything else goes through cash_clearing
+func clearingAccount(operationTypeID int64) repository.LedgerAccount {
+	switch operationTypeID {
+	case OperationTypeTransferDebit, OperationTypeTransferCredit:
+		return repository.LedgerAccount{Code: ledgerTransferClearing, Type: "asset"}
+	case OperationTypeInterest:
+		return repository.LedgerAccount{Code: ledgerInterestIncome, Type: "income"}
+	case OperationTypeLateFee, OperationTypeFee:
+		return repository.LedgerAccount{Code: ledgerFeeIncome, Type: "income"}
+	}
+	return repository.LedgerAccount{Code: ledgerCashClearing, Type: "asset"}
+}
+
+// transactionEntry records a new transaction: a debit increases the customer receivable and a
+// credit the customer credit, each attributed to the transaction. The entry takes effect at its event date.
+func transactionEntry(txn *repository.Transaction) *repository.JournalEntry {
+	customer := customerCredit(txn.AccountID)
+	if txn.Amount < 0 {
+		customer = customerReceivable(txn.AccountID)
+	}
+	return &repository.JournalEntry{
+		Kind:          journalEntryKinds[txn.OperationTypeID],
+		TransactionID: txn.ID,
+		CreatedAt:     txn.EventDate,
+		Postings: []repository.Posting{
+			{Account: customer, TransactionID: txn.ID, Amount: -txn.Amount},
+			{Account: clearingAccount(txn.OperationTypeID), Amount: txn.Amount},
+		},
+	}
+}
+
+// dischargeEntry records amount of the credit transaction paying off the outstanding one, as of the credit
+func dischargeEntry(creditTxn, outstandingTxn *repository.Transaction, amount float64) *repository.JournalEntry {
+	return &repository.JournalEntry{
+		Kind:          "discharge",
+		TransactionID: creditTxn.ID,
+		CreatedAt:     creditTxn.EventDate,
+		Postings: []repository.Posting{
+			{Account: customerCredit(creditTxn.AccountID), TransactionID: creditTxn.ID, Amount: amount},
+			{Account: customerReceivable(outstandingTxn.AccountID), TransactionID: outstandingTxn.ID, Amount: -amount},
+		},
+	}
+}
+
+// dischargeAdjustmentEntry records amount more of the credit transaction paying off the outstanding one, or
+// less when negative, once a back-dated transaction changed what the credit discharges, as of at
+func dischargeAdjustmentEntry(creditTxn, outstandingTxn *repository.Transaction, amount float64, at time.Time) *repository.JournalEntry {
+	entry := dischargeEntry(creditTxn, outstandingTxn, amount)
+	entry.Kind, entry.CreatedAt = "discharge_adjustment", at
+	return entry
+}
@@ -0,0 +1,163 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLedgerService(mockDB pgxmock.PgxPoolIface) service.LedgerService {
+	return service.NewLedgerService(
+		repository.NewLedgerRepository(mockDB),
+		repository.NewTransactionsRepository(mockDB),
+		repository.NewAccountsRepository(mockDB),
+	)
+}
+
+func expectLedger(mockDB pgxmock.PgxPoolIface, byTransaction *pgxmock.Rows) {
+	mockDB.ExpectQuery(`SELECT a.id, .* FROM accounts a .* WHERE a.id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(accountColumns).
+			AddRow(int64(1), int64(1), "12345678900", "credit", "active", accountCreatedAt))
+	mockDB.ExpectQuery(`SELECT la.code, la.type`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"code", "type", "balance"}).
+			AddRow("customer_credit:1", "liability", -10.0).
+			AddRow("customer_receivable:1", "asset", 0.0))
+	mockDB.ExpectQuery(`SELECT p.transaction_id`).
+		WithArgs(int64(1)).
+		WillReturnRows(byTransaction)
+	mockDB.ExpectQuery(`SELECT id, operation_type_id, amount, balance, event_date, COALESCE\(transfer_id, 0\), COALESCE\(parent_transaction_id, 0\)`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "operation_type_id", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id"}).
+			AddRow(int64(7), int64(1), -50.0, 0.0, time.Now(), int64(0), int64(0)).
+			AddRow(int64(8), int64(4), 60.0, 10.0, time.Now(), int64(0), int64(0)))
+}
+
+func TestVerifyAccount(t *testing.T) {
+	t.Run("Balances matching the ledger are consistent", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		ledgerService := newLedgerService(mockDB)
+		expectLedger(mockDB, pgxmock.NewRows([]string{"transaction_id", "balance"}).
+			AddRow(int64(7), 0.0).
+			AddRow(int64(8), 10.0))
+
+		report, err := ledgerService.VerifyAccount(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.True(t, report.Consistent)
+		assert.Empty(t, report.Mismatches)
+		assert.Len(t, report.Balances, 2)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Balance diverging from the ledger is reported", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		ledgerService := newLedgerService(mockDB)
+		expectLedger(mockDB, pgxmock.NewRows([]string{"transaction_id", "balance"}).
+			AddRow(int64(7), -50.0).
+			AddRow(int64(8), 10.0))
+
+		report, err := ledgerService.VerifyAccount(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.False(t, report.Consistent)
+		assert.Equal(t, []service.LedgerMismatch{{TransactionID: 7, ProjectedBalance: 0, LedgerBalance: -50}}, report.Mismatches)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Missing account should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		ledgerService := newLedgerService(mockDB)
+		mockDB.ExpectQuery(`SELECT a.id, .* FROM accounts a .* WHERE a.id = \$1`).
+			WithArgs(int64(1)).
+			WillReturnError(pgx.ErrNoRows)
+
+		report, err := ledgerService.VerifyAccount(context.Background(), 1)
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+		assert.Nil(t, report)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error during ledger fetch should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		ledgerService := newLedgerService(mockDB)
+		mockDB.ExpectQuery(`SELECT a.id, .* FROM accounts a .* WHERE a.id = \$1`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(accountColumns).
+				AddRow(int64(1), int64(1), "12345678900", "credit", "active", accountCreatedAt))
+		mockDB.ExpectQuery(`SELECT la.code, la.type`).
+			WithArgs(int64(1)).
+			WillReturnError(errors.New("database error"))
+
+		report, err := ledgerService.VerifyAccount(context.Background(), 1)
+		assert.ErrorIs(t, err, service.ErrFailedToFetchLedger)
+		assert.Nil(t, report)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestLedgerTracksTransactionsAndDischarges(t *testing.T) {
+	store := memory.NewStore()
+	trxRepo := memory.NewTransactionsRepository(store)
+	accRepo := memory.NewAccountsRepository(store)
+	ledgerRepo := memory.NewLedgerRepository(store)
+	transactor := memory.NewTransactor(store)
+	ctx := context.Background()
+
+	accService := service.NewAccountsService(accRepo, memory.NewCustomersRepository(store), memory.NewAuditRepository(store), transactor)
+	trxService := service.NewTransactionsService(trxRepo, accRepo, ledgerRepo, memory.NewFeesRepository(store), memory.NewScreeningRepository(store), memory.NewAuditRepository(store), memory.NewBillingRepository(store), transactor, clock.System(), service.DuplicatePolicy{}, service.BackdatingPolicy{})
+	trfService := service.NewTransfersService(memory.NewTransfersRepository(store), trxRepo, accRepo, ledgerRepo, memory.NewAuditRepository(store), transactor, clock.System())
+	ledgerService := service.NewLedgerService(ledgerRepo, trxRepo, accRepo)
+
+	source, err := accService.CreateAccount(ctx, 0, "1", service.ProductCredit)
+	require.NoError(t, err)
+	destination, err := accService.CreateAccount(ctx, 0, "2", service.ProductCredit)
+	require.NoError(t, err)
+
+	_, err = trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: source.ID, OperationTypeID: 1, Amount: 50.5})
+	require.NoError(t, err)
+	_, err = trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: source.ID, OperationTypeID: 3, Amount: 23.5})
+	require.NoError(t, err)
+	_, err = trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: source.ID, OperationTypeID: 4, Amount: 60})
+	require.NoError(t, err)
+	_, err = trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: destination.ID, OperationTypeID: 1, Amount: 10})
+	require.NoError(t, err)
+	_, _, err = trfService.CreateTransfer(ctx, source.ID, destination.ID, 25.25)
+	require.NoError(t, err)
+
+	for _, account := range []*repository.Account{source, destination} {
+		report, err := ledgerService.VerifyAccount(ctx, account.ID)
+		require.NoError(t, err)
+		assert.True(t, report.Consistent, "account %d: %+v", account.ID, report.Mismatches)
+	}
+
+	report, err := ledgerService.VerifyAccount(ctx, source.ID)
+	require.NoError(t, err)
+	balances := map[string]float64{}
+	for _, balance := range report.Balances {
+		balances[balance.Code] = balance.Balance
+	}
+	// 50.50 + 23.50 and its 5.00 fee + 25.25 owed, 60 of it paid off by the voucher
+	assert.Equal(t, map[string]float64{"customer_credit:1": 0, "customer_receivable:1": 44.25}, balances)
+}
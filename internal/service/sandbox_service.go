@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxClockAdvance bounds a single advance of the sandbox clock, as every day crossed runs the daily jobs
+const maxClockAdvance = 366 * 24 * time.Hour
+
+func NewSandboxService(clock *clock.Virtual, stmtService StatementsService, accrualService AccrualsService) SandboxService {
+	return &sandboxService{clock: clock, stmtService: stmtService, accrualService: accrualService}
+}
+
+// Now returns the sandbox's virtual time
+func (s *sandboxService) Now() time.Time {
+	return s.clock.Now()
+}
+
+// AdvanceClock moves the virtual time forward to `to` one day at a time, running the jobs each new day
+// would run in production: closing the cycles that closed the day before, then accruing the day's interest
+// and late fees. A job failing for some accounts does not stop the others nor the clock; its errors are
+// returned along with what was done.
+func (s *sandboxService) AdvanceClock(ctx context.Context, to time.Time) (_ *ClockAdvance, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "SandboxService.AdvanceClock", trace.WithAttributes(
+		attribute.String("sandbox.to", to.UTC().Format(time.RFC3339)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	to = to.UTC()
+	from := s.clock.Now()
+	if !to.After(from) {
+		return nil, ErrClockBackwards
+	}
+	if to.Sub(from) > maxClockAdvance {
+		return nil, ErrClockAdvanceTooFar
+	}
+
+	advance := &ClockAdvance{From: from, Statements: []*repository.Statement{}, AccrualRuns: []*repository.AccrualRun{}}
+	var errs []error
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	for ; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if err := s.clock.AdvanceTo(day); err != nil {
+			return nil, err
+		}
+
+		statements, err := s.stmtService.CloseCycles(ctx, day.AddDate(0, 0, -1))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to close the cycles of %s: %w", day.AddDate(0, 0, -1).Format(dateLayout), err))
+		}
+		advance.Statements = append(advance.Statements, statements...)
+
+		runs, err := s.accrualService.Accrue(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to accrue %s: %w", day.Format(dateLayout), err))
+		}
+		advance.AccrualRuns = append(advance.AccrualRuns, runs...)
+	}
+	// The jobs take time on a running clock, which may already be past to
+	if to.After(s.clock.Now()) {
+		if err := s.clock.AdvanceTo(to); err != nil {
+			return nil, err
+		}
+	}
+	advance.To = s.clock.Now()
+
+	span.SetAttributes(
+		attribute.Int("billing.statements", len(advance.Statements)),
+		attribute.Int("accrual.runs", len(advance.AccrualRuns)),
+	)
+	return advance, errors.Join(errs...)
+}
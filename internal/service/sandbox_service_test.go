@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdvanceClock(t *testing.T) {
+	store := memory.NewStore()
+	trxRepo := memory.NewTransactionsRepository(store)
+	accRepo := memory.NewAccountsRepository(store)
+	ledgerRepo := memory.NewLedgerRepository(store)
+	billingRepo := memory.NewBillingRepository(store)
+	transactor := memory.NewTransactor(store)
+	ctx := context.Background()
+
+	start := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	virtual := clock.NewVirtual(clock.Fixed(start))
+
+	accService := service.NewAccountsService(accRepo, memory.NewCustomersRepository(store), memory.NewAuditRepository(store), transactor)
+	trxService := service.NewTransactionsService(trxRepo, accRepo, ledgerRepo, memory.NewFeesRepository(store), memory.NewScreeningRepository(store), memory.NewAuditRepository(store), memory.NewBillingRepository(store), transactor, virtual, service.DuplicatePolicy{}, service.BackdatingPolicy{})
+	stmtService := service.NewStatementsService(billingRepo, accRepo, trxRepo, ledgerRepo, memory.NewAuditRepository(store), transactor)
+	accrualService := service.NewAccrualsService(memory.NewAccrualsRepository(store), billingRepo, accRepo, trxRepo, trxService, transactor, virtual)
+	sandboxService := service.NewSandboxService(virtual, stmtService, accrualService)
+
+	account, err := accService.CreateAccount(ctx, 0, "1", service.ProductCredit)
+	require.NoError(t, err)
+	purchase, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: account.ID, OperationTypeID: 1, Amount: 100})
+	require.NoError(t, err)
+	assert.Equal(t, start, purchase.EventDate)
+
+	t.Run("Runs the jobs of every day crossed", func(t *testing.T) {
+		to := time.Date(2025, 4, 13, 12, 0, 0, 0, time.UTC)
+
+		advance, err := sandboxService.AdvanceClock(ctx, to)
+		require.NoError(t, err)
+		assert.Equal(t, start, advance.From)
+		assert.Equal(t, to, advance.To)
+		assert.Equal(t, to, sandboxService.Now())
+
+		// The cycle closing on April 1st is closed on the 2nd and falls due on the 11th
+		require.Len(t, advance.Statements, 1)
+		statement := advance.Statements[0]
+		assert.Equal(t, "2025-03-02", statement.PeriodStart)
+		assert.Equal(t, "2025-04-01", statement.PeriodEnd)
+		assert.Equal(t, 100.0, statement.ClosingBalance)
+		assert.Equal(t, "2025-04-11", statement.DueDate)
+
+		// Interest and the late fee accrue from the 12th
+		require.Len(t, advance.AccrualRuns, 2)
+		assert.Equal(t, "2025-04-12", advance.AccrualRuns[0].AccrualDate)
+		require.Len(t, advance.AccrualRuns[0].Accruals, 2)
+		assert.Equal(t, 0.07, advance.AccrualRuns[0].Accruals[0].Amount)
+		assert.Equal(t, 25.0, advance.AccrualRuns[0].Accruals[1].Amount)
+		assert.Equal(t, "2025-04-13", advance.AccrualRuns[1].AccrualDate)
+		require.Len(t, advance.AccrualRuns[1].Accruals, 1)
+	})
+
+	t.Run("Transactions are dated with the virtual time", func(t *testing.T) {
+		payment, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: account.ID, OperationTypeID: 4, Amount: 50})
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2025, 4, 13, 12, 0, 0, 0, time.UTC), payment.EventDate)
+	})
+
+	t.Run("The clock only moves forward", func(t *testing.T) {
+		advance, err := sandboxService.AdvanceClock(ctx, start)
+		assert.ErrorIs(t, err, service.ErrClockBackwards)
+		assert.Nil(t, advance)
+	})
+
+	t.Run("The clock advances at most a year at once", func(t *testing.T) {
+		advance, err := sandboxService.AdvanceClock(ctx, sandboxService.Now().AddDate(2, 0, 0))
+		assert.ErrorIs(t, err, service.ErrClockAdvanceTooFar)
+		assert.Nil(t, advance)
+	})
+}
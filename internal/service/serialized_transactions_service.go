@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/executor"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+)
+
+// NewSerializedTransactionsService returns trxService with the transactions it creates run by exec, on the
+// shard of their account. Transactions of an account then wait their turn in process instead of on the
+// account lock, which still guards them against other instances and other writers. Only transactions
+// created outside a unit of work may go through it: one waiting on the executor while holding an account
+// lock would block the shard of that account until it times out.
+func NewSerializedTransactionsService(trxService TransactionsService, exec *executor.Executor) TransactionsService {
+	return &serializedTransactionsService{TransactionsService: trxService, executor: exec}
+}
+
+// CreateTransaction creates a transaction as trxService does once the transactions of its account submitted
+// before it are posted. It returns ErrTrxQueueFull rather than waiting when too many are, and ErrTrxTimeout
+// when it could not be posted within the executor's timeout.
+func (s *serializedTransactionsService) CreateTransaction(ctx context.Context, txn NewTransaction) (*repository.Transaction, error) {
+	var transaction *repository.Transaction
+	err := s.executor.Do(ctx, txn.AccountID, func(ctx context.Context) error {
+		var err error
+		transaction, err = s.TransactionsService.CreateTransaction(ctx, txn)
+		return err
+	})
+	switch {
+	case errors.Is(err, executor.ErrQueueFull), errors.Is(err, executor.ErrStopped):
+		return nil, ErrTrxQueueFull
+	case errors.Is(err, context.DeadlineExceeded):
+		return nil, ErrTrxTimeout
+	case err != nil:
+		return nil, err
+	}
+	return transaction, nil
+}
@@ -0,0 +1,148 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/executor"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingTransactionsService creates transactions once released
+type blockingTransactionsService struct {
+	service.TransactionsService
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+// newBlockingTransactionsService returns a service released at the latest when the test ends, so a failed
+// assertion cannot leave the executor's worker blocked
+func newBlockingTransactionsService(t *testing.T) *blockingTransactionsService {
+	s := &blockingTransactionsService{started: make(chan struct{}, 1), release: make(chan struct{})}
+	t.Cleanup(s.unblock)
+	return s
+}
+
+func (s *blockingTransactionsService) unblock() {
+	s.once.Do(func() { close(s.release) })
+}
+
+func (s *blockingTransactionsService) CreateTransaction(_ context.Context, txn service.NewTransaction) (*repository.Transaction, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return &repository.Transaction{AccountID: txn.AccountID}, nil
+}
+
+func newExecutor(t *testing.T, cfg executor.Config) *executor.Executor {
+	exec, err := executor.New(cfg)
+	require.NoError(t, err)
+	exec.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, exec.Stop(ctx))
+	})
+	return exec
+}
+
+func TestSerializedCreateTransaction(t *testing.T) {
+	t.Run("Concurrent transactions of an account should post as if sent one by one", func(t *testing.T) {
+		store := memory.NewStore()
+		trxRepo := memory.NewTransactionsRepository(store)
+		accRepo := memory.NewAccountsRepository(store)
+		auditRepo := memory.NewAuditRepository(store)
+		transactor := memory.NewTransactor(store)
+		trxService := service.NewTransactionsService(trxRepo, accRepo, memory.NewLedgerRepository(store), memory.NewFeesRepository(store), memory.NewScreeningRepository(store), auditRepo, memory.NewBillingRepository(store), transactor, clock.System(), service.DuplicatePolicy{}, service.BackdatingPolicy{})
+		accService := service.NewAccountsService(accRepo, memory.NewCustomersRepository(store), auditRepo, transactor)
+		serialized := service.NewSerializedTransactionsService(trxService, newExecutor(t, executor.Config{Shards: 4, QueueSize: 100, Timeout: 5 * time.Second}))
+
+		var accounts []*repository.Account
+		for _, document := range []string{"1", "2"} {
+			account, err := accService.CreateAccount(context.Background(), 0, document, service.ProductCredit)
+			require.NoError(t, err)
+			accounts = append(accounts, account)
+		}
+
+		var wg sync.WaitGroup
+		for _, account := range accounts {
+			for i := range 20 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					txn := service.NewTransaction{AccountID: account.ID, OperationTypeID: 1, Amount: 10}
+					if i%2 == 1 {
+						txn = service.NewTransaction{AccountID: account.ID, OperationTypeID: 4, Amount: 15}
+					}
+					_, err := serialized.CreateTransaction(context.Background(), txn)
+					assert.NoError(t, err)
+				}()
+			}
+		}
+		wg.Wait()
+
+		// Whatever the order, the account owes nothing and holds 10 × 5 of credit
+		for _, account := range accounts {
+			transactions, err := trxRepo.GetTransactionsByAccountID(context.Background(), account.ID)
+			require.NoError(t, err)
+			require.Len(t, transactions, 20)
+			var balance float64
+			for _, txn := range transactions {
+				balance += txn.Balance
+			}
+			assert.InDelta(t, 50.0, balance, 0.001)
+		}
+	})
+
+	t.Run("Busy account should be turned down rather than queued without bound", func(t *testing.T) {
+		exec := newExecutor(t, executor.Config{Shards: 1, QueueSize: 1, Timeout: 5 * time.Second})
+		blocking := newBlockingTransactionsService(t)
+		serialized := service.NewSerializedTransactionsService(blocking, exec)
+
+		// The first transaction holds the worker before the second one takes the only queue slot
+		done := make(chan error, 2)
+		submit := func() {
+			_, err := serialized.CreateTransaction(context.Background(), service.NewTransaction{AccountID: 1})
+			done <- err
+		}
+		go submit()
+		<-blocking.started
+		go submit()
+		require.Eventually(t, func() bool { return exec.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+		_, err := serialized.CreateTransaction(context.Background(), service.NewTransaction{AccountID: 1})
+		assert.ErrorIs(t, err, service.ErrTrxQueueFull)
+
+		blocking.unblock()
+		<-blocking.started
+		assert.NoError(t, <-done)
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("Transaction left waiting past the timeout should not be posted", func(t *testing.T) {
+		exec := newExecutor(t, executor.Config{Shards: 1, QueueSize: 10, Timeout: 50 * time.Millisecond})
+		blocking := newBlockingTransactionsService(t)
+		serialized := service.NewSerializedTransactionsService(blocking, exec)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := serialized.CreateTransaction(context.Background(), service.NewTransaction{AccountID: 1})
+			done <- err
+		}()
+		<-blocking.started
+
+		_, err := serialized.CreateTransaction(context.Background(), service.NewTransaction{AccountID: 1})
+		assert.ErrorIs(t, err, service.ErrTrxTimeout)
+
+		blocking.unblock()
+		assert.NoError(t, <-done)
+		assert.Equal(t, int64(1), exec.Stats().Expired)
+	})
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Minimum payment of a statement: a share of the closing balance, at least the floor, at most the balance
+const (
+	minimumPaymentRate  = 0.10
+	minimumPaymentFloor = 25.00
+)
+
+func NewStatementsService(
+	billingRepo repository.BillingRepository,
+	accRepo repository.AccountsRepository,
+	trxRepo repository.TransactionsRepository,
+	ledgerRepo repository.LedgerRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+) StatementsService {
+	return &statementsService{
+		billingRepo: billingRepo,
+		accRepo:     accRepo,
+		trxRepo:     trxRepo,
+		ledgerRepo:  ledgerRepo,
+		auditRepo:   auditRepo,
+		transactor:  transactor,
+	}
+}
+
+// GetBillingCycle retrieves the billing cycle of an account
+func (s *statementsService) GetBillingCycle(ctx context.Context, accountID int64) (*repository.BillingCycle, error) {
+	cycle, err := s.billingRepo.GetBillingCycle(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, ErrFailedToFetchAccount
+	}
+	return cycle, nil
+}
+
+// SetBillingCycle configures when the statements of a credit account close and fall due.
+// It applies from the next cycle closed, and the change is recorded in the audit log.
+func (s *statementsService) SetBillingCycle(ctx context.Context, accountID int64, closingDay, dueDayOffset int) (*repository.BillingCycle, error) {
+	if closingDay < 1 || closingDay > 28 {
+		return nil, ErrInvalidClosingDay
+	}
+	if dueDayOffset < 1 || dueDayOffset > 60 {
+		return nil, ErrInvalidDueDayOffset
+	}
+
+	var cycle *repository.BillingCycle
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		accounts, err := s.accRepo.LockAccounts(ctx, accountID)
+		if err != nil {
+			return ErrFailedToFetchAccount
+		}
+		if len(accounts) == 0 {
+			return ErrAccountNotFound
+		}
+		if accounts[0].Product != ProductCredit {
+			return ErrBillingNotSupported
+		}
+
+		previous, err := s.billingRepo.GetBillingCycle(ctx, accountID)
+		if err != nil {
+			return ErrFailedToFetchAccount
+		}
+		cycle, err = s.billingRepo.UpsertBillingCycle(ctx, &repository.BillingCycle{
+			AccountID:    accountID,
+			ClosingDay:   closingDay,
+			DueDayOffset: dueDayOffset,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, s.auditRepo, AuditActionBillingCycleUpdated, AuditEntityBillingCycle, accountID, previous, cycle)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cycle, nil
+}
+
+// CloseCycles generates the statement of every credit account whose cycle closes on closingDate.
+// The period runs from the day after the account's previous statement, or after the previous closing
+// date for its first one, through closingDate. Closing is idempotent: periods already closed are skipped.
+// closingDate should have ended, so that no transaction of the period is still to come.
+func (s *statementsService) CloseCycles(ctx context.Context, closingDate time.Time) (_ []*repository.Statement, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "StatementsService.CloseCycles", trace.WithAttributes(
+		attribute.String("billing.closing_date", closingDate.Format(dateLayout)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	closingDate = time.Date(closingDate.Year(), closingDate.Month(), closingDate.Day(), 0, 0, 0, 0, time.UTC)
+	cycles, err := s.billingRepo.GetBillingCyclesByClosingDay(ctx, ProductCredit, closingDate.Day())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch billing cycles: %w", err)
+	}
+
+	statements := []*repository.Statement{}
+	var errs []error
+	for _, cycle := range cycles {
+		statement, err := s.closeCycle(ctx, cycle, closingDate)
+		if errors.Is(err, ErrStatementAlreadyClosed) {
+			continue
+		}
+		if err != nil {
+			log.Error().Ctx(ctx).Err(err).Int64("account_id", cycle.AccountID).Msg("failed to close billing cycle")
+			errs = append(errs, fmt.Errorf("account %d: %w", cycle.AccountID, err))
+			continue
+		}
+		statements = append(statements, statement)
+	}
+
+	span.SetAttributes(attribute.Int("billing.statements", len(statements)))
+	return statements, errors.Join(errs...)
+}
+
+// closeCycle snapshots the account's period ending on closingDate into a statement
+func (s *statementsService) closeCycle(ctx context.Context, cycle *repository.BillingCycle, closingDate time.Time) (*repository.Statement, error) {
+	periodStart := closingDate.AddDate(0, -1, 1)
+	var openingBalance float64
+
+	latest, err := s.billingRepo.GetLatestStatementByAccountID(ctx, cycle.AccountID)
+	switch {
+	case err == nil:
+		latestEnd, err := time.Parse(dateLayout, latest.PeriodEnd)
+		if err != nil {
+			return nil, fmt.Errorf("invalid period_end of statement %d: %w", latest.ID, err)
+		}
+		if !latestEnd.Before(closingDate) {
+			return nil, ErrStatementAlreadyClosed
+		}
+		periodStart = latestEnd.AddDate(0, 0, 1)
+		openingBalance = latest.ClosingBalance
+	case errors.Is(err, pgx.ErrNoRows):
+		// First statement: everything before the period is carried over as the opening balance
+		earlier, err := s.trxRepo.GetTransactionsByAccountIDInPeriod(ctx, cycle.AccountID, time.Time{}, periodStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+		}
+		for _, txn := range earlier {
+			openingBalance -= txn.Amount
+		}
+	default:
+		return nil, ErrFailedToFetchStatement
+	}
+
+	from, to := periodStart, closingDate.AddDate(0, 0, 1)
+	transactions, err := s.trxRepo.GetTransactionsByAccountIDInPeriod(ctx, cycle.AccountID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	discharges, err := s.ledgerRepo.GetDischargesByAccountID(ctx, cycle.AccountID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch discharges: %w", err)
+	}
+
+	closingBalance := openingBalance
+	lines := make([]repository.StatementLine, 0, len(transactions)+len(discharges))
+	for _, txn := range transactions {
+		closingBalance -= txn.Amount
+		lines = append(lines, repository.StatementLine{
+			Kind:                 journalEntryKinds[txn.OperationTypeID],
+			TransactionID:        txn.ID,
+			RelatedTransactionID: txn.ParentTransactionID,
+			Amount:               txn.Amount,
+			EventDate:            txn.EventDate,
+		})
+	}
+	for _, entry := range discharges {
+		// The receivable posting names the debit that was paid; the entry names the credit that paid it.
+		// Adjustments that undo a discharge credit the receivable back, so their lines come out negative.
+		for _, p := range entry.Postings {
+			if p.Account.AccountID == cycle.AccountID && p.Account.Type == "asset" {
+				lines = append(lines, repository.StatementLine{
+					Kind:                 entry.Kind,
+					TransactionID:        p.TransactionID,
+					RelatedTransactionID: entry.TransactionID,
+					Amount:               -p.Amount,
+					EventDate:            entry.CreatedAt,
+				})
+			}
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].EventDate.Before(lines[j].EventDate) })
+
+	closingBalance = FormatAmount(closingBalance)
+	statement, err := s.billingRepo.InsertStatement(ctx, &repository.Statement{
+		AccountID:      cycle.AccountID,
+		PeriodStart:    periodStart.Format(dateLayout),
+		PeriodEnd:      closingDate.Format(dateLayout),
+		OpeningBalance: FormatAmount(openingBalance),
+		ClosingBalance: closingBalance,
+		MinimumPayment: MinimumPayment(closingBalance),
+		DueDate:        closingDate.AddDate(0, 0, cycle.DueDayOffset).Format(dateLayout),
+		Lines:          lines,
+	})
+	if err != nil {
+		return nil, determinePgxError(err)
+	}
+	return statement, nil
+}
+
+// MinimumPayment is the least amount due for a statement closing with balance owed
+func MinimumPayment(balance float64) float64 {
+	if balance <= 0 {
+		return 0
+	}
+	return FormatAmount(math.Min(balance, math.Max(balance*minimumPaymentRate, minimumPaymentFloor)))
+}
+
+// GetStatements retrieves the statements of an account, latest first, without their lines
+func (s *statementsService) GetStatements(ctx context.Context, accountID int64) ([]*repository.Statement, error) {
+	if _, err := s.accRepo.GetAccountByID(ctx, accountID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, ErrFailedToFetchAccount
+	}
+
+	statements, err := s.billingRepo.GetStatementsByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, ErrFailedToFetchStatement
+	}
+	if statements == nil {
+		statements = []*repository.Statement{}
+	}
+	return statements, nil
+}
+
+// GetStatement retrieves a statement of an account with its lines
+func (s *statementsService) GetStatement(ctx context.Context, accountID, statementID int64) (*repository.Statement, error) {
+	statement, err := s.billingRepo.GetStatementByID(ctx, accountID, statementID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrStatementNotFound
+		}
+		return nil, ErrFailedToFetchStatement
+	}
+	return statement, nil
+}
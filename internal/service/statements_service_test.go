@@ -0,0 +1,292 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var statementColumns = []string{"id", "account_id", "period_start", "period_end", "opening_balance", "closing_balance", "minimum_payment", "due_date", "created_at"}
+
+var dischargeColumns = []string{"id", "kind", "transaction_id", "correlation_id", "created_at", "code", "type", "account_id", "posting_transaction_id", "amount"}
+
+func newStatementsService(mockDB pgxmock.PgxPoolIface) service.StatementsService {
+	return service.NewStatementsService(
+		repository.NewBillingRepository(mockDB),
+		repository.NewAccountsRepository(mockDB),
+		repository.NewTransactionsRepository(mockDB),
+		repository.NewLedgerRepository(mockDB),
+		repository.NewAuditRepository(mockDB),
+		repository.NewTransactor(mockDB),
+	)
+}
+
+func TestSetBillingCycle(t *testing.T) {
+	t.Run("Valid cycle of a credit account should be saved", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		stmtService := newStatementsService(mockDB)
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		mockDB.ExpectQuery(`FROM accounts a LEFT JOIN billing_cycles bc`).
+			WithArgs(int64(1), repository.DefaultClosingDay, repository.DefaultDueDayOffset).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "closing_day", "due_day_offset"}).AddRow(int64(1), 1, 10))
+		mockDB.ExpectExec(`INSERT INTO billing_cycles .* ON CONFLICT \(account_id\) DO UPDATE`).
+			WithArgs(int64(1), 15, 20).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectAuditEventStates(mockDB, service.AuditActionBillingCycleUpdated, service.AuditEntityBillingCycle, 1,
+			`{"account_id":1,"closing_day":1,"due_day_offset":10}`, `{"account_id":1,"closing_day":15,"due_day_offset":20}`)
+		mockDB.ExpectCommit()
+
+		cycle, err := stmtService.SetBillingCycle(context.Background(), 1, 15, 20)
+		assert.NoError(t, err)
+		assert.Equal(t, &repository.BillingCycle{AccountID: 1, ClosingDay: 15, DueDayOffset: 20}, cycle)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Prepaid account should be rejected", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		stmtService := newStatementsService(mockDB)
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).
+			WithArgs([]int64{1}).
+			WillReturnRows(pgxmock.NewRows(accountColumns).
+				AddRow(int64(1), int64(1), "12345678900", "prepaid", "active", accountCreatedAt))
+		mockDB.ExpectRollback()
+
+		cycle, err := stmtService.SetBillingCycle(context.Background(), 1, 15, 20)
+		assert.ErrorIs(t, err, service.ErrBillingNotSupported)
+		assert.Nil(t, cycle)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Out of range days should be rejected", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		stmtService := newStatementsService(mockDB)
+		ctx := context.Background()
+
+		_, err = stmtService.SetBillingCycle(ctx, 1, 29, 10)
+		assert.ErrorIs(t, err, service.ErrInvalidClosingDay)
+		_, err = stmtService.SetBillingCycle(ctx, 1, 0, 10)
+		assert.ErrorIs(t, err, service.ErrInvalidClosingDay)
+		_, err = stmtService.SetBillingCycle(ctx, 1, 1, 0)
+		assert.ErrorIs(t, err, service.ErrInvalidDueDayOffset)
+		_, err = stmtService.SetBillingCycle(ctx, 1, 1, 61)
+		assert.ErrorIs(t, err, service.ErrInvalidDueDayOffset)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestCloseCycles(t *testing.T) {
+	closingDate := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	periodStart := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	expectCycles := func(mockDB pgxmock.PgxPoolIface) {
+		mockDB.ExpectQuery(`SELECT a.id, COALESCE\(bc.closing_day, \$3\), COALESCE\(bc.due_day_offset, \$4\) FROM accounts a`).
+			WithArgs("credit", 1, repository.DefaultClosingDay, repository.DefaultDueDayOffset).
+			WillReturnRows(pgxmock.NewRows([]string{"account_id", "closing_day", "due_day_offset"}).
+				AddRow(int64(1), 1, 10))
+	}
+
+	t.Run("First statement should carry earlier transactions and snapshot the period", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		stmtService := newStatementsService(mockDB)
+		trxColumns := []string{"id", "operation_type_id", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id"}
+		purchasedAt := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+		paidAt := time.Date(2025, 2, 20, 12, 0, 0, 0, time.UTC)
+
+		expectCycles(mockDB)
+		mockDB.ExpectQuery(`SELECT .* FROM statements WHERE account_id = \$1 ORDER BY period_end DESC LIMIT 1`).
+			WithArgs(int64(1)).
+			WillReturnError(pgx.ErrNoRows)
+		mockDB.ExpectQuery(`SELECT .* FROM transactions WHERE account_id = \$1 AND event_date >= \$2 AND event_date < \$3`).
+			WithArgs(int64(1), time.Time{}, periodStart).
+			WillReturnRows(pgxmock.NewRows(trxColumns).
+				AddRow(int64(4), int64(1), -100.0, -100.0, periodStart.AddDate(0, 0, -5), int64(0), int64(0)))
+		mockDB.ExpectQuery(`SELECT .* FROM transactions WHERE account_id = \$1 AND event_date >= \$2 AND event_date < \$3`).
+			WithArgs(int64(1), periodStart, periodEnd).
+			WillReturnRows(pgxmock.NewRows(trxColumns).
+				AddRow(int64(5), int64(3), -50.0, -50.0, purchasedAt, int64(0), int64(0)).
+				AddRow(int64(7), int64(9), -5.0, -5.0, purchasedAt, int64(0), int64(5)).
+				AddRow(int64(6), int64(4), 30.0, 0.0, paidAt, int64(0), int64(0)))
+		mockDB.ExpectQuery(`FROM journal_entries je .* WHERE je.kind IN \('discharge', 'discharge_adjustment'\)`).
+			WithArgs(int64(1), periodStart, periodEnd).
+			WillReturnRows(pgxmock.NewRows(dischargeColumns).
+				AddRow(int64(9), "discharge", int64(6), "", paidAt, "customer_credit:1", "liability", int64(1), int64(6), 30.0).
+				AddRow(int64(9), "discharge", int64(6), "", paidAt, "customer_receivable:1", "asset", int64(1), int64(4), -30.0))
+		mockDB.ExpectQuery(`INSERT INTO statements`).
+			WithArgs(int64(1), "2025-02-02", "2025-03-01", 100.0, 125.0, 25.0, "2025-03-11", "",
+				[]string{"withdrawal", "fee", "payment", "discharge"},
+				[]int64{5, 7, 6, 4},
+				[]int64{0, 5, 0, 6},
+				[]float64{-50, -5, 30, 30},
+				[]time.Time{purchasedAt, purchasedAt, paidAt, paidAt}).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), time.Now()))
+
+		statements, err := stmtService.CloseCycles(context.Background(), closingDate)
+		assert.NoError(t, err)
+		require.Len(t, statements, 1)
+		assert.Equal(t, 100.0, statements[0].OpeningBalance)
+		assert.Equal(t, 125.0, statements[0].ClosingBalance)
+		assert.Equal(t, "2025-03-11", statements[0].DueDate)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Discharge adjustments should be listed with their sign", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		stmtService := newStatementsService(mockDB)
+		trxColumns := []string{"id", "operation_type_id", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id"}
+		purchasedAt := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+		paidAt := time.Date(2025, 2, 20, 12, 0, 0, 0, time.UTC)
+		recomputedAt := time.Date(2025, 2, 22, 9, 0, 0, 0, time.UTC)
+
+		expectCycles(mockDB)
+		mockDB.ExpectQuery(`SELECT .* FROM statements WHERE account_id = \$1 ORDER BY period_end DESC LIMIT 1`).
+			WithArgs(int64(1)).
+			WillReturnError(pgx.ErrNoRows)
+		mockDB.ExpectQuery(`SELECT .* FROM transactions WHERE account_id = \$1 AND event_date >= \$2 AND event_date < \$3`).
+			WithArgs(int64(1), time.Time{}, periodStart).
+			WillReturnRows(pgxmock.NewRows(trxColumns))
+		mockDB.ExpectQuery(`SELECT .* FROM transactions WHERE account_id = \$1 AND event_date >= \$2 AND event_date < \$3`).
+			WithArgs(int64(1), periodStart, periodEnd).
+			WillReturnRows(pgxmock.NewRows(trxColumns).
+				AddRow(int64(4), int64(1), -50.0, -20.0, purchasedAt, int64(0), int64(0)).
+				AddRow(int64(5), int64(1), -30.0, 0.0, purchasedAt.Add(time.Hour), int64(0), int64(0)).
+				AddRow(int64(6), int64(4), 30.0, 0.0, paidAt, int64(0), int64(0)))
+		// Purchase 4, back-dated before purchase 5, moved the payment from debt 5 onto debt 4
+		mockDB.ExpectQuery(`FROM journal_entries je .* WHERE je.kind IN \('discharge', 'discharge_adjustment'\)`).
+			WithArgs(int64(1), periodStart, periodEnd).
+			WillReturnRows(pgxmock.NewRows(dischargeColumns).
+				AddRow(int64(9), "discharge", int64(6), "", paidAt, "customer_credit:1", "liability", int64(1), int64(6), 30.0).
+				AddRow(int64(9), "discharge", int64(6), "", paidAt, "customer_receivable:1", "asset", int64(1), int64(5), -30.0).
+				AddRow(int64(11), "discharge_adjustment", int64(6), "", recomputedAt, "customer_credit:1", "liability", int64(1), int64(6), -30.0).
+				AddRow(int64(11), "discharge_adjustment", int64(6), "", recomputedAt, "customer_receivable:1", "asset", int64(1), int64(5), 30.0).
+				AddRow(int64(12), "discharge_adjustment", int64(6), "", recomputedAt, "customer_credit:1", "liability", int64(1), int64(6), 30.0).
+				AddRow(int64(12), "discharge_adjustment", int64(6), "", recomputedAt, "customer_receivable:1", "asset", int64(1), int64(4), -30.0))
+		mockDB.ExpectQuery(`INSERT INTO statements`).
+			WithArgs(int64(1), "2025-02-02", "2025-03-01", 0.0, 50.0, 25.0, "2025-03-11", "",
+				[]string{"purchase", "purchase", "payment", "discharge", "discharge_adjustment", "discharge_adjustment"},
+				[]int64{4, 5, 6, 5, 5, 4},
+				[]int64{0, 0, 0, 6, 6, 6},
+				[]float64{-50, -30, 30, 30, -30, 30},
+				[]time.Time{purchasedAt, purchasedAt.Add(time.Hour), paidAt, paidAt, recomputedAt, recomputedAt}).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), time.Now()))
+
+		statements, err := stmtService.CloseCycles(context.Background(), closingDate)
+		assert.NoError(t, err)
+		require.Len(t, statements, 1)
+		assert.Equal(t, 50.0, statements[0].ClosingBalance)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Closed period should be skipped", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		stmtService := newStatementsService(mockDB)
+
+		expectCycles(mockDB)
+		mockDB.ExpectQuery(`SELECT .* FROM statements WHERE account_id = \$1 ORDER BY period_end DESC LIMIT 1`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(statementColumns).
+				AddRow(int64(1), int64(1), "2025-02-02", "2025-03-01", 0.0, 120.0, 25.0, "2025-03-11", time.Now()))
+
+		statements, err := stmtService.CloseCycles(context.Background(), closingDate)
+		assert.NoError(t, err)
+		assert.Empty(t, statements)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Next statement should open with the previous closing balance", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		stmtService := newStatementsService(mockDB)
+		nextClosing := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+		expectCycles(mockDB)
+		mockDB.ExpectQuery(`SELECT .* FROM statements WHERE account_id = \$1 ORDER BY period_end DESC LIMIT 1`).
+			WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(statementColumns).
+				AddRow(int64(1), int64(1), "2025-02-02", "2025-03-01", 0.0, 120.0, 25.0, "2025-03-11", time.Now()))
+		mockDB.ExpectQuery(`SELECT .* FROM transactions WHERE account_id = \$1 AND event_date >= \$2`).
+			WithArgs(int64(1), periodEnd, nextClosing.AddDate(0, 0, 1)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "operation_type_id", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id"}))
+		mockDB.ExpectQuery(`FROM journal_entries je`).
+			WithArgs(int64(1), periodEnd, nextClosing.AddDate(0, 0, 1)).
+			WillReturnRows(pgxmock.NewRows(dischargeColumns))
+		mockDB.ExpectQuery(`INSERT INTO statements`).
+			WithArgs(int64(1), "2025-03-02", "2025-04-01", 120.0, 120.0, 25.0, "2025-04-11", "",
+				[]string{}, []int64{}, []int64{}, []float64{}, []time.Time{}).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(2), time.Now()))
+
+		statements, err := stmtService.CloseCycles(context.Background(), nextClosing)
+		assert.NoError(t, err)
+		require.Len(t, statements, 1)
+		assert.Equal(t, "2025-03-02", statements[0].PeriodStart)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetStatement(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	stmtService := newStatementsService(mockDB)
+
+	mockDB.ExpectQuery(`SELECT .* FROM statements WHERE id = \$1 AND account_id = \$2`).
+		WithArgs(int64(7), int64(1)).
+		WillReturnError(pgx.ErrNoRows)
+
+	statement, err := stmtService.GetStatement(context.Background(), 1, 7)
+	assert.ErrorIs(t, err, service.ErrStatementNotFound)
+	assert.Nil(t, statement)
+	assert.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+func TestMinimumPayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		balance  float64
+		expected float64
+	}{
+		{"Credit balance owes nothing", -40, 0},
+		{"Nothing owed", 0, 0},
+		{"Small balance is due in full", 12.5, 12.5},
+		{"Floor applies below the rate", 120, 25},
+		{"Rate applies above the floor", 1234.56, 123.46},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, service.MinimumPayment(tt.balance))
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func NewTransactionRequestsService(requestsRepo repository.TransactionRequestsRepository, trxRepo repository.TransactionsRepository, accRepo repository.AccountsRepository, trxService TransactionsService, transactor repository.Transactor) TransactionRequestsService {
+	return &transactionRequestsService{
+		requestsRepo: requestsRepo,
+		trxRepo:      trxRepo,
+		accRepo:      accRepo,
+		trxService:   trxService,
+		transactor:   transactor,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// SubmitTransaction validates a transaction and queues it for a worker to post as by CreateTransaction. The
+// account must exist and be active; it is checked again, with duplicates and screening, when the request is
+// processed.
+func (s *transactionRequestsService) SubmitTransaction(ctx context.Context, txn NewTransaction) (_ *repository.TransactionRequest, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransactionRequestsService.SubmitTransaction", trace.WithAttributes(
+		attribute.Int64("account.id", txn.AccountID),
+		attribute.Int64("operation_type.id", txn.OperationTypeID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if postedInternally(txn.OperationTypeID) {
+		return nil, ErrInvalidOperationType
+	}
+	if _, err := signedAmount(txn.OperationTypeID, txn.Amount); err != nil {
+		return nil, err
+	}
+
+	account, err := s.accRepo.GetAccountByID(ctx, txn.AccountID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidAccountID
+		}
+		return nil, ErrFailedToFetchAccount
+	}
+	if account.Status != AccountStatusActive {
+		return nil, ErrAccountNotActive
+	}
+
+	request, err := s.requestsRepo.InsertTransactionRequest(ctx, &repository.TransactionRequest{
+		AccountID:         txn.AccountID,
+		OperationTypeID:   txn.OperationTypeID,
+		Amount:            FormatAmount(txn.Amount),
+		MerchantReference: txn.MerchantReference,
+	})
+	if err != nil {
+		return nil, ErrFailedToQueueTrx
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return request, nil
+}
+
+// GetTransactionRequest retrieves a request with, once it succeeded, the transaction it posted
+func (s *transactionRequestsService) GetTransactionRequest(ctx context.Context, requestID int64) (*repository.TransactionRequest, error) {
+	request, err := s.requestsRepo.GetTransactionRequestByID(ctx, requestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTransactionRequestNotFound
+		}
+		return nil, ErrFailedToFetchTrxRequest
+	}
+	if request.Status == repository.TransactionRequestSucceeded {
+		if request.Transaction, err = s.trxRepo.GetTransactionByID(ctx, request.TransactionID); err != nil {
+			return nil, ErrFailedToFetchTrx
+		}
+	}
+	return request, nil
+}
+
+// ProcessNext claims the next queued request and posts its transaction in the same unit of work, with the
+// correlation id and principal it was submitted with, so a request is never posted twice. A rejected
+// transaction fails the request with the reason; the denial of a screened one is still recorded. A request
+// that could not be processed at all fails too, rather than being retried forever, unless the context was
+// canceled. It reports whether a request was claimed.
+func (s *transactionRequestsService) ProcessNext(ctx context.Context) (_ bool, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransactionRequestsService.ProcessNext")
+	defer func() { endSpan(span, err) }()
+
+	var request *repository.TransactionRequest
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		claimed, err := s.requestsRepo.ClaimTransactionRequest(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to claim transaction request: %w", err)
+		}
+		request = claimed
+		span.SetAttributes(attribute.Int64("transaction_request.id", request.ID), attribute.Int64("account.id", request.AccountID))
+
+		ctx = middleware.WithPrincipal(middleware.WithCorrelationID(ctx, request.CorrelationID), request.Principal)
+		txn, err := s.trxService.CreateTransaction(ctx, NewTransaction{
+			AccountID:         request.AccountID,
+			OperationTypeID:   request.OperationTypeID,
+			Amount:            request.Amount,
+			MerchantReference: request.MerchantReference,
+		})
+		if err != nil {
+			if !rejected(err) {
+				return err
+			}
+			return s.requestsRepo.FailTransactionRequest(ctx, request.ID, requestErrorCode(err), err.Error())
+		}
+		return s.requestsRepo.CompleteTransactionRequest(ctx, request.ID, txn.ID)
+	})
+	if request == nil || err == nil {
+		return request != nil, err
+	}
+
+	// Nothing of the unit of work was kept; another worker may have claimed the request since, in which case
+	// it is no longer pending and left alone
+	if ctx.Err() == nil {
+		failErr := s.requestsRepo.FailTransactionRequest(ctx, request.ID, RequestErrCodeFailed, ErrFailedToProcessTrxRequest.Error())
+		if failErr != nil && !errors.Is(failErr, pgx.ErrNoRows) {
+			err = errors.Join(err, failErr)
+		}
+	}
+	return true, fmt.Errorf("failed to process transaction request %d: %w", request.ID, err)
+}
+
+// Run processes queued requests with the given number of workers until ctx is done, letting each worker
+// finish the request in hand. Idle workers poll the queue every pollInterval, and one of them is woken as soon
+// as a request is submitted through this service.
+func (s *transactionRequestsService) Run(ctx context.Context, workers int, pollInterval time.Duration) {
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx, pollInterval)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *transactionRequestsService) work(ctx context.Context, pollInterval time.Duration) {
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+
+	for ctx.Err() == nil {
+		processed, err := s.ProcessNext(context.WithoutCancel(ctx))
+		if err != nil {
+			log.Error().Ctx(ctx).Err(err).Msg("failed to process transaction request")
+		}
+		if processed && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-s.wake:
+		case <-poll.C:
+		}
+	}
+}
+
+// rejected reports whether a transaction failed for a reason of its own, before anything was written
+// but the record of its screening
+func rejected(err error) bool {
+	for _, target := range []error{
+		ErrInvalidAccountID, ErrAccountNotActive, ErrInvalidOperationType, ErrInvalidAmount, ErrNegativeAmount,
+		ErrDuplicateTransaction, ErrTransactionDenied,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestErrorCode is the error code a rejected transaction fails its request with
+func requestErrorCode(err error) string {
+	var denied *ScreeningDeniedError
+	switch {
+	case errors.Is(err, ErrDuplicateTransaction):
+		return RequestErrCodeDuplicate
+	case errors.As(err, &denied):
+		return denied.ReasonCodes[0]
+	}
+	return RequestErrCodeRejected
+}
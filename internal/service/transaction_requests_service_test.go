@@ -0,0 +1,255 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var transactionRequestColumns = []string{"id", "account_id", "operation_type_id", "amount", "merchant_reference", "status",
+	"transaction_id", "error_code", "error_detail", "correlation_id", "principal", "created_at", "updated_at"}
+
+func newTransactionRequestsService(mockDB pgxmock.PgxPoolIface) service.TransactionRequestsService {
+	return service.NewTransactionRequestsService(
+		repository.NewTransactionRequestsRepository(mockDB),
+		repository.NewTransactionsRepository(mockDB),
+		repository.NewAccountsRepository(mockDB),
+		newTransactionsService(mockDB),
+		repository.NewTransactor(mockDB),
+	)
+}
+
+func expectGetAccount(mockDB pgxmock.PgxPoolIface, status string) {
+	mockDB.ExpectQuery(`FROM accounts a .* WHERE a.id = \$1`).
+		WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(1), "12345678900", "credit", status, accountCreatedAt))
+}
+
+func TestSubmitTransaction(t *testing.T) {
+	t.Run("Valid transaction should be queued", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		expectGetAccount(mockDB, "active")
+		mockDB.ExpectQuery(`INSERT INTO transaction_requests`).
+			WithArgs(int64(1), int64(1), 12.35, "order-1", "", "").
+			WillReturnRows(pgxmock.NewRows(transactionRequestColumns).
+				AddRow(int64(3), int64(1), int64(1), 12.35, "order-1", "pending", int64(0), "", "", "", "", testNow, testNow))
+
+		request, err := newTransactionRequestsService(mockDB).SubmitTransaction(context.Background(), service.NewTransaction{
+			AccountID: 1, OperationTypeID: 1, Amount: 12.345, MerchantReference: "order-1",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), request.ID)
+		assert.Equal(t, repository.TransactionRequestPending, request.Status)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid transactions should be rejected before they are queued", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+		requestsService := newTransactionRequestsService(mockDB)
+
+		_, err = requestsService.SubmitTransaction(context.Background(), service.NewTransaction{AccountID: 1, OperationTypeID: service.OperationTypeFee, Amount: 10})
+		assert.ErrorIs(t, err, service.ErrInvalidOperationType)
+		_, err = requestsService.SubmitTransaction(context.Background(), service.NewTransaction{AccountID: 1, OperationTypeID: 1, Amount: -10})
+		assert.ErrorIs(t, err, service.ErrNegativeAmount)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Transactions of missing or inactive accounts should be rejected", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+		requestsService := newTransactionRequestsService(mockDB)
+
+		mockDB.ExpectQuery(`FROM accounts a .* WHERE a.id = \$1`).WithArgs(int64(1)).WillReturnError(pgx.ErrNoRows)
+		_, err = requestsService.SubmitTransaction(context.Background(), service.NewTransaction{AccountID: 1, OperationTypeID: 1, Amount: 10})
+		assert.ErrorIs(t, err, service.ErrInvalidAccountID)
+
+		expectGetAccount(mockDB, "blocked")
+		_, err = requestsService.SubmitTransaction(context.Background(), service.NewTransaction{AccountID: 1, OperationTypeID: 1, Amount: 10})
+		assert.ErrorIs(t, err, service.ErrAccountNotActive)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestProcessNext(t *testing.T) {
+	t.Run("Empty queue should claim nothing", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`FOR UPDATE SKIP LOCKED`).WillReturnError(pgx.ErrNoRows)
+		mockDB.ExpectCommit()
+
+		processed, err := newTransactionRequestsService(mockDB).ProcessNext(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, processed)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Posted transaction should complete the request in the same unit of work", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`FOR UPDATE SKIP LOCKED`).
+			WillReturnRows(pgxmock.NewRows(transactionRequestColumns).
+				AddRow(int64(3), int64(1), int64(1), 50.0, "", "pending", int64(0), "", "", "", "", testNow, testNow))
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(1), -50.0, -50.0, testNow, testNow, "", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(10), testNow, -50.0))
+		expectChainLink(mockDB, 10)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 10)
+		expectJournalEntry(mockDB, "purchase", 10, "customer_receivable:1", "cash_clearing", []int64{10, 0}, 50)
+		expectNoFeeRule(mockDB, 1)
+		mockDB.ExpectExec(`UPDATE transaction_requests SET status = 'succeeded'`).
+			WithArgs(int64(3), int64(10)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDB.ExpectCommit()
+
+		processed, err := newTransactionRequestsService(mockDB).ProcessNext(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, processed)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Rejected transaction should fail the request", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`FOR UPDATE SKIP LOCKED`).
+			WillReturnRows(pgxmock.NewRows(transactionRequestColumns).
+				AddRow(int64(3), int64(1), int64(1), 50.0, "", "pending", int64(0), "", "", "", "", testNow, testNow))
+		expectLockAccount(mockDB, "blocked")
+		mockDB.ExpectExec(`UPDATE transaction_requests SET status = 'failed'`).
+			WithArgs(int64(3), service.RequestErrCodeRejected, service.ErrAccountNotActive.Error()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDB.ExpectCommit()
+
+		processed, err := newTransactionRequestsService(mockDB).ProcessNext(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, processed)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestTransactionRequestProcessing(t *testing.T) {
+	newServices := func(t *testing.T) (service.TransactionRequestsService, repository.AuditRepository, *repository.Account) {
+		store := memory.NewStore()
+		trxRepo := memory.NewTransactionsRepository(store)
+		accRepo := memory.NewAccountsRepository(store)
+		auditRepo := memory.NewAuditRepository(store)
+		transactor := memory.NewTransactor(store)
+
+		trxService := service.NewTransactionsService(trxRepo, accRepo, memory.NewLedgerRepository(store), memory.NewFeesRepository(store), memory.NewScreeningRepository(store), auditRepo, memory.NewBillingRepository(store), transactor, clock.System(), service.DuplicatePolicy{Window: 10 * time.Minute, Action: service.DuplicateActionReject}, service.BackdatingPolicy{})
+		account, err := service.NewAccountsService(accRepo, memory.NewCustomersRepository(store), auditRepo, transactor).CreateAccount(context.Background(), 0, "1", service.ProductCredit)
+		require.NoError(t, err)
+		return service.NewTransactionRequestsService(memory.NewTransactionRequestsRepository(store), trxRepo, accRepo, trxService, transactor), auditRepo, account
+	}
+
+	t.Run("Queued transactions should be posted in order as if submitted synchronously", func(t *testing.T) {
+		requestsService, auditRepo, account := newServices(t)
+		ctx := middleware.WithPrincipal(middleware.WithCorrelationID(context.Background(), "flow-1"), "alice")
+
+		var requestIDs []int64
+		for _, txn := range []service.NewTransaction{
+			{AccountID: account.ID, OperationTypeID: 1, Amount: 50, MerchantReference: "order-1"},
+			{AccountID: account.ID, OperationTypeID: 4, Amount: 80},
+			{AccountID: account.ID, OperationTypeID: 1, Amount: 50, MerchantReference: "order-1"},
+			{AccountID: account.ID, OperationTypeID: 1, Amount: 1500},
+		} {
+			request, err := requestsService.SubmitTransaction(ctx, txn)
+			require.NoError(t, err)
+			requestIDs = append(requestIDs, request.ID)
+		}
+
+		for {
+			processed, err := requestsService.ProcessNext(context.Background())
+			require.NoError(t, err)
+			if !processed {
+				break
+			}
+		}
+
+		purchase, err := requestsService.GetTransactionRequest(ctx, requestIDs[0])
+		require.NoError(t, err)
+		assert.Equal(t, repository.TransactionRequestSucceeded, purchase.Status)
+		require.NotNil(t, purchase.Transaction)
+		assert.Equal(t, purchase.TransactionID, purchase.Transaction.ID)
+		assert.Equal(t, "flow-1", purchase.Transaction.CorrelationID)
+
+		credit, err := requestsService.GetTransactionRequest(ctx, requestIDs[1])
+		require.NoError(t, err)
+		assert.Equal(t, 30.0, credit.Transaction.Balance)
+
+		duplicate, err := requestsService.GetTransactionRequest(ctx, requestIDs[2])
+		require.NoError(t, err)
+		assert.Equal(t, repository.TransactionRequestFailed, duplicate.Status)
+		assert.Equal(t, service.RequestErrCodeDuplicate, duplicate.ErrorCode)
+		assert.Nil(t, duplicate.Transaction)
+
+		denied, err := requestsService.GetTransactionRequest(ctx, requestIDs[3])
+		require.NoError(t, err)
+		assert.Equal(t, repository.TransactionRequestFailed, denied.Status)
+		assert.Equal(t, "new_account_large_debit", denied.ErrorCode)
+		assert.Contains(t, denied.ErrorDetail, service.ErrTransactionDenied.Error())
+
+		// The transactions are attributed to whoever submitted them
+		events, err := auditRepo.GetAuditEvents(ctx, repository.AuditEventFilter{EntityType: service.AuditEntityTransaction, EntityID: purchase.TransactionID})
+		require.NoError(t, err)
+		require.NotEmpty(t, events)
+		assert.Equal(t, "alice", events[0].Principal)
+		assert.Equal(t, "flow-1", events[0].CorrelationID)
+	})
+
+	t.Run("Workers should process submitted transactions until stopped", func(t *testing.T) {
+		requestsService, _, account := newServices(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			requestsService.Run(ctx, 2, time.Hour)
+		}()
+
+		request, err := requestsService.SubmitTransaction(context.Background(), service.NewTransaction{AccountID: account.ID, OperationTypeID: 1, Amount: 50})
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			request, err := requestsService.GetTransactionRequest(context.Background(), request.ID)
+			return err == nil && request.Status == repository.TransactionRequestSucceeded
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("workers did not stop")
+		}
+	})
+
+	t.Run("Unknown request should not be found", func(t *testing.T) {
+		requestsService, _, _ := newServices(t)
+
+		_, err := requestsService.GetTransactionRequest(context.Background(), 999)
+		assert.ErrorIs(t, err, service.ErrTransactionRequestNotFound)
+	})
+}
@@ -1,147 +1,668 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
+	"slices"
+	"time"
 
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
 	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
 	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func NewTransactionsService(trxRepo repository.TransactionsRepository, accRepo repository.AccountsRepository) TransactionsService {
-	return &transactionsService{trxRepo: trxRepo, accRepo: accRepo}
+func NewTransactionsService(
+	trxRepo repository.TransactionsRepository,
+	accRepo repository.AccountsRepository,
+	ledgerRepo repository.LedgerRepository,
+	feeRepo repository.FeesRepository,
+	screeningRepo repository.ScreeningRepository,
+	auditRepo repository.AuditRepository,
+	billingRepo repository.BillingRepository,
+	transactor repository.Transactor,
+	clock clock.Clock,
+	duplicates DuplicatePolicy,
+	backdating BackdatingPolicy,
+) TransactionsService {
+	return &transactionsService{
+		trxRepo:       trxRepo,
+		accRepo:       accRepo,
+		ledgerRepo:    ledgerRepo,
+		feeRepo:       feeRepo,
+		screeningRepo: screeningRepo,
+		auditRepo:     auditRepo,
+		billingRepo:   billingRepo,
+		transactor:    transactor,
+		clock:         clock,
+		duplicates:    duplicates,
+		backdating:    backdating,
+	}
+}
+
+// CreateTransaction validates, screens and creates a transaction booked with the service's clock and dated
+// with it too, unless it took place earlier within the back-dating window. The transaction, its screening,
+// its fee, their journal entries and audit events and any discharge it triggers are recorded in one unit of
+// work with the account locked; a back-dated transaction has the discharges of the credits after it
+// recomputed. A transaction denied by the screening rules is not posted, but
+// its screening is still recorded, and a *ScreeningDeniedError is returned. A transaction looking like a
+// resend of a recent one is rejected with a *DuplicateTransactionError or flagged, per the duplicate policy,
+// unless forced.
+func (s *transactionsService) CreateTransaction(ctx context.Context, txn NewTransaction) (_ *repository.Transaction, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransactionsService.CreateTransaction", trace.WithAttributes(
+		attribute.Int64("account.id", txn.AccountID),
+		attribute.Int64("operation_type.id", txn.OperationTypeID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if postedInternally(txn.OperationTypeID) {
+		return nil, ErrInvalidOperationType
+	}
+
+	return s.createTransaction(ctx, txn, false)
 }
 
-// CreateTransaction validates and creates a transaction
-func (s *transactionsService) CreateTransaction(ctx context.Context, accountID, operationTypeID int64, amount float64) (*repository.Transaction, error) {
-	// Check if the account exists
-	if _, err := s.accRepo.GetAccountByID(ctx, accountID); err != nil {
+// postedInternally reports whether transactions of the operation type are only posted by the service:
+// transfer legs by transfers, charges by the accrual job and fees with their transaction
+func postedInternally(operationTypeID int64) bool {
+	switch operationTypeID {
+	case OperationTypeTransferDebit, OperationTypeTransferCredit, OperationTypeInterest, OperationTypeLateFee, OperationTypeFee:
+		return true
+	}
+	return false
+}
+
+// GetTransaction retrieves a transaction by its ID
+func (s *transactionsService) GetTransaction(ctx context.Context, transactionID int64) (*repository.Transaction, error) {
+	transaction, err := s.trxRepo.GetTransactionByID(ctx, transactionID)
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrInvalidAccountID
+			return nil, ErrTransactionNotFound
 		}
-		return nil, fmt.Errorf("failed to fetch account: %w", err)
+		return nil, ErrFailedToFetchTrx
+	}
+	return transaction, nil
+}
+
+// CreateCharge creates an interest or late fee charge like any other debt, so later credits discharge it.
+// Blocked accounts are charged too; closed ones are not.
+func (s *transactionsService) CreateCharge(ctx context.Context, accountID, operationTypeID int64, amount float64) (_ *repository.Transaction, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransactionsService.CreateCharge", trace.WithAttributes(
+		attribute.Int64("account.id", accountID),
+		attribute.Int64("operation_type.id", operationTypeID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if operationTypeID != OperationTypeInterest && operationTypeID != OperationTypeLateFee {
+		return nil, ErrInvalidOperationType
+	}
+
+	return s.createTransaction(ctx, NewTransaction{AccountID: accountID, OperationTypeID: operationTypeID, Amount: amount}, true)
+}
+
+// createTransaction records a transaction of the account, which must be active, along with its screening and
+// the fee the account's product charges on it if any. Charges are levied on blocked accounts too and are
+// neither screened nor checked for duplicates.
+func (s *transactionsService) createTransaction(ctx context.Context, txn NewTransaction, charge bool) (*repository.Transaction, error) {
+	accountID, operationTypeID := txn.AccountID, txn.OperationTypeID
+
+	amount, err := signedAmount(operationTypeID, txn.Amount)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate amount: must be strictly positive.
+	var transaction *repository.Transaction
+	var denied *ScreeningDeniedError
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Check if the account exists and accepts transactions
+		accounts, err := s.accRepo.LockAccounts(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to fetch account: %w", err)
+		}
+		if len(accounts) == 0 {
+			return ErrInvalidAccountID
+		}
+		if status := accounts[0].Status; status != AccountStatusActive && (!charge || status != AccountStatusBlocked) {
+			return ErrAccountNotActive
+		}
+		now := s.clock.Now()
+		eventDate, err := s.eventDate(txn, now)
+		if err != nil {
+			return err
+		}
+		// Decided from the request: the stored event date loses the clock's sub-microsecond precision
+		backdated := eventDate.Before(now)
+		if backdated {
+			if err := s.checkPeriodOpen(ctx, accountID, eventDate); err != nil {
+				return err
+			}
+		}
+
+		// Look for an earlier copy of the transaction; the account lock serializes concurrent resends
+		var duplicateOfID int64
+		if !charge {
+			original, err := s.findDuplicate(ctx, txn, amount, now)
+			if err != nil {
+				return err
+			}
+			if original != nil {
+				duplicateOfID = original.ID
+			}
+		}
+
+		// Screen the transaction against the account's recent activity
+		var screening *repository.ScreeningEvaluation
+		if !charge {
+			screening, err = s.screen(ctx, accounts[0], operationTypeID, amount, now)
+			if err != nil {
+				return err
+			}
+		}
+		if screening != nil && screening.Outcome == repository.ScreeningOutcomeDeny {
+			// The denial is recorded for audit, so the unit of work commits without the transaction
+			if _, err := s.screeningRepo.InsertScreeningEvaluation(ctx, screening); err != nil {
+				return err
+			}
+			denied = &ScreeningDeniedError{ReasonCodes: screening.ReasonCodes}
+			return nil
+		}
+
+		// Set balance as amount (initially)
+		balance := amount
+
+		// Insert transaction record
+		transaction, err = s.trxRepo.InsertTransaction(ctx, accountID, operationTypeID, amount, balance, eventDate, now, txn.MerchantReference, duplicateOfID)
+		if err != nil {
+			return determinePgxError(err)
+		}
+		if err := s.chainTransaction(ctx, transaction); err != nil {
+			return err
+		}
+		if err := auditTransactionCreated(ctx, s.auditRepo, transaction); err != nil {
+			return err
+		}
+		if screening != nil {
+			screening.TransactionID = transaction.ID
+			if transaction.Screening, err = s.screeningRepo.InsertScreeningEvaluation(ctx, screening); err != nil {
+				return err
+			}
+		}
+		if err := s.postJournalEntry(ctx, transactionEntry(transaction)); err != nil {
+			return err
+		}
+		fee, err := s.postFee(ctx, accounts[0], transaction)
+		if err != nil {
+			return err
+		}
+		if fee != nil {
+			transaction.Fees = []*repository.Transaction{fee}
+		}
+
+		// Process Payment Discharge
+		// when a credit transaction is found, or replay the discharges a back-dated one comes before
+		switch {
+		case backdated:
+			if err := s.recomputeDischarges(ctx, transaction, now); err != nil {
+				return fmt.Errorf("discharge recomputation error: %w", err)
+			}
+		case operationTypeID == 4:
+			if err := s.processPaymentDischarge(ctx, transaction); err != nil {
+				return fmt.Errorf("payment discharge error: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if denied != nil {
+		return nil, denied
+	}
+
+	return transaction, nil
+}
+
+// signedAmount validates the amount of a new transaction, which must be strictly positive, and returns it
+// signed per the operation type with exactly two decimal places
+func signedAmount(operationTypeID int64, amount float64) (float64, error) {
 	if amount <= 0 {
 		if amount == 0 {
-			return nil, ErrInvalidAmount
+			return 0, ErrInvalidAmount
 		}
-		return nil, ErrNegativeAmount
+		return 0, ErrNegativeAmount
 	}
 
-	// Ensure amount is appropriately signed
 	amount, err := EnforceAmountSign(operationTypeID, amount)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	return FormatAmount(amount), nil
+}
+
+// eventDate returns when the transaction took place: now, unless it is dated earlier within the back-dating
+// window
+func (s *transactionsService) eventDate(txn NewTransaction, now time.Time) (time.Time, error) {
+	switch {
+	case txn.EventDate.IsZero():
+		return now, nil
+	case txn.EventDate.After(now):
+		return time.Time{}, ErrEventDateInFuture
+	case txn.EventDate.Before(now.Add(-s.backdating.Window)):
+		return time.Time{}, ErrEventDateTooOld
+	}
+	return txn.EventDate.UTC(), nil
+}
+
+// checkPeriodOpen turns down an event date in a statement period of the account already closed: the
+// statement snapshots its period, so a transaction back-dated into it would never be listed
+func (s *transactionsService) checkPeriodOpen(ctx context.Context, accountID int64, eventDate time.Time) error {
+	latest, err := s.billingRepo.GetLatestStatementByAccountID(ctx, accountID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch statement: %w", err)
+	}
+	latestEnd, err := time.Parse(dateLayout, latest.PeriodEnd)
+	if err != nil {
+		return fmt.Errorf("invalid period_end of statement %d: %w", latest.ID, err)
+	}
+	if eventDate.Before(latestEnd.AddDate(0, 0, 1)) {
+		return ErrEventDateClosed
+	}
+	return nil
+}
+
+// findDuplicate looks for a transaction the new one of the signed amount looks like a resend of, posted
+// within the duplicate window before now. Under the reject action a match is returned as a
+// *DuplicateTransactionError; under the flag action it is returned for the new transaction to reference.
+func (s *transactionsService) findDuplicate(ctx context.Context, txn NewTransaction, amount float64, now time.Time) (*repository.Transaction, error) {
+	if s.duplicates.Window <= 0 {
+		return nil, nil
+	}
+	if txn.Force {
+		log.Warn().Ctx(ctx).Int64("account_id", txn.AccountID).Int64("operation_type_id", txn.OperationTypeID).Float64("amount", amount).
+			Msg("duplicate check overridden")
+		return nil, nil
 	}
 
-	// Format the amount to have exactly two decimal places.
-	amount = FormatAmount(amount)
+	original, err := s.trxRepo.GetDuplicateTransaction(ctx, txn.AccountID, txn.OperationTypeID, amount, txn.MerchantReference, now.Add(-s.duplicates.Window))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up duplicate transactions: %w", err)
+	}
 
-	// Set balance as amount (initially)
-	balance := amount
+	log.Warn().Ctx(ctx).Int64("account_id", txn.AccountID).Int64("operation_type_id", txn.OperationTypeID).Float64("amount", amount).
+		Int64("original_id", original.ID).Str("action", s.duplicates.Action).Msg("suspected duplicate transaction")
+	if s.duplicates.Action == DuplicateActionReject {
+		return nil, &DuplicateTransactionError{Original: original}
+	}
+	return original, nil
+}
 
-	// Insert transaction record
-	transaction, err := s.trxRepo.InsertTransaction(ctx, accountID, operationTypeID, amount, balance)
+// screen evaluates the screening rules applying to a transaction of amount on the account at now against the
+// account's recent activity. It returns nil when no rule applies.
+func (s *transactionsService) screen(ctx context.Context, account *repository.Account, operationTypeID int64, amount float64, now time.Time) (*repository.ScreeningEvaluation, error) {
+	rules, err := s.screeningRepo.GetScreeningRules(ctx, account.Product)
 	if err != nil {
-		return nil, determinePgxError(err)
+		return nil, fmt.Errorf("failed to fetch screening rules: %w", err)
+	}
+	rules = slices.DeleteFunc(rules, func(rule *repository.ScreeningRule) bool {
+		return !screeningRuleApplies(rule, account, operationTypeID, now)
+	})
+	if len(rules) == 0 {
+		return nil, nil
 	}
 
-	// Process Payment Discharge
-	// when a credit transaction is found
-	if operationTypeID == 4 {
-		err := s.processPaymentDischarge(ctx, transaction)
+	var windowHours int
+	for _, rule := range rules {
+		windowHours = max(windowHours, rule.WindowHours)
+	}
+	var activity []*repository.Transaction
+	if windowHours > 0 {
+		activity, err = s.trxRepo.GetTransactionsByAccountIDSince(ctx, account.ID, now.Add(-time.Duration(windowHours)*time.Hour))
 		if err != nil {
-			return nil, fmt.Errorf("payment discharge error: %w", err)
+			return nil, fmt.Errorf("failed to fetch account activity: %w", err)
 		}
 	}
 
-	return transaction, nil
+	return screeningEvaluation(ctx, rules, account, activity, operationTypeID, amount, now), nil
+}
+
+// screeningEvaluation screens a transaction of amount on the account at now against the rules applying to it
+// and the account's activity within their windows
+func screeningEvaluation(ctx context.Context, rules []*repository.ScreeningRule, account *repository.Account, activity []*repository.Transaction, operationTypeID int64, amount float64, now time.Time) *repository.ScreeningEvaluation {
+	outcome, reasonCodes := ScreenTransaction(rules, account, activity, operationTypeID, amount, now)
+	log.Info().Ctx(ctx).Int64("account_id", account.ID).Int64("operation_type_id", operationTypeID).Float64("amount", amount).
+		Int("rules", len(rules)).Str("outcome", outcome).Strs("reason_codes", reasonCodes).Msg("transaction screened")
+	return &repository.ScreeningEvaluation{
+		AccountID:       account.ID,
+		OperationTypeID: operationTypeID,
+		Amount:          amount,
+		Outcome:         outcome,
+		ReasonCodes:     reasonCodes,
+	}
 }
 
-// processPaymentDischarge applies a payment transaction against outstanding purchase/withdrawal transactions.
-func (s *transactionsService) processPaymentDischarge(ctx context.Context, creditTxn *repository.Transaction) error {
-	// PLAN
-	// 1. Initialize the credit amount (op.type 4).
-	// 2. Define the total dischargeable amount as the credit.
-	// 3. Retrieve outstanding transactions for the account.
-	// 4. Iterate over the transactions to apply payment discharge.
-	// 5. Update each outstanding transaction’s balance as discharge is applied.
-	// 6. Continue until the credit is fully allocated.
-	// 7. Update the op.type 4 transaction with its new balance.
+// ScreenTransaction evaluates the rules against a transaction of amount on the account at now, given the
+// account's transactions within the rules' windows. It returns the outcome and the codes of the rules that
+// tripped, the denying ones first; a transaction no rule trips on is allowed.
+func ScreenTransaction(rules []*repository.ScreeningRule, account *repository.Account, activity []*repository.Transaction, operationTypeID int64, amount float64, now time.Time) (string, []string) {
+	amount = math.Abs(amount)
 
-	creditedAmount := creditTxn.Amount
-	log.Info().Msgf("Starting Payment Discharge for Txn: %d: creditedAmount = %.2f", creditTxn.ID, creditedAmount)
+	var denials, reviews []string
+	for _, rule := range rules {
+		if !screeningRuleApplies(rule, account, operationTypeID, now) {
+			continue
+		}
+
+		var count int
+		var total float64
+		since := now.Add(-time.Duration(rule.WindowHours) * time.Hour)
+		for _, txn := range activity {
+			if slices.Contains(rule.OperationTypeIDs, txn.OperationTypeID) && !txn.EventDate.Before(since) && !txn.EventDate.After(now) {
+				count++
+				total += math.Abs(txn.Amount)
+			}
+		}
+
+		var tripped bool
+		switch rule.Kind {
+		case repository.ScreeningKindCount:
+			tripped = float64(count+1) > rule.Threshold
+		case repository.ScreeningKindAmount:
+			tripped = FormatAmount(total+amount) > rule.Threshold
+		case repository.ScreeningKindSingleAmount:
+			tripped = amount > rule.Threshold
+		}
+		if !tripped {
+			continue
+		}
+		if rule.Action == repository.ScreeningOutcomeDeny {
+			denials = append(denials, rule.Code)
+		} else {
+			reviews = append(reviews, rule.Code)
+		}
+	}
+
+	switch {
+	case len(denials) > 0:
+		return repository.ScreeningOutcomeDeny, append(denials, reviews...)
+	case len(reviews) > 0:
+		return repository.ScreeningOutcomeReview, reviews
+	}
+	return repository.ScreeningOutcomeAllow, []string{}
+}
 
-	// Fetch all outstanding balances for the account.
-	outstandingTxns, err := s.trxRepo.GetOutstandingTransactionsByAccountID(ctx, creditTxn.AccountID)
+// screeningRuleApplies reports whether the rule screens transactions of the operation type on the account
+func screeningRuleApplies(rule *repository.ScreeningRule, account *repository.Account, operationTypeID int64, now time.Time) bool {
+	if !slices.Contains(rule.OperationTypeIDs, operationTypeID) {
+		return false
+	}
+	if rule.Product != "" && rule.Product != account.Product {
+		return false
+	}
+	return rule.MaxAccountAgeDays == 0 || now.Sub(account.CreatedAt) < time.Duration(rule.MaxAccountAgeDays)*24*time.Hour
+}
+
+// postFee posts the fee the account's product charges on the transaction, if any, as a debt of its own
+// linked to the transaction
+func (s *transactionsService) postFee(ctx context.Context, account *repository.Account, txn *repository.Transaction) (*repository.Transaction, error) {
+	rule, err := s.feeRepo.GetFeeRule(ctx, account.Product, txn.OperationTypeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
-		fmt.Println("101")
-		fmt.Println(err)
-		return err
+		return nil, fmt.Errorf("failed to fetch fee rule: %w", err)
 	}
 
-	var totalDischarge float64
-	for _, outstandingTxn := range outstandingTxns {
-		if creditedAmount <= 0 {
-			log.Info().Msg("no more credited amount left to process discharge")
-			break
-		}
+	amount := FeeAmount(rule, txn.Amount)
+	if amount <= 0 {
+		return nil, nil
+	}
+	fee, err := s.trxRepo.InsertFeeTransaction(ctx, txn.ID, txn.AccountID, OperationTypeFee, -amount, -amount, txn.EventDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert fee: %w", determinePgxError(err))
+	}
+	if err := s.chainTransaction(ctx, fee); err != nil {
+		return nil, err
+	}
+	if err := auditTransactionCreated(ctx, s.auditRepo, fee); err != nil {
+		return nil, err
+	}
+	if err := s.postJournalEntry(ctx, transactionEntry(fee)); err != nil {
+		return nil, err
+	}
+	return fee, nil
+}
 
-		// Calculate the total absolute outstanding amount per txn
-		outstandingAbsValue := -outstandingTxn.Balance
-		log.Info().Msgf("processing discharge for outstanding txn: %d, current outstanding balance: %.2f, current outstanding amount: %.2f", outstandingTxn.ID, outstandingTxn.Balance, outstandingAbsValue)
+// FeeAmount prices the fee the rule charges on a transaction of amount, rounded to the cent
+func FeeAmount(rule *repository.FeeRule, amount float64) float64 {
+	amount = math.Abs(amount)
 
-		// Calculate the dischargeable amount for this transaction
-		dischargeableAmount := creditedAmount
-		if creditedAmount > outstandingAbsValue {
-			dischargeableAmount = outstandingAbsValue
+	var fee float64
+	switch rule.Kind {
+	case repository.FeeKindFlat:
+		fee = rule.FlatAmount
+	case repository.FeeKindPercent:
+		fee = rule.Percent * amount
+	case repository.FeeKindTiered:
+		for _, tier := range rule.Tiers {
+			if tier.UpTo == 0 || amount <= tier.UpTo {
+				fee = tier.FlatAmount + tier.Percent*amount
+				break
+			}
 		}
+	}
 
-		// Calculate the new balance for the outstanding transaction
-		newBalance := outstandingTxn.Balance + dischargeableAmount
-		if err := s.trxRepo.UpdateTransactionBalance(ctx, outstandingTxn.ID, newBalance); err != nil {
-			fmt.Println("126")
-			fmt.Println(err)
-			return err
-		}
+	if rule.MinAmount > 0 && fee < rule.MinAmount {
+		fee = rule.MinAmount
+	}
+	if rule.MaxAmount > 0 && fee > rule.MaxAmount {
+		fee = rule.MaxAmount
+	}
+	return FormatAmount(fee)
+}
+
+// processPaymentDischarge applies a credit voucher or the credit leg of a transfer against outstanding debits.
+// However many debts it discharges, it takes three statements: one applying the credit to the debts, oldest
+// first, one posting a discharge journal entry per debt and one recording the balance changes in the audit log.
+func (s *transactionsService) processPaymentDischarge(ctx context.Context, creditTxn *repository.Transaction) (err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransactionsService.processPaymentDischarge", trace.WithAttributes(
+		attribute.Int64("transaction.id", creditTxn.ID),
+		attribute.Int64("account.id", creditTxn.AccountID),
+	))
+	defer func() { endSpan(span, err) }()
 
-		// After processing, update the total discharged amount,
-		// adjust outstanding transaction balances, and finalize the credit transaction balance.
-		creditedAmount -= dischargeableAmount
-		totalDischarge += dischargeableAmount
+	log.Info().Ctx(ctx).Msgf("Starting Payment Discharge for Txn: %d: creditedAmount = %.2f", creditTxn.ID, creditTxn.Balance)
+
+	discharges, err := s.trxRepo.DischargeTransactions(ctx, creditTxn)
+	if err != nil {
+		return fmt.Errorf("failed to discharge outstanding transactions: %w", err)
+	}
+	span.SetAttributes(attribute.Int("discharge.count", len(discharges)))
+	if len(discharges) == 0 {
+		log.Info().Ctx(ctx).Msgf("no outstanding transactions to discharge for txn %d", creditTxn.ID)
+		return nil
 	}
 
-	// At-last, update the credit transaction's latest balance value
-	// after the payment discharge process is completed
-	newBalanceForCreditTxn := creditTxn.Amount - totalDischarge
-	if err := s.trxRepo.UpdateTransactionBalance(ctx, creditTxn.ID, newBalanceForCreditTxn); err != nil {
-		fmt.Println("137")
+	var totalDischarge float64
+	entries := make([]*repository.JournalEntry, 0, len(discharges))
+	audits := make([]balanceUpdate, 0, len(discharges)+1)
+	for _, discharge := range discharges {
+		outstandingTxn := &repository.Transaction{ID: discharge.TransactionID, AccountID: creditTxn.AccountID}
+		entries = append(entries, dischargeEntry(creditTxn, outstandingTxn, FormatAmount(discharge.Amount)))
+		audits = append(audits, balanceUpdate{discharge.TransactionID, discharge.Balance, FormatAmount(discharge.Balance + discharge.Amount)})
+		totalDischarge += discharge.Amount
+	}
+	newBalanceForCreditTxn := FormatAmount(creditTxn.Balance - totalDischarge)
+	audits = append(audits, balanceUpdate{creditTxn.ID, creditTxn.Balance, newBalanceForCreditTxn})
+
+	if err := s.postJournalEntries(ctx, entries); err != nil {
+		return err
+	}
+	if err := auditBalancesUpdated(ctx, s.auditRepo, audits); err != nil {
 		return err
 	}
 
 	creditTxn.Balance = newBalanceForCreditTxn
-	log.Info().Msgf("Finished payment discharge for txn %d; total discharged = %.2f, final payment balance = %.2f",
+	log.Info().Ctx(ctx).Msgf("Finished payment discharge for txn %d; total discharged = %.2f, final payment balance = %.2f",
 		creditTxn.ID, totalDischarge, newBalanceForCreditTxn)
 	return nil
 }
 
+// recomputeDischarges replays the discharges of the back-dated transaction, if a credit, and of the account's
+// credits that took place after it, as though every transaction had been posted in event order: each credit
+// discharges the debts that took place before it, oldest first. What a credit now discharges of a debt beyond
+// or short of what it did is posted as a discharge adjustment as of now, and the balances that change are
+// updated and audited.
+func (s *transactionsService) recomputeDischarges(ctx context.Context, backdated *repository.Transaction, now time.Time) (err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransactionsService.recomputeDischarges", trace.WithAttributes(
+		attribute.Int64("transaction.id", backdated.ID),
+		attribute.Int64("account.id", backdated.AccountID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	transactions, err := s.trxRepo.GetTransactionsByAccountID(ctx, backdated.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch account transactions: %w", err)
+	}
+	replayed := make(map[int64]bool)
+	for _, txn := range transactions {
+		if txn.Amount > 0 && (txn.ID == backdated.ID || txn.EventDate.After(backdated.EventDate)) {
+			replayed[txn.ID] = true
+		}
+	}
+	if len(replayed) == 0 {
+		return nil
+	}
+	allocations, err := s.ledgerRepo.GetDischargeAllocations(ctx, backdated.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch discharge allocations: %w", err)
+	}
+
+	// Undo what the replayed credits discharged, then discharge again in event order, in cents
+	type allocation struct{ creditID, debtID int64 }
+	balances := make(map[int64]int64, len(transactions))
+	for _, txn := range transactions {
+		balances[txn.ID] = cents(txn.Balance)
+	}
+	before, after := make(map[allocation]int64), make(map[allocation]int64)
+	for _, a := range allocations {
+		if replayed[a.CreditTransactionID] {
+			before[allocation{a.CreditTransactionID, a.DebtTransactionID}] = cents(a.Amount)
+			balances[a.CreditTransactionID] += cents(a.Amount)
+			balances[a.DebtTransactionID] -= cents(a.Amount)
+		}
+	}
+	for i, creditTxn := range transactions {
+		if !replayed[creditTxn.ID] {
+			continue
+		}
+		for _, debt := range transactions[:i] {
+			if balances[creditTxn.ID] <= 0 {
+				break
+			}
+			if debt.Amount >= 0 || balances[debt.ID] >= 0 {
+				continue
+			}
+			amount := min(-balances[debt.ID], balances[creditTxn.ID])
+			after[allocation{creditTxn.ID, debt.ID}] += amount
+			balances[creditTxn.ID] -= amount
+			balances[debt.ID] += amount
+		}
+	}
+
+	pairs := slices.Collect(maps.Keys(before))
+	for pair := range after {
+		if _, ok := before[pair]; !ok {
+			pairs = append(pairs, pair)
+		}
+	}
+	slices.SortFunc(pairs, func(a, b allocation) int {
+		return cmp.Or(cmp.Compare(a.creditID, b.creditID), cmp.Compare(a.debtID, b.debtID))
+	})
+	var entries []*repository.JournalEntry
+	for _, pair := range pairs {
+		if delta := after[pair] - before[pair]; delta != 0 {
+			creditTxn := &repository.Transaction{ID: pair.creditID, AccountID: backdated.AccountID}
+			outstandingTxn := &repository.Transaction{ID: pair.debtID, AccountID: backdated.AccountID}
+			entries = append(entries, dischargeAdjustmentEntry(creditTxn, outstandingTxn, float64(delta)/100, now))
+		}
+	}
+	span.SetAttributes(attribute.Int("discharge.replayed", len(replayed)), attribute.Int("discharge.adjustments", len(entries)))
+	if len(entries) == 0 {
+		return nil
+	}
+
+	updated := make(map[int64]float64)
+	var audits []balanceUpdate
+	for _, txn := range transactions {
+		if balance := float64(balances[txn.ID]) / 100; balances[txn.ID] != cents(txn.Balance) {
+			updated[txn.ID] = balance
+			audits = append(audits, balanceUpdate{txn.ID, txn.Balance, balance})
+		}
+	}
+	if err := s.trxRepo.UpdateTransactionBalances(ctx, updated); err != nil {
+		return fmt.Errorf("failed to update transaction balances: %w", err)
+	}
+	if err := s.postJournalEntries(ctx, entries); err != nil {
+		return err
+	}
+	if err := auditBalancesUpdated(ctx, s.auditRepo, audits); err != nil {
+		return err
+	}
+
+	if balance, ok := updated[backdated.ID]; ok {
+		backdated.Balance = balance
+	}
+	log.Info().Ctx(ctx).Msgf("Recomputed discharges of %d credits after back-dated txn %d; %d adjustments posted",
+		len(replayed), backdated.ID, len(entries))
+	return nil
+}
+
+// postJournalEntry appends the entry to the ledger
+func (s *transactionsService) postJournalEntry(ctx context.Context, entry *repository.JournalEntry) error {
+	if _, err := s.ledgerRepo.PostJournalEntry(ctx, entry); err != nil {
+		return fmt.Errorf("failed to post %s journal entry: %w", entry.Kind, determinePgxError(err))
+	}
+	return nil
+}
+
+// postJournalEntries appends the entries to the ledger in a single statement
+func (s *transactionsService) postJournalEntries(ctx context.Context, entries []*repository.JournalEntry) error {
+	if err := s.ledgerRepo.PostJournalEntries(ctx, entries); err != nil {
+		return fmt.Errorf("failed to post discharge journal entries: %w", determinePgxError(err))
+	}
+	return nil
+}
+
 // EnforceAmountSign ensures that certain transaction types have positive/negative amounts
 func EnforceAmountSign(operationTypeID int64, amount float64) (float64, error) {
 	switch operationTypeID {
-	case 1, 2, 3: // Purchases and withdrawals → Negative amount
+	case 1, 2, 3, OperationTypeTransferDebit, OperationTypeInterest, OperationTypeLateFee, OperationTypeFee: // Purchases, withdrawals, transfer debits, charges and fees → Negative amount
 		return -math.Abs(amount), nil
-	case 4: // // Credit Voucher → Positive amount
+	case 4, OperationTypeTransferCredit: // Credit Voucher and transfer credits → Positive amount
 		return math.Abs(amount), nil
 	default:
 		return 0, ErrInvalidOperationType
 	}
 }
 
+// cents converts an amount to a whole number of cents
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
 // FormatAmount ensures the float has exactly two decimals. .
 func FormatAmount(amount float64) float64 {
 	formatted := fmt.Sprintf("%.2f", amount)
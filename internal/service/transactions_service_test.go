@@ -3,94 +3,349 @@ package service_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
 	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository/memory"
 	"github.com/ashwingopalsamy/transactions-service/internal/service"
 	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func newTransactionsService(mockDB pgxmock.PgxPoolIface) service.TransactionsService {
+	return newTransactionsServiceWithDuplicates(mockDB, service.DuplicatePolicy{})
+}
+
+func newTransactionsServiceWithDuplicates(mockDB pgxmock.PgxPoolIface, duplicates service.DuplicatePolicy) service.TransactionsService {
+	return service.NewTransactionsService(
+		repository.NewTransactionsRepository(mockDB),
+		repository.NewAccountsRepository(mockDB),
+		repository.NewLedgerRepository(mockDB),
+		repository.NewFeesRepository(mockDB),
+		repository.NewScreeningRepository(mockDB),
+		repository.NewAuditRepository(mockDB),
+		repository.NewBillingRepository(mockDB),
+		repository.NewTransactor(mockDB),
+		clock.Fixed(testNow),
+		duplicates,
+		service.BackdatingPolicy{},
+	)
+}
+
+// testNow is the time of the clock of the services under test
+var testNow = time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+// expectJournalEntry expects a journal entry posting amount to the first ledger account and its
+// opposite to the second, with each posting attributed to the matching transaction
+func expectJournalEntry(mockDB pgxmock.PgxPoolIface, kind string, transactionID int64, first, second string, attributed []int64, amount float64) {
+	mockDB.ExpectQuery(`INSERT INTO journal_entries`).
+		WithArgs(kind, transactionID, []string{first, second}, pgxmock.AnyArg(), pgxmock.AnyArg(), "", attributed, []float64{amount, -amount}, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), time.Now()))
+}
+
+// expectAuditEvent expects an audit event of the action on the entity, whatever its states
+func expectAuditEvent(mockDB pgxmock.PgxPoolIface, action, entityType string, entityID int64) {
+	mockDB.ExpectQuery(`INSERT INTO audit_events`).
+		WithArgs(action, entityType, entityID, pgxmock.AnyArg(), pgxmock.AnyArg(), "", "", "", "").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), testNow))
+}
+
+// expectDischarge expects the credit of the account to discharge the debts by the amounts given, and the
+// discharge journal entries and balance audit events that follow
+func expectDischarge(mockDB pgxmock.PgxPoolIface, accountID, creditID int64, creditBalance float64, debtIDs []int64, balances, amounts []float64) {
+	rows := pgxmock.NewRows([]string{"id", "balance", "amount"})
+	for i, id := range debtIDs {
+		rows.AddRow(id, balances[i], amounts[i])
+	}
+	mockDB.ExpectQuery(`WITH credit AS .* SELECT id, balance, amount FROM allocations`).
+		WithArgs(accountID, creditID).
+		WillReturnRows(rows)
+
+	kinds, codes, entryNumbers, transactionIDs, postings := []string{}, []string{}, []int64{}, []int64{}, []float64{}
+	auditIDs, befores, afters := []int64{}, []string{}, []string{}
+	var total float64
+	for i, id := range debtIDs {
+		kinds = append(kinds, "discharge")
+		codes = append(codes, fmt.Sprintf("customer_credit:%d", accountID), fmt.Sprintf("customer_receivable:%d", accountID))
+		entryNumbers = append(entryNumbers, int64(i+1), int64(i+1))
+		transactionIDs = append(transactionIDs, creditID, id)
+		postings = append(postings, amounts[i], -amounts[i])
+		auditIDs = append(auditIDs, id)
+		befores = append(befores, fmt.Sprintf(`{"balance":%g}`, balances[i]))
+		afters = append(afters, fmt.Sprintf(`{"balance":%g}`, service.FormatAmount(balances[i]+amounts[i])))
+		total += amounts[i]
+	}
+	mockDB.ExpectExec(`INSERT INTO postings`).
+		WithArgs(kinds, pgxmock.AnyArg(), pgxmock.AnyArg(), codes, pgxmock.AnyArg(), pgxmock.AnyArg(), entryNumbers, transactionIDs, postings, "").
+		WillReturnResult(pgxmock.NewResult("INSERT", int64(len(postings))))
+
+	auditIDs = append(auditIDs, creditID)
+	befores = append(befores, fmt.Sprintf(`{"balance":%g}`, creditBalance))
+	afters = append(afters, fmt.Sprintf(`{"balance":%g}`, service.FormatAmount(creditBalance-total)))
+	actions, entityTypes := make([]string, len(auditIDs)), make([]string, len(auditIDs))
+	for i := range auditIDs {
+		actions[i], entityTypes[i] = service.AuditActionTransactionBalanceUpdated, service.AuditEntityTransaction
+	}
+	mockDB.ExpectExec(`INSERT INTO audit_events .* FROM unnest`).
+		WithArgs(actions, entityTypes, auditIDs, befores, afters, "", "", "", "").
+		WillReturnResult(pgxmock.NewResult("INSERT", int64(len(auditIDs))))
+}
+
+// expectChainLink expects the transaction to start its account's chain
+func expectChainLink(mockDB pgxmock.PgxPoolIface, transactionID int64) {
+	mockDB.ExpectQuery(`SELECT hash FROM transactions`).
+		WithArgs(pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"hash"}))
+	mockDB.ExpectExec(`UPDATE transactions SET prev_hash`).
+		WithArgs(transactionID, service.GenesisHash, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+}
+
+// expectAuditEventStates expects an audit event of the action on the entity with the JSON states given
+func expectAuditEventStates(mockDB pgxmock.PgxPoolIface, action, entityType string, entityID int64, before, after string) {
+	mockDB.ExpectQuery(`INSERT INTO audit_events`).
+		WithArgs(action, entityType, entityID, before, after, "", "", "", "").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), testNow))
+}
+
+// expectNoFeeRule expects the fee rule lookup for transactions of the operation type on credit accounts
+// to find none
+func expectNoFeeRule(mockDB pgxmock.PgxPoolIface, operationTypeID int64) {
+	mockDB.ExpectQuery(`FROM fee_rules fr`).
+		WithArgs("credit", operationTypeID).
+		WillReturnRows(pgxmock.NewRows(feeRuleColumns))
+}
+
+// expectNoScreeningRules expects the screening rules lookup for credit accounts to find none
+func expectNoScreeningRules(mockDB pgxmock.PgxPoolIface) {
+	mockDB.ExpectQuery(`FROM screening_rules`).
+		WithArgs("credit").
+		WillReturnRows(pgxmock.NewRows(screeningRuleColumns))
+}
+
+var accountTransactionColumns = []string{"id", "operation_type_id", "amount", "balance", "event_date", "transfer_id", "parent_transaction_id"}
+
+var screeningRuleColumns = []string{"id", "code", "product", "operation_type_ids", "kind", "window_hours", "threshold", "max_account_age_days", "action"}
+
+var feeRuleColumns = []string{"id", "kind", "flat_amount", "percent", "min_amount", "max_amount", "has_tier", "up_to", "tier_flat_amount", "tier_percent"}
+
+func expectLockAccount(mockDB pgxmock.PgxPoolIface, status string) {
+	mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).
+		WithArgs([]int64{1}).
+		WillReturnRows(pgxmock.NewRows(accountColumns).
+			AddRow(int64(1), int64(1), "12345678900", "credit", status, accountCreatedAt))
+}
+
 func TestCreateTransaction(t *testing.T) {
 	t.Run("Valid transaction should succeed", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
-			WillReturnRows(pgxmock.NewRows([]string{"id", "document_number"}).
-				AddRow(int64(1), "12345678900"))
-
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
 		mockDB.ExpectQuery(`INSERT INTO transactions`).
-			WithArgs(int64(1), int64(2), float64(-100.00), -100.00).
+			WithArgs(int64(1), int64(2), float64(-100.00), -100.00, testNow, testNow, "", int64(0), "").
 			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).
-				AddRow(int64(1), time.Now(), 100.00))
+				AddRow(int64(1), time.Now(), -100.00))
+		expectChainLink(mockDB, 1)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 1)
+		expectJournalEntry(mockDB, "purchase", 1, "customer_receivable:1", "cash_clearing", []int64{1, 0}, 100)
+		expectNoFeeRule(mockDB, 2)
+		mockDB.ExpectCommit()
 
-		transaction, err := trxService.CreateTransaction(ctx, int64(1), 2, 100.00)
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 2, Amount: 100.00})
 		assert.NoError(t, err)
 		assert.NotNil(t, transaction)
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
 
-	t.Run("Payment Discharge Process Successful", func(t *testing.T) {
+	t.Run("Withdrawal should post its fee as a linked debt", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(3), -400.0, -400.0, testNow, testNow, "", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).
+				AddRow(int64(1), testNow, -400.0))
+		expectChainLink(mockDB, 1)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 1)
+		expectJournalEntry(mockDB, "withdrawal", 1, "customer_receivable:1", "cash_clearing", []int64{1, 0}, 400)
+		mockDB.ExpectQuery(`FROM fee_rules fr`).
+			WithArgs("credit", int64(3)).
+			WillReturnRows(pgxmock.NewRows(feeRuleColumns).
+				AddRow(int64(1), "percent", 0.0, 0.03, 5.0, 0.0, false, 0.0, 0.0, 0.0))
+		mockDB.ExpectQuery(`INSERT INTO transactions \(parent_transaction_id`).
+			WithArgs(int64(1), int64(1), service.OperationTypeFee, -12.0, -12.0, testNow, "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "booking_date", "balance"}).
+				AddRow(int64(2), testNow, testNow, -12.0))
+		expectChainLink(mockDB, 2)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 2)
+		expectJournalEntry(mockDB, "fee", 2, "customer_receivable:1", "fee_income", []int64{2, 0}, 12)
+		mockDB.ExpectCommit()
+
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 3, Amount: 400})
+		assert.NoError(t, err)
+		require.Len(t, transaction.Fees, 1)
+		assert.Equal(t, int64(2), transaction.Fees[0].ID)
+		assert.Equal(t, int64(1), transaction.Fees[0].ParentTransactionID)
+		assert.Equal(t, -12.0, transaction.Fees[0].Amount)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Transaction denied by screening should be recorded but not posted", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		mockDB.ExpectQuery(`FROM screening_rules`).
+			WithArgs("credit").
+			WillReturnRows(pgxmock.NewRows(screeningRuleColumns).
+				AddRow(int64(1), "withdrawal_velocity", "", []int64{3}, "count", 24, 2.0, 0, "deny"))
+		mockDB.ExpectQuery(`FROM transactions WHERE account_id = \$1 AND event_date >= \$2`).
+			WithArgs(int64(1), testNow.Add(-24*time.Hour)).
+			WillReturnRows(pgxmock.NewRows(accountTransactionColumns).
+				AddRow(int64(1), int64(3), -20.0, -20.0, testNow.Add(-2*time.Hour), int64(0), int64(0)).
+				AddRow(int64(2), int64(3), -30.0, -30.0, testNow.Add(-time.Hour), int64(0), int64(0)))
+		mockDB.ExpectQuery(`INSERT INTO screening_evaluations`).
+			WithArgs(int64(1), int64(3), -50.0, "deny", []string{"withdrawal_velocity"}, int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), testNow))
+		mockDB.ExpectCommit()
+
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 3, Amount: 50})
+		assert.ErrorIs(t, err, service.ErrTransactionDenied)
+		var denied *service.ScreeningDeniedError
+		require.ErrorAs(t, err, &denied)
+		assert.Equal(t, []string{"withdrawal_velocity"}, denied.ReasonCodes)
+		assert.Nil(t, transaction)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
-			WillReturnRows(pgxmock.NewRows([]string{"id", "document_number"}).
-				AddRow(int64(1), "12345678900"))
+	t.Run("Transaction flagged for review should be posted with its screening", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsService(mockDB)
+		ctx := context.Background()
 
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		mockDB.ExpectQuery(`FROM screening_rules`).
+			WithArgs("credit").
+			WillReturnRows(pgxmock.NewRows(screeningRuleColumns).
+				AddRow(int64(4), "large_debit", "", []int64{1, 2, 3}, "single_amount", 0, 2500.0, 0, "review"))
 		mockDB.ExpectQuery(`INSERT INTO transactions`).
-			WithArgs(int64(1), int64(4), float64(200.00), 200.00).
+			WithArgs(int64(1), int64(1), -3000.0, -3000.0, testNow, testNow, "", int64(0), "").
 			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).
-				AddRow(int64(3), time.Now(), 200.00))
+				AddRow(int64(1), testNow, -3000.0))
+		expectChainLink(mockDB, 1)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 1)
+		mockDB.ExpectQuery(`INSERT INTO screening_evaluations`).
+			WithArgs(int64(1), int64(1), -3000.0, "review", []string{"large_debit"}, int64(1), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), testNow))
+		expectJournalEntry(mockDB, "purchase", 1, "customer_receivable:1", "cash_clearing", []int64{1, 0}, 3000)
+		expectNoFeeRule(mockDB, 1)
+		mockDB.ExpectCommit()
 
-		creditTxn := &repository.Transaction{
-			ID:              int64(3),
-			AccountID:       int64(1),
-			OperationTypeID: int64(4),
-			Amount:          200.00,
-			Balance:         200.00,
-		}
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 1, Amount: 3000})
+		assert.NoError(t, err)
+		require.NotNil(t, transaction.Screening)
+		assert.Equal(t, repository.ScreeningOutcomeReview, transaction.Screening.Outcome)
+		assert.Equal(t, []string{"large_debit"}, transaction.Screening.ReasonCodes)
+		assert.Equal(t, int64(1), transaction.Screening.TransactionID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
 
-		outstandingRows := pgxmock.NewRows([]string{"id", "amount", "balance", "event_date"}).
-			AddRow(int64(1), -100.00, -100.00, time.Now()).
-			AddRow(int64(2), -100.00, -100.00, time.Now())
+	t.Run("Payment Discharge Process Successful", func(t *testing.T) {
+		ctx := context.Background()
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
 
-		mockDB.ExpectQuery(`SELECT id, amount, balance, event_date FROM transactions WHERE account_id = \$1`).
-			WithArgs(creditTxn.AccountID).
-			WillReturnRows(outstandingRows)
+		trxService := newTransactionsService(mockDB)
 
-		mockDB.ExpectExec(`UPDATE transactions SET balance = \$1, updated_at = CURRENT_TIMESTAMP WHERE id = \$2`).
-			WithArgs(0.00, int64(1)).
-			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(4), float64(200.00), 200.00, testNow, testNow, "", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).
+				AddRow(int64(3), time.Now(), 200.00))
+		expectChainLink(mockDB, 3)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 3)
+		expectJournalEntry(mockDB, "payment", 3, "customer_credit:1", "cash_clearing", []int64{3, 0}, -200)
+		expectNoFeeRule(mockDB, 4)
 
-		mockDB.ExpectExec(`UPDATE transactions SET balance = \$1, updated_at = CURRENT_TIMESTAMP WHERE id = \$2`).
-			WithArgs(0.00, int64(2)).
-			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		expectDischarge(mockDB, 1, 3, 200, []int64{1, 2}, []float64{-100, -100}, []float64{100, 100})
+		mockDB.ExpectCommit()
 
-		mockDB.ExpectExec(`UPDATE transactions SET balance = \$1, updated_at = CURRENT_TIMESTAMP WHERE id = \$2`).
-			WithArgs(0.00, creditTxn.ID).
-			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 4, Amount: 200.00})
+		assert.NoError(t, err)
+		require.NotNil(t, transaction)
+		assert.Equal(t, 0.0, transaction.Balance)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
 
-		transaction, err := trxService.CreateTransaction(ctx, int64(1), int64(4), 200.00)
+	t.Run("Payment on a clock finer than the database should not count as back-dated", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
 		assert.NoError(t, err)
-		assert.NotNil(t, transaction)
+		defer mockDB.Close()
+
+		now := testNow.Add(1500 * time.Nanosecond)
+		trxService := service.NewTransactionsService(
+			repository.NewTransactionsRepository(mockDB),
+			repository.NewAccountsRepository(mockDB),
+			repository.NewLedgerRepository(mockDB),
+			repository.NewFeesRepository(mockDB),
+			repository.NewScreeningRepository(mockDB),
+			repository.NewAuditRepository(mockDB),
+			repository.NewBillingRepository(mockDB),
+			repository.NewTransactor(mockDB),
+			clock.Fixed(now),
+			service.DuplicatePolicy{},
+			service.BackdatingPolicy{Window: time.Hour},
+		)
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(4), 200.0, 200.0, now, now, "", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).
+				AddRow(int64(3), now.Truncate(time.Microsecond), 200.0))
+		expectChainLink(mockDB, 3)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 3)
+		expectJournalEntry(mockDB, "payment", 3, "customer_credit:1", "cash_clearing", []int64{3, 0}, -200)
+		expectNoFeeRule(mockDB, 4)
+		expectDischarge(mockDB, 1, 3, 200, []int64{1}, []float64{-50}, []float64{50})
+		mockDB.ExpectCommit()
+
+		transaction, err := trxService.CreateTransaction(context.Background(), service.NewTransaction{AccountID: 1, OperationTypeID: 4, Amount: 200})
+		assert.NoError(t, err)
+		require.NotNil(t, transaction)
+		assert.Equal(t, 150.0, transaction.Balance)
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
 
@@ -99,16 +354,16 @@ func TestCreateTransaction(t *testing.T) {
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
-			WillReturnError(pgx.ErrNoRows)
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).
+			WithArgs([]int64{1}).
+			WillReturnRows(pgxmock.NewRows(accountColumns))
+		mockDB.ExpectRollback()
 
-		transaction, err := trxService.CreateTransaction(ctx, 1, 4, 100.00)
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 4, Amount: 100.00})
 		assert.Error(t, err)
 		assert.Equal(t, service.ErrInvalidAccountID, err)
 		assert.Nil(t, transaction)
@@ -120,16 +375,16 @@ func TestCreateTransaction(t *testing.T) {
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).
+			WithArgs([]int64{1}).
 			WillReturnError(errors.New("database error"))
+		mockDB.ExpectRollback()
 
-		transaction, err := trxService.CreateTransaction(ctx, 1, 4, 100.00)
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 4, Amount: 100.00})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to fetch account")
 		assert.Nil(t, transaction)
@@ -141,17 +396,10 @@ func TestCreateTransaction(t *testing.T) {
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
-			WillReturnRows(pgxmock.NewRows([]string{"id", "document_number"}).
-				AddRow(int64(1), "12345678900"))
-
-		transaction, err := trxService.CreateTransaction(ctx, 1, 4, 0)
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 4, Amount: 0})
 		assert.Error(t, err)
 		assert.Equal(t, service.ErrInvalidAmount, err)
 		assert.Nil(t, transaction)
@@ -163,17 +411,10 @@ func TestCreateTransaction(t *testing.T) {
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
-			WillReturnRows(pgxmock.NewRows([]string{"id", "document_number"}).
-				AddRow(int64(1), "12345678900"))
-
-		transaction, err := trxService.CreateTransaction(ctx, 1, 4, -50.00)
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 4, Amount: -50.00})
 		assert.Error(t, err)
 		assert.Equal(t, service.ErrNegativeAmount, err)
 		assert.Nil(t, transaction)
@@ -185,43 +426,83 @@ func TestCreateTransaction(t *testing.T) {
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
-			WillReturnRows(pgxmock.NewRows([]string{"id", "document_number"}).
-				AddRow(int64(1), "12345678900"))
-
-		transaction, err := trxService.CreateTransaction(ctx, 1, 99, 100.00)
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 99, Amount: 100.00})
 		assert.Error(t, err)
 		assert.Equal(t, service.ErrInvalidOperationType, err)
 		assert.Nil(t, transaction)
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
 
-	t.Run("Database error during transaction insertion should fail", func(t *testing.T) {
+	t.Run("Blocked account should fail", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
-			WillReturnRows(pgxmock.NewRows([]string{"id", "document_number"}).
-				AddRow(int64(1), "12345678900"))
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "blocked")
+		mockDB.ExpectRollback()
+
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 1, Amount: 50})
+		assert.ErrorIs(t, err, service.ErrAccountNotActive)
+		assert.Nil(t, transaction)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Transfer operation types should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
 
+		trxService := newTransactionsService(mockDB)
+		ctx := context.Background()
+
+		for _, operationTypeID := range []int64{service.OperationTypeTransferDebit, service.OperationTypeTransferCredit} {
+			transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: operationTypeID, Amount: 50})
+			assert.ErrorIs(t, err, service.ErrInvalidOperationType)
+			assert.Nil(t, transaction)
+		}
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Charge and fee operation types should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsService(mockDB)
+		ctx := context.Background()
+
+		for _, operationTypeID := range []int64{service.OperationTypeInterest, service.OperationTypeLateFee, service.OperationTypeFee} {
+			transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: operationTypeID, Amount: 50})
+			assert.ErrorIs(t, err, service.ErrInvalidOperationType)
+			assert.Nil(t, transaction)
+		}
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Database error during transaction insertion should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
 		mockDB.ExpectQuery(`INSERT INTO transactions`).
-			WithArgs(int64(1), int64(4), float64(100.00), 100.00).
+			WithArgs(int64(1), int64(4), float64(100.00), 100.00, testNow, testNow, "", int64(0), "").
 			WillReturnError(errors.New("database error"))
+		mockDB.ExpectRollback()
 
-		transaction, err := trxService.CreateTransaction(ctx, 1, 4, 100.00)
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 4, Amount: 100.00})
 		assert.Error(t, err)
 		assert.Nil(t, transaction)
 		assert.NoError(t, mockDB.ExpectationsWereMet())
@@ -232,52 +513,428 @@ func TestCreateTransaction(t *testing.T) {
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
-			WillReturnRows(pgxmock.NewRows([]string{"id", "document_number"}).
-				AddRow(int64(1), "12345678900"))
-
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
 		mockDB.ExpectQuery(`INSERT INTO transactions`).
-			WithArgs(int64(1), int64(4), float64(100.00), 100.00).
+			WithArgs(int64(1), int64(4), float64(100.00), 100.00, testNow, testNow, "", int64(0), "").
 			WillReturnError(errors.New("violates foreign key constraint transactions_account_id_fkey"))
+		mockDB.ExpectRollback()
 
-		transaction, err := trxService.CreateTransaction(ctx, 1, 4, 100.00)
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 4, Amount: 100.00})
 		assert.Error(t, err)
 		assert.Equal(t, service.ErrInvalidAccountID, err)
 		assert.Nil(t, transaction)
 		assert.NoError(t, mockDB.ExpectationsWereMet())
 	})
 
-	t.Run("Foreign key violation for operation type ID should fail", func(t *testing.T) {
+	t.Run("Unbalanced journal entry should roll back the transaction", func(t *testing.T) {
 		mockDB, err := pgxmock.NewPool()
 		assert.NoError(t, err)
 		defer mockDB.Close()
 
-		trxRepo := repository.NewTransactionsRepository(mockDB)
-		accRepo := repository.NewAccountsRepository(mockDB)
-		trxService := service.NewTransactionsService(trxRepo, accRepo)
+		trxService := newTransactionsService(mockDB)
 		ctx := context.Background()
 
-		mockDB.ExpectQuery(`SELECT id, document_number FROM accounts WHERE id = \$1`).
-			WithArgs(int64(1)).
-			WillReturnRows(pgxmock.NewRows([]string{"id", "document_number"}).
-				AddRow(int64(1), "12345678900"))
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(1), -100.00, -100.00, testNow, testNow, "", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).
+				AddRow(int64(1), time.Now(), -100.00))
+		expectChainLink(mockDB, 1)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 1)
+		mockDB.ExpectQuery(`INSERT INTO journal_entries`).
+			WithArgs("purchase", int64(1), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), "", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("ERROR: journal entry 1 does not balance: postings sum to 0.01 (SQLSTATE 23514)"))
+		mockDB.ExpectRollback()
+
+		transaction, err := trxService.CreateTransaction(ctx, service.NewTransaction{AccountID: 1, OperationTypeID: 1, Amount: 100.00})
+		assert.ErrorIs(t, err, service.ErrUnbalancedJournalEntry)
+		assert.Nil(t, transaction)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestCreateTransactionDuplicates(t *testing.T) {
+	window := 10 * time.Minute
+	original := testNow.Add(-2 * time.Minute)
+
+	expectDuplicateLookup := func(mockDB pgxmock.PgxPoolIface, rows *pgxmock.Rows) {
+		mockDB.ExpectQuery(`FROM transactions WHERE account_id = \$1 AND operation_type_id = \$2 AND amount = \$3`).
+			WithArgs(int64(1), int64(1), -20.0, testNow.Add(-window), "ref-1").
+			WillReturnRows(rows)
+	}
+	duplicateRows := func() *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"id", "balance", "event_date", "merchant_reference"}).AddRow(int64(7), -20.0, original, "ref-1")
+	}
+	purchase := service.NewTransaction{AccountID: 1, OperationTypeID: 1, Amount: 20, MerchantReference: "ref-1"}
+
+	t.Run("Resend within the window should be rejected", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsServiceWithDuplicates(mockDB, service.DuplicatePolicy{Window: window, Action: service.DuplicateActionReject})
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectDuplicateLookup(mockDB, duplicateRows())
+		mockDB.ExpectRollback()
+
+		transaction, err := trxService.CreateTransaction(context.Background(), purchase)
+		assert.ErrorIs(t, err, service.ErrDuplicateTransaction)
+		var duplicate *service.DuplicateTransactionError
+		require.ErrorAs(t, err, &duplicate)
+		assert.Equal(t, int64(7), duplicate.Original.ID)
+		assert.Nil(t, transaction)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
 
+	t.Run("Resend within the window should be posted flagged", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsServiceWithDuplicates(mockDB, service.DuplicatePolicy{Window: window, Action: service.DuplicateActionFlag})
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectDuplicateLookup(mockDB, duplicateRows())
+		expectNoScreeningRules(mockDB)
 		mockDB.ExpectQuery(`INSERT INTO transactions`).
-			WithArgs(int64(1), int64(99), float64(100.00)).
-			WillReturnError(errors.New("violates foreign key constraint transactions_operation_type_id_fkey"))
+			WithArgs(int64(1), int64(1), -20.0, -20.0, testNow, testNow, "ref-1", int64(7), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(8), testNow, -20.0))
+		expectChainLink(mockDB, 8)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 8)
+		expectJournalEntry(mockDB, "purchase", 8, "customer_receivable:1", "cash_clearing", []int64{8, 0}, 20)
+		expectNoFeeRule(mockDB, 1)
+		mockDB.ExpectCommit()
 
-		transaction, err := trxService.CreateTransaction(ctx, 1, 99, 100.00)
-		assert.Error(t, err)
-		assert.Equal(t, service.ErrInvalidOperationType, err)
+		transaction, err := trxService.CreateTransaction(context.Background(), purchase)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), transaction.DuplicateOfID)
+		assert.Equal(t, "ref-1", transaction.MerchantReference)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Transaction without a match should be posted", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsServiceWithDuplicates(mockDB, service.DuplicatePolicy{Window: window, Action: service.DuplicateActionReject})
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectDuplicateLookup(mockDB, pgxmock.NewRows([]string{"id", "balance", "event_date", "merchant_reference"}))
+		expectNoScreeningRules(mockDB)
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(1), -20.0, -20.0, testNow, testNow, "ref-1", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(8), testNow, -20.0))
+		expectChainLink(mockDB, 8)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 8)
+		expectJournalEntry(mockDB, "purchase", 8, "customer_receivable:1", "cash_clearing", []int64{8, 0}, 20)
+		expectNoFeeRule(mockDB, 1)
+		mockDB.ExpectCommit()
+
+		transaction, err := trxService.CreateTransaction(context.Background(), purchase)
+		require.NoError(t, err)
+		assert.Zero(t, transaction.DuplicateOfID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Forced transaction should skip the check", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsServiceWithDuplicates(mockDB, service.DuplicatePolicy{Window: window, Action: service.DuplicateActionReject})
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		expectNoScreeningRules(mockDB)
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), int64(1), -20.0, -20.0, testNow, testNow, "ref-1", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(8), testNow, -20.0))
+		expectChainLink(mockDB, 8)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 8)
+		expectJournalEntry(mockDB, "purchase", 8, "customer_receivable:1", "cash_clearing", []int64{8, 0}, 20)
+		expectNoFeeRule(mockDB, 1)
+		mockDB.ExpectCommit()
+
+		forced := purchase
+		forced.Force = true
+		transaction, err := trxService.CreateTransaction(context.Background(), forced)
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), transaction.ID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestCreateTransactionEventDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		eventDate time.Time
+		wantErr   error
+	}{
+		{"Future event date should fail", testNow.Add(time.Minute), service.ErrEventDateInFuture},
+		{"Event date before the back-dating window should fail", testNow.Add(-time.Minute), service.ErrEventDateTooOld},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB, err := pgxmock.NewPool()
+			assert.NoError(t, err)
+			defer mockDB.Close()
+
+			mockDB.ExpectBegin()
+			expectLockAccount(mockDB, "active")
+			mockDB.ExpectRollback()
+
+			transaction, err := newTransactionsService(mockDB).CreateTransaction(context.Background(), service.NewTransaction{AccountID: 1, OperationTypeID: 1, Amount: 50, EventDate: tt.eventDate})
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, transaction)
+			assert.NoError(t, mockDB.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBackdatedTransactionsRecomputeDischarges(t *testing.T) {
+	type fixture struct {
+		trxService    service.TransactionsService
+		ledgerService service.LedgerService
+		trxRepo       repository.TransactionsRepository
+		ledgerRepo    repository.LedgerRepository
+		billingRepo   repository.BillingRepository
+		clock         *clock.Virtual
+		accountID     int64
+	}
+	setup := func(t *testing.T) fixture {
+		store := memory.NewStore()
+		trxRepo := memory.NewTransactionsRepository(store)
+		accRepo := memory.NewAccountsRepository(store)
+		ledgerRepo := memory.NewLedgerRepository(store)
+		billingRepo := memory.NewBillingRepository(store)
+		transactor := memory.NewTransactor(store)
+		virtual := clock.NewVirtual(clock.Fixed(testNow))
+
+		accService := service.NewAccountsService(accRepo, memory.NewCustomersRepository(store), memory.NewAuditRepository(store), transactor)
+		trxService := service.NewTransactionsService(trxRepo, accRepo, ledgerRepo, memory.NewFeesRepository(store), memory.NewScreeningRepository(store), memory.NewAuditRepository(store), billingRepo, transactor, virtual, service.DuplicatePolicy{}, service.BackdatingPolicy{Window: 72 * time.Hour})
+		account, err := accService.CreateAccount(context.Background(), 0, "1", service.ProductCredit)
+		require.NoError(t, err)
+		return fixture{trxService, service.NewLedgerService(ledgerRepo, trxRepo, accRepo), trxRepo, ledgerRepo, billingRepo, virtual, account.ID}
+	}
+	post := func(t *testing.T, f fixture, txn service.NewTransaction, at time.Time) *repository.Transaction {
+		t.Helper()
+		require.NoError(t, f.clock.AdvanceTo(at))
+		txn.AccountID = f.accountID
+		posted, err := f.trxService.CreateTransaction(context.Background(), txn)
+		require.NoError(t, err)
+		return posted
+	}
+	balances := func(t *testing.T, f fixture, transactions ...*repository.Transaction) []float64 {
+		t.Helper()
+		var got []float64
+		for _, txn := range transactions {
+			txn, err := f.trxRepo.GetTransactionByID(context.Background(), txn.ID)
+			require.NoError(t, err)
+			got = append(got, txn.Balance)
+		}
+		return got
+	}
+
+	t.Run("Back-dated debt should be discharged first by the later credits", func(t *testing.T) {
+		f := setup(t)
+		ctx := context.Background()
+
+		first := post(t, f, service.NewTransaction{OperationTypeID: 1, Amount: 30}, testNow)
+		payment := post(t, f, service.NewTransaction{OperationTypeID: 4, Amount: 50}, testNow.Add(time.Hour))
+		later := post(t, f, service.NewTransaction{OperationTypeID: 1, Amount: 40}, testNow.Add(2*time.Hour))
+		assert.Equal(t, []float64{0, 20, -40}, balances(t, f, first, payment, later))
+
+		backdated := post(t, f, service.NewTransaction{OperationTypeID: 1, Amount: 25, EventDate: testNow.Add(-time.Hour)}, testNow.Add(3*time.Hour))
+		assert.Equal(t, testNow.Add(-time.Hour), backdated.EventDate)
+		assert.Equal(t, testNow.Add(3*time.Hour), backdated.BookingDate)
+		assert.Zero(t, backdated.Balance)
+
+		// The payment now takes the back-dated debt in full and what is left of it goes to the first one
+		assert.Equal(t, []float64{-5, 0, -40, 0}, balances(t, f, first, payment, later, backdated))
+		allocations, err := f.ledgerRepo.GetDischargeAllocations(ctx, f.accountID)
+		require.NoError(t, err)
+		assert.Equal(t, []*repository.Allocation{
+			{CreditTransactionID: payment.ID, DebtTransactionID: first.ID, Amount: 25},
+			{CreditTransactionID: payment.ID, DebtTransactionID: backdated.ID, Amount: 25},
+		}, allocations)
+
+		report, err := f.ledgerService.VerifyAccount(ctx, f.accountID)
+		require.NoError(t, err)
+		assert.True(t, report.Consistent, "%+v", report.Mismatches)
+	})
+
+	t.Run("Back-dated credit should take the oldest debts from the later credits", func(t *testing.T) {
+		f := setup(t)
+		ctx := context.Background()
+
+		first := post(t, f, service.NewTransaction{OperationTypeID: 1, Amount: 30}, testNow)
+		second := post(t, f, service.NewTransaction{OperationTypeID: 1, Amount: 20}, testNow.Add(time.Hour))
+		payment := post(t, f, service.NewTransaction{OperationTypeID: 4, Amount: 30}, testNow.Add(2*time.Hour))
+		assert.Equal(t, []float64{0, -20, 0}, balances(t, f, first, second, payment))
+
+		// Paid between the two debts, the back-dated credit can only take the first one
+		backdated := post(t, f, service.NewTransaction{OperationTypeID: 4, Amount: 25, EventDate: testNow.Add(30 * time.Minute)}, testNow.Add(3*time.Hour))
+		assert.Zero(t, backdated.Balance)
+
+		assert.Equal(t, []float64{0, 0, 5, 0}, balances(t, f, first, second, payment, backdated))
+		allocations, err := f.ledgerRepo.GetDischargeAllocations(ctx, f.accountID)
+		require.NoError(t, err)
+		assert.Equal(t, []*repository.Allocation{
+			{CreditTransactionID: payment.ID, DebtTransactionID: first.ID, Amount: 5},
+			{CreditTransactionID: payment.ID, DebtTransactionID: second.ID, Amount: 20},
+			{CreditTransactionID: backdated.ID, DebtTransactionID: first.ID, Amount: 25},
+		}, allocations)
+
+		report, err := f.ledgerService.VerifyAccount(ctx, f.accountID)
+		require.NoError(t, err)
+		assert.True(t, report.Consistent, "%+v", report.Mismatches)
+	})
+
+	t.Run("Event date in a closed statement period should fail", func(t *testing.T) {
+		f := setup(t)
+		ctx := context.Background()
+
+		_, err := f.billingRepo.InsertStatement(ctx, &repository.Statement{
+			AccountID: f.accountID, PeriodStart: "2025-02-15", PeriodEnd: "2025-03-14", DueDate: "2025-03-24",
+		})
+		require.NoError(t, err)
+
+		closed, err := f.trxService.CreateTransaction(ctx, service.NewTransaction{
+			AccountID: f.accountID, OperationTypeID: 1, Amount: 25, EventDate: time.Date(2025, 3, 14, 23, 0, 0, 0, time.UTC),
+		})
+		assert.ErrorIs(t, err, service.ErrEventDateClosed)
+		assert.Nil(t, closed)
+
+		// The open period starts the day after the statement's period_end
+		open := post(t, f, service.NewTransaction{OperationTypeID: 1, Amount: 25, EventDate: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)}, testNow)
+		assert.Equal(t, time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC), open.EventDate)
+	})
+}
+
+func TestGetTransaction(t *testing.T) {
+	t.Run("Existing transaction", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectQuery(`FROM transactions WHERE id = \$1`).
+			WithArgs(int64(8)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "account_id", "operation_type_id", "amount", "balance", "event_date", "booking_date", "transfer_id", "parent_transaction_id", "merchant_reference", "duplicate_of_transaction_id", "prev_hash", "hash"}).
+				AddRow(int64(8), int64(1), int64(1), -20.0, -20.0, testNow, testNow, int64(0), int64(0), "ref-1", int64(7), "", ""))
+
+		transaction, err := newTransactionsService(mockDB).GetTransaction(context.Background(), 8)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), transaction.DuplicateOfID)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown transaction", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		mockDB.ExpectQuery(`FROM transactions WHERE id = \$1`).
+			WithArgs(int64(999)).
+			WillReturnError(pgx.ErrNoRows)
+
+		transaction, err := newTransactionsService(mockDB).GetTransaction(context.Background(), 999)
+		assert.ErrorIs(t, err, service.ErrTransactionNotFound)
 		assert.Nil(t, transaction)
 	})
+}
+
+func TestCreateCharge(t *testing.T) {
+	t.Run("Interest should be charged to a blocked account as income", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
 
+		trxService := newTransactionsService(mockDB)
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "blocked")
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), service.OperationTypeInterest, -0.12, -0.12, testNow, testNow, "", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).
+				AddRow(int64(9), time.Now(), -0.12))
+		expectChainLink(mockDB, 9)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 9)
+		expectJournalEntry(mockDB, "interest", 9, "customer_receivable:1", "interest_income", []int64{9, 0}, 0.12)
+		expectNoFeeRule(mockDB, service.OperationTypeInterest)
+		mockDB.ExpectCommit()
+
+		transaction, err := trxService.CreateCharge(context.Background(), 1, service.OperationTypeInterest, 0.12)
+		assert.NoError(t, err)
+		assert.Equal(t, -0.12, transaction.Amount)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Late fee should be earned as fee income", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsService(mockDB)
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "active")
+		mockDB.ExpectQuery(`INSERT INTO transactions`).
+			WithArgs(int64(1), service.OperationTypeLateFee, -25.0, -25.0, testNow, testNow, "", int64(0), "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).
+				AddRow(int64(10), time.Now(), -25.0))
+		expectChainLink(mockDB, 10)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 10)
+		expectJournalEntry(mockDB, "late_fee", 10, "customer_receivable:1", "fee_income", []int64{10, 0}, 25)
+		expectNoFeeRule(mockDB, service.OperationTypeLateFee)
+		mockDB.ExpectCommit()
+
+		_, err = trxService.CreateCharge(context.Background(), 1, service.OperationTypeLateFee, 25)
+		assert.NoError(t, err)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Closed account should not be charged", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsService(mockDB)
+
+		mockDB.ExpectBegin()
+		expectLockAccount(mockDB, "closed")
+		mockDB.ExpectRollback()
+
+		transaction, err := trxService.CreateCharge(context.Background(), 1, service.OperationTypeInterest, 1)
+		assert.ErrorIs(t, err, service.ErrAccountNotActive)
+		assert.Nil(t, transaction)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Other operation types should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		trxService := newTransactionsService(mockDB)
+
+		transaction, err := trxService.CreateCharge(context.Background(), 1, 1, 50)
+		assert.ErrorIs(t, err, service.ErrInvalidOperationType)
+		assert.Nil(t, transaction)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
 }
 
 func TestFormatAmount(t *testing.T) {
@@ -317,6 +974,9 @@ func TestEnforceAmountSign(t *testing.T) {
 		{"Credit Voucher - Negative to Positive", 4, -75.25, 75.25, nil},
 		{"Normal Purchase - Negative Remains Negative", 1, -200.00, -200.00, nil},
 		{"Credit Voucher - Positive Remains Positive", 4, 150.00, 150.00, nil},
+		{"Interest Charge - Positive to Negative", 7, 0.12, -0.12, nil},
+		{"Late Fee - Positive to Negative", 8, 25.00, -25.00, nil},
+		{"Fee - Positive to Negative", 9, 5.00, -5.00, nil},
 		{"Invalid Operation Type", 99, 100.00, 0, service.ErrInvalidOperationType},
 	}
 
@@ -332,3 +992,76 @@ func TestEnforceAmountSign(t *testing.T) {
 		})
 	}
 }
+
+func TestFeeAmount(t *testing.T) {
+	tiered := &repository.FeeRule{Kind: repository.FeeKindTiered, MaxAmount: 10, Tiers: []repository.FeeTier{
+		{UpTo: 100, FlatAmount: 1},
+		{Percent: 0.01},
+	}}
+
+	tests := []struct {
+		name     string
+		rule     *repository.FeeRule
+		amount   float64
+		expected float64
+	}{
+		{"Flat", &repository.FeeRule{Kind: repository.FeeKindFlat, FlatAmount: 2.5}, -40, 2.5},
+		{"Percent", &repository.FeeRule{Kind: repository.FeeKindPercent, Percent: 0.03}, -400, 12},
+		{"Percent rounds to the cent", &repository.FeeRule{Kind: repository.FeeKindPercent, Percent: 0.03}, -333.33, 10},
+		{"Percent below the minimum", &repository.FeeRule{Kind: repository.FeeKindPercent, Percent: 0.03, MinAmount: 5}, -50, 5},
+		{"Percent above the maximum", &repository.FeeRule{Kind: repository.FeeKindPercent, Percent: 0.03, MaxAmount: 20}, -1000, 20},
+		{"First tier", tiered, -80, 1},
+		{"First tier bound is inclusive", tiered, -100, 1},
+		{"Last tier", tiered, -500, 5},
+		{"Last tier above the maximum", tiered, -2000, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, service.FeeAmount(tt.rule, tt.amount))
+		})
+	}
+}
+
+func TestScreenTransaction(t *testing.T) {
+	account := &repository.Account{ID: 1, Product: "credit", CreatedAt: testNow.AddDate(0, 0, -3)}
+	withdrawalVelocity := &repository.ScreeningRule{Code: "withdrawal_velocity", OperationTypeIDs: []int64{3}, Kind: "count", WindowHours: 24, Threshold: 2, Action: "deny"}
+	dailyDebits := &repository.ScreeningRule{Code: "daily_debit_limit", OperationTypeIDs: []int64{1, 3}, Kind: "amount", WindowHours: 24, Threshold: 100, Action: "deny"}
+	newAccount := &repository.ScreeningRule{Code: "new_account_large_debit", OperationTypeIDs: []int64{1, 3}, Kind: "single_amount", Threshold: 50, MaxAccountAgeDays: 7, Action: "deny"}
+	largeDebit := &repository.ScreeningRule{Code: "large_debit", OperationTypeIDs: []int64{1, 3}, Kind: "single_amount", Threshold: 40, Action: "review"}
+	prepaidOnly := &repository.ScreeningRule{Code: "prepaid_debit", Product: "prepaid", OperationTypeIDs: []int64{1}, Kind: "single_amount", Action: "deny"}
+	activity := []*repository.Transaction{
+		{OperationTypeID: 3, Amount: -30, EventDate: testNow.Add(-25 * time.Hour)}, // outside the window
+		{OperationTypeID: 3, Amount: -30, EventDate: testNow.Add(-2 * time.Hour)},
+		{OperationTypeID: 1, Amount: -20, EventDate: testNow.Add(-time.Hour)},
+		{OperationTypeID: 4, Amount: 500, EventDate: testNow.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name            string
+		rules           []*repository.ScreeningRule
+		account         *repository.Account
+		operationTypeID int64
+		amount          float64
+		outcome         string
+		reasonCodes     []string
+	}{
+		{"No rule trips", []*repository.ScreeningRule{withdrawalVelocity, dailyDebits, prepaidOnly}, account, 3, -10, "allow", []string{}},
+		{"Count up to the threshold", []*repository.ScreeningRule{withdrawalVelocity}, account, 3, -10, "allow", []string{}},
+		{"Count over the threshold", []*repository.ScreeningRule{{Code: "c", OperationTypeIDs: []int64{3}, Kind: "count", WindowHours: 48, Threshold: 2, Action: "deny"}}, account, 3, -10, "deny", []string{"c"}},
+		{"Amount over the threshold", []*repository.ScreeningRule{dailyDebits}, account, 1, -51, "deny", []string{"daily_debit_limit"}},
+		{"Amount up to the threshold", []*repository.ScreeningRule{dailyDebits}, account, 1, -50, "allow", []string{}},
+		{"Single amount on a new account", []*repository.ScreeningRule{newAccount}, account, 1, -60, "deny", []string{"new_account_large_debit"}},
+		{"Single amount on an older account", []*repository.ScreeningRule{newAccount}, &repository.Account{Product: "credit", CreatedAt: testNow.AddDate(0, 0, -8)}, 1, -60, "allow", []string{}},
+		{"Review", []*repository.ScreeningRule{largeDebit}, account, 1, -45, "review", []string{"large_debit"}},
+		{"Denials come first", []*repository.ScreeningRule{largeDebit, newAccount}, account, 1, -60, "deny", []string{"new_account_large_debit", "large_debit"}},
+		{"Other operation types are not screened", []*repository.ScreeningRule{largeDebit}, account, 4, 1000, "allow", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, reasonCodes := service.ScreenTransaction(tt.rules, tt.account, activity, tt.operationTypeID, tt.amount, testNow)
+			assert.Equal(t, tt.outcome, outcome)
+			assert.Equal(t, tt.reasonCodes, reasonCodes)
+		})
+	}
+}
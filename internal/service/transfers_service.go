@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func NewTransfersService(
+	transferRepo repository.TransfersRepository,
+	trxRepo repository.TransactionsRepository,
+	accRepo repository.AccountsRepository,
+	ledgerRepo repository.LedgerRepository,
+	auditRepo repository.AuditRepository,
+	transactor repository.Transactor,
+	clock clock.Clock,
+) TransfersService {
+	return &transfersService{
+		transferRepo: transferRepo,
+		trxRepo:      trxRepo,
+		accRepo:      accRepo,
+		auditRepo:    auditRepo,
+		transactor:   transactor,
+		clock:        clock,
+		discharger: &transactionsService{
+			trxRepo:    trxRepo,
+			accRepo:    accRepo,
+			ledgerRepo: ledgerRepo,
+			auditRepo:  auditRepo,
+			transactor: transactor,
+			clock:      clock,
+		},
+	}
+}
+
+// CreateTransfer moves amount from the source to the destination account in one unit of work.
+// It posts a debit leg on the source and a credit leg on the destination, and the credit
+// discharges the destination's outstanding debits like a credit voucher. Both accounts are
+// locked in id order first, so opposite transfers between the same accounts cannot deadlock.
+// Each leg is journaled against transfer_clearing, which nets to zero across the pair, and the
+// transfer and its legs are recorded in the audit log.
+func (s *transfersService) CreateTransfer(ctx context.Context, sourceAccountID, destinationAccountID int64, amount float64) (_ *repository.Transfer, _ []*repository.Transaction, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "TransfersService.CreateTransfer", trace.WithAttributes(
+		attribute.Int64("transfer.source_account.id", sourceAccountID),
+		attribute.Int64("transfer.destination_account.id", destinationAccountID),
+	))
+	defer func() { endSpan(span, err) }()
+
+	if sourceAccountID == destinationAccountID {
+		return nil, nil, ErrSameAccountTransfer
+	}
+	if amount <= 0 {
+		if amount == 0 {
+			return nil, nil, ErrInvalidAmount
+		}
+		return nil, nil, ErrNegativeAmount
+	}
+	amount = FormatAmount(amount)
+
+	var transfer *repository.Transfer
+	var legs []*repository.Transaction
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.lockActiveAccounts(ctx, sourceAccountID, destinationAccountID); err != nil {
+			return err
+		}
+
+		var err error
+		transfer, err = s.transferRepo.InsertTransfer(ctx, sourceAccountID, destinationAccountID, amount)
+		if err != nil {
+			return fmt.Errorf("failed to insert transfer: %w", err)
+		}
+		if err := recordAudit(ctx, s.auditRepo, AuditActionTransferCreated, AuditEntityTransfer, transfer.ID, nil, transfer); err != nil {
+			return err
+		}
+
+		now := s.clock.Now()
+		debit, err := s.trxRepo.InsertTransferLeg(ctx, transfer.ID, sourceAccountID, OperationTypeTransferDebit, -amount, -amount, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert debit leg: %w", err)
+		}
+		credit, err := s.trxRepo.InsertTransferLeg(ctx, transfer.ID, destinationAccountID, OperationTypeTransferCredit, amount, amount, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert credit leg: %w", err)
+		}
+		for _, leg := range []*repository.Transaction{debit, credit} {
+			if err := s.discharger.chainTransaction(ctx, leg); err != nil {
+				return err
+			}
+			if err := auditTransactionCreated(ctx, s.auditRepo, leg); err != nil {
+				return err
+			}
+			if err := s.discharger.postJournalEntry(ctx, transactionEntry(leg)); err != nil {
+				return err
+			}
+		}
+
+		if err := s.discharger.processPaymentDischarge(ctx, credit); err != nil {
+			return fmt.Errorf("payment discharge error: %w", err)
+		}
+
+		legs = []*repository.Transaction{debit, credit}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return transfer, legs, nil
+}
+
+// lockActiveAccounts locks both accounts and checks they exist and are active
+func (s *transfersService) lockActiveAccounts(ctx context.Context, sourceAccountID, destinationAccountID int64) error {
+	accounts, err := s.accRepo.LockAccounts(ctx, sourceAccountID, destinationAccountID)
+	if err != nil {
+		return ErrFailedToFetchAccount
+	}
+
+	found := make(map[int64]*repository.Account, len(accounts))
+	for _, account := range accounts {
+		found[account.ID] = account
+	}
+
+	source, ok := found[sourceAccountID]
+	if !ok {
+		return ErrSourceAccountNotFound
+	}
+	destination, ok := found[destinationAccountID]
+	if !ok {
+		return ErrDestinationAccountNotFound
+	}
+	if source.Status != AccountStatusActive || destination.Status != AccountStatusActive {
+		return ErrAccountNotActive
+	}
+	return nil
+}
+
+// GetTransfer retrieves a transfer and its legs by transferID
+func (s *transfersService) GetTransfer(ctx context.Context, transferID int64) (*repository.Transfer, []*repository.Transaction, error) {
+	transfer, err := s.transferRepo.GetTransferByID(ctx, transferID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrTransferNotFound
+		}
+		return nil, nil, ErrFailedToFetchTransfer
+	}
+
+	legs, err := s.trxRepo.GetTransactionsByTransferID(ctx, transferID)
+	if err != nil {
+		return nil, nil, ErrFailedToFetchTransfer
+	}
+	return transfer, legs, nil
+}
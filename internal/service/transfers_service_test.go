@@ -0,0 +1,168 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"github.com/ashwingopalsamy/transactions-service/internal/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTransfersService(mockDB pgxmock.PgxPoolIface) service.TransfersService {
+	return service.NewTransfersService(
+		repository.NewTransfersRepository(mockDB),
+		repository.NewTransactionsRepository(mockDB),
+		repository.NewAccountsRepository(mockDB),
+		repository.NewLedgerRepository(mockDB),
+		repository.NewAuditRepository(mockDB),
+		repository.NewTransactor(mockDB),
+		clock.Fixed(testNow),
+	)
+}
+
+func TestCreateTransfer(t *testing.T) {
+	t.Run("Valid transfer should post both legs and discharge the destination", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transferService := newTransfersService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).
+			WithArgs([]int64{2, 1}).
+			WillReturnRows(pgxmock.NewRows(accountColumns).
+				AddRow(int64(1), int64(7), "1", "credit", "active", accountCreatedAt).
+				AddRow(int64(2), int64(8), "2", "credit", "active", accountCreatedAt))
+		mockDB.ExpectQuery(`INSERT INTO transfers`).
+			WithArgs(int64(2), int64(1), 25.5, "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(10), time.Now()))
+		expectAuditEvent(mockDB, service.AuditActionTransferCreated, service.AuditEntityTransfer, 10)
+		mockDB.ExpectQuery(`INSERT INTO transactions \(transfer_id`).
+			WithArgs(int64(10), int64(2), int64(5), -25.5, -25.5, testNow, "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(100), time.Now(), -25.5))
+		mockDB.ExpectQuery(`INSERT INTO transactions \(transfer_id`).
+			WithArgs(int64(10), int64(1), int64(6), 25.5, 25.5, testNow, "").
+			WillReturnRows(pgxmock.NewRows([]string{"id", "event_date", "balance"}).AddRow(int64(101), time.Now(), 25.5))
+		expectChainLink(mockDB, 100)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 100)
+		expectJournalEntry(mockDB, "transfer_debit", 100, "customer_receivable:2", "transfer_clearing", []int64{100, 0}, 25.5)
+		expectChainLink(mockDB, 101)
+		expectAuditEvent(mockDB, service.AuditActionTransactionCreated, service.AuditEntityTransaction, 101)
+		expectJournalEntry(mockDB, "transfer_credit", 101, "customer_credit:1", "transfer_clearing", []int64{101, 0}, -25.5)
+		expectDischarge(mockDB, 1, 101, 25.5, []int64{50}, []float64{-10}, []float64{10})
+		mockDB.ExpectCommit()
+
+		transfer, legs, err := transferService.CreateTransfer(ctx, 2, 1, 25.5)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), transfer.ID)
+		assert.Len(t, legs, 2)
+		assert.Equal(t, 15.5, legs[1].Balance)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Blocked destination should be rejected and rolled back", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transferService := newTransfersService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).
+			WithArgs([]int64{1, 2}).
+			WillReturnRows(pgxmock.NewRows(accountColumns).
+				AddRow(int64(1), int64(7), "1", "credit", "active", accountCreatedAt).
+				AddRow(int64(2), int64(8), "2", "credit", "blocked", accountCreatedAt))
+		mockDB.ExpectRollback()
+
+		transfer, legs, err := transferService.CreateTransfer(ctx, 1, 2, 10)
+		assert.ErrorIs(t, err, service.ErrAccountNotActive)
+		assert.Nil(t, transfer)
+		assert.Nil(t, legs)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown destination should be rejected", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transferService := newTransfersService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery(`SELECT .* FROM accounts a .* FOR UPDATE OF a`).
+			WithArgs([]int64{1, 999}).
+			WillReturnRows(pgxmock.NewRows(accountColumns).AddRow(int64(1), int64(7), "1", "credit", "active", accountCreatedAt))
+		mockDB.ExpectRollback()
+
+		_, _, err = transferService.CreateTransfer(ctx, 1, 999, 10)
+		assert.ErrorIs(t, err, service.ErrDestinationAccountNotFound)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid requests should fail before reaching the database", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transferService := newTransfersService(mockDB)
+		ctx := context.Background()
+
+		_, _, err = transferService.CreateTransfer(ctx, 1, 1, 10)
+		assert.ErrorIs(t, err, service.ErrSameAccountTransfer)
+		_, _, err = transferService.CreateTransfer(ctx, 1, 2, 0)
+		assert.ErrorIs(t, err, service.ErrInvalidAmount)
+		_, _, err = transferService.CreateTransfer(ctx, 1, 2, -5)
+		assert.ErrorIs(t, err, service.ErrNegativeAmount)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func TestGetTransfer(t *testing.T) {
+	t.Run("Existing transfer should return both legs", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transferService := newTransfersService(mockDB)
+		ctx := context.Background()
+
+		mockDB.ExpectQuery(`SELECT .* FROM transfers WHERE id = \$1`).
+			WithArgs(int64(10)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "source_account_id", "destination_account_id", "amount", "created_at"}).
+				AddRow(int64(10), int64(2), int64(1), 25.5, time.Now()))
+		mockDB.ExpectQuery(`SELECT .* FROM transactions\s+WHERE transfer_id = \$1`).
+			WithArgs(int64(10)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "account_id", "operation_type_id", "amount", "balance", "event_date"}).
+				AddRow(int64(100), int64(2), int64(5), -25.5, -25.5, time.Now()).
+				AddRow(int64(101), int64(1), int64(6), 25.5, 15.5, time.Now()))
+
+		transfer, legs, err := transferService.GetTransfer(ctx, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), transfer.SourceAccountID)
+		assert.Len(t, legs, 2)
+		assert.NoError(t, mockDB.ExpectationsWereMet())
+	})
+
+	t.Run("Unknown transfer should fail", func(t *testing.T) {
+		mockDB, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		defer mockDB.Close()
+
+		transferService := newTransfersService(mockDB)
+
+		mockDB.ExpectQuery(`SELECT .* FROM transfers WHERE id = \$1`).WithArgs(int64(999)).WillReturnError(pgx.ErrNoRows)
+
+		_, _, err = transferService.GetTransfer(context.Background(), 999)
+		assert.ErrorIs(t, err, service.ErrTransferNotFound)
+	})
+}
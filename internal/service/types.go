@@ -3,35 +3,395 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/ashwingopalsamy/transactions-service/internal/clock"
+	"github.com/ashwingopalsamy/transactions-service/internal/config"
+	"github.com/ashwingopalsamy/transactions-service/internal/executor"
 	"github.com/ashwingopalsamy/transactions-service/internal/repository"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+type CustomersService interface {
+	CreateCustomer(ctx context.Context, customer NewCustomer) (*repository.Customer, error)
+	GetCustomer(ctx context.Context, customerID int64) (*repository.Customer, error)
+	GetCustomerAccounts(ctx context.Context, customerID int64) ([]*repository.Account, error)
+}
+
 type AccountsService interface {
-	CreateAccount(ctx context.Context, documentNumber string) (*repository.Account, error)
+	CreateAccount(ctx context.Context, customerID int64, documentNumber, product string) (*repository.Account, error)
 	GetAccount(ctx context.Context, accountID int64) (*repository.Account, error)
+	SetAccountStatus(ctx context.Context, accountID int64, status string) (*repository.Account, error)
 }
 
 type TransactionsService interface {
-	CreateTransaction(ctx context.Context, accountID, operationTypeID int64, amount float64) (*repository.Transaction, error)
+	CreateTransaction(ctx context.Context, transaction NewTransaction) (*repository.Transaction, error)
+	GetTransaction(ctx context.Context, transactionID int64) (*repository.Transaction, error)
+	CreateCharge(ctx context.Context, accountID, operationTypeID int64, amount float64) (*repository.Transaction, error)
+	CreateTransactionBatch(ctx context.Context, transactions []NewTransaction, mode string) ([]BatchItemResult, error)
+}
+
+type TransfersService interface {
+	CreateTransfer(ctx context.Context, sourceAccountID, destinationAccountID int64, amount float64) (*repository.Transfer, []*repository.Transaction, error)
+	GetTransfer(ctx context.Context, transferID int64) (*repository.Transfer, []*repository.Transaction, error)
+}
+
+type StatementsService interface {
+	GetBillingCycle(ctx context.Context, accountID int64) (*repository.BillingCycle, error)
+	SetBillingCycle(ctx context.Context, accountID int64, closingDay, dueDayOffset int) (*repository.BillingCycle, error)
+	CloseCycles(ctx context.Context, closingDate time.Time) ([]*repository.Statement, error)
+	GetStatements(ctx context.Context, accountID int64) ([]*repository.Statement, error)
+	GetStatement(ctx context.Context, accountID, statementID int64) (*repository.Statement, error)
+}
+
+type AccrualsService interface {
+	Accrue(ctx context.Context) ([]*repository.AccrualRun, error)
+}
+
+type SandboxService interface {
+	Now() time.Time
+	AdvanceClock(ctx context.Context, to time.Time) (*ClockAdvance, error)
+}
+
+type LedgerService interface {
+	VerifyAccount(ctx context.Context, accountID int64) (*LedgerReport, error)
+}
+
+type AuditService interface {
+	GetAuditEvents(ctx context.Context, filter repository.AuditEventFilter) ([]*repository.AuditEvent, error)
+	RecordConfigChange(ctx context.Context, setting string, before, after any) error
+}
+
+type IntegrityService interface {
+	VerifyChain(ctx context.Context, accountID int64) (*ChainReport, error)
+	VerifyChains(ctx context.Context) ([]*ChainReport, error)
+}
+
+type ExportService interface {
+	ExportTransactions(ctx context.Context, accountID int64, from, to time.Time, sink TransactionSink) error
+}
+
+type TransactionRequestsService interface {
+	SubmitTransaction(ctx context.Context, transaction NewTransaction) (*repository.TransactionRequest, error)
+	GetTransactionRequest(ctx context.Context, requestID int64) (*repository.TransactionRequest, error)
+	ProcessNext(ctx context.Context) (bool, error)
+	Run(ctx context.Context, workers int, pollInterval time.Duration)
+}
+
+// TransactionSink receives an account's exported transactions: Begin once the account is found, with the
+// balance the period opens with, Write per transaction as it is read, and End after the last one
+type TransactionSink interface {
+	Begin(account *repository.Account, from, to time.Time, opening float64) error
+	Write(txn *repository.Transaction) error
+	End() error
+}
+
+type customersService struct {
+	custRepo   repository.CustomersRepository
+	accRepo    repository.AccountsRepository
+	auditRepo  repository.AuditRepository
+	transactor repository.Transactor
+	clock      clock.Clock
 }
 
 type accountsService struct {
-	accRepo repository.AccountsRepository
+	accRepo    repository.AccountsRepository
+	custRepo   repository.CustomersRepository
+	auditRepo  repository.AuditRepository
+	transactor repository.Transactor
+}
+
+// NewCustomer holds the details of a customer to register
+type NewCustomer struct {
+	DocumentNumber string
+	Name           string
+	BirthDate      string // YYYY-MM-DD
+	Email          string
+	Phone          string
+}
+
+// NewTransaction holds the details of a transaction to create
+type NewTransaction struct {
+	AccountID         int64
+	OperationTypeID   int64
+	Amount            float64
+	MerchantReference string    // the processor's reference of the purchase, if any
+	EventDate         time.Time // when the transaction took place, if earlier than it is posted
+	Force             bool      // posts the transaction even when it looks like a duplicate; for operators only
+}
+
+// Batch modes: an atomic batch posts every item or none, a best-effort one posts the items that pass and
+// reports why the others did not
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+)
+
+// MaxBatchItems caps the items of a batch
+const MaxBatchItems = 10000
+
+// BatchItemResult is the outcome of the item of a batch at Index: the posted transaction, or the error
+// that kept it from being posted
+type BatchItemResult struct {
+	Index       int
+	Transaction *repository.Transaction
+	Err         error
+}
+
+// BatchItemError fails an atomic batch at the item at Index; nothing of the batch is posted
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// Actions on a suspected duplicate: post it flagged with the transaction it duplicates, or reject it
+const (
+	DuplicateActionFlag   = config.DuplicateActionFlag
+	DuplicateActionReject = config.DuplicateActionReject
+)
+
+// DuplicatePolicy decides what happens to a transaction matching the account, operation type, amount and
+// merchant reference of one posted within Window; a zero Window disables the check
+type DuplicatePolicy struct {
+	Window time.Duration
+	Action string
 }
 
+// BackdatingPolicy bounds how long before it is posted a transaction may have taken place; a zero Window
+// rejects back-dated transactions
+type BackdatingPolicy struct {
+	Window time.Duration
+}
+
+// Account products
+const (
+	ProductCredit  = "credit"
+	ProductPrepaid = "prepaid"
+)
+
+// Account statuses; only active accounts accept transactions and transfers, and closing is final
+const (
+	AccountStatusActive  = "active"
+	AccountStatusBlocked = "blocked"
+	AccountStatusClosed  = "closed"
+)
+
+// Operation types posted by transfers rather than through the transactions API
+const (
+	OperationTypeTransferDebit  int64 = 5
+	OperationTypeTransferCredit int64 = 6
+)
+
+// Operation types of the charges levied by the accrual job rather than through the transactions API
+const (
+	OperationTypeInterest int64 = 7
+	OperationTypeLateFee  int64 = 8
+)
+
+// OperationTypeFee is the operation type of the fees posted with the transactions that incur them
+const OperationTypeFee int64 = 9
+
+// Audit actions, named after the entity they change
+const (
+	AuditActionCustomerCreated           = "customer.created"
+	AuditActionAccountCreated            = "account.created"
+	AuditActionAccountStatusChanged      = "account.status_changed"
+	AuditActionTransactionCreated        = "transaction.created"
+	AuditActionTransactionBalanceUpdated = "transaction.balance_updated"
+	AuditActionTransferCreated           = "transfer.created"
+	AuditActionBillingCycleUpdated       = "billing_cycle.updated"
+	AuditActionConfigUpdated             = "config.updated"
+)
+
+// Audited entity types; config is the service's runtime configuration, which has no id
+const (
+	AuditEntityCustomer     = "customer"
+	AuditEntityAccount      = "account"
+	AuditEntityTransaction  = "transaction"
+	AuditEntityTransfer     = "transfer"
+	AuditEntityBillingCycle = "billing_cycle"
+	AuditEntityConfig       = "config"
+)
+
+// Page sizes of audit event listings
+const (
+	DefaultAuditEventsLimit = 100
+	MaxAuditEventsLimit     = 500
+)
+
+// dateLayout is the format of calendar dates such as birth dates and statement periods
+const dateLayout = "2006-01-02"
+
 type transactionsService struct {
+	trxRepo       repository.TransactionsRepository
+	accRepo       repository.AccountsRepository
+	ledgerRepo    repository.LedgerRepository
+	feeRepo       repository.FeesRepository
+	screeningRepo repository.ScreeningRepository
+	auditRepo     repository.AuditRepository
+	billingRepo   repository.BillingRepository
+	transactor    repository.Transactor
+	clock         clock.Clock
+	duplicates    DuplicatePolicy
+	backdating    BackdatingPolicy
+}
+
+type transfersService struct {
+	transferRepo repository.TransfersRepository
+	trxRepo      repository.TransactionsRepository
+	accRepo      repository.AccountsRepository
+	auditRepo    repository.AuditRepository
+	transactor   repository.Transactor
+	clock        clock.Clock
+	discharger   *transactionsService
+}
+
+type ledgerService struct {
+	ledgerRepo repository.LedgerRepository
+	trxRepo    repository.TransactionsRepository
+	accRepo    repository.AccountsRepository
+}
+
+type statementsService struct {
+	billingRepo repository.BillingRepository
+	accRepo     repository.AccountsRepository
+	trxRepo     repository.TransactionsRepository
+	ledgerRepo  repository.LedgerRepository
+	auditRepo   repository.AuditRepository
+	transactor  repository.Transactor
+}
+
+type accrualsService struct {
+	accrualRepo repository.AccrualsRepository
+	billingRepo repository.BillingRepository
+	accRepo     repository.AccountsRepository
+	trxRepo     repository.TransactionsRepository
+	trxService  TransactionsService
+	transactor  repository.Transactor
+	clock       clock.Clock
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+type integrityService struct {
+	trxRepo repository.TransactionsRepository
+	accRepo repository.AccountsRepository
+}
+
+type exportService struct {
 	trxRepo repository.TransactionsRepository
 	accRepo repository.AccountsRepository
 }
 
+type transactionRequestsService struct {
+	requestsRepo repository.TransactionRequestsRepository
+	trxRepo      repository.TransactionsRepository
+	accRepo      repository.AccountsRepository
+	trxService   TransactionsService
+	transactor   repository.Transactor
+	wake         chan struct{} // signals idle workers that a request was submitted
+}
+
+// serializedTransactionsService posts the transactions of an account one at a time on the executor's shard
+// of the account, ahead of the account lock
+type serializedTransactionsService struct {
+	TransactionsService
+	executor *executor.Executor
+}
+
+type sandboxService struct {
+	mu             sync.Mutex // serializes advances so each day's jobs run once
+	clock          *clock.Virtual
+	stmtService    StatementsService
+	accrualService AccrualsService
+}
+
+// ClockAdvance is the outcome of moving the sandbox clock forward: the statements closed and the
+// accrual runs made on the days crossed
+type ClockAdvance struct {
+	From        time.Time
+	To          time.Time
+	Statements  []*repository.Statement
+	AccrualRuns []*repository.AccrualRun
+}
+
+// LedgerReport is the outcome of verifying an account's transaction balances against the ledger
+type LedgerReport struct {
+	AccountID  int64
+	Balances   []*repository.LedgerAccountBalance
+	Consistent bool
+	Mismatches []LedgerMismatch
+}
+
+// LedgerMismatch is a transaction whose stored balance differs from the one derived from the ledger
+type LedgerMismatch struct {
+	TransactionID    int64
+	ProjectedBalance float64
+	LedgerBalance    float64
+}
+
+// ChainReport is the outcome of recomputing an account's transaction hash chain. Unchained counts the
+// transactions posted before the chain started; Head is the hash of the last link.
+type ChainReport struct {
+	AccountID int64
+	Intact    bool
+	Length    int
+	Unchained int
+	Head      string
+	Break     *ChainBreak
+}
+
+// ChainBreak is the first link of a chain that does not hold: the transaction whose stored hash or
+// previous hash differs from the expected one, or that was left out of the chain
+type ChainBreak struct {
+	TransactionID int64
+	Reason        string
+	Expected      string
+	Actual        string
+}
+
+// Reasons a chain link breaks
+const (
+	ChainBreakHashMismatch     = "hash_mismatch"
+	ChainBreakPrevHashMismatch = "prev_hash_mismatch"
+	ChainBreakMissingHash      = "missing_hash"
+)
+
+// Customer-related errors
+var (
+	ErrCustomerNotFound      = errors.New("customer not found")
+	ErrCustomerAlreadyExists = errors.New("document_number already exists")
+	ErrInvalidCustomerName   = errors.New("name cannot be empty")
+	ErrInvalidBirthDate      = errors.New("invalid birth_date: must be a past date formatted as YYYY-MM-DD")
+	ErrInvalidEmail          = errors.New("invalid email")
+	ErrFailedToFetchCustomer = errors.New("failed to fetch customer")
+)
+
 // Account-related errors
 var (
-	ErrAccountNotFound       = errors.New("account not found")
-	ErrAccountAlreadyExists  = errors.New("document_number already exists")
-	ErrInvalidDocumentNumber = errors.New("document_number cannot be empty")
-	ErrFailedToFetchAccount  = errors.New("failed to fetch account")
+	ErrAccountNotFound        = errors.New("account not found")
+	ErrAccountAlreadyExists   = errors.New("customer already holds an account of this product")
+	ErrInvalidDocumentNumber  = errors.New("document_number cannot be empty")
+	ErrDocumentNumberMismatch = errors.New("document_number does not match the customer's")
+	ErrInvalidProduct         = errors.New("invalid product: must be credit or prepaid")
+	ErrInvalidAccountStatus   = errors.New("invalid status: must be active, blocked or closed")
+	ErrAccountClosed          = errors.New("account is closed")
+	ErrAccountNotActive       = errors.New("account is blocked or closed")
+	ErrFailedToFetchAccount   = errors.New("failed to fetch account")
 )
 
 // Transaction-related errors
@@ -41,6 +401,117 @@ var (
 	ErrInvalidAmount        = errors.New("invalid amount: amount must not be zero")
 	ErrNegativeAmount       = errors.New("invalid amount: amount must not be negative")
 	ErrTransactionFailed    = errors.New("failed to insert transaction")
+	ErrTransactionDenied    = errors.New("transaction denied by screening rules")
+	ErrDuplicateTransaction = errors.New("suspected duplicate transaction")
+	ErrTransactionNotFound  = errors.New("transaction not found")
+	ErrEventDateInFuture    = errors.New("invalid event_date: must not be in the future")
+	ErrEventDateTooOld      = errors.New("invalid event_date: older than the back-dating window")
+	ErrEventDateClosed      = errors.New("invalid event_date: falls in a statement period already closed")
+	ErrFailedToFetchTrx     = errors.New("failed to fetch transaction")
+)
+
+// ScreeningDeniedError reports a transaction denied by screening rules; ReasonCodes are the codes of
+// the rules that denied it. It matches ErrTransactionDenied.
+type ScreeningDeniedError struct {
+	ReasonCodes []string
+}
+
+func (e *ScreeningDeniedError) Error() string {
+	return ErrTransactionDenied.Error() + ": " + strings.Join(e.ReasonCodes, ", ")
+}
+
+func (e *ScreeningDeniedError) Is(target error) bool {
+	return target == ErrTransactionDenied
+}
+
+// DuplicateTransactionError reports a transaction rejected as a resend of Original. It matches
+// ErrDuplicateTransaction.
+type DuplicateTransactionError struct {
+	Original *repository.Transaction
+}
+
+func (e *DuplicateTransactionError) Error() string {
+	return fmt.Sprintf("%s: matches transaction %d posted at %s", ErrDuplicateTransaction, e.Original.ID, e.Original.EventDate.Format(time.RFC3339))
+}
+
+func (e *DuplicateTransactionError) Is(target error) bool {
+	return target == ErrDuplicateTransaction
+}
+
+// Batch-related errors
+var (
+	ErrEmptyBatch       = errors.New("batch has no transactions")
+	ErrBatchTooLarge    = fmt.Errorf("batch exceeds %d transactions", MaxBatchItems)
+	ErrInvalidBatchMode = fmt.Errorf("invalid mode: must be %s or %s", BatchModeAtomic, BatchModeBestEffort)
+)
+
+// Error codes of failed transaction requests: rejected requests report the code of their rejection, the
+// screening rule's for denials, and requests that could not be processed report RequestErrCodeFailed
+const (
+	RequestErrCodeDuplicate = "duplicate_transaction"
+	RequestErrCodeRejected  = "transaction_error"
+	RequestErrCodeFailed    = "processing_error"
+)
+
+// Transaction request-related errors
+var (
+	ErrTransactionRequestNotFound = errors.New("transaction request not found")
+	ErrFailedToFetchTrxRequest    = errors.New("failed to fetch transaction request")
+	ErrFailedToQueueTrx           = errors.New("failed to queue transaction")
+	ErrFailedToProcessTrxRequest  = errors.New("failed to process transaction")
+)
+
+// Executor-related errors
+var (
+	ErrTrxQueueFull = errors.New("too many transactions in progress for the account, retry later")
+	ErrTrxTimeout   = errors.New("transaction timed out before it could be posted")
+)
+
+// Transfer-related errors
+var (
+	ErrSameAccountTransfer        = errors.New("source and destination accounts must differ")
+	ErrSourceAccountNotFound      = errors.New("invalid source_account_id: account does not exist")
+	ErrDestinationAccountNotFound = errors.New("invalid destination_account_id: account does not exist")
+	ErrTransferNotFound           = errors.New("transfer not found")
+	ErrFailedToFetchTransfer      = errors.New("failed to fetch transfer")
+)
+
+// Billing-related errors
+var (
+	ErrInvalidClosingDay      = errors.New("invalid closing_day: must be between 1 and 28")
+	ErrInvalidDueDayOffset    = errors.New("invalid due_day_offset: must be between 1 and 60")
+	ErrBillingNotSupported    = errors.New("billing cycles apply to credit accounts only")
+	ErrStatementNotFound      = errors.New("statement not found")
+	ErrStatementAlreadyClosed = errors.New("statement period already closed")
+	ErrFailedToFetchStatement = errors.New("failed to fetch statement")
+)
+
+// Accrual-related errors
+var (
+	ErrAccrualAlreadyRun = errors.New("accrual already run for the account and day")
+)
+
+// Sandbox-related errors
+var (
+	ErrClockBackwards     = errors.New("invalid time: the sandbox clock only moves forward")
+	ErrClockAdvanceTooFar = errors.New("invalid time: the sandbox clock advances at most 366 days at once")
+)
+
+// Ledger-related errors
+var (
+	ErrUnbalancedJournalEntry = errors.New("journal entry does not balance")
+	ErrFailedToFetchLedger    = errors.New("failed to fetch ledger")
+)
+
+// Audit-related errors
+var (
+	ErrInvalidAuditFilter       = errors.New("invalid audit event filter")
+	ErrFailedToFetchAuditEvents = errors.New("failed to fetch audit events")
+)
+
+// Integrity-related errors
+var (
+	ErrFailedToFetchChain = errors.New("failed to fetch transaction chain")
 )
 
 // determinePgxError maps pgx constraint violations to known errors.
@@ -52,6 +523,9 @@ func determinePgxError(err error) error {
 	errMsg := err.Error()
 
 	if strings.Contains(errMsg, "violates foreign key constraint") {
+		if strings.Contains(errMsg, "accounts_customer_id_fkey") {
+			return ErrCustomerNotFound
+		}
 		if strings.Contains(errMsg, "transactions_account_id_fkey") {
 			return ErrInvalidAccountID
 		}
@@ -60,9 +534,37 @@ func determinePgxError(err error) error {
 		}
 	}
 
+	if strings.Contains(errMsg, "does not balance") {
+		return ErrUnbalancedJournalEntry
+	}
+
 	if strings.Contains(errMsg, "unique constraint") {
+		if strings.Contains(errMsg, "customers_document_number_key") {
+			return ErrCustomerAlreadyExists
+		}
+		if strings.Contains(errMsg, "statements_account_id_period_end_key") {
+			return ErrStatementAlreadyClosed
+		}
+		if strings.Contains(errMsg, "accrual_runs_pkey") {
+			return ErrAccrualAlreadyRun
+		}
 		return ErrAccountAlreadyExists
 	}
 
 	return err
 }
+
+// endSpan records err on the span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Export-related errors
+var (
+	ErrInvalidExportPeriod = errors.New("invalid period: from must be before to")
+	ErrFailedToExport      = errors.New("failed to export transactions")
+)
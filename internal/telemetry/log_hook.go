@@ -0,0 +1,20 @@
+package telemetry
+
+import (
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceHook adds trace_id and span_id to log events that carry a context with an active span.
+// Attach a context to an event with zerolog's Event.Ctx.
+type TraceHook struct{}
+
+// Run implements zerolog.Hook
+func (TraceHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	spanCtx := trace.SpanContextFromContext(e.GetCtx())
+	if !spanCtx.IsValid() {
+		return
+	}
+
+	e.Str("trace_id", spanCtx.TraceID().String()).Str("span_id", spanCtx.SpanID().String())
+}
@@ -0,0 +1,68 @@
+package telemetry_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func TestTraceIDFromContext(t *testing.T) {
+	setupRecorder(t)
+
+	t.Run("No active span returns empty trace id", func(t *testing.T) {
+		assert.Equal(t, "", telemetry.TraceIDFromContext(context.Background()))
+	})
+
+	t.Run("Active span returns its trace id", func(t *testing.T) {
+		ctx, span := telemetry.Tracer().Start(context.Background(), "test")
+		defer span.End()
+
+		assert.Equal(t, span.SpanContext().TraceID().String(), telemetry.TraceIDFromContext(ctx))
+	})
+}
+
+func TestTraceHook(t *testing.T) {
+	setupRecorder(t)
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Hook(telemetry.TraceHook{})
+
+	ctx, span := telemetry.Tracer().Start(context.Background(), "test")
+	defer span.End()
+
+	logger.Info().Ctx(ctx).Msg("with span")
+	assert.Contains(t, buf.String(), `"trace_id":"`+span.SpanContext().TraceID().String()+`"`)
+
+	buf.Reset()
+	logger.Info().Msg("without span")
+	assert.NotContains(t, buf.String(), "trace_id")
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := setupRecorder(t)
+	tracer := telemetry.NewQueryTracer()
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT id FROM accounts WHERE id = $1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "db.SELECT", spans[0].Name())
+	}
+}
@@ -6,26 +6,29 @@ import (
 	"net/http"
 
 	"github.com/ashwingopalsamy/transactions-service/internal/middleware"
+	"github.com/ashwingopalsamy/transactions-service/internal/telemetry"
 )
 
 // ErrorResponse structures our errors
 type ErrorResponse struct {
-	ID     string `json:"id"`
-	Code   string `json:"code"`
-	Status int    `json:"status"`
-	Title  string `json:"title"`
-	Detail string `json:"detail"`
+	ID      string `json:"id"`
+	TraceID string `json:"trace_id,omitempty"`
+	Code    string `json:"code"`
+	Status  int    `json:"status"`
+	Title   string `json:"title"`
+	Detail  string `json:"detail"`
 }
 
 // WriteError writes error responses in JSON
 func WriteError(w http.ResponseWriter, ctx context.Context, status int, code, title, detail string) {
 	reqID := middleware.GetRequestIDFromContext(ctx)
 	errorResp := ErrorResponse{
-		ID:     reqID,
-		Code:   code,
-		Status: status,
-		Title:  title,
-		Detail: detail,
+		ID:      reqID,
+		TraceID: telemetry.TraceIDFromContext(ctx),
+		Code:    code,
+		Status:  status,
+		Title:   title,
+		Detail:  detail,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,9 @@
+// Package schema embeds the SQL migrations so the service binary can apply them itself.
+package schema
+
+import "embed"
+
+// Migrations holds the goose SQL migrations under migrations/
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,31 @@
+-- +goose Up
+
+-- +goose StatementBegin
+ALTER TABLE accounts
+    ADD COLUMN correlation_id TEXT;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN correlation_id TEXT;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_transactions_correlation_id ON transactions (correlation_id);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_correlation_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN correlation_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE accounts
+    DROP COLUMN correlation_id;
+-- +goose StatementEnd
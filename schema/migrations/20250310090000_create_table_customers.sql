@@ -0,0 +1,81 @@
+-- +goose Up
+
+-- +goose StatementBegin
+CREATE TABLE customers (
+    id BIGSERIAL PRIMARY KEY,
+    document_number TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL DEFAULT '',
+    birth_date DATE,
+    email TEXT,
+    phone TEXT,
+    correlation_id TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER updatedat_timestamp_trigger_customers
+    BEFORE UPDATE ON customers
+    FOR EACH ROW EXECUTE FUNCTION updatedat_timestamp();
+-- +goose StatementEnd
+
+-- Every existing account becomes the single account of a new customer holding its document number
+-- +goose StatementBegin
+INSERT INTO customers (document_number, correlation_id, created_at, updated_at)
+SELECT document_number, correlation_id, created_at, created_at
+FROM accounts
+ORDER BY id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE accounts
+    ADD COLUMN customer_id BIGINT REFERENCES customers (id),
+    ADD COLUMN product TEXT NOT NULL DEFAULT 'credit' CHECK (product IN ('credit', 'prepaid'));
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE accounts a
+SET customer_id = c.id
+FROM customers c
+WHERE c.document_number = a.document_number;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE accounts
+    ALTER COLUMN customer_id SET NOT NULL,
+    DROP COLUMN document_number,
+    ADD CONSTRAINT accounts_customer_id_product_key UNIQUE (customer_id, product);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- Rolling back fails if a customer holds more than one account
+-- +goose StatementBegin
+ALTER TABLE accounts
+    ADD COLUMN document_number TEXT;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE accounts a
+SET document_number = c.document_number
+FROM customers c
+WHERE c.id = a.customer_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE accounts
+    ALTER COLUMN document_number SET NOT NULL,
+    ADD CONSTRAINT accounts_document_number_key UNIQUE (document_number),
+    DROP CONSTRAINT accounts_customer_id_product_key,
+    DROP COLUMN product,
+    DROP COLUMN customer_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS updatedat_timestamp_trigger_customers ON customers;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS customers;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- +goose StatementBegin
+ALTER TABLE accounts
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'blocked', 'closed'));
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+ALTER TABLE accounts
+    DROP COLUMN status;
+-- +goose StatementEnd
@@ -0,0 +1,54 @@
+-- +goose Up
+
+-- +goose StatementBegin
+CREATE TABLE transfers (
+    id BIGSERIAL PRIMARY KEY,
+    source_account_id BIGINT NOT NULL REFERENCES accounts(id),
+    destination_account_id BIGINT NOT NULL REFERENCES accounts(id),
+    amount NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    correlation_id TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CHECK (source_account_id <> destination_account_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN transfer_id BIGINT REFERENCES transfers(id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_transactions_transfer_id ON transactions (transfer_id) WHERE transfer_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- The legs of a transfer: the debit behaves like a withdrawal, the credit like a credit voucher
+-- +goose StatementBegin
+INSERT INTO
+    operation_types (id, description)
+VALUES
+    (5, 'Transfer Debit'),
+    (6, 'Transfer Credit');
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+SELECT setval(pg_get_serial_sequence('operation_types', 'id'), (SELECT MAX(id) FROM operation_types));
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DELETE FROM operation_types WHERE id IN (5, 6);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_transfer_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN transfer_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS transfers;
+-- +goose StatementEnd
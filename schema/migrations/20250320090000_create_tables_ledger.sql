@@ -0,0 +1,187 @@
+-- +goose Up
+
+-- Ledger accounts are either global (cash_clearing, fee_income, ...) or belong to one account
+-- (customer_receivable:<id>, customer_credit:<id>). Posting amounts are signed: debits are
+-- positive and credits negative, so every journal entry must sum to zero.
+
+-- +goose StatementBegin
+CREATE TABLE ledger_accounts (
+    code TEXT PRIMARY KEY,
+    type TEXT NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'income', 'expense')),
+    account_id BIGINT REFERENCES accounts(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_ledger_accounts_account_id ON ledger_accounts (account_id) WHERE account_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE journal_entries (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    transaction_id BIGINT REFERENCES transactions(id),
+    correlation_id TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TABLE postings (
+    id BIGSERIAL PRIMARY KEY,
+    journal_entry_id BIGINT NOT NULL REFERENCES journal_entries(id),
+    ledger_account_code TEXT NOT NULL REFERENCES ledger_accounts(code),
+    transaction_id BIGINT REFERENCES transactions(id),
+    amount NUMERIC(15,2) NOT NULL CHECK (amount <> 0)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_postings_journal_entry_id ON postings (journal_entry_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_postings_ledger_account_code ON postings (ledger_account_code);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_postings_transaction_id ON postings (transaction_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION ledger_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME USING ERRCODE = 'restrict_violation';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER journal_entries_append_only
+    BEFORE UPDATE OR DELETE ON journal_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER postings_append_only
+    BEFORE UPDATE OR DELETE ON postings
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+-- +goose StatementEnd
+
+-- Checked at commit, once every posting of the entry has been inserted
+-- +goose StatementBegin
+CREATE FUNCTION journal_entry_balanced() RETURNS TRIGGER AS $$
+DECLARE
+    total NUMERIC;
+BEGIN
+    SELECT COALESCE(SUM(amount), 0) INTO total FROM postings WHERE journal_entry_id = NEW.journal_entry_id;
+    IF total <> 0 THEN
+        RAISE EXCEPTION 'journal entry % does not balance: postings sum to %', NEW.journal_entry_id, total
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'postings_journal_entry_balanced';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE CONSTRAINT TRIGGER postings_journal_entry_balanced
+    AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION journal_entry_balanced();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO ledger_accounts (code, type)
+VALUES
+    ('cash_clearing', 'asset'),
+    ('transfer_clearing', 'asset'),
+    ('fee_income', 'income'),
+    ('interest_income', 'income'),
+    ('opening_balance_adjustment', 'equity');
+-- +goose StatementEnd
+
+-- Backfill: customer ledger accounts for every account, ...
+-- +goose StatementBegin
+INSERT INTO ledger_accounts (code, type, account_id)
+SELECT 'customer_receivable:' || id, 'asset', id FROM accounts
+UNION ALL
+SELECT 'customer_credit:' || id, 'liability', id FROM accounts;
+-- +goose StatementEnd
+
+-- ... one entry per existing transaction for its original amount, ...
+-- +goose StatementBegin
+INSERT INTO journal_entries (kind, transaction_id, correlation_id, created_at)
+SELECT
+    CASE operation_type_id
+        WHEN 3 THEN 'withdrawal'
+        WHEN 4 THEN 'payment'
+        WHEN 5 THEN 'transfer_debit'
+        WHEN 6 THEN 'transfer_credit'
+        ELSE 'purchase'
+    END,
+    id, correlation_id, event_date
+FROM transactions
+ORDER BY id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO postings (journal_entry_id, ledger_account_code, transaction_id, amount)
+SELECT je.id, CASE WHEN t.amount < 0 THEN 'customer_receivable:' ELSE 'customer_credit:' END || t.account_id, t.id, -t.amount
+FROM journal_entries je JOIN transactions t ON t.id = je.transaction_id
+UNION ALL
+SELECT je.id, CASE WHEN t.operation_type_id IN (5, 6) THEN 'transfer_clearing' ELSE 'cash_clearing' END, NULL, t.amount
+FROM journal_entries je JOIN transactions t ON t.id = je.transaction_id;
+-- +goose StatementEnd
+
+-- ... and one entry per account for what was discharged so far. Which credit paid which debit was
+-- never recorded, so debits and credits are discharged in bulk; any residual goes to
+-- opening_balance_adjustment so the entry balances.
+-- +goose StatementBegin
+CREATE TEMPORARY TABLE discharge_backfill ON COMMIT DROP AS
+SELECT account_id, nextval(pg_get_serial_sequence('journal_entries', 'id')) AS journal_entry_id
+FROM transactions
+WHERE balance <> amount
+GROUP BY account_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO journal_entries (id, kind)
+SELECT journal_entry_id, 'discharge_backfill' FROM discharge_backfill;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO postings (journal_entry_id, ledger_account_code, transaction_id, amount)
+SELECT d.journal_entry_id, CASE WHEN t.amount < 0 THEN 'customer_receivable:' ELSE 'customer_credit:' END || t.account_id, t.id, t.amount - t.balance
+FROM transactions t JOIN discharge_backfill d ON d.account_id = t.account_id
+WHERE t.balance <> t.amount
+UNION ALL
+SELECT d.journal_entry_id, 'opening_balance_adjustment', NULL, SUM(t.balance - t.amount)
+FROM transactions t JOIN discharge_backfill d ON d.account_id = t.account_id
+WHERE t.balance <> t.amount
+GROUP BY d.journal_entry_id
+HAVING SUM(t.balance - t.amount) <> 0;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS postings;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS journal_entries;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS ledger_accounts;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP FUNCTION IF EXISTS journal_entry_balanced;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP FUNCTION IF EXISTS ledger_append_only;
+-- +goose StatementEnd
@@ -0,0 +1,72 @@
+-- +goose Up
+
+-- Credit accounts without a row here close on day 1 and are due 10 days later
+-- +goose StatementBegin
+CREATE TABLE billing_cycles (
+    account_id BIGINT PRIMARY KEY REFERENCES accounts(id),
+    closing_day SMALLINT NOT NULL CHECK (closing_day BETWEEN 1 AND 28),
+    due_day_offset SMALLINT NOT NULL CHECK (due_day_offset BETWEEN 1 AND 60),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER updatedat_timestamp_trigger_billing_cycles
+    BEFORE UPDATE ON billing_cycles
+    FOR EACH ROW EXECUTE FUNCTION updatedat_timestamp();
+-- +goose StatementEnd
+
+-- Balances are what the account owes; a negative balance is credit in the customer's favour
+-- +goose StatementBegin
+CREATE TABLE statements (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES accounts(id),
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    opening_balance NUMERIC(15,2) NOT NULL,
+    closing_balance NUMERIC(15,2) NOT NULL,
+    minimum_payment NUMERIC(15,2) NOT NULL CHECK (minimum_payment >= 0),
+    due_date DATE NOT NULL,
+    correlation_id TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT statements_account_id_period_end_key UNIQUE (account_id, period_end),
+    CHECK (period_start <= period_end AND due_date > period_end)
+);
+-- +goose StatementEnd
+
+-- Discharge lines record which credit (related_transaction_id) paid which debit (transaction_id);
+-- they move nothing between the opening and closing balance
+-- +goose StatementBegin
+CREATE TABLE statement_lines (
+    id BIGSERIAL PRIMARY KEY,
+    statement_id BIGINT NOT NULL REFERENCES statements(id),
+    kind TEXT NOT NULL,
+    transaction_id BIGINT NOT NULL REFERENCES transactions(id),
+    related_transaction_id BIGINT REFERENCES transactions(id),
+    amount NUMERIC(15,2) NOT NULL,
+    event_date TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_statement_lines_statement_id ON statement_lines (statement_id);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS statement_lines;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS statements;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS updatedat_timestamp_trigger_billing_cycles ON billing_cycles;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS billing_cycles;
+-- +goose StatementEnd
@@ -0,0 +1,110 @@
+-- +goose Up
+
+-- Charges levied by the daily accrual job; they are debts like purchases and discharged by credits
+-- +goose StatementBegin
+INSERT INTO
+    operation_types (id, description)
+VALUES
+    (7, 'Interest Charge'),
+    (8, 'Late Fee');
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+SELECT setval(pg_get_serial_sequence('operation_types', 'id'), (SELECT MAX(id) FROM operation_types));
+-- +goose StatementEnd
+
+-- A policy with an operation type prices the past-due debts of that type; the product's policy without
+-- one prices its other debts and its late fees. Debts accrue from grace_days after their statement is due.
+-- +goose StatementBegin
+CREATE TABLE accrual_policies (
+    id BIGSERIAL PRIMARY KEY,
+    product TEXT NOT NULL CHECK (product IN ('credit', 'prepaid')),
+    operation_type_id BIGINT REFERENCES operation_types(id),
+    apr NUMERIC(7,4) NOT NULL DEFAULT 0 CHECK (apr >= 0),
+    late_fee_amount NUMERIC(15,2) NOT NULL DEFAULT 0 CHECK (late_fee_amount >= 0),
+    late_fee_percent NUMERIC(7,4) NOT NULL DEFAULT 0 CHECK (late_fee_percent >= 0),
+    grace_days SMALLINT NOT NULL DEFAULT 0 CHECK (grace_days >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX accrual_policies_product_key ON accrual_policies (product) WHERE operation_type_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX accrual_policies_product_operation_type_id_key ON accrual_policies (product, operation_type_id)
+    WHERE operation_type_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER updatedat_timestamp_trigger_accrual_policies
+    BEFORE UPDATE ON accrual_policies
+    FOR EACH ROW EXECUTE FUNCTION updatedat_timestamp();
+-- +goose StatementEnd
+
+-- Withdrawals are cash advances and carry a higher rate
+-- +goose StatementBegin
+INSERT INTO
+    accrual_policies (product, operation_type_id, apr, late_fee_amount, late_fee_percent, grace_days)
+VALUES
+    ('credit', NULL, 0.2400, 25.00, 0, 0),
+    ('credit', 3, 0.3600, 0, 0, 0);
+-- +goose StatementEnd
+
+-- One run per account and day makes the accrual job idempotent
+-- +goose StatementBegin
+CREATE TABLE accrual_runs (
+    account_id BIGINT NOT NULL REFERENCES accounts(id),
+    accrual_date DATE NOT NULL,
+    correlation_id TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT accrual_runs_pkey PRIMARY KEY (account_id, accrual_date)
+);
+-- +goose StatementEnd
+
+-- The charges of a run; a late fee is levied once per statement
+-- +goose StatementBegin
+CREATE TABLE accruals (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL,
+    accrual_date DATE NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('interest', 'late_fee')),
+    transaction_id BIGINT NOT NULL UNIQUE REFERENCES transactions(id),
+    statement_id BIGINT REFERENCES statements(id),
+    amount NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    FOREIGN KEY (account_id, accrual_date) REFERENCES accrual_runs (account_id, accrual_date),
+    CHECK ((kind = 'late_fee') = (statement_id IS NOT NULL))
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX accruals_statement_id_late_fee_key ON accruals (statement_id) WHERE kind = 'late_fee';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_accruals_account_id ON accruals (account_id);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS accruals;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS accrual_runs;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS updatedat_timestamp_trigger_accrual_policies ON accrual_policies;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS accrual_policies;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DELETE FROM operation_types WHERE id IN (7, 8);
+-- +goose StatementEnd
@@ -0,0 +1,101 @@
+-- +goose Up
+
+-- Fees posted alongside the transaction that incurred them; they are debts like purchases
+-- +goose StatementBegin
+INSERT INTO
+    operation_types (id, description)
+VALUES
+    (9, 'Fee');
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+SELECT setval(pg_get_serial_sequence('operation_types', 'id'), (SELECT MAX(id) FROM operation_types));
+-- +goose StatementEnd
+
+-- A fee names the transaction that incurred it, and only fees do
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN parent_transaction_id BIGINT REFERENCES transactions(id),
+    ADD CONSTRAINT transactions_parent_transaction_id_check CHECK ((operation_type_id = 9) = (parent_transaction_id IS NOT NULL));
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_transactions_parent_transaction_id ON transactions (parent_transaction_id) WHERE parent_transaction_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- A rule prices the fee of the transactions of an operation type on accounts of a product: flat_amount,
+-- percent of the amount, or by the first tier the amount falls in. The fee is then bounded by
+-- min_amount and max_amount when set.
+-- +goose StatementBegin
+CREATE TABLE fee_rules (
+    id BIGSERIAL PRIMARY KEY,
+    product TEXT NOT NULL CHECK (product IN ('credit', 'prepaid')),
+    operation_type_id BIGINT NOT NULL REFERENCES operation_types(id),
+    kind TEXT NOT NULL CHECK (kind IN ('flat', 'percent', 'tiered')),
+    flat_amount NUMERIC(15,2) NOT NULL DEFAULT 0 CHECK (flat_amount >= 0),
+    percent NUMERIC(7,4) NOT NULL DEFAULT 0 CHECK (percent >= 0),
+    min_amount NUMERIC(15,2) CHECK (min_amount >= 0),
+    max_amount NUMERIC(15,2) CHECK (max_amount >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fee_rules_product_operation_type_id_key UNIQUE (product, operation_type_id),
+    CHECK (min_amount IS NULL OR max_amount IS NULL OR min_amount <= max_amount)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER updatedat_timestamp_trigger_fee_rules
+    BEFORE UPDATE ON fee_rules
+    FOR EACH ROW EXECUTE FUNCTION updatedat_timestamp();
+-- +goose StatementEnd
+
+-- The tiers of a tiered rule; a tier prices amounts up to up_to, the last one without a bound
+-- +goose StatementBegin
+CREATE TABLE fee_rule_tiers (
+    id BIGSERIAL PRIMARY KEY,
+    fee_rule_id BIGINT NOT NULL REFERENCES fee_rules(id) ON DELETE CASCADE,
+    up_to NUMERIC(15,2) CHECK (up_to > 0),
+    flat_amount NUMERIC(15,2) NOT NULL DEFAULT 0 CHECK (flat_amount >= 0),
+    percent NUMERIC(7,4) NOT NULL DEFAULT 0 CHECK (percent >= 0),
+    CONSTRAINT fee_rule_tiers_fee_rule_id_up_to_key UNIQUE NULLS NOT DISTINCT (fee_rule_id, up_to)
+);
+-- +goose StatementEnd
+
+-- Withdrawals carry a cash advance fee on credit accounts and an ATM fee on prepaid ones
+-- +goose StatementBegin
+INSERT INTO
+    fee_rules (product, operation_type_id, kind, flat_amount, percent, min_amount, max_amount)
+VALUES
+    ('credit', 3, 'percent', 0, 0.0300, 5.00, NULL),
+    ('prepaid', 3, 'tiered', 0, 0, NULL, 10.00);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO
+    fee_rule_tiers (fee_rule_id, up_to, flat_amount, percent)
+SELECT id, t.up_to, t.flat_amount, t.percent
+FROM fee_rules, (VALUES (100.00, 1.00, 0), (NULL, 0, 0.0100)) AS t(up_to, flat_amount, percent)
+WHERE product = 'prepaid' AND operation_type_id = 3;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS fee_rule_tiers;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS updatedat_timestamp_trigger_fee_rules ON fee_rules;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS fee_rules;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions DROP COLUMN IF EXISTS parent_transaction_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DELETE FROM operation_types WHERE id = 9;
+-- +goose StatementEnd
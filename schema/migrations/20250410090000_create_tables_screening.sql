@@ -0,0 +1,77 @@
+-- +goose Up
+
+-- A rule screens the transactions of its operation types before they are posted, on accounts of its product
+-- or of every product when product is NULL, and on accounts younger than max_account_age_days when set.
+-- A count rule trips when the transaction would make more than threshold such transactions within the last
+-- window_hours, an amount rule when it would bring their amounts above threshold, and a single_amount rule
+-- when the transaction alone is above threshold. A tripped rule denies the transaction or flags it for
+-- review, reporting code as the reason.
+-- +goose StatementBegin
+CREATE TABLE screening_rules (
+    id BIGSERIAL PRIMARY KEY,
+    code TEXT NOT NULL,
+    product TEXT CHECK (product IN ('credit', 'prepaid')),
+    operation_type_ids BIGINT[] NOT NULL CHECK (cardinality(operation_type_ids) > 0),
+    kind TEXT NOT NULL CHECK (kind IN ('count', 'amount', 'single_amount')),
+    window_hours INTEGER NOT NULL DEFAULT 0 CHECK (window_hours >= 0),
+    threshold NUMERIC(15,2) NOT NULL CHECK (threshold >= 0),
+    max_account_age_days INTEGER CHECK (max_account_age_days > 0),
+    action TEXT NOT NULL CHECK (action IN ('deny', 'review')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT screening_rules_code_key UNIQUE (code),
+    CHECK ((kind = 'single_amount') = (window_hours = 0))
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER updatedat_timestamp_trigger_screening_rules
+    BEFORE UPDATE ON screening_rules
+    FOR EACH ROW EXECUTE FUNCTION updatedat_timestamp();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO
+    screening_rules (code, product, operation_type_ids, kind, window_hours, threshold, max_account_age_days, action)
+VALUES
+    ('withdrawal_velocity', NULL, '{3}', 'count', 24, 5, NULL, 'deny'),
+    ('daily_debit_limit', NULL, '{1,2,3,5}', 'amount', 24, 5000.00, NULL, 'deny'),
+    ('new_account_large_debit', NULL, '{1,2,3}', 'single_amount', 0, 1000.00, 7, 'deny'),
+    ('large_debit', NULL, '{1,2,3}', 'single_amount', 0, 2500.00, NULL, 'review');
+-- +goose StatementEnd
+
+-- Every screening of a transaction, kept for audit. Denied transactions are never posted and have no
+-- transaction_id; reason_codes are the codes of the rules that tripped.
+-- +goose StatementBegin
+CREATE TABLE screening_evaluations (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES accounts(id),
+    operation_type_id BIGINT NOT NULL REFERENCES operation_types(id),
+    amount NUMERIC(15,2) NOT NULL,
+    outcome TEXT NOT NULL CHECK (outcome IN ('allow', 'deny', 'review')),
+    reason_codes TEXT[] NOT NULL DEFAULT '{}',
+    transaction_id BIGINT UNIQUE REFERENCES transactions(id),
+    correlation_id TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CHECK ((outcome = 'deny') = (transaction_id IS NULL))
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX idx_screening_evaluations_account_id ON screening_evaluations (account_id);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS screening_evaluations;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS updatedat_timestamp_trigger_screening_rules ON screening_rules;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS screening_rules;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+
+-- The processor's reference of a purchase, if any, and the earlier transaction a transaction was posted
+-- despite looking like a resend of, when the service flags rather than rejects suspected duplicates
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN merchant_reference TEXT,
+    ADD COLUMN duplicate_of_transaction_id BIGINT REFERENCES transactions(id);
+-- +goose StatementEnd
+
+-- Suspected duplicates are looked up by account, operation type and amount within a recent window
+-- +goose StatementBegin
+CREATE INDEX idx_transactions_duplicate_lookup ON transactions (account_id, operation_type_id, amount, event_date);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_duplicate_lookup;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS duplicate_of_transaction_id,
+    DROP COLUMN IF EXISTS merchant_reference;
+-- +goose StatementEnd
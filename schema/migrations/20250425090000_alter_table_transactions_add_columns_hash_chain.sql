@@ -0,0 +1,27 @@
+-- +goose Up
+
+-- Tamper evidence: each transaction carries the SHA-256 of its immutable fields and of the hash of the
+-- account's previous transaction, so editing, removing or reordering a past transaction breaks the chain.
+-- Transactions posted before the chain existed keep NULL hashes.
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN prev_hash TEXT,
+    ADD COLUMN hash TEXT;
+-- +goose StatementEnd
+
+-- A link can only be extended once, so the chain of an account cannot fork
+-- +goose StatementBegin
+CREATE UNIQUE INDEX idx_transactions_chain_link ON transactions (account_id, prev_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_chain_link;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS hash,
+    DROP COLUMN IF EXISTS prev_hash;
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+
+-- Transactions submitted for asynchronous processing, queued until a worker posts them. A request is pending
+-- until processed, then succeeded with the transaction it posted or failed with an error_code and
+-- error_detail. amount is as submitted, unsigned. The correlation id and principal of the submission are
+-- carried over to the transaction and its audit events.
+-- +goose StatementBegin
+CREATE TABLE transaction_requests (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES accounts(id),
+    operation_type_id BIGINT NOT NULL REFERENCES operation_types(id),
+    amount NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    merchant_reference TEXT,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    transaction_id BIGINT UNIQUE REFERENCES transactions(id),
+    error_code TEXT,
+    error_detail TEXT,
+    correlation_id TEXT,
+    principal TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CHECK ((status = 'succeeded') = (transaction_id IS NOT NULL)),
+    CHECK ((status = 'failed') = (error_code IS NOT NULL))
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER updatedat_timestamp_trigger_transaction_requests
+    BEFORE UPDATE ON transaction_requests
+    FOR EACH ROW EXECUTE FUNCTION updatedat_timestamp();
+-- +goose StatementEnd
+
+-- Workers claim the oldest pending request of an account, so the queue is the pending requests by account
+-- +goose StatementBegin
+CREATE INDEX idx_transaction_requests_pending ON transaction_requests (account_id, id) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transaction_requests_pending;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS updatedat_timestamp_trigger_transaction_requests ON transaction_requests;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TABLE IF EXISTS transaction_requests;
+-- +goose StatementEnd
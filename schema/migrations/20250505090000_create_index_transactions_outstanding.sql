@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- A payment discharge reads the account's unpaid debts in event order; once paid off, a debt leaves the index
+-- +goose StatementBegin
+CREATE INDEX idx_transactions_outstanding ON transactions (account_id, event_date, id) WHERE balance < 0;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_outstanding;
+-- +goose StatementEnd
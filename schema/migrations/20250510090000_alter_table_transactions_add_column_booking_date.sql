@@ -0,0 +1,25 @@
+-- +goose Up
+
+-- A transaction is booked when the service posts it, which is later than its event date when back-dated;
+-- transactions posted so far were booked as they took place
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ADD COLUMN booking_date TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+UPDATE transactions SET booking_date = event_date;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+ALTER TABLE transactions
+    ALTER COLUMN booking_date SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN booking_date SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+ALTER TABLE transactions
+    DROP COLUMN booking_date;
+-- +goose StatementEnd
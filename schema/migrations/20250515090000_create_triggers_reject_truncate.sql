@@ -0,0 +1,35 @@
+-- +goose Up
+
+-- Row triggers keep the audit log and the ledger append-only against UPDATE and DELETE, but TRUNCATE fires
+-- no row trigger: statement triggers turn it down too
+-- +goose StatementBegin
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_event_change();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER journal_entries_no_truncate
+    BEFORE TRUNCATE ON journal_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_append_only();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER postings_no_truncate
+    BEFORE TRUNCATE ON postings
+    FOR EACH STATEMENT EXECUTE FUNCTION ledger_append_only();
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS postings_no_truncate ON postings;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS journal_entries_no_truncate ON journal_entries;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events;
+-- +goose StatementEnd